@@ -28,4 +28,19 @@ const (
 	// created using the endpoint name (i.e. instead of generating a service name for all endpoints,
 	// this endpoint should be statically accessible)
 	DiscoverableAttribute EndpointAttribute = "discoverable"
+
+	// ServiceTypeAttribute defines how a public endpoint that uses the tcp or udp protocol is exposed outside
+	// of the cluster, as such endpoints cannot be served by routes or ingresses. Supported values are "NodePort"
+	// (default) and "LoadBalancer".
+	ServiceTypeAttribute EndpointAttribute = "serviceType"
+
+	// AnnotationsAttribute defines additional annotations (a map of string keys to string values) to be added
+	// to the ingress or route created for a public endpoint, or to the NodePort or LoadBalancer service created for a
+	// public tcp or udp endpoint. These take precedence over the default annotations applied by the routing solver.
+	AnnotationsAttribute EndpointAttribute = "annotations"
+
+	// HealthCheckPathAttribute defines the path that should be used when checking whether an endpoint is
+	// reachable, e.g. "/healthz". If unset, the endpoint's URL is used as-is. For the main endpoint, this
+	// path is used when checking whether the workspace is ready instead of the default "healthz".
+	HealthCheckPathAttribute EndpointAttribute = "healthCheckPath"
 )
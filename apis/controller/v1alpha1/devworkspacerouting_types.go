@@ -29,6 +29,10 @@ type DevWorkspaceRoutingSpec struct {
 	Endpoints map[string]EndpointList `json:"endpoints"`
 	// Selector that should be used by created services to point to the devworkspace Pod
 	PodSelector map[string]string `json:"podSelector"`
+	// Selectors for pods of machines that do not run in the devworkspace Pod, keyed by machine name. Services created
+	// for the endpoints of these machines should use the machine's selector instead of podSelector.
+	// +optional
+	MachinePodSelectors map[string]map[string]string `json:"machinePodSelectors,omitempty"`
 }
 
 type DevWorkspaceRoutingClass string
@@ -38,6 +42,7 @@ const (
 	DevWorkspaceRoutingCluster     DevWorkspaceRoutingClass = "cluster"
 	DevWorkspaceRoutingClusterTLS  DevWorkspaceRoutingClass = "cluster-tls"
 	DevWorkspaceRoutingWebTerminal DevWorkspaceRoutingClass = "web-terminal"
+	DevWorkspaceRoutingTunnel      DevWorkspaceRoutingClass = "tunnel"
 )
 
 // DevWorkspaceRoutingStatus defines the observed state of DevWorkspaceRouting
@@ -49,8 +54,27 @@ type DevWorkspaceRoutingStatus struct {
 	ExposedEndpoints map[string]ExposedEndpointList `json:"exposedEndpoints,omitempty"`
 	// Routing reconcile phase
 	Phase DevWorkspaceRoutingPhase `json:"phase,omitempty"`
+	// Claim identifies the controller handling this routing. Controllers handling a routing should set the claim
+	// and renew its heartbeat while the routing is not ready; a claimed routing whose heartbeat is older than the
+	// claim timeout is considered unhandled. Controllers that do not claim routings must set the phase instead,
+	// which implicitly claims the routing without a heartbeat. A routing that has neither a claim nor a phase
+	// once the claim timeout expires is considered unhandled.
+	// +optional
+	Claim *RoutingClaim `json:"claim,omitempty"`
 	// Message is a user-readable message explaining the current phase (e.g. reason for failure)
 	Message string `json:"message,omitempty"`
+	// Reachability of exposed endpoints, as observed by probing each endpoint's URL. Only populated
+	// if endpoint probes are enabled in the controller configuration.
+	// +optional
+	EndpointStatuses []EndpointStatus `json:"endpointStatuses,omitempty"`
+}
+
+// RoutingClaim is set by the controller handling a DevWorkspaceRouting
+type RoutingClaim struct {
+	// Controller is the name of the controller handling the routing
+	Controller string `json:"controller"`
+	// HeartbeatTime is the last time the controller confirmed that it is handling the routing
+	HeartbeatTime metav1.Time `json:"heartbeatTime"`
 }
 
 // Valid phases for devworkspacerouting
@@ -72,6 +96,20 @@ type ExposedEndpoint struct {
 	Attributes devfileAttr.Attributes `json:"attributes,omitempty"`
 }
 
+type EndpointStatus struct {
+	// Name of the exposed endpoint
+	Name string `json:"name"`
+	// Public URL of the exposed endpoint
+	Url string `json:"url"`
+	// Whether the endpoint responded successfully to the last probe. Unset if readiness is unknown, e.g. because the
+	// endpoint has not been probed yet or its protocol cannot be probed.
+	// +optional
+	Ready *bool `json:"ready,omitempty"`
+	// Message is a user-readable message explaining why the endpoint is not ready
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
 type EndpointList []dw.Endpoint
 
 type ExposedEndpointList []ExposedEndpoint
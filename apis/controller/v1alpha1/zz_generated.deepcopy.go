@@ -109,6 +109,23 @@ func (in *DevWorkspaceRoutingSpec) DeepCopyInto(out *DevWorkspaceRoutingSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.MachinePodSelectors != nil {
+		in, out := &in.MachinePodSelectors, &out.MachinePodSelectors
+		*out = make(map[string]map[string]string, len(*in))
+		for key, val := range *in {
+			var outVal map[string]string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make(map[string]string, len(*in))
+				for key, val := range *in {
+					(*out)[key] = val
+				}
+			}
+			(*out)[key] = outVal
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DevWorkspaceRoutingSpec.
@@ -146,6 +163,18 @@ func (in *DevWorkspaceRoutingStatus) DeepCopyInto(out *DevWorkspaceRoutingStatus
 			(*out)[key] = outVal
 		}
 	}
+	if in.Claim != nil {
+		in, out := &in.Claim, &out.Claim
+		*out = new(RoutingClaim)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.EndpointStatuses != nil {
+		in, out := &in.EndpointStatuses, &out.EndpointStatuses
+		*out = make([]EndpointStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DevWorkspaceRoutingStatus.
@@ -179,6 +208,26 @@ func (in EndpointList) DeepCopy() EndpointList {
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EndpointStatus) DeepCopyInto(out *EndpointStatus) {
+	*out = *in
+	if in.Ready != nil {
+		in, out := &in.Ready, &out.Ready
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EndpointStatus.
+func (in *EndpointStatus) DeepCopy() *EndpointStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(EndpointStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ExposedEndpoint) DeepCopyInto(out *ExposedEndpoint) {
 	*out = *in
@@ -279,6 +328,13 @@ func (in *PodAdditions) DeepCopyInto(out *PodAdditions) {
 			(*out)[key] = val
 		}
 	}
+	if in.HostAliases != nil {
+		in, out := &in.HostAliases, &out.HostAliases
+		*out = make([]v1.HostAlias, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodAdditions.
@@ -290,3 +346,19 @@ func (in *PodAdditions) DeepCopy() *PodAdditions {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RoutingClaim) DeepCopyInto(out *RoutingClaim) {
+	*out = *in
+	in.HeartbeatTime.DeepCopyInto(&out.HeartbeatTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RoutingClaim.
+func (in *RoutingClaim) DeepCopy() *RoutingClaim {
+	if in == nil {
+		return nil
+	}
+	out := new(RoutingClaim)
+	in.DeepCopyInto(out)
+	return out
+}
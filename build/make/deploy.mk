@@ -51,6 +51,14 @@ else
 endif
 
 
+### install_tunnel_server: Installs the tunnel server used by the tunnel routing class. The controller must be installed first
+install_tunnel_server: _print_vars generate_deployment
+ifeq ($(PLATFORM),kubernetes)
+	$(K8S_CLI) apply -f deploy/current/kubernetes/tunnel-server.yaml
+else
+	$(K8S_CLI) apply -f deploy/current/openshift/tunnel-server.yaml
+endif
+
 ### install_plugin_templates: Deploys the sample plugin templates to namespace devworkspace-plugins:
 install_plugin_templates: _print_vars
 	$(K8S_CLI) create namespace devworkspace-plugins || true
@@ -73,8 +81,10 @@ uninstall: generate_deployment
 	$(K8S_CLI) delete devworkspaceroutings.controller.devfile.io --all-namespaces --all --wait || true
 
 ifeq ($(PLATFORM),kubernetes)
+	$(K8S_CLI) delete --ignore-not-found -f deploy/current/kubernetes/tunnel-server.yaml || true
 	$(K8S_CLI) delete --ignore-not-found -f deploy/current/kubernetes/combined.yaml || true
 else
+	$(K8S_CLI) delete --ignore-not-found -f deploy/current/openshift/tunnel-server.yaml || true
 	$(K8S_CLI) delete --ignore-not-found -f deploy/current/openshift/combined.yaml || true
 endif
 
@@ -0,0 +1,51 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package devworkspacerouting
+
+import (
+	"sort"
+	"strings"
+
+	maputils "github.com/devfile/devworkspace-operator/internal/map"
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+)
+
+// syncManagedAnnotations returns the annotations an object on the cluster should have given the annotations the
+// controller computed for it. The keys of specAnnotations are recorded in the
+// constants.DevWorkspaceManagedAnnotationsAnnotation annotation, so that annotations the controller set previously but
+// that are no longer in specAnnotations are removed, while annotations added by others are preserved. Returns whether
+// the annotations differ from clusterAnnotations. Pass nil clusterAnnotations for objects that are being created.
+func syncManagedAnnotations(specAnnotations, clusterAnnotations map[string]string) (annotations map[string]string, changed bool) {
+	annotations = map[string]string{}
+	for k, v := range clusterAnnotations {
+		annotations[k] = v
+	}
+	if previouslyManaged := clusterAnnotations[constants.DevWorkspaceManagedAnnotationsAnnotation]; previouslyManaged != "" {
+		for _, key := range strings.Split(previouslyManaged, ",") {
+			if _, ok := specAnnotations[key]; !ok {
+				delete(annotations, key)
+			}
+		}
+	}
+	var managed []string
+	for k, v := range specAnnotations {
+		if k == constants.DevWorkspaceManagedAnnotationsAnnotation {
+			continue
+		}
+		annotations[k] = v
+		managed = append(managed, k)
+	}
+	sort.Strings(managed)
+	annotations[constants.DevWorkspaceManagedAnnotationsAnnotation] = strings.Join(managed, ",")
+	return annotations, !maputils.Equal(annotations, clusterAnnotations)
+}
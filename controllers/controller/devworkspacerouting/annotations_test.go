@@ -0,0 +1,119 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package devworkspacerouting
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+)
+
+func TestSyncManagedAnnotations(t *testing.T) {
+	tests := []struct {
+		name                string
+		specAnnotations     map[string]string
+		clusterAnnotations  map[string]string
+		expectedAnnotations map[string]string
+		expectedChanged     bool
+	}{
+		{
+			name:            "Records managed annotations for new objects",
+			specAnnotations: map[string]string{"b": "1", "a": "2"},
+			expectedAnnotations: map[string]string{
+				"a": "2",
+				"b": "1",
+				constants.DevWorkspaceManagedAnnotationsAnnotation: "a,b",
+			},
+			expectedChanged: true,
+		},
+		{
+			name:            "Reports no change when annotations are in sync",
+			specAnnotations: map[string]string{"a": "1"},
+			clusterAnnotations: map[string]string{
+				"a": "1",
+				constants.DevWorkspaceManagedAnnotationsAnnotation: "a",
+			},
+			expectedAnnotations: map[string]string{
+				"a": "1",
+				constants.DevWorkspaceManagedAnnotationsAnnotation: "a",
+			},
+			expectedChanged: false,
+		},
+		{
+			name:            "Updates changed annotation values",
+			specAnnotations: map[string]string{"a": "2"},
+			clusterAnnotations: map[string]string{
+				"a": "1",
+				constants.DevWorkspaceManagedAnnotationsAnnotation: "a",
+			},
+			expectedAnnotations: map[string]string{
+				"a": "2",
+				constants.DevWorkspaceManagedAnnotationsAnnotation: "a",
+			},
+			expectedChanged: true,
+		},
+		{
+			name:            "Removes annotations that are no longer managed",
+			specAnnotations: map[string]string{"a": "1"},
+			clusterAnnotations: map[string]string{
+				"a": "1",
+				"b": "1",
+				constants.DevWorkspaceManagedAnnotationsAnnotation: "a,b",
+			},
+			expectedAnnotations: map[string]string{
+				"a": "1",
+				constants.DevWorkspaceManagedAnnotationsAnnotation: "a",
+			},
+			expectedChanged: true,
+		},
+		{
+			name:            "Preserves annotations not managed by the controller",
+			specAnnotations: map[string]string{"a": "1"},
+			clusterAnnotations: map[string]string{
+				"a":        "1",
+				"b":        "1",
+				"external": "value",
+				constants.DevWorkspaceManagedAnnotationsAnnotation: "a,b",
+			},
+			expectedAnnotations: map[string]string{
+				"a":        "1",
+				"external": "value",
+				constants.DevWorkspaceManagedAnnotationsAnnotation: "a",
+			},
+			expectedChanged: true,
+		},
+		{
+			name:            "Starts managing annotations on objects without managed annotations",
+			specAnnotations: map[string]string{"a": "1"},
+			clusterAnnotations: map[string]string{
+				"a":        "1",
+				"external": "value",
+			},
+			expectedAnnotations: map[string]string{
+				"a":        "1",
+				"external": "value",
+				constants.DevWorkspaceManagedAnnotationsAnnotation: "a",
+			},
+			expectedChanged: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations, changed := syncManagedAnnotations(tt.specAnnotations, tt.clusterAnnotations)
+			assert.Equal(t, tt.expectedAnnotations, annotations)
+			assert.Equal(t, tt.expectedChanged, changed)
+		})
+	}
+}
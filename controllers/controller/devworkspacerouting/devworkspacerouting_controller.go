@@ -30,12 +30,14 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/api/extensions/v1beta1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	controllerv1alpha1 "github.com/devfile/devworkspace-operator/apis/controller/v1alpha1"
 )
@@ -46,6 +48,9 @@ var (
 
 const devWorkspaceRoutingFinalizer = "devworkspacerouting.controller.devfile.io"
 
+// routingClaimController is the name used by this controller when claiming DevWorkspaceRoutings
+const routingClaimController = "devworkspacerouting.controller.devfile.io"
+
 // DevWorkspaceRoutingReconciler reconciles a DevWorkspaceRouting object
 type DevWorkspaceRoutingReconciler struct {
 	client.Client
@@ -53,26 +58,30 @@ type DevWorkspaceRoutingReconciler struct {
 	Scheme *runtime.Scheme
 	// SolverGetter will be used to get solvers for a particular devWorkspaceRouting
 	SolverGetter solvers.RoutingSolverGetter
+	// prober probes exposed endpoints if endpoint probes are enabled
+	prober endpointProber
 }
 
 // +kubebuilder:rbac:groups=controller.devfile.io,resources=devworkspaceroutings,verbs=*
 // +kubebuilder:rbac:groups=controller.devfile.io,resources=devworkspaceroutings/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups="",resources=services,verbs=*
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
 // +kubebuilder:rbac:groups=extensions,resources=ingresses,verbs=*
 // +kubebuilder:rbac:groups=route.openshift.io,resources=routes,verbs=*
 // +kubebuidler:rbac:groups=route.openshift.io,resources=routes/status,verbs=get,list,watch
 // +kubebuilder:rbac:groups=route.openshift.io,resources=routes/custom-host,verbs=create
 
-func (r *DevWorkspaceRoutingReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+func (r *DevWorkspaceRoutingReconciler) Reconcile(req ctrl.Request) (result ctrl.Result, err error) {
 	ctx := context.Background()
 
 	reqLogger := r.Log.WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name)
 
 	// Fetch the DevWorkspaceRouting instance
 	instance := &controllerv1alpha1.DevWorkspaceRouting{}
-	err := r.Get(ctx, req.NamespacedName, instance)
+	err = r.Get(ctx, req.NamespacedName, instance)
 	if err != nil {
 		if k8sErrors.IsNotFound(err) {
+			r.prober.forget(req.NamespacedName)
 			// Request object not found, could have been deleted after reconcile request.
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
 			// Return and don't requeue
@@ -100,6 +109,7 @@ func (r *DevWorkspaceRoutingReconciler) Reconcile(req ctrl.Request) (ctrl.Result
 	// indicated by the deletion timestamp being set.
 	if instance.GetDeletionTimestamp() != nil {
 		reqLogger.Info("Finalizing DevWorkspaceRouting")
+		r.prober.forget(req.NamespacedName)
 		return reconcile.Result{}, r.finalize(solver, instance)
 	}
 
@@ -107,15 +117,28 @@ func (r *DevWorkspaceRoutingReconciler) Reconcile(req ctrl.Request) (ctrl.Result
 		return reconcile.Result{}, nil
 	}
 
+	if err := r.renewClaim(instance); err != nil {
+		return reconcile.Result{}, err
+	}
+	defer func() {
+		// Requeue to renew the claim before it expires while the routing is not ready
+		heartbeatInterval := config.ControllerCfg.GetRoutingHeartbeatInterval()
+		if err == nil && instance.Status.Phase == controllerv1alpha1.RoutingPreparing &&
+			(result.RequeueAfter == 0 || result.RequeueAfter > heartbeatInterval) {
+			result.RequeueAfter = heartbeatInterval
+		}
+	}()
+
 	// Add finalizer for this CR if not already present
 	if err := r.setFinalizer(reqLogger, solver, instance); err != nil {
 		return reconcile.Result{}, err
 	}
 
 	workspaceMeta := solvers.DevWorkspaceMetadata{
-		DevWorkspaceId: instance.Spec.DevWorkspaceId,
-		Namespace:      instance.Namespace,
-		PodSelector:    instance.Spec.PodSelector,
+		DevWorkspaceId:      instance.Spec.DevWorkspaceId,
+		Namespace:           instance.Namespace,
+		PodSelector:         instance.Spec.PodSelector,
+		MachinePodSelectors: instance.Spec.MachinePodSelectors,
 	}
 
 	restrictedAccess, setRestrictedAccess := instance.Annotations[constants.DevWorkspaceRestrictedAccessAnnotation]
@@ -128,7 +151,7 @@ func (r *DevWorkspaceRoutingReconciler) Reconcile(req ctrl.Request) (ctrl.Result
 				duration = 1 * time.Second
 			}
 			reqLogger.Info("controller not ready for devworkspace routing. Retrying", "DelayMs", duration.Milliseconds())
-			return reconcile.Result{RequeueAfter: duration}, r.reconcileStatus(instance, nil, nil, false, "Waiting for DevWorkspaceRouting controller to be ready")
+			return reconcile.Result{RequeueAfter: duration}, r.reconcileStatus(instance, nil, nil, nil, false, "Waiting for DevWorkspaceRouting controller to be ready")
 		}
 
 		var invalid *solvers.RoutingInvalid
@@ -175,10 +198,10 @@ func (r *DevWorkspaceRoutingReconciler) Reconcile(req ctrl.Request) (ctrl.Result
 	servicesInSync, clusterServices, err := r.syncServices(instance, services)
 	if err != nil {
 		reqLogger.Error(err, "Error syncing services")
-		return reconcile.Result{Requeue: true}, r.reconcileStatus(instance, nil, nil, false, "Preparing services")
+		return reconcile.Result{Requeue: true}, r.reconcileStatus(instance, nil, nil, nil, false, "Preparing services")
 	} else if !servicesInSync {
 		reqLogger.Info("Services not in sync")
-		return reconcile.Result{Requeue: true}, r.reconcileStatus(instance, nil, nil, false, "Preparing services")
+		return reconcile.Result{Requeue: true}, r.reconcileStatus(instance, nil, nil, nil, false, "Preparing services")
 	}
 
 	clusterRoutingObj := solvers.RoutingObjects{
@@ -189,20 +212,20 @@ func (r *DevWorkspaceRoutingReconciler) Reconcile(req ctrl.Request) (ctrl.Result
 		routesInSync, clusterRoutes, err := r.syncRoutes(instance, routes)
 		if err != nil {
 			reqLogger.Error(err, "Error syncing routes")
-			return reconcile.Result{Requeue: true}, r.reconcileStatus(instance, nil, nil, false, "Preparing routes")
+			return reconcile.Result{Requeue: true}, r.reconcileStatus(instance, nil, nil, nil, false, "Preparing routes")
 		} else if !routesInSync {
 			reqLogger.Info("Routes not in sync")
-			return reconcile.Result{Requeue: true}, r.reconcileStatus(instance, nil, nil, false, "Preparing routes")
+			return reconcile.Result{Requeue: true}, r.reconcileStatus(instance, nil, nil, nil, false, "Preparing routes")
 		}
 		clusterRoutingObj.Routes = clusterRoutes
 	} else {
 		ingressesInSync, clusterIngresses, err := r.syncIngresses(instance, ingresses)
 		if err != nil {
 			reqLogger.Error(err, "Error syncing ingresses")
-			return reconcile.Result{Requeue: true}, r.reconcileStatus(instance, nil, nil, false, "Preparing ingresses")
+			return reconcile.Result{Requeue: true}, r.reconcileStatus(instance, nil, nil, nil, false, "Preparing ingresses")
 		} else if !ingressesInSync {
 			reqLogger.Info("Ingresses not in sync")
-			return reconcile.Result{Requeue: true}, r.reconcileStatus(instance, nil, nil, false, "Preparing ingresses")
+			return reconcile.Result{Requeue: true}, r.reconcileStatus(instance, nil, nil, nil, false, "Preparing ingresses")
 		}
 		clusterRoutingObj.Ingresses = clusterIngresses
 	}
@@ -213,7 +236,26 @@ func (r *DevWorkspaceRoutingReconciler) Reconcile(req ctrl.Request) (ctrl.Result
 		return reconcile.Result{}, r.markRoutingFailed(instance, fmt.Sprintf("Could not get exposed endpoints for DevWorkspace: %s", err))
 	}
 
-	return reconcile.Result{}, r.reconcileStatus(instance, &routingObjects, exposedEndpoints, endpointsAreReady, "")
+	var endpointStatuses []controllerv1alpha1.EndpointStatus
+	if endpointsAreReady && config.ControllerCfg.GetEndpointProbesEnabled() {
+		var recheckEndpoints bool
+		endpointStatuses, recheckEndpoints, err = r.getEndpointStatuses(instance, exposedEndpoints)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		// Endpoints are probed in the background; results are picked up on the next reconcile. Endpoint
+		// reachability is informational and does not block the routing from being ready. Endpoints are probed
+		// periodically even once they are all ready, to notice endpoints that become unreachable.
+		probeInterval := endpointProbeReadyInterval
+		if recheckEndpoints {
+			probeInterval = endpointProbeRequeueInterval
+		}
+		if result.RequeueAfter == 0 || result.RequeueAfter > probeInterval {
+			result.RequeueAfter = probeInterval
+		}
+	}
+
+	return result, r.reconcileStatus(instance, &routingObjects, exposedEndpoints, endpointStatuses, endpointsAreReady, "")
 }
 
 // setFinalizer ensures a finalizer is set on a devWorkspaceRouting instance; no-op if finalizer is already present.
@@ -253,6 +295,21 @@ func (r *DevWorkspaceRoutingReconciler) finalize(solver solvers.RoutingSolver, i
 	return nil
 }
 
+// renewClaim sets the claim on a DevWorkspaceRouting to signal that this controller handles it, and renews the claim's
+// heartbeat if it is older than the heartbeat interval. No-op if the claim is current.
+func (r *DevWorkspaceRoutingReconciler) renewClaim(instance *controllerv1alpha1.DevWorkspaceRouting) error {
+	claim := instance.Status.Claim
+	if claim != nil && claim.Controller == routingClaimController &&
+		time.Since(claim.HeartbeatTime.Time) < config.ControllerCfg.GetRoutingHeartbeatInterval() {
+		return nil
+	}
+	instance.Status.Claim = &controllerv1alpha1.RoutingClaim{
+		Controller:    routingClaimController,
+		HeartbeatTime: metav1.Now(),
+	}
+	return r.Status().Update(context.TODO(), instance)
+}
+
 func (r *DevWorkspaceRoutingReconciler) markRoutingFailed(instance *controllerv1alpha1.DevWorkspaceRouting, message string) error {
 	instance.Status.Message = message
 	instance.Status.Phase = controllerv1alpha1.RoutingFailed
@@ -263,6 +320,7 @@ func (r *DevWorkspaceRoutingReconciler) reconcileStatus(
 	instance *controllerv1alpha1.DevWorkspaceRouting,
 	routingObjects *solvers.RoutingObjects,
 	exposedEndpoints map[string]controllerv1alpha1.ExposedEndpointList,
+	endpointStatuses []controllerv1alpha1.EndpointStatus,
 	endpointsReady bool,
 	message string) error {
 
@@ -273,13 +331,15 @@ func (r *DevWorkspaceRoutingReconciler) reconcileStatus(
 	}
 	if instance.Status.Phase == controllerv1alpha1.RoutingReady &&
 		cmp.Equal(instance.Status.PodAdditions, routingObjects.PodAdditions) &&
-		cmp.Equal(instance.Status.ExposedEndpoints, exposedEndpoints) {
+		cmp.Equal(instance.Status.ExposedEndpoints, exposedEndpoints) &&
+		cmp.Equal(instance.Status.EndpointStatuses, endpointStatuses) {
 		return nil
 	}
 	instance.Status.Phase = controllerv1alpha1.RoutingReady
 	instance.Status.Message = "DevWorkspaceRouting prepared"
 	instance.Status.PodAdditions = routingObjects.PodAdditions
 	instance.Status.ExposedEndpoints = exposedEndpoints
+	instance.Status.EndpointStatuses = endpointStatuses
 	return r.Status().Update(context.TODO(), instance)
 }
 
@@ -312,6 +372,11 @@ func (r *DevWorkspaceRoutingReconciler) SetupWithManager(mgr ctrl.Manager) error
 		For(&controllerv1alpha1.DevWorkspaceRouting{}).
 		Owns(&corev1.Service{}).
 		Owns(&v1beta1.Ingress{})
+	if config.ControllerCfg.GetEndpointProbesEnabled() {
+		// Pods are only watched to start probing endpoints as soon as the workspace pod is running
+		bld.Watches(&source.Kind{Type: &corev1.Pod{}}, workspacePodsHandler())
+		bld.WithEventFilter(podPredicates)
+	}
 	if infrastructure.IsOpenShift() {
 		bld.Owns(&routeV1.Route{})
 	}
@@ -0,0 +1,259 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package devworkspacerouting
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controllerv1alpha1 "github.com/devfile/devworkspace-operator/apis/controller/v1alpha1"
+	"github.com/devfile/devworkspace-operator/pkg/common"
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+)
+
+const (
+	endpointProbeTimeout = 3 * time.Second
+	// endpointProbeRequeueInterval is the delay between probes while some endpoints are not yet reachable
+	endpointProbeRequeueInterval = 5 * time.Second
+	// endpointProbeReadyInterval is the delay between probes once all endpoints are reachable, so that endpoints that
+	// stop being reachable are noticed
+	endpointProbeReadyInterval = 30 * time.Second
+)
+
+// probeHttpClient is used for probing the reachability of exposed endpoints
+var probeHttpClient = &http.Client{
+	Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	},
+	Timeout: endpointProbeTimeout,
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		// Redirects (e.g. to a login page) mean the endpoint is reachable
+		return http.ErrUseLastResponse
+	},
+}
+
+// endpointProber probes exposed endpoints in the background, so that slow or unreachable endpoints do not block the
+// reconcile loop. Results are stored per DevWorkspaceRouting and read on subsequent reconciles.
+type endpointProber struct {
+	lock  sync.Mutex
+	state map[types.NamespacedName]*probeState
+}
+
+type probeState struct {
+	inProgress bool
+	probedAt   time.Time
+	// results maps endpoint URLs to the result of the last probe
+	results map[string]probeResult
+}
+
+type probeResult struct {
+	ready   *bool
+	message string
+}
+
+// getEndpointStatuses returns the status of every exposed endpoint, based on the results of the last probe, and
+// starts a new probe in the background if results are outdated. If no pod for the workspace is running, endpoints are
+// not probed and are reported as not ready. Returns whether endpoints should be checked again soon, i.e. whether some
+// endpoints are not ready or have not been probed yet; otherwise, endpoints should still be checked again after
+// endpointProbeReadyInterval.
+func (r *DevWorkspaceRoutingReconciler) getEndpointStatuses(
+	routing *controllerv1alpha1.DevWorkspaceRouting,
+	exposedEndpoints map[string]controllerv1alpha1.ExposedEndpointList) (statuses []controllerv1alpha1.EndpointStatus, recheck bool, err error) {
+
+	podRunning, err := r.hasRunningWorkspacePod(routing)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var machineNames []string
+	for machineName := range exposedEndpoints {
+		machineNames = append(machineNames, machineName)
+	}
+	sort.Strings(machineNames)
+	var endpoints []controllerv1alpha1.ExposedEndpoint
+	for _, machineName := range machineNames {
+		endpoints = append(endpoints, exposedEndpoints[machineName]...)
+	}
+
+	key := types.NamespacedName{Name: routing.Name, Namespace: routing.Namespace}
+	var results map[string]probeResult
+	if podRunning {
+		results = r.prober.getResults(key, endpoints)
+	} else {
+		r.prober.forget(key)
+	}
+
+	for _, endpoint := range endpoints {
+		status := controllerv1alpha1.EndpointStatus{
+			Name: endpoint.Name,
+			Url:  endpoint.Url,
+		}
+		if !podRunning {
+			status.Ready = boolPtr(false)
+			status.Message = "Waiting for workspace pod to be running"
+			recheck = true
+		} else if result, ok := results[endpoint.Url]; ok {
+			status.Ready, status.Message = result.ready, result.message
+			if result.ready != nil && !*result.ready {
+				recheck = true
+			}
+		} else {
+			status.Message = "Waiting for endpoint to be probed"
+			recheck = true
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, recheck, nil
+}
+
+// getResults returns the results of the last probe of a DevWorkspaceRouting's endpoints. If no probe is in progress
+// and results are older than endpointProbeRequeueInterval, the endpoints are probed again in the background.
+func (p *endpointProber) getResults(key types.NamespacedName, endpoints []controllerv1alpha1.ExposedEndpoint) map[string]probeResult {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.state == nil {
+		p.state = map[types.NamespacedName]*probeState{}
+	}
+	state, ok := p.state[key]
+	if !ok {
+		state = &probeState{}
+		p.state[key] = state
+	}
+	if !state.inProgress && time.Since(state.probedAt) >= endpointProbeRequeueInterval {
+		state.inProgress = true
+		go p.probe(key, state, endpoints)
+	}
+	return state.results
+}
+
+func (p *endpointProber) probe(key types.NamespacedName, state *probeState, endpoints []controllerv1alpha1.ExposedEndpoint) {
+	results := map[string]probeResult{}
+	for _, endpoint := range endpoints {
+		ready, message := probeEndpoint(endpoint)
+		results[endpoint.Url] = probeResult{ready: ready, message: message}
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	state.inProgress = false
+	state.probedAt = time.Now()
+	state.results = results
+}
+
+// forget drops stored results for a DevWorkspaceRouting, e.g. once it is deleted or its workspace is stopped
+func (p *endpointProber) forget(key types.NamespacedName) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	delete(p.state, key)
+}
+
+func (r *DevWorkspaceRoutingReconciler) hasRunningWorkspacePod(routing *controllerv1alpha1.DevWorkspaceRouting) (bool, error) {
+	pods := &corev1.PodList{}
+	err := r.List(context.TODO(), pods, client.InNamespace(routing.Namespace), client.MatchingLabels(routing.Spec.PodSelector))
+	if err != nil {
+		return false, err
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// probeEndpoint checks whether an exposed endpoint is reachable. For http(s) and ws(s) endpoints, a GET request is
+// issued against the endpoint's URL, using the health check path attribute if defined. For tcp endpoints, a connection
+// is opened. Other endpoints cannot be probed, and their readiness is reported as unknown (nil).
+func probeEndpoint(endpoint controllerv1alpha1.ExposedEndpoint) (ready *bool, message string) {
+	if endpoint.Url == "" {
+		return boolPtr(false), "Endpoint URL is not yet available"
+	}
+	endpointUrl, err := url.Parse(endpoint.Url)
+	if err != nil {
+		return boolPtr(false), fmt.Sprintf("Failed to parse endpoint URL: %s", err)
+	}
+	switch endpointUrl.Scheme {
+	case "http", "https", "ws", "wss":
+		return probeHTTPEndpoint(endpoint, endpointUrl)
+	case "tcp":
+		conn, err := net.DialTimeout("tcp", endpointUrl.Host, endpointProbeTimeout)
+		if err != nil {
+			return boolPtr(false), fmt.Sprintf("Failed to connect to endpoint: %s", err)
+		}
+		conn.Close()
+		return boolPtr(true), ""
+	default:
+		return nil, fmt.Sprintf("Probing endpoints using protocol %s is not supported", endpointUrl.Scheme)
+	}
+}
+
+func probeHTTPEndpoint(endpoint controllerv1alpha1.ExposedEndpoint, endpointUrl *url.URL) (ready *bool, message string) {
+	probeUrl := *endpointUrl
+	// Websocket endpoints are served over http(s)
+	probeUrl.Scheme = strings.Replace(probeUrl.Scheme, "ws", "http", 1)
+	if endpoint.Attributes.Exists(string(controllerv1alpha1.HealthCheckPathAttribute)) {
+		healthPath := endpoint.Attributes.GetString(string(controllerv1alpha1.HealthCheckPathAttribute), nil)
+		probeUrl.Path = strings.TrimRight(probeUrl.Path, "/") + "/" + strings.TrimLeft(healthPath, "/")
+	}
+
+	resp, err := probeHttpClient.Get(probeUrl.String())
+	if err != nil {
+		return boolPtr(false), fmt.Sprintf("Failed to reach endpoint: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		// Endpoint is protected by authentication, but is being served
+		return boolPtr(true), ""
+	}
+	if resp.StatusCode/100 == 2 || resp.StatusCode/100 == 3 {
+		return boolPtr(true), ""
+	}
+	return boolPtr(false), fmt.Sprintf("Endpoint returned status code %d", resp.StatusCode)
+}
+
+// workspacePodsHandler maps workspace pods to the DevWorkspaceRouting for that workspace, to allow probing endpoints
+// as soon as the workspace pod starts running.
+func workspacePodsHandler() handler.EventHandler {
+	podToRouting := func(mapObj handler.MapObject) []reconcile.Request {
+		workspaceId, ok := mapObj.Meta.GetLabels()[constants.DevWorkspaceIDLabel]
+		if !ok {
+			return nil
+		}
+		return []reconcile.Request{
+			{
+				NamespacedName: types.NamespacedName{
+					Name:      common.DevWorkspaceRoutingName(workspaceId),
+					Namespace: mapObj.Meta.GetNamespace(),
+				},
+			},
+		}
+	}
+	return &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(podToRouting)}
+}
+
+func boolPtr(value bool) *bool {
+	return &value
+}
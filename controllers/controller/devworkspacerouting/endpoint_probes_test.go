@@ -0,0 +1,177 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package devworkspacerouting
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devfile/api/v2/pkg/attributes"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+
+	controllerv1alpha1 "github.com/devfile/devworkspace-operator/apis/controller/v1alpha1"
+)
+
+func TestProbeEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok", "/app/healthz":
+			w.WriteHeader(http.StatusOK)
+		case "/redirect":
+			http.Redirect(w, r, "/login", http.StatusFound)
+		case "/protected":
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	closedListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	closedAddr := closedListener.Addr().String()
+	closedListener.Close()
+
+	tests := []struct {
+		name            string
+		endpoint        controllerv1alpha1.ExposedEndpoint
+		expectedReady   *bool
+		expectedMessage string
+	}{
+		{
+			name:          "Endpoint returning 200 is ready",
+			endpoint:      controllerv1alpha1.ExposedEndpoint{Url: server.URL + "/ok"},
+			expectedReady: boolPtr(true),
+		},
+		{
+			name:          "Redirecting endpoint is ready",
+			endpoint:      controllerv1alpha1.ExposedEndpoint{Url: server.URL + "/redirect"},
+			expectedReady: boolPtr(true),
+		},
+		{
+			name:          "Endpoint requiring authentication is ready",
+			endpoint:      controllerv1alpha1.ExposedEndpoint{Url: server.URL + "/protected"},
+			expectedReady: boolPtr(true),
+		},
+		{
+			name:            "Endpoint returning error status is not ready",
+			endpoint:        controllerv1alpha1.ExposedEndpoint{Url: server.URL + "/unavailable"},
+			expectedReady:   boolPtr(false),
+			expectedMessage: "Endpoint returned status code 503",
+		},
+		{
+			name: "Uses health check path",
+			endpoint: controllerv1alpha1.ExposedEndpoint{
+				Url:        server.URL + "/app/",
+				Attributes: attributes.Attributes{}.PutString(string(controllerv1alpha1.HealthCheckPathAttribute), "/healthz"),
+			},
+			expectedReady: boolPtr(true),
+		},
+		{
+			name:          "Probes websocket endpoint over http",
+			endpoint:      controllerv1alpha1.ExposedEndpoint{Url: strings.Replace(server.URL, "http", "ws", 1) + "/ok"},
+			expectedReady: boolPtr(true),
+		},
+		{
+			name:          "TCP endpoint accepting connections is ready",
+			endpoint:      controllerv1alpha1.ExposedEndpoint{Url: "tcp://" + listener.Addr().String()},
+			expectedReady: boolPtr(true),
+		},
+		{
+			name:          "TCP endpoint refusing connections is not ready",
+			endpoint:      controllerv1alpha1.ExposedEndpoint{Url: "tcp://" + closedAddr},
+			expectedReady: boolPtr(false),
+		},
+		{
+			name:            "Endpoint without URL is not ready",
+			endpoint:        controllerv1alpha1.ExposedEndpoint{Url: ""},
+			expectedReady:   boolPtr(false),
+			expectedMessage: "Endpoint URL is not yet available",
+		},
+		{
+			name:            "Readiness of endpoint with unsupported protocol is unknown",
+			endpoint:        controllerv1alpha1.ExposedEndpoint{Url: "udp://127.0.0.1:5000"},
+			expectedReady:   nil,
+			expectedMessage: "Probing endpoints using protocol udp is not supported",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ready, message := probeEndpoint(tt.endpoint)
+			assert.Equal(t, tt.expectedReady, ready, "Unexpected readiness (message: %s)", message)
+			if tt.expectedMessage != "" {
+				assert.Equal(t, tt.expectedMessage, message)
+			}
+		})
+	}
+}
+
+func TestEndpointProberState(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	key := types.NamespacedName{Name: "test-routing", Namespace: "test-namespace"}
+	endpoints := []controllerv1alpha1.ExposedEndpoint{{Name: "web", Url: server.URL}}
+	prober := &endpointProber{}
+
+	results := prober.getResults(key, endpoints)
+	assert.Empty(t, results, "Should not have results before first probe completes")
+	assert.Eventually(t, func() bool {
+		return len(prober.getResults(key, endpoints)) == 1
+	}, 5*time.Second, 10*time.Millisecond, "Should store results once probe completes")
+	result := prober.getResults(key, endpoints)[server.URL]
+	assert.Equal(t, boolPtr(true), result.ready)
+
+	prober.getResults(key, endpoints)
+	prober.lock.Lock()
+	inProgress := prober.state[key].inProgress
+	prober.lock.Unlock()
+	assert.False(t, inProgress, "Should not probe again before results are outdated")
+
+	prober.lock.Lock()
+	prober.state[key].probedAt = time.Now().Add(-endpointProbeRequeueInterval)
+	prober.lock.Unlock()
+	results = prober.getResults(key, endpoints)
+	assert.Len(t, results, 1, "Should return previous results while probing again")
+	prober.lock.Lock()
+	inProgress = prober.state[key].inProgress
+	prober.lock.Unlock()
+	assert.True(t, inProgress, "Should probe again once results are outdated")
+
+	prober.forget(key)
+	prober.lock.Lock()
+	_, ok := prober.state[key]
+	prober.lock.Unlock()
+	assert.False(t, ok, "Should drop state when forgotten")
+}
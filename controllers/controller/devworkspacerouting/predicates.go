@@ -14,8 +14,10 @@ package devworkspacerouting
 
 import (
 	"github.com/devfile/devworkspace-operator/controllers/controller/devworkspacerouting/solvers"
+	"github.com/devfile/devworkspace-operator/pkg/constants"
 
 	controllerv1alpha1 "github.com/devfile/devworkspace-operator/apis/controller/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 )
@@ -67,3 +69,42 @@ func getRoutingPredicatesForSolverFunc(solverGetter solvers.RoutingSolverGetter)
 		},
 	}
 }
+
+// podPredicates filters pod events so that only changes to a workspace pod's phase trigger a reconcile; pods are
+// only watched to allow probing endpoints once the workspace is running.
+var podPredicates = predicate.Funcs{
+	CreateFunc: func(ev event.CreateEvent) bool {
+		if _, ok := ev.Object.(*corev1.Pod); !ok {
+			// that's no Pod. Let other predicates decide
+			return true
+		}
+		return false
+	},
+	DeleteFunc: func(ev event.DeleteEvent) bool {
+		if _, ok := ev.Object.(*corev1.Pod); !ok {
+			return true
+		}
+		return false
+	},
+	UpdateFunc: func(ev event.UpdateEvent) bool {
+		newObj, ok := ev.ObjectNew.(*corev1.Pod)
+		if !ok {
+			return true
+		}
+		oldObj, ok := ev.ObjectOld.(*corev1.Pod)
+		if !ok {
+			// Should never happen
+			return true
+		}
+		if _, ok := newObj.Labels[constants.DevWorkspaceIDLabel]; !ok {
+			return false
+		}
+		return oldObj.Status.Phase != newObj.Status.Phase
+	},
+	GenericFunc: func(ev event.GenericEvent) bool {
+		if _, ok := ev.Object.(*corev1.Pod); !ok {
+			return true
+		}
+		return false
+	},
+}
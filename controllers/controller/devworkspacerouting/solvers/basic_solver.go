@@ -39,8 +39,9 @@ var nginxIngressAnnotations = func(endpointName string) map[string]string {
 
 // Basic solver exposes endpoints without any authentication
 // According to the current cluster there is different behavior:
-// Kubernetes: use Ingresses without TLS
+// Kubernetes: use Ingresses, with TLS enabled only for secure endpoints
 // OpenShift: use Routes with TLS enabled
+// Public endpoints using the tcp or udp protocol are exposed via NodePort or LoadBalancer services instead.
 type BasicSolver struct{}
 
 var _ RoutingSolver = (*BasicSolver)(nil)
@@ -64,11 +65,19 @@ func (s *BasicSolver) GetSpecObjects(routing *controllerv1alpha1.DevWorkspaceRou
 	spec := routing.Spec
 	services := getServicesForEndpoints(spec.Endpoints, workspaceMeta)
 	services = append(services, GetDiscoverableServicesForEndpoints(spec.Endpoints, workspaceMeta)...)
+	externalServices, err := getExternalServicesForEndpoints(spec.Endpoints, workspaceMeta)
+	if err != nil {
+		return routingObjects, err
+	}
+	services = append(services, externalServices...)
 	routingObjects.Services = services
 	if infrastructure.IsOpenShift() {
-		routingObjects.Routes = getRoutesForSpec(*routingSuffix, spec.Endpoints, workspaceMeta)
+		routingObjects.Routes, err = getRoutesForSpec(*routingSuffix, routing, workspaceMeta)
 	} else {
-		routingObjects.Ingresses = getIngressesForSpec(*routingSuffix, spec.Endpoints, workspaceMeta)
+		routingObjects.Ingresses, err = getIngressesForSpec(*routingSuffix, routing, workspaceMeta)
+	}
+	if err != nil {
+		return routingObjects, err
 	}
 
 	return routingObjects, nil
@@ -49,6 +49,11 @@ func (s *ClusterSolver) GetSpecObjects(routing *controllerv1alpha1.DevWorkspaceR
 	if s.TLS {
 		readOnlyMode := int32(420)
 		for idx, service := range services {
+			// Serving certificates are only mounted in the DevWorkspace's main pod, so services for machines that run in
+			// other pods are not secured
+			if _, isMachineService := service.Annotations[constants.DevWorkspaceMachineNameAnnotation]; isMachineService {
+				continue
+			}
 			if services[idx].Annotations == nil {
 				services[idx].Annotations = map[string]string{}
 			}
@@ -87,7 +92,7 @@ func (s *ClusterSolver) GetExposedEndpoints(
 			if endpoint.Exposure == dw.NoneEndpointExposure {
 				continue
 			}
-			url, err := resolveServiceHostnameForEndpoint(endpoint, routingObj.Services)
+			url, err := resolveServiceHostnameForEndpoint(endpoint, getServicesForMachine(machineName, routingObj.Services))
 			if err != nil {
 				return nil, false, err
 			}
@@ -105,22 +110,17 @@ func (s *ClusterSolver) GetExposedEndpoints(
 
 func resolveServiceHostnameForEndpoint(endpoint dw.Endpoint, services []corev1.Service) (string, error) {
 	for _, service := range services {
-		if service.Annotations[constants.DevWorkspaceDiscoverableServiceAnnotation] == "true" {
-			continue
-		}
 		for _, servicePort := range service.Spec.Ports {
 			if servicePort.Port == int32(endpoint.TargetPort) {
-				return getHostnameFromService(service, servicePort.Port), nil
+				return getHostnameFromService(service, endpoint, servicePort.Port), nil
 			}
 		}
 	}
 	return "", fmt.Errorf("could not find service for endpoint %s", endpoint.Name)
 }
 
-func getHostnameFromService(service corev1.Service, port int32) string {
-	scheme := "http"
-	if _, ok := service.Annotations[serviceServingCertAnnot]; ok {
-		scheme = "https"
-	}
-	return fmt.Sprintf("%s://%s.%s.svc:%d", scheme, service.Name, service.Namespace, port)
+func getHostnameFromService(service corev1.Service, endpoint dw.Endpoint, port int32) string {
+	_, secure := service.Annotations[serviceServingCertAnnot]
+	host := fmt.Sprintf("%s.%s.svc:%d", service.Name, service.Namespace, port)
+	return getURLForEndpoint(endpoint, host, "", secure)
 }
@@ -13,10 +13,15 @@
 package solvers
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
 	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
 
 	controllerv1alpha1 "github.com/devfile/devworkspace-operator/apis/controller/v1alpha1"
 	"github.com/devfile/devworkspace-operator/pkg/common"
+	"github.com/devfile/devworkspace-operator/pkg/config"
 	"github.com/devfile/devworkspace-operator/pkg/constants"
 
 	routeV1 "github.com/openshift/api/route/v1"
@@ -24,19 +29,39 @@ import (
 	"k8s.io/api/extensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 type DevWorkspaceMetadata struct {
 	DevWorkspaceId string
 	Namespace      string
 	PodSelector    map[string]string
+	// MachinePodSelectors are selectors for the pods of machines that do not run in the DevWorkspace's main pod,
+	// keyed by machine name
+	MachinePodSelectors map[string]map[string]string
+}
+
+// podSelectorForMachine returns the selector for the pod that serves a machine's endpoints
+func (meta DevWorkspaceMetadata) podSelectorForMachine(machineName string) map[string]string {
+	if selector, ok := meta.MachinePodSelectors[machineName]; ok {
+		return selector
+	}
+	return meta.PodSelector
+}
+
+// serviceNameForMachine returns the name of the service that exposes a machine's endpoints
+func (meta DevWorkspaceMetadata) serviceNameForMachine(machineName string) string {
+	if _, ok := meta.MachinePodSelectors[machineName]; ok {
+		return common.MachineServiceName(meta.DevWorkspaceId, machineName)
+	}
+	return common.ServiceName(meta.DevWorkspaceId)
 }
 
 // GetDiscoverableServicesForEndpoints converts the endpoint list into a set of services, each corresponding to a single discoverable
 // endpoint from the list. Endpoints with the NoneEndpointExposure are ignored.
 func GetDiscoverableServicesForEndpoints(endpoints map[string]controllerv1alpha1.EndpointList, meta DevWorkspaceMetadata) []corev1.Service {
 	var services []corev1.Service
-	for _, machineEndpoints := range endpoints {
+	for machineName, machineEndpoints := range endpoints {
 		for _, endpoint := range machineEndpoints {
 			if endpoint.Exposure == dw.NoneEndpointExposure {
 				continue
@@ -48,7 +73,7 @@ func GetDiscoverableServicesForEndpoints(endpoints map[string]controllerv1alpha1
 				// Also endpoint names may not be valid as service names
 				servicePort := corev1.ServicePort{
 					Name:       common.EndpointName(endpoint.Name),
-					Protocol:   corev1.ProtocolTCP,
+					Protocol:   getServicePortProtocol(endpoint),
 					Port:       int32(endpoint.TargetPort),
 					TargetPort: intstr.FromInt(endpoint.TargetPort),
 				}
@@ -65,7 +90,7 @@ func GetDiscoverableServicesForEndpoints(endpoints map[string]controllerv1alpha1
 					},
 					Spec: corev1.ServiceSpec{
 						Ports:    []corev1.ServicePort{servicePort},
-						Selector: meta.PodSelector,
+						Selector: meta.podSelectorForMachine(machineName),
 						Type:     corev1.ServiceTypeClusterIP,
 					},
 				})
@@ -75,14 +100,38 @@ func GetDiscoverableServicesForEndpoints(endpoints map[string]controllerv1alpha1
 	return services
 }
 
-// GetServiceForEndpoints returns a single service that exposes all endpoints of given exposure types, possibly also including the discoverable types.
-// `nil` is returned if the service would expose no ports satisfying the provided criteria.
+// GetServiceForEndpoints returns a single service that exposes all endpoints of machines in the DevWorkspace's main pod of given
+// exposure types, possibly also including the discoverable types. `nil` is returned if the service would expose no ports satisfying
+// the provided criteria.
 func GetServiceForEndpoints(endpoints map[string]controllerv1alpha1.EndpointList, meta DevWorkspaceMetadata, includeDiscoverable bool, exposureType ...dw.EndpointExposure) *corev1.Service {
+	mainPodEndpoints := map[string]controllerv1alpha1.EndpointList{}
+	for machineName, machineEndpoints := range endpoints {
+		if _, ok := meta.MachinePodSelectors[machineName]; !ok {
+			mainPodEndpoints[machineName] = machineEndpoints
+		}
+	}
+	return getServiceForEndpoints(mainPodEndpoints, common.ServiceName(meta.DevWorkspaceId), meta.PodSelector, meta, includeDiscoverable, exposureType...)
+}
+
+// getMachineServiceForEndpoints returns a service that exposes the endpoints of a machine that does not run in the DevWorkspace's
+// main pod, using the machine's pod selector. `nil` is returned if the service would expose no ports satisfying the provided criteria.
+func getMachineServiceForEndpoints(machineName string, endpoints controllerv1alpha1.EndpointList, meta DevWorkspaceMetadata, includeDiscoverable bool, exposureType ...dw.EndpointExposure) *corev1.Service {
+	machineEndpoints := map[string]controllerv1alpha1.EndpointList{machineName: endpoints}
+	service := getServiceForEndpoints(machineEndpoints, meta.serviceNameForMachine(machineName), meta.podSelectorForMachine(machineName), meta, includeDiscoverable, exposureType...)
+	if service != nil {
+		service.Annotations = map[string]string{
+			constants.DevWorkspaceMachineNameAnnotation: machineName,
+		}
+	}
+	return service
+}
+
+func getServiceForEndpoints(endpoints map[string]controllerv1alpha1.EndpointList, name string, selector map[string]string, meta DevWorkspaceMetadata, includeDiscoverable bool, exposureType ...dw.EndpointExposure) *corev1.Service {
 	// "set" of ports that are still left for exposure
-	ports := map[int]bool{}
+	ports := map[servicePortKey]bool{}
 	for _, es := range endpoints {
 		for _, endpoint := range es {
-			ports[endpoint.TargetPort] = true
+			ports[getServicePortKey(endpoint)] = true
 		}
 	}
 
@@ -104,12 +153,12 @@ func GetServiceForEndpoints(endpoints map[string]controllerv1alpha1.EndpointList
 				continue
 			}
 
-			if ports[endpoint.TargetPort] {
+			if portKey := getServicePortKey(endpoint); ports[portKey] {
 				// make sure we don't mention the same port twice
-				ports[endpoint.TargetPort] = false
+				ports[portKey] = false
 				exposedPorts = append(exposedPorts, corev1.ServicePort{
 					Name:       common.EndpointName(endpoint.Name),
-					Protocol:   corev1.ProtocolTCP,
+					Protocol:   portKey.protocol,
 					Port:       int32(endpoint.TargetPort),
 					TargetPort: intstr.FromInt(endpoint.TargetPort),
 				})
@@ -123,14 +172,14 @@ func GetServiceForEndpoints(endpoints map[string]controllerv1alpha1.EndpointList
 
 	return &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      common.ServiceName(meta.DevWorkspaceId),
+			Name:      name,
 			Namespace: meta.Namespace,
 			Labels: map[string]string{
 				constants.DevWorkspaceIDLabel: meta.DevWorkspaceId,
 			},
 		},
 		Spec: corev1.ServiceSpec{
-			Selector: meta.PodSelector,
+			Selector: selector,
 			Type:     corev1.ServiceTypeClusterIP,
 			Ports:    exposedPorts,
 		},
@@ -142,45 +191,151 @@ func getServicesForEndpoints(endpoints map[string]controllerv1alpha1.EndpointLis
 		return nil
 	}
 
+	var services []corev1.Service
 	service := GetServiceForEndpoints(endpoints, meta, true, dw.PublicEndpointExposure, dw.InternalEndpointExposure)
-	if service == nil {
-		return nil
+	if service != nil {
+		services = append(services, *service)
+	}
+
+	// Iterate in a stable order so that the list of services does not change between reconciles
+	var machineNames []string
+	for machineName := range meta.MachinePodSelectors {
+		if _, ok := endpoints[machineName]; ok {
+			machineNames = append(machineNames, machineName)
+		}
+	}
+	sort.Strings(machineNames)
+	for _, machineName := range machineNames {
+		machineService := getMachineServiceForEndpoints(machineName, endpoints[machineName], meta, true, dw.PublicEndpointExposure, dw.InternalEndpointExposure)
+		if machineService != nil {
+			services = append(services, *machineService)
+		}
 	}
+	return services
+}
 
-	return []corev1.Service{
-		*service,
+// getServicesForMachine returns the services that expose a machine's endpoints: the service created for the machine if
+// it does not run in the DevWorkspace's main pod, or otherwise the services for the main pod. Services for discoverable
+// endpoints are not included.
+func getServicesForMachine(machineName string, services []corev1.Service) []corev1.Service {
+	var mainPodServices, machineServices []corev1.Service
+	for _, service := range services {
+		if service.Annotations[constants.DevWorkspaceDiscoverableServiceAnnotation] == "true" {
+			continue
+		}
+		serviceMachine, isMachineService := service.Annotations[constants.DevWorkspaceMachineNameAnnotation]
+		switch {
+		case !isMachineService:
+			mainPodServices = append(mainPodServices, service)
+		case serviceMachine == machineName:
+			machineServices = append(machineServices, service)
+		}
+	}
+	if len(machineServices) > 0 {
+		return machineServices
+	}
+	return mainPodServices
+}
+
+// getExternalServicesForEndpoints returns a NodePort or LoadBalancer service for each public endpoint that uses the
+// tcp or udp protocol, as these endpoints cannot be exposed via routes or ingresses. The type of service is determined
+// by the endpoint's serviceType attribute.
+func getExternalServicesForEndpoints(endpoints map[string]controllerv1alpha1.EndpointList, meta DevWorkspaceMetadata) ([]corev1.Service, error) {
+	var services []corev1.Service
+	for machineName, machineEndpoints := range endpoints {
+		for _, endpoint := range machineEndpoints {
+			if endpoint.Exposure != dw.PublicEndpointExposure || !isTCPOrUDPEndpoint(endpoint) {
+				continue
+			}
+			serviceType, err := getExternalServiceType(endpoint)
+			if err != nil {
+				return nil, err
+			}
+			// Annotations defined on the endpoint apply to the service, as it is what exposes the endpoint outside the cluster
+			annotations, err := getAnnotationsForEndpoint(nil, nil, endpoint)
+			if err != nil {
+				return nil, err
+			}
+			endpointName := common.EndpointName(endpoint.Name)
+			services = append(services, corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      common.EndpointServiceName(meta.DevWorkspaceId, endpointName, endpoint.TargetPort),
+					Namespace: meta.Namespace,
+					Labels: map[string]string{
+						constants.DevWorkspaceIDLabel: meta.DevWorkspaceId,
+					},
+					Annotations: annotations,
+				},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{
+						{
+							Name:       endpointName,
+							Protocol:   getServicePortProtocol(endpoint),
+							Port:       int32(endpoint.TargetPort),
+							TargetPort: intstr.FromInt(endpoint.TargetPort),
+						},
+					},
+					Selector: meta.podSelectorForMachine(machineName),
+					Type:     serviceType,
+				},
+			})
+		}
 	}
+	return services, nil
 }
 
-func getRoutesForSpec(routingSuffix string, endpoints map[string]controllerv1alpha1.EndpointList, meta DevWorkspaceMetadata) []routeV1.Route {
+func getRoutesForSpec(routingSuffix string, routing *controllerv1alpha1.DevWorkspaceRouting, meta DevWorkspaceMetadata) ([]routeV1.Route, error) {
 	var routes []routeV1.Route
-	for _, machineEndpoints := range endpoints {
+	for machineName, machineEndpoints := range routing.Spec.Endpoints {
 		for _, endpoint := range machineEndpoints {
-			if endpoint.Exposure != dw.PublicEndpointExposure {
+			if endpoint.Exposure != dw.PublicEndpointExposure || isTCPOrUDPEndpoint(endpoint) {
 				continue
 			}
-			routes = append(routes, getRouteForEndpoint(routingSuffix, endpoint, meta))
+			route, err := getRouteForEndpoint(routingSuffix, machineName, endpoint, getRoutingClassAnnotations(routing), meta)
+			if err != nil {
+				return nil, err
+			}
+			routes = append(routes, route)
 		}
 	}
-	return routes
+	return routes, nil
 }
 
-func getIngressesForSpec(routingSuffix string, endpoints map[string]controllerv1alpha1.EndpointList, meta DevWorkspaceMetadata) []v1beta1.Ingress {
+func getIngressesForSpec(routingSuffix string, routing *controllerv1alpha1.DevWorkspaceRouting, meta DevWorkspaceMetadata) ([]v1beta1.Ingress, error) {
 	var ingresses []v1beta1.Ingress
-	for _, machineEndpoints := range endpoints {
+	for machineName, machineEndpoints := range routing.Spec.Endpoints {
 		for _, endpoint := range machineEndpoints {
-			if endpoint.Exposure != dw.PublicEndpointExposure {
+			if endpoint.Exposure != dw.PublicEndpointExposure || isTCPOrUDPEndpoint(endpoint) {
 				continue
 			}
-			ingresses = append(ingresses, getIngressForEndpoint(routingSuffix, endpoint, meta))
+			ingress, err := getIngressForEndpoint(routingSuffix, machineName, endpoint, getRoutingClassAnnotations(routing), meta)
+			if err != nil {
+				return nil, err
+			}
+			ingresses = append(ingresses, ingress)
 		}
 	}
-	return ingresses
+	return ingresses, nil
 }
 
-func getRouteForEndpoint(routingSuffix string, endpoint dw.Endpoint, meta DevWorkspaceMetadata) routeV1.Route {
+func getRouteForEndpoint(routingSuffix, machineName string, endpoint dw.Endpoint, routingAnnotations map[string]string, meta DevWorkspaceMetadata) (routeV1.Route, error) {
 	targetEndpoint := intstr.FromInt(endpoint.TargetPort)
 	endpointName := common.EndpointName(endpoint.Name)
+	host := common.WorkspaceHostname(routingSuffix, meta.DevWorkspaceId)
+	path := common.EndpointPath(endpointName)
+	customHost, err := getCustomHostnameForEndpoint(endpoint, meta)
+	if err != nil {
+		return routeV1.Route{}, err
+	}
+	if customHost != "" {
+		// Each endpoint gets its own host, so there's no need to route based on path
+		host = customHost
+		path = "/"
+	}
+	annotations, err := getAnnotationsForEndpoint(routeAnnotations(endpointName), routingAnnotations, endpoint)
+	if err != nil {
+		return routeV1.Route{}, err
+	}
 	return routeV1.Route{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      common.RouteName(meta.DevWorkspaceId, endpointName),
@@ -188,31 +343,49 @@ func getRouteForEndpoint(routingSuffix string, endpoint dw.Endpoint, meta DevWor
 			Labels: map[string]string{
 				constants.DevWorkspaceIDLabel: meta.DevWorkspaceId,
 			},
-			Annotations: routeAnnotations(endpointName),
+			Annotations: annotations,
 		},
 		Spec: routeV1.RouteSpec{
-			Host: common.WorkspaceHostname(routingSuffix, meta.DevWorkspaceId),
-			Path: common.EndpointPath(endpointName),
+			Host: host,
+			Path: path,
 			TLS: &routeV1.TLSConfig{
 				InsecureEdgeTerminationPolicy: routeV1.InsecureEdgeTerminationPolicyRedirect,
 				Termination:                   routeV1.TLSTerminationEdge,
 			},
 			To: routeV1.RouteTargetReference{
 				Kind: "Service",
-				Name: common.ServiceName(meta.DevWorkspaceId),
+				Name: meta.serviceNameForMachine(machineName),
 			},
 			Port: &routeV1.RoutePort{
 				TargetPort: targetEndpoint,
 			},
 		},
-	}
+	}, nil
 }
 
-func getIngressForEndpoint(routingSuffix string, endpoint dw.Endpoint, meta DevWorkspaceMetadata) v1beta1.Ingress {
+func getIngressForEndpoint(routingSuffix, machineName string, endpoint dw.Endpoint, routingAnnotations map[string]string, meta DevWorkspaceMetadata) (v1beta1.Ingress, error) {
 	targetEndpoint := intstr.FromInt(endpoint.TargetPort)
 	endpointName := common.EndpointName(endpoint.Name)
 	hostname := common.EndpointHostname(routingSuffix, meta.DevWorkspaceId, endpointName, endpoint.TargetPort)
+	customHost, err := getCustomHostnameForEndpoint(endpoint, meta)
+	if err != nil {
+		return v1beta1.Ingress{}, err
+	}
+	if customHost != "" {
+		hostname = customHost
+	}
 	ingressPathType := v1beta1.PathTypeImplementationSpecific
+	defaultAnnotations := nginxIngressAnnotations(endpoint.Name)
+	var ingressTLS []v1beta1.IngressTLS
+	if endpoint.Secure {
+		// No secret is specified; the ingress controller's default certificate is used for the host.
+		ingressTLS = []v1beta1.IngressTLS{{Hosts: []string{hostname}}}
+		defaultAnnotations["nginx.ingress.kubernetes.io/ssl-redirect"] = "true"
+	}
+	annotations, err := getAnnotationsForEndpoint(defaultAnnotations, routingAnnotations, endpoint)
+	if err != nil {
+		return v1beta1.Ingress{}, err
+	}
 	return v1beta1.Ingress{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      common.RouteName(meta.DevWorkspaceId, endpointName),
@@ -220,9 +393,10 @@ func getIngressForEndpoint(routingSuffix string, endpoint dw.Endpoint, meta DevW
 			Labels: map[string]string{
 				constants.DevWorkspaceIDLabel: meta.DevWorkspaceId,
 			},
-			Annotations: nginxIngressAnnotations(endpoint.Name),
+			Annotations: annotations,
 		},
 		Spec: v1beta1.IngressSpec{
+			TLS: ingressTLS,
 			Rules: []v1beta1.IngressRule{
 				{
 					Host: hostname,
@@ -231,7 +405,7 @@ func getIngressForEndpoint(routingSuffix string, endpoint dw.Endpoint, meta DevW
 							Paths: []v1beta1.HTTPIngressPath{
 								{
 									Backend: v1beta1.IngressBackend{
-										ServiceName: common.ServiceName(meta.DevWorkspaceId),
+										ServiceName: meta.serviceNameForMachine(machineName),
 										ServicePort: targetEndpoint,
 									},
 									PathType: &ingressPathType,
@@ -243,5 +417,115 @@ func getIngressForEndpoint(routingSuffix string, endpoint dw.Endpoint, meta DevW
 				},
 			},
 		},
+	}, nil
+}
+
+// servicePortKey identifies a port on a service; the same port number may be exposed for both tcp and udp.
+type servicePortKey struct {
+	port     int
+	protocol corev1.Protocol
+}
+
+func getServicePortKey(endpoint dw.Endpoint) servicePortKey {
+	return servicePortKey{
+		port:     endpoint.TargetPort,
+		protocol: getServicePortProtocol(endpoint),
+	}
+}
+
+// getServicePortProtocol returns the transport protocol to use for a service port that exposes an endpoint. All
+// endpoint protocols except udp are served over TCP.
+func getServicePortProtocol(endpoint dw.Endpoint) corev1.Protocol {
+	if endpoint.Protocol == dw.UDPEndpointProtocol {
+		return corev1.ProtocolUDP
+	}
+	return corev1.ProtocolTCP
+}
+
+// isTCPOrUDPEndpoint returns whether an endpoint uses a raw tcp or udp protocol, i.e. cannot be exposed via an
+// HTTP-based route or ingress.
+func isTCPOrUDPEndpoint(endpoint dw.Endpoint) bool {
+	return endpoint.Protocol == dw.TCPEndpointProtocol || endpoint.Protocol == dw.UDPEndpointProtocol
+}
+
+func getExternalServiceType(endpoint dw.Endpoint) (corev1.ServiceType, error) {
+	if !endpoint.Attributes.Exists(string(controllerv1alpha1.ServiceTypeAttribute)) {
+		return corev1.ServiceTypeNodePort, nil
+	}
+	var err error
+	serviceType := endpoint.Attributes.GetString(string(controllerv1alpha1.ServiceTypeAttribute), &err)
+	if err != nil {
+		return "", &RoutingInvalid{Reason: fmt.Sprintf("could not read attribute %s on endpoint %s: %s", controllerv1alpha1.ServiceTypeAttribute, endpoint.Name, err)}
+	}
+	switch corev1.ServiceType(serviceType) {
+	case corev1.ServiceTypeNodePort:
+		return corev1.ServiceTypeNodePort, nil
+	case corev1.ServiceTypeLoadBalancer:
+		return corev1.ServiceTypeLoadBalancer, nil
+	default:
+		return "", &RoutingInvalid{Reason: fmt.Sprintf("unsupported %s '%s' for endpoint %s", controllerv1alpha1.ServiceTypeAttribute, serviceType, endpoint.Name)}
+	}
+}
+
+// getCustomHostnameForEndpoint computes the hostname for an endpoint from the hostname template in the controller
+// config. If no template is configured, an empty string is returned.
+func getCustomHostnameForEndpoint(endpoint dw.Endpoint, meta DevWorkspaceMetadata) (string, error) {
+	hostnameTemplate, err := config.ControllerCfg.GetRoutingHostnameTemplate()
+	if err != nil {
+		return "", fmt.Errorf("failed to parse hostname template: %w", err)
+	}
+	if hostnameTemplate == nil {
+		return "", nil
+	}
+	hostname := &strings.Builder{}
+	err = hostnameTemplate.Execute(hostname, config.HostnameTemplateData{
+		Endpoint:  common.EndpointName(endpoint.Name),
+		Workspace: meta.DevWorkspaceId,
+		Namespace: meta.Namespace,
+		Port:      endpoint.TargetPort,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to compute hostname for endpoint %s: %w", endpoint.Name, err)
+	}
+	if errs := validation.IsDNS1123Subdomain(hostname.String()); len(errs) > 0 {
+		return "", &RoutingInvalid{Reason: fmt.Sprintf("hostname '%s' for endpoint %s is invalid: %s", hostname.String(), endpoint.Name, strings.Join(errs, ", "))}
+	}
+	return hostname.String(), nil
+}
+
+// getRoutingClassAnnotations returns the annotations on a DevWorkspaceRouting that were propagated from the DevWorkspace
+// for its routing class, i.e. of the form "<routingClass>.routing.controller.devfile.io/<anything>".
+func getRoutingClassAnnotations(routing *controllerv1alpha1.DevWorkspaceRouting) map[string]string {
+	annotations := map[string]string{}
+	for k, v := range routing.Annotations {
+		if strings.Contains(k, constants.RoutingAnnotationInfix) {
+			annotations[k] = v
+		}
+	}
+	return annotations
+}
+
+// getAnnotationsForEndpoint merges annotations for the ingress, route or external service of an endpoint. Routing class annotations take
+// precedence over the solver's default annotations, and annotations defined in the endpoint's attributes take precedence
+// over both. The endpoint name annotation is always set, as it is required for resolving endpoint URLs.
+func getAnnotationsForEndpoint(defaultAnnotations, routingAnnotations map[string]string, endpoint dw.Endpoint) (map[string]string, error) {
+	annotations := map[string]string{}
+	for k, v := range defaultAnnotations {
+		annotations[k] = v
+	}
+	for k, v := range routingAnnotations {
+		annotations[k] = v
+	}
+	if endpoint.Attributes.Exists(string(controllerv1alpha1.AnnotationsAttribute)) {
+		endpointAnnotations := map[string]string{}
+		err := endpoint.Attributes.GetInto(string(controllerv1alpha1.AnnotationsAttribute), &endpointAnnotations)
+		if err != nil {
+			return nil, &RoutingInvalid{Reason: fmt.Sprintf("failed to read attribute %s on endpoint %s: %s", controllerv1alpha1.AnnotationsAttribute, endpoint.Name, err)}
+		}
+		for k, v := range endpointAnnotations {
+			annotations[k] = v
+		}
 	}
+	annotations[constants.DevWorkspaceEndpointNameAnnotation] = endpoint.Name
+	return annotations, nil
 }
@@ -0,0 +1,160 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package solvers
+
+import (
+	"testing"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	"github.com/devfile/api/v2/pkg/attributes"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	controllerv1alpha1 "github.com/devfile/devworkspace-operator/apis/controller/v1alpha1"
+	"github.com/devfile/devworkspace-operator/pkg/config"
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+)
+
+func TestGetCustomHostnameForEndpoint(t *testing.T) {
+	meta := DevWorkspaceMetadata{DevWorkspaceId: "workspaceid", Namespace: "test-namespace"}
+	tests := []struct {
+		name             string
+		hostnameTemplate string
+		endpoint         dw.Endpoint
+		expectedHostname string
+		expectedErr      string
+	}{
+		{
+			name:             "Returns empty hostname when no template is configured",
+			endpoint:         dw.Endpoint{Name: "web", TargetPort: 8080},
+			expectedHostname: "",
+		},
+		{
+			name:             "Computes hostname from template",
+			hostnameTemplate: "{{.Endpoint}}-{{.Port}}-{{.Workspace}}.{{.Namespace}}.apps.example.com",
+			endpoint:         dw.Endpoint{Name: "web", TargetPort: 8080},
+			expectedHostname: "web-8080-workspaceid.test-namespace.apps.example.com",
+		},
+		{
+			name:             "Uses normalized endpoint name",
+			hostnameTemplate: "{{.Endpoint}}.apps.example.com",
+			endpoint:         dw.Endpoint{Name: "Web_App", TargetPort: 8080},
+			expectedHostname: "web-app.apps.example.com",
+		},
+		{
+			name:             "Returns error when template cannot be executed",
+			hostnameTemplate: "{{.Unknown}}.apps.example.com",
+			endpoint:         dw.Endpoint{Name: "web", TargetPort: 8080},
+			expectedErr:      "failed to compute hostname for endpoint web",
+		},
+		{
+			name:             "Returns error when hostname is invalid",
+			hostnameTemplate: "{{.Endpoint}}_{{.Workspace}}.apps.example.com",
+			endpoint:         dw.Endpoint{Name: "web", TargetPort: 8080},
+			expectedErr:      "workspace routing is invalid: hostname 'web_workspaceid.apps.example.com' for endpoint web is invalid",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configMap := &corev1.ConfigMap{Data: map[string]string{}}
+			if tt.hostnameTemplate != "" {
+				configMap.Data["devworkspace.routing.hostname_template"] = tt.hostnameTemplate
+			}
+			config.SetupConfigForTesting(configMap)
+			hostname, err := getCustomHostnameForEndpoint(tt.endpoint, meta)
+			if tt.expectedErr != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.expectedErr)
+				}
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.expectedHostname, hostname)
+			}
+		})
+	}
+}
+
+func TestGetAnnotationsForEndpoint(t *testing.T) {
+	tests := []struct {
+		name                string
+		defaultAnnotations  map[string]string
+		routingAnnotations  map[string]string
+		endpointAnnotations interface{}
+		expectedAnnotations map[string]string
+		expectedErr         string
+	}{
+		{
+			name:                "Sets endpoint name annotation without other annotations",
+			expectedAnnotations: map[string]string{constants.DevWorkspaceEndpointNameAnnotation: "web"},
+		},
+		{
+			name:               "Routing class annotations take precedence over default annotations",
+			defaultAnnotations: map[string]string{"default": "default", "shared": "default"},
+			routingAnnotations: map[string]string{"routing": "routing", "shared": "routing"},
+			expectedAnnotations: map[string]string{
+				"default": "default",
+				"routing": "routing",
+				"shared":  "routing",
+				constants.DevWorkspaceEndpointNameAnnotation: "web",
+			},
+		},
+		{
+			name:                "Endpoint annotations take precedence over routing class and default annotations",
+			defaultAnnotations:  map[string]string{"default": "default", "shared": "default"},
+			routingAnnotations:  map[string]string{"routing": "routing", "shared": "routing"},
+			endpointAnnotations: map[string]string{"endpoint": "endpoint", "shared": "endpoint"},
+			expectedAnnotations: map[string]string{
+				"default":  "default",
+				"routing":  "routing",
+				"endpoint": "endpoint",
+				"shared":   "endpoint",
+				constants.DevWorkspaceEndpointNameAnnotation: "web",
+			},
+		},
+		{
+			name:                "Endpoint name annotation cannot be overridden",
+			defaultAnnotations:  map[string]string{constants.DevWorkspaceEndpointNameAnnotation: "default"},
+			routingAnnotations:  map[string]string{constants.DevWorkspaceEndpointNameAnnotation: "routing"},
+			endpointAnnotations: map[string]string{constants.DevWorkspaceEndpointNameAnnotation: "endpoint"},
+			expectedAnnotations: map[string]string{constants.DevWorkspaceEndpointNameAnnotation: "web"},
+		},
+		{
+			name:                "Returns error when endpoint annotations are invalid",
+			endpointAnnotations: []string{"not", "a", "map"},
+			expectedErr:         "workspace routing is invalid: failed to read attribute annotations on endpoint web",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint := dw.Endpoint{Name: "web", TargetPort: 8080}
+			if tt.endpointAnnotations != nil {
+				var err error
+				endpoint.Attributes = attributes.Attributes{}.Put(string(controllerv1alpha1.AnnotationsAttribute), tt.endpointAnnotations, &err)
+				if err != nil {
+					t.Fatalf("failed to set endpoint attribute: %s", err)
+				}
+			}
+			annotations, err := getAnnotationsForEndpoint(tt.defaultAnnotations, tt.routingAnnotations, endpoint)
+			if tt.expectedErr != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.expectedErr)
+				}
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.expectedAnnotations, annotations)
+			}
+		})
+	}
+}
@@ -19,7 +19,10 @@ import (
 
 	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
 
+	corev1 "k8s.io/api/core/v1"
+
 	controllerv1alpha1 "github.com/devfile/devworkspace-operator/apis/controller/v1alpha1"
+	"github.com/devfile/devworkspace-operator/pkg/config"
 	"github.com/devfile/devworkspace-operator/pkg/constants"
 )
 
@@ -55,6 +58,9 @@ func getExposedEndpoints(
 func resolveURLForEndpoint(
 	endpoint dw.Endpoint,
 	routingObj RoutingObjects) (string, error) {
+	if isTCPOrUDPEndpoint(endpoint) {
+		return resolveURLForExternalService(endpoint, routingObj.Services)
+	}
 	for _, route := range routingObj.Routes {
 		if route.Annotations[constants.DevWorkspaceEndpointNameAnnotation] == endpoint.Name {
 			return getURLForEndpoint(endpoint, route.Spec.Host, route.Spec.Path, route.Spec.TLS != nil), nil
@@ -63,7 +69,7 @@ func resolveURLForEndpoint(
 	for _, ingress := range routingObj.Ingresses {
 		if ingress.Annotations[constants.DevWorkspaceEndpointNameAnnotation] == endpoint.Name {
 			if len(ingress.Spec.Rules) == 1 {
-				return getURLForEndpoint(endpoint, ingress.Spec.Rules[0].Host, "", false), nil // no TLS supported for ingresses yet
+				return getURLForEndpoint(endpoint, ingress.Spec.Rules[0].Host, "", len(ingress.Spec.TLS) > 0), nil
 			} else {
 				return "", fmt.Errorf("ingress %s contains multiple rules", ingress.Name)
 			}
@@ -72,8 +78,49 @@ func resolveURLForEndpoint(
 	return "", fmt.Errorf("could not find ingress/route for endpoint '%s'", endpoint.Name)
 }
 
+// resolveURLForExternalService resolves the URL for a tcp or udp endpoint exposed via a NodePort or LoadBalancer service.
+// If the service is not yet assigned an external address, an empty string is returned.
+func resolveURLForExternalService(endpoint dw.Endpoint, services []corev1.Service) (string, error) {
+	for _, service := range services {
+		if service.Annotations[constants.DevWorkspaceEndpointNameAnnotation] != endpoint.Name || len(service.Spec.Ports) != 1 {
+			continue
+		}
+		switch service.Spec.Type {
+		case corev1.ServiceTypeNodePort:
+			nodePort := service.Spec.Ports[0].NodePort
+			if nodePort == 0 {
+				return "", nil
+			}
+			// The routing suffix usually resolves to the cluster's ingress controller or router rather than to its
+			// nodes, so the host used to reach node ports must be configured separately
+			nodePortHost := config.ControllerCfg.GetRoutingNodePortHost()
+			if nodePortHost == "" {
+				return "", &RoutingInvalid{Reason: fmt.Sprintf("the controller configuration does not define a host for "+
+					"NodePort services, which is required to expose endpoint %s", endpoint.Name)}
+			}
+			return getURLForEndpoint(endpoint, fmt.Sprintf("%s:%d", nodePortHost, nodePort), "", false), nil
+		case corev1.ServiceTypeLoadBalancer:
+			for _, lbIngress := range service.Status.LoadBalancer.Ingress {
+				host := lbIngress.Hostname
+				if host == "" {
+					host = lbIngress.IP
+				}
+				if host != "" {
+					return getURLForEndpoint(endpoint, fmt.Sprintf("%s:%d", host, endpoint.TargetPort), "", false), nil
+				}
+			}
+			return "", nil
+		}
+	}
+	return "", fmt.Errorf("could not find service for endpoint '%s'", endpoint.Name)
+}
+
 func getURLForEndpoint(endpoint dw.Endpoint, host, basePath string, secure bool) string {
 	protocol := endpoint.Protocol
+	if protocol == "" {
+		// Devfile API spec: default protocol is http
+		protocol = dw.HTTPEndpointProtocol
+	}
 	if secure && endpoint.Secure {
 		protocol = dw.EndpointProtocol(getSecureProtocol(string(protocol)))
 	}
@@ -81,7 +128,7 @@ func getURLForEndpoint(endpoint dw.Endpoint, host, basePath string, secure bool)
 	if endpoint.Path != "" {
 		// the only one slash should be between these path segments.
 		// Path.join does not suite here since it eats trailing slash which may be critical for the application
-		p = fmt.Sprintf("%s/%s", strings.TrimRight(basePath, "/"), strings.TrimLeft(p, endpoint.Path))
+		p = fmt.Sprintf("%s/%s", strings.TrimRight(basePath, "/"), strings.TrimLeft(endpoint.Path, "/"))
 	} else {
 		p = basePath
 	}
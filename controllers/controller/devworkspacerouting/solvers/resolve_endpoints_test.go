@@ -0,0 +1,366 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package solvers
+
+import (
+	"testing"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	"github.com/devfile/api/v2/pkg/attributes"
+	routeV1 "github.com/openshift/api/route/v1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	controllerv1alpha1 "github.com/devfile/devworkspace-operator/apis/controller/v1alpha1"
+	"github.com/devfile/devworkspace-operator/pkg/config"
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+)
+
+func TestGetURLForEndpoint(t *testing.T) {
+	tests := []struct {
+		name        string
+		endpoint    dw.Endpoint
+		host        string
+		basePath    string
+		secure      bool
+		expectedURL string
+	}{
+		{
+			name:        "Defaults to http protocol",
+			endpoint:    dw.Endpoint{Name: "web"},
+			host:        "web.example.com",
+			expectedURL: "http://web.example.com",
+		},
+		{
+			name:        "Uses https for secure http endpoint with TLS",
+			endpoint:    dw.Endpoint{Name: "web", Protocol: dw.HTTPEndpointProtocol, Secure: true},
+			host:        "web.example.com",
+			secure:      true,
+			expectedURL: "https://web.example.com",
+		},
+		{
+			name:        "Uses wss for secure websocket endpoint with TLS",
+			endpoint:    dw.Endpoint{Name: "ws", Protocol: dw.WSEndpointProtocol, Secure: true},
+			host:        "ws.example.com",
+			secure:      true,
+			expectedURL: "wss://ws.example.com",
+		},
+		{
+			name:        "Uses insecure protocol for secure endpoint without TLS",
+			endpoint:    dw.Endpoint{Name: "web", Protocol: dw.HTTPEndpointProtocol, Secure: true},
+			host:        "web.example.com",
+			secure:      false,
+			expectedURL: "http://web.example.com",
+		},
+		{
+			name:        "Uses insecure protocol for endpoint that is not secure even with TLS",
+			endpoint:    dw.Endpoint{Name: "web", Protocol: dw.HTTPEndpointProtocol},
+			host:        "web.example.com",
+			secure:      true,
+			expectedURL: "http://web.example.com",
+		},
+		{
+			name:        "Appends endpoint path",
+			endpoint:    dw.Endpoint{Name: "web", Path: "/api"},
+			host:        "web.example.com",
+			expectedURL: "http://web.example.com/api",
+		},
+		{
+			name:        "Joins base path and endpoint path with a single slash",
+			endpoint:    dw.Endpoint{Name: "web", Path: "/api/"},
+			host:        "example.com",
+			basePath:    "/workspace1234/web/",
+			expectedURL: "http://example.com/workspace1234/web/api/",
+		},
+		{
+			name:        "Uses base path if endpoint has no path",
+			endpoint:    dw.Endpoint{Name: "web"},
+			host:        "example.com",
+			basePath:    "/workspace1234/web/",
+			expectedURL: "http://example.com/workspace1234/web/",
+		},
+		{
+			name:        "Uses tcp protocol",
+			endpoint:    dw.Endpoint{Name: "db", Protocol: dw.TCPEndpointProtocol, Secure: true},
+			host:        "10.0.0.1:5432",
+			secure:      true,
+			expectedURL: "tcp://10.0.0.1:5432",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedURL, getURLForEndpoint(tt.endpoint, tt.host, tt.basePath, tt.secure))
+		})
+	}
+}
+
+func TestResolveURLForEndpoint(t *testing.T) {
+	endpointAnnotations := func(name string) map[string]string {
+		return map[string]string{constants.DevWorkspaceEndpointNameAnnotation: name}
+	}
+	routingObj := RoutingObjects{
+		Routes: []routeV1.Route{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "route", Annotations: endpointAnnotations("route-endpoint")},
+				Spec: routeV1.RouteSpec{
+					Host: "workspace.example.com",
+					Path: "/route-endpoint/",
+					TLS:  &routeV1.TLSConfig{Termination: routeV1.TLSTerminationEdge},
+				},
+			},
+		},
+		Ingresses: []v1beta1.Ingress{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "ingress", Annotations: endpointAnnotations("ingress-endpoint")},
+				Spec: v1beta1.IngressSpec{
+					Rules: []v1beta1.IngressRule{{Host: "ingress-endpoint.example.com"}},
+				},
+			},
+		},
+	}
+	tests := []struct {
+		name        string
+		endpoint    dw.Endpoint
+		expectedURL string
+		expectedErr string
+	}{
+		{
+			name:        "Resolves secure URL with path from route",
+			endpoint:    dw.Endpoint{Name: "route-endpoint", Secure: true, Path: "api"},
+			expectedURL: "https://workspace.example.com/route-endpoint/api",
+		},
+		{
+			name:        "Resolves insecure URL from ingress without TLS",
+			endpoint:    dw.Endpoint{Name: "ingress-endpoint", Secure: true},
+			expectedURL: "http://ingress-endpoint.example.com",
+		},
+		{
+			name:        "Fails if endpoint has no route or ingress",
+			endpoint:    dw.Endpoint{Name: "missing"},
+			expectedErr: "could not find ingress/route for endpoint 'missing'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpointURL, err := resolveURLForEndpoint(tt.endpoint, routingObj)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.expectedURL, endpointURL)
+			}
+		})
+	}
+}
+
+func TestResolveURLForExternalService(t *testing.T) {
+	externalService := func(endpointName string, serviceType corev1.ServiceType, nodePort int32, lbIngress ...corev1.LoadBalancerIngress) corev1.Service {
+		return corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        endpointName,
+				Annotations: map[string]string{constants.DevWorkspaceEndpointNameAnnotation: endpointName},
+			},
+			Spec: corev1.ServiceSpec{
+				Type:  serviceType,
+				Ports: []corev1.ServicePort{{Port: 5000, NodePort: nodePort}},
+			},
+			Status: corev1.ServiceStatus{
+				LoadBalancer: corev1.LoadBalancerStatus{Ingress: lbIngress},
+			},
+		}
+	}
+	services := []corev1.Service{
+		externalService("nodeport", corev1.ServiceTypeNodePort, 30500),
+		externalService("nodeport-pending", corev1.ServiceTypeNodePort, 0),
+		externalService("lb-hostname", corev1.ServiceTypeLoadBalancer, 30501, corev1.LoadBalancerIngress{Hostname: "lb.example.com"}),
+		externalService("lb-ip", corev1.ServiceTypeLoadBalancer, 30502, corev1.LoadBalancerIngress{IP: "192.168.0.10"}),
+		externalService("lb-pending", corev1.ServiceTypeLoadBalancer, 30503),
+	}
+	tests := []struct {
+		name         string
+		nodePortHost string
+		endpoint     dw.Endpoint
+		expectedURL  string
+		expectedErr  string
+	}{
+		{
+			name:         "Uses configured node port host for NodePort service",
+			nodePortHost: "node.example.com",
+			endpoint:     dw.Endpoint{Name: "nodeport", TargetPort: 5000, Protocol: dw.UDPEndpointProtocol},
+			expectedURL:  "udp://node.example.com:30500",
+		},
+		{
+			name:         "Returns empty URL while node port is not assigned",
+			nodePortHost: "node.example.com",
+			endpoint:     dw.Endpoint{Name: "nodeport-pending", TargetPort: 5000, Protocol: dw.TCPEndpointProtocol},
+			expectedURL:  "",
+		},
+		{
+			name:        "Fails for NodePort service if no node port host is configured",
+			endpoint:    dw.Endpoint{Name: "nodeport", TargetPort: 5000, Protocol: dw.TCPEndpointProtocol},
+			expectedErr: "workspace routing is invalid: the controller configuration does not define a host for NodePort services, which is required to expose endpoint nodeport",
+		},
+		{
+			name:        "Uses load balancer hostname",
+			endpoint:    dw.Endpoint{Name: "lb-hostname", TargetPort: 5000, Protocol: dw.TCPEndpointProtocol},
+			expectedURL: "tcp://lb.example.com:5000",
+		},
+		{
+			name:        "Uses load balancer IP",
+			endpoint:    dw.Endpoint{Name: "lb-ip", TargetPort: 5000, Protocol: dw.UDPEndpointProtocol},
+			expectedURL: "udp://192.168.0.10:5000",
+		},
+		{
+			name:        "Returns empty URL while load balancer is not provisioned",
+			endpoint:    dw.Endpoint{Name: "lb-pending", TargetPort: 5000, Protocol: dw.TCPEndpointProtocol},
+			expectedURL: "",
+		},
+		{
+			name:        "Fails if endpoint has no service",
+			endpoint:    dw.Endpoint{Name: "missing", TargetPort: 5000, Protocol: dw.TCPEndpointProtocol},
+			expectedErr: "could not find service for endpoint 'missing'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configMap := &corev1.ConfigMap{Data: map[string]string{}}
+			if tt.nodePortHost != "" {
+				configMap.Data["devworkspace.routing.node_port_host"] = tt.nodePortHost
+			}
+			config.SetupConfigForTesting(configMap)
+			endpointURL, err := resolveURLForExternalService(tt.endpoint, services)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.expectedURL, endpointURL)
+			}
+		})
+	}
+}
+
+func TestGetExternalServicesForEndpoints(t *testing.T) {
+	meta := DevWorkspaceMetadata{
+		DevWorkspaceId: "workspace1234",
+		Namespace:      "test-namespace",
+		PodSelector:    map[string]string{constants.DevWorkspaceIDLabel: "workspace1234"},
+	}
+	tests := []struct {
+		name             string
+		endpoint         dw.Endpoint
+		expectedType     corev1.ServiceType
+		expectedProtocol corev1.Protocol
+		expectedErr      string
+		expectNoService  bool
+	}{
+		{
+			name:             "Exposes tcp endpoint via NodePort service by default",
+			endpoint:         dw.Endpoint{Name: "db", TargetPort: 5432, Protocol: dw.TCPEndpointProtocol, Exposure: dw.PublicEndpointExposure},
+			expectedType:     corev1.ServiceTypeNodePort,
+			expectedProtocol: corev1.ProtocolTCP,
+		},
+		{
+			name: "Exposes udp endpoint via LoadBalancer service",
+			endpoint: dw.Endpoint{
+				Name:       "dns",
+				TargetPort: 53,
+				Protocol:   dw.UDPEndpointProtocol,
+				Exposure:   dw.PublicEndpointExposure,
+				Attributes: attributes.Attributes{}.PutString(string(controllerv1alpha1.ServiceTypeAttribute), "LoadBalancer"),
+			},
+			expectedType:     corev1.ServiceTypeLoadBalancer,
+			expectedProtocol: corev1.ProtocolUDP,
+		},
+		{
+			name: "Rejects unsupported service type",
+			endpoint: dw.Endpoint{
+				Name:       "db",
+				TargetPort: 5432,
+				Protocol:   dw.TCPEndpointProtocol,
+				Exposure:   dw.PublicEndpointExposure,
+				Attributes: attributes.Attributes{}.PutString(string(controllerv1alpha1.ServiceTypeAttribute), "ExternalName"),
+			},
+			expectedErr: "unsupported serviceType 'ExternalName' for endpoint db",
+		},
+		{
+			name:            "Does not expose http endpoint via external service",
+			endpoint:        dw.Endpoint{Name: "web", TargetPort: 8080, Protocol: dw.HTTPEndpointProtocol, Exposure: dw.PublicEndpointExposure},
+			expectNoService: true,
+		},
+		{
+			name:            "Does not expose internal tcp endpoint via external service",
+			endpoint:        dw.Endpoint{Name: "db", TargetPort: 5432, Protocol: dw.TCPEndpointProtocol, Exposure: dw.InternalEndpointExposure},
+			expectNoService: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			services, err := getExternalServicesForEndpoints(map[string]controllerv1alpha1.EndpointList{
+				"tools": {tt.endpoint},
+			}, meta)
+			if tt.expectedErr != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.expectedErr)
+				}
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			if tt.expectNoService {
+				assert.Empty(t, services)
+				return
+			}
+			if assert.Len(t, services, 1) {
+				service := services[0]
+				assert.Equal(t, tt.expectedType, service.Spec.Type)
+				if assert.Len(t, service.Spec.Ports, 1) {
+					assert.Equal(t, tt.expectedProtocol, service.Spec.Ports[0].Protocol)
+					assert.Equal(t, int32(tt.endpoint.TargetPort), service.Spec.Ports[0].Port)
+				}
+				assert.Equal(t, tt.endpoint.Name, service.Annotations[constants.DevWorkspaceEndpointNameAnnotation])
+				assert.Equal(t, meta.PodSelector, service.Spec.Selector)
+			}
+		})
+	}
+}
+
+func TestGetServiceForEndpointsExposesUDPPorts(t *testing.T) {
+	meta := DevWorkspaceMetadata{
+		DevWorkspaceId: "workspace1234",
+		Namespace:      "test-namespace",
+		PodSelector:    map[string]string{constants.DevWorkspaceIDLabel: "workspace1234"},
+	}
+	endpoints := map[string]controllerv1alpha1.EndpointList{
+		"tools": {
+			{Name: "dns-tcp", TargetPort: 53, Protocol: dw.TCPEndpointProtocol, Exposure: dw.InternalEndpointExposure},
+			{Name: "dns-udp", TargetPort: 53, Protocol: dw.UDPEndpointProtocol, Exposure: dw.InternalEndpointExposure},
+			{Name: "web", TargetPort: 8080, Protocol: dw.HTTPEndpointProtocol, Exposure: dw.PublicEndpointExposure},
+			{Name: "web-duplicate", TargetPort: 8080, Protocol: dw.WSEndpointProtocol, Exposure: dw.PublicEndpointExposure},
+		},
+	}
+	service := GetServiceForEndpoints(endpoints, meta, true, dw.PublicEndpointExposure, dw.InternalEndpointExposure)
+	if !assert.NotNil(t, service) {
+		return
+	}
+	ports := map[corev1.Protocol][]int32{}
+	for _, port := range service.Spec.Ports {
+		ports[port.Protocol] = append(ports[port.Protocol], port.Port)
+	}
+	assert.ElementsMatch(t, []int32{53, 8080}, ports[corev1.ProtocolTCP], "Should expose each tcp port once")
+	assert.ElementsMatch(t, []int32{53}, ports[corev1.ProtocolUDP], "Should expose udp port alongside tcp port with same number")
+}
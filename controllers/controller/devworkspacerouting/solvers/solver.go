@@ -58,6 +58,11 @@ type RoutingSolver interface {
 	GetExposedEndpoints(endpoints map[string]controllerv1alpha1.EndpointList, routingObj RoutingObjects) (exposedEndpoints map[string]controllerv1alpha1.ExposedEndpointList, ready bool, err error)
 }
 
+// RoutingSolverGetter allows external controllers to handle custom routing classes. A controller handling a
+// DevWorkspaceRouting is expected to claim it by setting status.claim as soon as possible, and to renew the claim's
+// heartbeat while the routing is not ready. The DevWorkspace controller fails workspaces whose routing is not claimed,
+// or whose claim is not renewed, within the configured claim timeout, as this indicates no controller handles its
+// routing class.
 type RoutingSolverGetter interface {
 	// SetupControllerManager is called during the setup of the controller and can modify the controller manager with additional
 	// watches, etc., needed for the correct operation of the solver.
@@ -90,7 +95,8 @@ func (_ *SolverGetter) HasSolver(routingClass controllerv1alpha1.DevWorkspaceRou
 	case controllerv1alpha1.DevWorkspaceRoutingBasic,
 		controllerv1alpha1.DevWorkspaceRoutingCluster,
 		controllerv1alpha1.DevWorkspaceRoutingClusterTLS,
-		controllerv1alpha1.DevWorkspaceRoutingWebTerminal:
+		controllerv1alpha1.DevWorkspaceRoutingWebTerminal,
+		controllerv1alpha1.DevWorkspaceRoutingTunnel:
 		return true
 	default:
 		return false
@@ -109,6 +115,8 @@ func (_ *SolverGetter) GetSolver(_ client.Client, routingClass controllerv1alpha
 			return nil, fmt.Errorf("routing class %s only supported on OpenShift", routingClass)
 		}
 		return &ClusterSolver{TLS: true}, nil
+	case controllerv1alpha1.DevWorkspaceRoutingTunnel:
+		return &TunnelSolver{}, nil
 	default:
 		return nil, RoutingNotSupported
 	}
@@ -0,0 +1,94 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package solvers
+
+import (
+	"fmt"
+	"strings"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+
+	controllerv1alpha1 "github.com/devfile/devworkspace-operator/apis/controller/v1alpha1"
+	tunnelclient "github.com/devfile/devworkspace-operator/tunnel/client"
+)
+
+// TunnelSolver exposes endpoints only within the cluster, via services. Public endpoints are reachable from outside
+// the cluster through the tunnel server, which authenticates users with their Kubernetes token. This allows accessing
+// workspaces on clusters that do not provide any ingress.
+// Exposed endpoints are reported with URLs of the form tunnel://<service>.<namespace>:<port>/<path>, which are resolved
+// by the tunnel server rather than by DNS.
+type TunnelSolver struct{}
+
+var _ RoutingSolver = (*TunnelSolver)(nil)
+
+func (s *TunnelSolver) FinalizerRequired(*controllerv1alpha1.DevWorkspaceRouting) bool {
+	return false
+}
+
+func (s *TunnelSolver) Finalize(*controllerv1alpha1.DevWorkspaceRouting) error {
+	return nil
+}
+
+func (s *TunnelSolver) GetSpecObjects(routing *controllerv1alpha1.DevWorkspaceRouting, workspaceMeta DevWorkspaceMetadata) (RoutingObjects, error) {
+	for _, machineEndpoints := range routing.Spec.Endpoints {
+		for _, endpoint := range machineEndpoints {
+			if endpoint.Exposure == dw.PublicEndpointExposure && endpoint.Protocol == dw.UDPEndpointProtocol {
+				return RoutingObjects{}, &RoutingInvalid{Reason: fmt.Sprintf("endpoint %s uses protocol udp, which is not supported by routing class %s", endpoint.Name, controllerv1alpha1.DevWorkspaceRoutingTunnel)}
+			}
+		}
+	}
+	services := getServicesForEndpoints(routing.Spec.Endpoints, workspaceMeta)
+	services = append(services, GetDiscoverableServicesForEndpoints(routing.Spec.Endpoints, workspaceMeta)...)
+	return RoutingObjects{
+		Services: services,
+	}, nil
+}
+
+func (s *TunnelSolver) GetExposedEndpoints(
+	endpoints map[string]controllerv1alpha1.EndpointList,
+	routingObj RoutingObjects) (exposedEndpoints map[string]controllerv1alpha1.ExposedEndpointList, ready bool, err error) {
+
+	exposedEndpoints = map[string]controllerv1alpha1.ExposedEndpointList{}
+
+	for machineName, machineEndpoints := range endpoints {
+		for _, endpoint := range machineEndpoints {
+			if endpoint.Exposure != dw.PublicEndpointExposure {
+				continue
+			}
+			url, err := resolveTunnelURLForEndpoint(endpoint, getServicesForMachine(machineName, routingObj.Services))
+			if err != nil {
+				return nil, false, err
+			}
+
+			exposedEndpoints[machineName] = append(exposedEndpoints[machineName], controllerv1alpha1.ExposedEndpoint{
+				Name:       endpoint.Name,
+				Url:        url,
+				Attributes: endpoint.Attributes,
+			})
+		}
+	}
+
+	return exposedEndpoints, true, nil
+}
+
+func resolveTunnelURLForEndpoint(endpoint dw.Endpoint, services []corev1.Service) (string, error) {
+	for _, service := range services {
+		for _, servicePort := range service.Spec.Ports {
+			if servicePort.Port == int32(endpoint.TargetPort) {
+				return fmt.Sprintf("%s://%s.%s:%d/%s", tunnelclient.TunnelURLScheme, service.Name, service.Namespace, servicePort.Port, strings.TrimLeft(endpoint.Path, "/")), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("could not find service for endpoint %s", endpoint.Name)
+}
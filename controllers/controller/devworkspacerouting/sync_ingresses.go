@@ -53,9 +53,11 @@ func (r *DevWorkspaceRoutingReconciler) syncIngresses(routing *controllerv1alpha
 	for _, specIngress := range specIngresses {
 		if contains, idx := listContainsIngressByName(specIngress, clusterIngresses); contains {
 			clusterIngress := clusterIngresses[idx]
-			if !cmp.Equal(specIngress, clusterIngress, ingressDiffOpts) {
-				// Update ingress's spec
+			annotations, annotationsChanged := syncManagedAnnotations(specIngress.Annotations, clusterIngress.Annotations)
+			if !cmp.Equal(specIngress, clusterIngress, ingressDiffOpts) || annotationsChanged {
+				// Update ingress's spec and annotations; annotations not managed by the controller are preserved
 				clusterIngress.Spec = specIngress.Spec
+				clusterIngress.Annotations = annotations
 				err := r.Update(context.TODO(), &clusterIngress)
 				if err != nil && !errors.IsConflict(err) {
 					return false, nil, err
@@ -63,6 +65,7 @@ func (r *DevWorkspaceRoutingReconciler) syncIngresses(routing *controllerv1alpha
 				ingressesInSync = false
 			}
 		} else {
+			specIngress.Annotations, _ = syncManagedAnnotations(specIngress.Annotations, nil)
 			err := r.Create(context.TODO(), &specIngress)
 			if err != nil {
 				return false, nil, err
@@ -54,9 +54,11 @@ func (r *DevWorkspaceRoutingReconciler) syncRoutes(routing *controllerv1alpha1.D
 	for _, specRoute := range specRoutes {
 		if contains, idx := listContainsRouteByName(specRoute, clusterRoutes); contains {
 			clusterRoute := clusterRoutes[idx]
-			if !cmp.Equal(specRoute, clusterRoute, routeDiffOpts) {
-				// Update route's spec
+			annotations, annotationsChanged := syncManagedAnnotations(specRoute.Annotations, clusterRoute.Annotations)
+			if !cmp.Equal(specRoute, clusterRoute, routeDiffOpts) || annotationsChanged {
+				// Update route's spec and annotations; annotations not managed by the controller are preserved
 				clusterRoute.Spec = specRoute.Spec
+				clusterRoute.Annotations = annotations
 				err := r.Update(context.TODO(), &clusterRoute)
 				if err != nil && !errors.IsConflict(err) {
 					return false, nil, err
@@ -65,6 +67,7 @@ func (r *DevWorkspaceRoutingReconciler) syncRoutes(routing *controllerv1alpha1.D
 				routesInSync = false
 			}
 		} else {
+			specRoute.Annotations, _ = syncManagedAnnotations(specRoute.Annotations, nil)
 			err := r.Create(context.TODO(), &specRoute)
 			if err != nil {
 				return false, nil, err
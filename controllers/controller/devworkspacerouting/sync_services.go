@@ -31,8 +31,8 @@ import (
 
 var serviceDiffOpts = cmp.Options{
 	cmpopts.IgnoreFields(corev1.Service{}, "TypeMeta", "ObjectMeta", "Status"),
-	cmpopts.IgnoreFields(corev1.ServiceSpec{}, "ClusterIP", "SessionAffinity"),
-	cmpopts.IgnoreFields(corev1.ServicePort{}, "TargetPort"),
+	cmpopts.IgnoreFields(corev1.ServiceSpec{}, "ClusterIP", "SessionAffinity", "ExternalTrafficPolicy", "HealthCheckNodePort"),
+	cmpopts.IgnoreFields(corev1.ServicePort{}, "TargetPort", "NodePort"),
 	cmpopts.SortSlices(func(a, b corev1.ServicePort) bool {
 		return strings.Compare(a.Name, b.Name) > 0
 	}),
@@ -58,11 +58,14 @@ func (r *DevWorkspaceRoutingReconciler) syncServices(routing *controllerv1alpha1
 	for _, specService := range specServices {
 		if contains, idx := listContainsByName(specService, clusterServices); contains {
 			clusterService := clusterServices[idx]
-			if !cmp.Equal(specService, clusterService, serviceDiffOpts) {
+			annotations, annotationsChanged := syncManagedAnnotations(specService.Annotations, clusterService.Annotations)
+			if !cmp.Equal(specService, clusterService, serviceDiffOpts) || annotationsChanged {
 				// Cannot naively copy spec, as clusterIP is unmodifiable
 				clusterIP := clusterService.Spec.ClusterIP
 				clusterService.Spec = specService.Spec
+				clusterService.Annotations = annotations
 				clusterService.Spec.ClusterIP = clusterIP
+				preserveNodePorts(&clusterService, clusterServices[idx].Spec.Ports)
 				err := r.Update(context.TODO(), &clusterService)
 				if err != nil && !errors.IsConflict(err) {
 					return false, nil, err
@@ -70,6 +73,7 @@ func (r *DevWorkspaceRoutingReconciler) syncServices(routing *controllerv1alpha1
 				servicesInSync = false
 			}
 		} else {
+			specService.Annotations, _ = syncManagedAnnotations(specService.Annotations, nil)
 			err := r.Create(context.TODO(), &specService)
 			if err != nil {
 				return false, nil, err
@@ -81,6 +85,21 @@ func (r *DevWorkspaceRoutingReconciler) syncServices(routing *controllerv1alpha1
 	return servicesInSync, clusterServices, nil
 }
 
+// preserveNodePorts copies node ports allocated on the cluster into the updated service's ports
+// to avoid having them reassigned on every update.
+func preserveNodePorts(service *corev1.Service, clusterPorts []corev1.ServicePort) {
+	if service.Spec.Type != corev1.ServiceTypeNodePort && service.Spec.Type != corev1.ServiceTypeLoadBalancer {
+		return
+	}
+	for idx, port := range service.Spec.Ports {
+		for _, clusterPort := range clusterPorts {
+			if port.Name == clusterPort.Name && port.NodePort == 0 {
+				service.Spec.Ports[idx].NodePort = clusterPort.NodePort
+			}
+		}
+	}
+}
+
 func (r *DevWorkspaceRoutingReconciler) getClusterServices(routing *controllerv1alpha1.DevWorkspaceRouting) ([]corev1.Service, error) {
 	found := &corev1.ServiceList{}
 	labelSelector, err := labels.Parse(fmt.Sprintf("%s=%s", constants.DevWorkspaceIDLabel, routing.Spec.DevWorkspaceId))
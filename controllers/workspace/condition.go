@@ -17,16 +17,65 @@ import (
 	corev1 "k8s.io/api/core/v1"
 )
 
+const (
+	// InvalidDevWorkspaceReason is the reason set on the DevWorkspaceValid condition when validation fails
+	InvalidDevWorkspaceReason = "InvalidDevWorkspace"
+	// PostStopPendingReason is the reason set on the PostStopCommandsSucceeded condition while a DevWorkspace that
+	// reached the Running phase is stopping and its postStop commands have not yet run
+	PostStopPendingReason = "PostStopPending"
+)
+
 const (
 	PullSecretsReady     dw.DevWorkspaceConditionType = "PullSecretsReady"
 	DevWorkspaceResolved dw.DevWorkspaceConditionType = "DevWorkspaceResolved"
 	StorageReady         dw.DevWorkspaceConditionType = "StorageReady"
 	DeploymentReady      dw.DevWorkspaceConditionType = "DeploymentReady"
+	// KubernetesComponentsReady is set when resources defined by kubernetes and openshift components have been applied.
+	// It is only set for DevWorkspaces that deploy such components, and is used to detect when all such components are
+	// removed from a running DevWorkspace.
+	KubernetesComponentsReady dw.DevWorkspaceConditionType = "KubernetesComponentsReady"
+	// ImagesBuilt is set when images described by image components have been built and pushed. It is only set for
+	// DevWorkspaces that build images.
+	ImagesBuilt dw.DevWorkspaceConditionType = "ImagesBuilt"
+	// DevWorkspaceWarning is set when a DevWorkspace can be started but contains issues the user should be made aware
+	// of, e.g. references to undefined variables. It is not included in conditionOrder as it does not affect the phase.
+	DevWorkspaceWarning dw.DevWorkspaceConditionType = "DevWorkspaceWarning"
+	// TemplatesUpToDate reports whether a running DevWorkspace uses the current content of the DevWorkspaceTemplates
+	// it references. It is only set for DevWorkspaces that use the 'manual' template update policy.
+	TemplatesUpToDate dw.DevWorkspaceConditionType = "TemplatesUpToDate"
+	// ImportLockVerified reports whether the locked content of plugins and parents matches the digests recorded when
+	// they were first resolved. It is only set for DevWorkspaces that have locked imports.
+	ImportLockVerified dw.DevWorkspaceConditionType = "ImportLockVerified"
+	// DevWorkspaceValid is set to false when a DevWorkspace fails validation. As DevWorkspace status is defined by the
+	// devfile API, the problems found are stored in its message as a JSON list of elements and messages (see
+	// validation.Problems) to allow clients to process them. It is only set for DevWorkspaces that are invalid.
+	DevWorkspaceValid dw.DevWorkspaceConditionType = "DevWorkspaceValid"
+	// PostStartCommandsSucceeded reports whether commands bound to the postStart event completed successfully. It is only
+	// set for DevWorkspaces that define postStart commands.
+	PostStartCommandsSucceeded dw.DevWorkspaceConditionType = "PostStartCommandsSucceeded"
+	// PostStopCommandsSucceeded reports whether commands bound to the postStop event completed successfully when the
+	// DevWorkspace was last stopped. It is only set for DevWorkspaces that were stopped after reaching the Running phase
+	// and define postStop commands, and is removed when the DevWorkspace is started again.
+	PostStopCommandsSucceeded dw.DevWorkspaceConditionType = "PostStopCommandsSucceeded"
 )
 
+// optionalConditions are conditions that only apply to some DevWorkspaces or only while an issue is present. Rather
+// than being set to Unknown when they are not observed during a reconcile, they are removed from the DevWorkspace's
+// status, e.g. DevWorkspaceWarning is cleared once the issues it reported are fixed.
+var optionalConditions = map[dw.DevWorkspaceConditionType]bool{
+	DevWorkspaceWarning:       true,
+	KubernetesComponentsReady: true,
+	TemplatesUpToDate:         true,
+	ImportLockVerified:        true,
+	DevWorkspaceValid:         true,
+	PostStopCommandsSucceeded: true,
+}
+
 var conditionOrder = []dw.DevWorkspaceConditionType{
 	DevWorkspaceResolved,
 	StorageReady,
+	KubernetesComponentsReady,
+	ImagesBuilt,
 	dw.DevWorkspaceRoutingReady,
 	dw.DevWorkspaceServiceAccountReady,
 	PullSecretsReady,
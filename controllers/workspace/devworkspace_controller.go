@@ -14,6 +14,7 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -28,7 +29,14 @@ import (
 	containerlib "github.com/devfile/devworkspace-operator/pkg/library/container"
 	"github.com/devfile/devworkspace-operator/pkg/library/flatten"
 	registry "github.com/devfile/devworkspace-operator/pkg/library/flatten/internal_registry"
+	"github.com/devfile/devworkspace-operator/pkg/library/flatten/network"
+	"github.com/devfile/devworkspace-operator/pkg/library/image"
+	"github.com/devfile/devworkspace-operator/pkg/library/kubernetes"
+	"github.com/devfile/devworkspace-operator/pkg/library/overrides"
 	"github.com/devfile/devworkspace-operator/pkg/library/projects"
+	"github.com/devfile/devworkspace-operator/pkg/library/validation"
+	nsconfig "github.com/devfile/devworkspace-operator/pkg/provision/config"
+	"github.com/devfile/devworkspace-operator/pkg/provision/importlock"
 	"github.com/devfile/devworkspace-operator/pkg/provision/metadata"
 	"github.com/devfile/devworkspace-operator/pkg/provision/storage"
 	"github.com/devfile/devworkspace-operator/pkg/timing"
@@ -40,6 +48,7 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -57,6 +66,16 @@ type DevWorkspaceReconciler struct {
 	client.Client
 	Log    logr.Logger
 	Scheme *runtime.Scheme
+	// httpClient is used to fetch plugins and parents referenced by URI or registry ID. Responses are cached across
+	// reconciles.
+	httpClient network.HTTPGetter
+	// ociClient is used to fetch plugins and parents published as OCI artifacts
+	ociClient *network.OCIClient
+	// internalRegistry resolves plugins that do not specify a registry, from DevWorkspaceTemplates in the operator's
+	// namespace or from the registry built into the controller image
+	internalRegistry registry.InternalRegistry
+	// restMapper is used to look up the resources for objects defined by kubernetes and openshift components
+	restMapper meta.RESTMapper
 }
 
 /////// CRD-related RBAC roles
@@ -68,6 +87,7 @@ type DevWorkspaceReconciler struct {
 // +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
 // +kubebuilder:rbac:groups="batch",resources=jobs,verbs=get;create;list;watch;update;patch;delete
 // +kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=mutatingwebhookconfigurations;validatingwebhookconfigurations,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
 // +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles;rolebindings;clusterroles;clusterrolebindings,verbs=get;list;watch;create;update
 // +kubebuilder:rbac:groups=oauth.openshift.io,resources=oauthclients,verbs=get;list;watch;create;update;patch;delete;deletecollection
 // +kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors,verbs=get;create
@@ -145,7 +165,7 @@ func (r *DevWorkspaceReconciler) Reconcile(req ctrl.Request) (reconcileResult ct
 	if !workspace.Spec.Started {
 		timing.ClearAnnotations(workspace)
 		r.syncTimingToCluster(ctx, workspace, map[string]string{}, reqLogger)
-		return r.stopWorkspace(workspace, reqLogger)
+		return r.stopWorkspace(workspace, clusterAPI, reqLogger)
 	}
 
 	// Prepare handling workspace status and condition
@@ -171,20 +191,89 @@ func (r *DevWorkspaceReconciler) Reconcile(req ctrl.Request) (reconcileResult ct
 		return reconcile.Result{Requeue: true}, err
 	}
 
+	// Remove postStop job from the last time the workspace was stopped, so that it runs again on the next stop
+	if err := provision.DeletePostStopJob(workspace, clusterAPI); err != nil {
+		return reconcile.Result{}, err
+	}
+
 	timing.SetTime(timingInfo, timing.ComponentsCreated)
+	importLock, err := importlock.GetImportLock(clusterWorkspace, clusterAPI)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	templateUpdatePolicy, err := getTemplateUpdatePolicy(workspace)
+	if err != nil {
+		return r.failWorkspace(workspace, fmt.Sprintf("Error processing devfile: %s", err), reqLogger, &reconcileStatus)
+	}
 	// TODO#185 : Temporarily do devfile flattening in main reconcile loop; this should be moved to a subcontroller.
 	flattenHelpers := flatten.ResolverTools{
 		WorkspaceNamespace: workspace.Namespace,
 		Context:            ctx,
 		K8sClient:          r.Client,
-		InternalRegistry:   &registry.InternalRegistryImpl{},
-		HttpClient:         http.DefaultClient,
+		InternalRegistry:   r.internalRegistry,
+		HttpClient:         r.httpClient,
+		OCIClient:          r.ociClient,
+		ImportLock:         importLock,
+		ResolvedImports:    flatten.ImportLock{},
+		// Running DevWorkspaces keep the content of referenced DevWorkspaceTemplates until restarted, if requested
+		PinKubernetesImports: templateUpdatePolicy == constants.TemplateUpdatePolicyManual &&
+			clusterWorkspace.Status.Phase == dw.DevWorkspaceStatusRunning,
+		OutdatedKubernetesImports: map[string]bool{},
+		InvalidLockedImports:      map[string]bool{},
+	}
+	flattenedWorkspace, warnings, err := flatten.ResolveDevWorkspace(&workspace.Spec.Template, flattenHelpers)
+	var fetchErr *network.NotReadyError
+	if errors.As(err, &fetchErr) {
+		reqLogger.Info(fetchErr.Message)
+		reconcileStatus.setConditionFalse(DevWorkspaceResolved, fmt.Sprintf("Resolving plugins and parents: %s", fetchErr.Message))
+		return reconcile.Result{Requeue: true, RequeueAfter: fetchErr.RequeueAfter}, nil
 	}
-	flattenedWorkspace, err := flatten.ResolveDevWorkspace(&workspace.Spec.Template, flattenHelpers)
 	if err != nil {
 		return r.failWorkspace(workspace, fmt.Sprintf("Error processing devfile: %s", err), reqLogger, &reconcileStatus)
 	}
+	if problems := validation.ValidateDevWorkspace(flattenedWorkspace); problems != nil {
+		return r.failWorkspaceValidation(workspace, problems, reqLogger, &reconcileStatus)
+	}
+	resourcePolicy, err := nsconfig.GetContainerResourcePolicy(workspace.Namespace, clusterAPI)
+	if err != nil {
+		return r.failWorkspace(workspace, fmt.Sprintf("Error reading container resource configuration: %s", err), reqLogger, &reconcileStatus)
+	}
+	if problems := validation.CheckContainerResourcePolicy(flattenedWorkspace, resourcePolicy); problems != nil {
+		return r.failWorkspaceValidation(workspace, problems, reqLogger, &reconcileStatus)
+	}
+	if lockInSync, err := r.syncImportLock(clusterWorkspace, flattenHelpers.ResolvedImports, clusterAPI); err != nil {
+		return reconcile.Result{}, err
+	} else if !lockInSync {
+		reqLogger.Info("Waiting for import lock to be updated")
+		return reconcile.Result{Requeue: true}, nil
+	}
 	workspace.Spec.Template = *flattenedWorkspace
+	podScheduling, err := nsconfig.GetPodScheduling(workspace, clusterAPI)
+	if err != nil {
+		return r.failWorkspace(workspace, fmt.Sprintf("Error reading pod scheduling configuration: %s", err), reqLogger, &reconcileStatus)
+	}
+	if err := overrides.CheckAllowedOverrides(&workspace.Spec.Template, config.ControllerCfg.GetAllowedSecurityContextOverrides()); err != nil {
+		return r.failWorkspace(workspace, fmt.Sprintf("Error processing devfile: %s", err), reqLogger, &reconcileStatus)
+	}
+	securityContexts, err := provision.GetSecurityContexts(workspace, clusterAPI)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if len(flattenHelpers.InvalidLockedImports) > 0 {
+		reconcileStatus.setConditionFalse(ImportLockVerified, formatInvalidLockedImportsMessage(flattenHelpers.InvalidLockedImports))
+	} else if len(importLock) > 0 {
+		reconcileStatus.setConditionTrue(ImportLockVerified, "Locked content of plugins and parents matches recorded digests")
+	}
+	if templateUpdatePolicy == constants.TemplateUpdatePolicyManual {
+		if len(flattenHelpers.OutdatedKubernetesImports) > 0 {
+			reconcileStatus.setConditionFalse(TemplatesUpToDate, formatOutdatedTemplatesMessage(flattenHelpers.OutdatedKubernetesImports))
+		} else {
+			reconcileStatus.setConditionTrue(TemplatesUpToDate, "DevWorkspace uses current content of referenced DevWorkspaceTemplates")
+		}
+	}
+	if warnings != nil {
+		reconcileStatus.setConditionTrue(DevWorkspaceWarning, flatten.FormatVariablesWarning(warnings))
+	}
 	reconcileStatus.setConditionTrue(DevWorkspaceResolved, "Resolved plugins and parents from DevWorkspace")
 
 	storageProvisioner, err := storage.GetProvisioner(workspace)
@@ -202,9 +291,13 @@ func (r *DevWorkspaceReconciler) Reconcile(req ctrl.Request) (reconcileResult ct
 	}
 
 	// Add init container to clone projects
-	projects.AddProjectClonerComponent(&workspace.Spec.Template)
+	projects.AddProjectClonerComponent(&workspace.Spec.Template, resourcePolicy)
 
-	devfilePodAdditions, err := containerlib.GetKubeContainersFromDevfile(&workspace.Spec.Template)
+	devfilePodAdditions, err := containerlib.GetKubeContainersFromDevfile(&workspace.Spec.Template, resourcePolicy)
+	if err != nil {
+		return r.failWorkspace(workspace, fmt.Sprintf("Error processing devfile: %s", err), reqLogger, &reconcileStatus)
+	}
+	dedicatedPods, err := containerlib.GetDedicatedPods(&workspace.Spec.Template, resourcePolicy)
 	if err != nil {
 		return r.failWorkspace(workspace, fmt.Sprintf("Error processing devfile: %s", err), reqLogger, &reconcileStatus)
 	}
@@ -231,6 +324,57 @@ func (r *DevWorkspaceReconciler) Reconcile(req ctrl.Request) (reconcileResult ct
 		return reconcile.Result{Requeue: true}, rbacStatus.Err
 	}
 
+	// Apply resources defined by kubernetes and openshift components
+	k8sComponents, err := kubernetes.GetComponentsToDeploy(&workspace.Spec.Template)
+	if err != nil {
+		return r.failWorkspace(workspace, fmt.Sprintf("Error processing devfile: %s", err), reqLogger, &reconcileStatus)
+	}
+	// Objects are also synced if the DevWorkspace previously deployed kubernetes components, so that objects for
+	// components that have been removed are deleted
+	hadK8sComponents := getConditionByType(clusterWorkspace.Status.Conditions, KubernetesComponentsReady) != nil
+	if len(k8sComponents) > 0 || hadK8sComponents {
+		k8sObjects, err := kubernetes.GetComponentObjects(k8sComponents, r.httpClient)
+		if err != nil {
+			return r.failWorkspace(workspace, fmt.Sprintf("Error processing devfile: %s", err), reqLogger, &reconcileStatus)
+		}
+		k8sComponentsStatus := provision.SyncKubernetesComponents(workspace, k8sObjects, r.restMapper, clusterAPI)
+		if !k8sComponentsStatus.Continue {
+			if k8sComponentsStatus.FailStartup {
+				return r.failWorkspace(workspace, k8sComponentsStatus.Info(), reqLogger, &reconcileStatus)
+			}
+			reqLogger.Info("Waiting on resources from kubernetes components to be ready")
+			reconcileStatus.setConditionFalse(KubernetesComponentsReady, "Applying resources from kubernetes components")
+			return reconcile.Result{Requeue: k8sComponentsStatus.Requeue}, k8sComponentsStatus.Err
+		}
+		if len(k8sComponents) > 0 {
+			reconcileStatus.setConditionTrue(KubernetesComponentsReady, "Resources from kubernetes components applied")
+		}
+	}
+
+	// Build images from image components and use them in containers that reference them
+	imageBuilds, err := image.GetImagesToBuild(&workspace.Spec.Template)
+	if err != nil {
+		return r.failWorkspace(workspace, fmt.Sprintf("Error processing devfile: %s", err), reqLogger, &reconcileStatus)
+	}
+	if len(imageBuilds) > 0 {
+		builtImages, imageBuildStatus := provision.SyncImageBuilds(workspace, imageBuilds, devfilePodAdditions, podScheduling, securityContexts, clusterAPI)
+		if !imageBuildStatus.Continue {
+			if imageBuildStatus.FailStartup {
+				return r.failWorkspace(workspace, imageBuildStatus.Info(), reqLogger, &reconcileStatus)
+			}
+			reqLogger.Info("Waiting on images to be built")
+			reconcileStatus.setConditionFalse(ImagesBuilt, "Building images")
+			return reconcile.Result{Requeue: imageBuildStatus.Requeue}, imageBuildStatus.Err
+		}
+		image.SubstituteImages(devfilePodAdditions, builtImages)
+		for idx, dedicatedPod := range dedicatedPods {
+			if builtImage, ok := builtImages[dedicatedPod.Container.Image]; ok {
+				dedicatedPods[idx].Container.Image = builtImage
+			}
+		}
+		reconcileStatus.setConditionTrue(ImagesBuilt, "Images built")
+	}
+
 	// Step two: Create routing, and wait for routing to be ready
 	timing.SetTime(timingInfo, timing.RoutingCreated)
 	routingStatus := provision.SyncRoutingToCluster(workspace, clusterAPI)
@@ -244,7 +388,7 @@ func (r *DevWorkspaceReconciler) Reconcile(req ctrl.Request) (reconcileResult ct
 			message = routingStatus.Message
 		}
 		reconcileStatus.setConditionFalse(dw.DevWorkspaceRoutingReady, message)
-		return reconcile.Result{Requeue: routingStatus.Requeue}, routingStatus.Err
+		return reconcile.Result{Requeue: routingStatus.Requeue, RequeueAfter: routingStatus.RequeueAfter}, routingStatus.Err
 	}
 	reconcileStatus.setConditionTrue(dw.DevWorkspaceRoutingReady, "Networking ready")
 	timing.SetTime(timingInfo, timing.RoutingReady)
@@ -304,11 +448,26 @@ func (r *DevWorkspaceReconciler) Reconcile(req ctrl.Request) (reconcileResult ct
 	allPodAdditions = append(allPodAdditions, pullSecretStatus.PodAdditions)
 	reconcileStatus.setConditionTrue(PullSecretsReady, "DevWorkspace secrets ready")
 
+	// Container components with dedicatedPod set run in their own deployments
+	dedicatedPodsStatus := provision.SyncDedicatedPods(workspace, dedicatedPods, pullSecretStatus.PodAdditions.PullSecrets, serviceAcctName, podScheduling, securityContexts, clusterAPI)
+	if !dedicatedPodsStatus.Continue {
+		if dedicatedPodsStatus.FailStartup {
+			return r.failWorkspace(workspace, dedicatedPodsStatus.Info(), reqLogger, &reconcileStatus)
+		}
+		reqLogger.Info("Waiting on dedicated pods to be ready")
+		reconcileStatus.setConditionFalse(DeploymentReady, "Waiting for dedicated pods to be ready")
+		return reconcile.Result{Requeue: dedicatedPodsStatus.Requeue}, dedicatedPodsStatus.Err
+	}
+	allPodAdditions = append(allPodAdditions, dedicatedPodsStatus.PodAdditions)
+
 	// Step six: Create deployment and wait for it to be ready
 	timing.SetTime(timingInfo, timing.DeploymentCreated)
-	deploymentStatus := provision.SyncDeploymentToCluster(workspace, allPodAdditions, serviceAcctName, clusterAPI)
+	deploymentStatus := provision.SyncDeploymentToCluster(workspace, allPodAdditions, serviceAcctName, podScheduling, securityContexts, clusterAPI)
 	if !deploymentStatus.Continue {
 		if deploymentStatus.FailStartup {
+			if deploymentStatus.PostStartFailed {
+				reconcileStatus.setConditionFalse(PostStartCommandsSucceeded, deploymentStatus.Message)
+			}
 			return r.failWorkspace(workspace, deploymentStatus.Info(), reqLogger, &reconcileStatus)
 		}
 		reqLogger.Info("Waiting on deployment to be ready")
@@ -316,9 +475,13 @@ func (r *DevWorkspaceReconciler) Reconcile(req ctrl.Request) (reconcileResult ct
 		return reconcile.Result{Requeue: deploymentStatus.Requeue}, deploymentStatus.Err
 	}
 	reconcileStatus.setConditionTrue(DeploymentReady, "DevWorkspace deployment ready")
+	if events := workspace.Spec.Template.Events; events != nil && len(events.PostStart) > 0 {
+		// Containers are only ready once their postStart hooks complete
+		reconcileStatus.setConditionTrue(PostStartCommandsSucceeded, "PostStart commands completed")
+	}
 	timing.SetTime(timingInfo, timing.DeploymentReady)
 
-	serverReady, err := checkServerStatus(clusterWorkspace)
+	serverReady, err := checkServerStatus(clusterWorkspace, routingStatus.ExposedEndpoints)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
@@ -333,7 +496,7 @@ func (r *DevWorkspaceReconciler) Reconcile(req ctrl.Request) (reconcileResult ct
 	return reconcile.Result{}, nil
 }
 
-func (r *DevWorkspaceReconciler) stopWorkspace(workspace *dw.DevWorkspace, logger logr.Logger) (reconcile.Result, error) {
+func (r *DevWorkspaceReconciler) stopWorkspace(workspace *dw.DevWorkspace, clusterAPI provision.ClusterAPI, logger logr.Logger) (reconcile.Result, error) {
 	status := currentStatus{phase: dw.DevWorkspaceStatusStopping}
 	if workspace.Status.Phase == devworkspacePhaseFailing || workspace.Status.Phase == dw.DevWorkspaceStatusFailed {
 		status.phase = workspace.Status.Phase
@@ -341,9 +504,16 @@ func (r *DevWorkspaceReconciler) stopWorkspace(workspace *dw.DevWorkspace, logge
 		if failedCondition != nil {
 			status.setCondition(dw.DevWorkspaceFailedStart, *failedCondition)
 		}
+		if postStartCondition := getConditionByType(workspace.Status.Conditions, PostStartCommandsSucceeded); postStartCondition != nil {
+			status.setCondition(PostStartCommandsSucceeded, *postStartCondition)
+		}
+		if validCondition := getConditionByType(workspace.Status.Conditions, DevWorkspaceValid); validCondition != nil {
+			status.setCondition(DevWorkspaceValid, *validCondition)
+		}
 	}
+	syncPostStopCondition(workspace, &status)
 
-	stopped, err := r.doStop(workspace, logger)
+	stopped, err := r.doStop(workspace, clusterAPI, &status, logger)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
@@ -359,7 +529,7 @@ func (r *DevWorkspaceReconciler) stopWorkspace(workspace *dw.DevWorkspace, logge
 	return r.updateWorkspaceStatus(workspace, logger, &status, reconcile.Result{}, nil)
 }
 
-func (r *DevWorkspaceReconciler) doStop(workspace *dw.DevWorkspace, logger logr.Logger) (stopped bool, err error) {
+func (r *DevWorkspaceReconciler) doStop(workspace *dw.DevWorkspace, clusterAPI provision.ClusterAPI, status *currentStatus, logger logr.Logger) (stopped bool, err error) {
 	workspaceDeployment := &appsv1.Deployment{}
 	namespaceName := types.NamespacedName{
 		Name:      common.DeploymentName(workspace.Status.DevWorkspaceId),
@@ -368,7 +538,7 @@ func (r *DevWorkspaceReconciler) doStop(workspace *dw.DevWorkspace, logger logr.
 	err = r.Get(context.TODO(), namespaceName, workspaceDeployment)
 	if err != nil {
 		if k8sErrors.IsNotFound(err) {
-			return true, nil
+			return r.cleanUpStoppedWorkspace(workspace, nil, clusterAPI, status, logger)
 		}
 		return false, err
 	}
@@ -384,7 +554,7 @@ func (r *DevWorkspaceReconciler) doStop(workspace *dw.DevWorkspace, logger logr.
 	}
 
 	if workspaceDeployment.Status.Replicas == 0 {
-		return true, nil
+		return r.cleanUpStoppedWorkspace(workspace, workspaceDeployment, clusterAPI, status, logger)
 	}
 	return false, nil
 }
@@ -402,6 +572,17 @@ func (r *DevWorkspaceReconciler) failWorkspace(workspace *dw.DevWorkspace, msg s
 	return reconcile.Result{}, nil
 }
 
+// failWorkspaceValidation marks a workspace as failed due to the provided validation problems, recording them in the
+// DevWorkspaceValid condition in addition to the failure message.
+func (r *DevWorkspaceReconciler) failWorkspaceValidation(workspace *dw.DevWorkspace, problems validation.Problems, logger logr.Logger, status *currentStatus) (reconcile.Result, error) {
+	status.setCondition(DevWorkspaceValid, dw.DevWorkspaceCondition{
+		Status:  corev1.ConditionFalse,
+		Reason:  InvalidDevWorkspaceReason,
+		Message: problems.Encode(),
+	})
+	return r.failWorkspace(workspace, problems.Error(), logger, status)
+}
+
 func (r *DevWorkspaceReconciler) syncTimingToCluster(
 	ctx context.Context, workspace *dw.DevWorkspace, timingInfo map[string]string, reqLogger logr.Logger) {
 	if timing.IsEnabled() {
@@ -457,20 +638,78 @@ func dwRelatedPodsHandler() handler.EventHandler {
 	return &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(podToDW)}
 }
 
+// syncImportLock stores the content of resolved plugins and parents for a DevWorkspace and records their digests and
+// the DevWorkspaceTemplates they were resolved from in the DevWorkspace's annotations, clearing any request to update
+// imports. Returns false if the DevWorkspace or lock were updated on the cluster.
+func (r *DevWorkspaceReconciler) syncImportLock(workspace *dw.DevWorkspace, resolved flatten.ImportLock, clusterAPI provision.ClusterAPI) (inSync bool, err error) {
+	if inSync, err := importlock.SyncImportLock(workspace, resolved, clusterAPI); err != nil || !inSync {
+		return false, err
+	}
+	lockAnnotation, err := importlock.GetImportLockAnnotation(resolved)
+	if err != nil {
+		return false, err
+	}
+	kubernetesImportsAnnotation := strings.Join(resolved.KubernetesImports(), ",")
+	if workspace.Annotations[constants.DevWorkspaceImportLockAnnotation] == lockAnnotation &&
+		workspace.Annotations[constants.DevWorkspaceKubernetesImportsAnnotation] == kubernetesImportsAnnotation &&
+		!importlock.ImportsUpdateRequested(workspace) {
+		return true, nil
+	}
+	if workspace.Annotations == nil {
+		workspace.Annotations = map[string]string{}
+	}
+	if lockAnnotation == "" {
+		delete(workspace.Annotations, constants.DevWorkspaceImportLockAnnotation)
+	} else {
+		workspace.Annotations[constants.DevWorkspaceImportLockAnnotation] = lockAnnotation
+	}
+	if kubernetesImportsAnnotation == "" {
+		delete(workspace.Annotations, constants.DevWorkspaceKubernetesImportsAnnotation)
+	} else {
+		workspace.Annotations[constants.DevWorkspaceKubernetesImportsAnnotation] = kubernetesImportsAnnotation
+	}
+	delete(workspace.Annotations, constants.DevWorkspaceUpdateImportsAnnotation)
+	return false, r.Update(context.TODO(), workspace)
+}
+
 func (r *DevWorkspaceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	maxConcurrentReconciles, err := config.GetMaxConcurrentReconciles()
 	if err != nil {
 		return err
 	}
 
-	// TODO: Set up indexing https://book.kubebuilder.io/cronjob-tutorial/controller-implementation.html#setup
+	r.httpClient = network.NewCachingHTTPGetter(network.CacheOptions{
+		TTL:            config.ControllerCfg.GetRemoteResourcesCacheTTL(),
+		MaxBodySize:    config.ControllerCfg.GetRemoteResourcesMaxSize(),
+		RequestTimeout: config.ControllerCfg.GetRemoteResourcesRequestTimeout(),
+		MaxRetries:     config.ControllerCfg.GetRemoteResourcesMaxRetries(),
+		MaxEntries:     config.ControllerCfg.GetRemoteResourcesCacheMaxEntries(),
+	})
+	r.ociClient = &network.OCIClient{
+		Client:      &http.Client{Timeout: config.ControllerCfg.GetRemoteResourcesRequestTimeout()},
+		MaxBodySize: config.ControllerCfg.GetRemoteResourcesMaxSize(),
+	}
+	r.restMapper = mgr.GetRESTMapper()
+	r.internalRegistry = &registry.ClusterInternalRegistry{
+		Client:    mgr.GetClient(),
+		Namespace: config.ConfigMapReference.Namespace,
+		Fallback:  &registry.InternalRegistryImpl{},
+	}
+	// Serve the list of available internal registry plugins alongside metrics so that clients can discover them
+	if err := mgr.AddMetricsExtraHandler(registry.PluginListPath, registry.NewPluginListHandler(r.internalRegistry)); err != nil {
+		return err
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &dw.DevWorkspace{}, templateRefsIndex, indexTemplateRefs); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		WithOptions(controller.Options{MaxConcurrentReconciles: maxConcurrentReconciles}).
 		For(&dw.DevWorkspace{}).
-		// List DevWorkspaceTemplates as owned to enable updating workspaces when templates
-		// are changed; this should be moved to whichever controller is responsible for flattening
-		// DevWorkspaces
-		Owns(&dw.DevWorkspaceTemplate{}).
+		// Watch DevWorkspaceTemplates to enable updating workspaces when templates they reference are changed; this
+		// should be moved to whichever controller is responsible for flattening DevWorkspaces
+		Watches(&source.Kind{Type: &dw.DevWorkspaceTemplate{}}, r.dwtRelatedWorkspacesHandler()).
 		Owns(&appsv1.Deployment{}).
 		Owns(&batchv1.Job{}).
 		Owns(&controllerv1alpha1.DevWorkspaceRouting{}).
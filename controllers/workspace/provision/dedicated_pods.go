@@ -0,0 +1,381 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package provision
+
+import (
+	"fmt"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/devfile/devworkspace-operator/apis/controller/v1alpha1"
+	"github.com/devfile/devworkspace-operator/controllers/workspace/env"
+	maputils "github.com/devfile/devworkspace-operator/internal/map"
+	"github.com/devfile/devworkspace-operator/pkg/common"
+	"github.com/devfile/devworkspace-operator/pkg/config"
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+	"github.com/devfile/devworkspace-operator/pkg/library/container"
+)
+
+type DedicatedPodsProvisioningStatus struct {
+	ProvisioningStatus
+	// PodAdditions contains host aliases for the DevWorkspace's main pod that resolve the name of each dedicated pod
+	// component to the service exposing it
+	PodAdditions v1alpha1.PodAdditions
+}
+
+// SyncDedicatedPods creates a deployment for each container component with dedicatedPod set, along with a service that
+// exposes the component's endpoints. The service's name is prefixed with the DevWorkspace ID; the returned PodAdditions
+// contain host aliases so that containers in the main pod can reach the component at <component-name>:<port>. Public
+// endpoints of dedicated pods are exposed by the DevWorkspaceRouting. Deployments and services for components that no
+// longer have dedicatedPod set are removed.
+//
+// Dedicated pods are scheduled in the same way as the DevWorkspace's main pod and use the same security contexts. Returns Continue once the pods for all
+// dedicated pod components are ready.
+func SyncDedicatedPods(workspace *dw.DevWorkspace, dedicatedPods []container.DedicatedPod, pullSecrets []corev1.LocalObjectReference,
+	saName string, scheduling config.PodScheduling, securityContexts *SecurityContexts, clusterAPI ClusterAPI) DedicatedPodsProvisioningStatus {
+	expected := map[string]bool{}
+	for _, dedicatedPod := range dedicatedPods {
+		expected[common.DedicatedPodDeploymentName(workspace.Status.DevWorkspaceId, dedicatedPod.ComponentName)] = true
+	}
+	if _, err := deleteDedicatedPodObjects(workspace, expected, clusterAPI); err != nil {
+		return DedicatedPodsProvisioningStatus{ProvisioningStatus: ProvisioningStatus{Err: err}}
+	}
+
+	requeue := false
+	allReady := true
+	podAdditions := v1alpha1.PodAdditions{}
+	for _, dedicatedPod := range dedicatedPods {
+		specDeployment, err := getSpecDedicatedPodDeployment(workspace, dedicatedPod, pullSecrets, saName, scheduling, securityContexts, clusterAPI)
+		if err != nil {
+			return DedicatedPodsProvisioningStatus{ProvisioningStatus: ProvisioningStatus{Err: err}}
+		}
+		if err := securityContexts.CheckRestricted(&specDeployment.Spec.Template); err != nil {
+			return DedicatedPodsProvisioningStatus{
+				ProvisioningStatus: ProvisioningStatus{
+					FailStartup: true,
+					Message:     fmt.Sprintf("Namespace %s enforces the restricted Pod Security Standard: %s", workspace.Namespace, err),
+				},
+			}
+		}
+		clusterDeployment, err := getClusterDeployment(specDeployment.Name, workspace.Namespace, clusterAPI.Client)
+		if err != nil {
+			return DedicatedPodsProvisioningStatus{ProvisioningStatus: ProvisioningStatus{Err: err}}
+		}
+		switch {
+		case clusterDeployment == nil:
+			clusterAPI.Logger.Info("Creating deployment for dedicated pod", "component", dedicatedPod.ComponentName)
+			clusterObj, err := securityContexts.ToClusterObject(specDeployment, clusterAPI.Scheme)
+			if err != nil {
+				return DedicatedPodsProvisioningStatus{ProvisioningStatus: ProvisioningStatus{Err: err}}
+			}
+			if err := clusterAPI.Client.Create(clusterAPI.Ctx, clusterObj); err != nil && !k8sErrors.IsAlreadyExists(err) {
+				return DedicatedPodsProvisioningStatus{ProvisioningStatus: ProvisioningStatus{Err: err}}
+			}
+			requeue = true
+		case !cmp.Equal(specDeployment, clusterDeployment, deploymentDiffOpts):
+			clusterAPI.Logger.Info("Updating deployment for dedicated pod", "component", dedicatedPod.ComponentName)
+			clusterDeployment.Spec = specDeployment.Spec
+			clusterObj, err := securityContexts.ToClusterObject(clusterDeployment, clusterAPI.Scheme)
+			if err != nil {
+				return DedicatedPodsProvisioningStatus{ProvisioningStatus: ProvisioningStatus{Err: err}}
+			}
+			if err := clusterAPI.Client.Update(clusterAPI.Ctx, clusterObj); err != nil && !k8sErrors.IsConflict(err) {
+				return DedicatedPodsProvisioningStatus{ProvisioningStatus: ProvisioningStatus{Err: err}}
+			}
+			requeue = true
+		case !checkDeploymentStatus(clusterDeployment):
+			allReady = false
+			failureMsg, err := checkFailedDedicatedPods(workspace, dedicatedPod.ComponentName, clusterAPI)
+			if err != nil {
+				return DedicatedPodsProvisioningStatus{ProvisioningStatus: ProvisioningStatus{Err: err}}
+			}
+			if failureMsg != "" {
+				return DedicatedPodsProvisioningStatus{ProvisioningStatus: ProvisioningStatus{FailStartup: true, Message: failureMsg}}
+			}
+		}
+
+		clusterService, serviceStatus := syncDedicatedPodService(workspace, dedicatedPod, clusterAPI)
+		if !serviceStatus.Continue {
+			if serviceStatus.FailStartup || serviceStatus.Err != nil {
+				return DedicatedPodsProvisioningStatus{ProvisioningStatus: serviceStatus}
+			}
+			requeue = true
+			continue
+		}
+		if clusterService != nil {
+			podAdditions.HostAliases = append(podAdditions.HostAliases, corev1.HostAlias{
+				IP:        clusterService.Spec.ClusterIP,
+				Hostnames: []string{dedicatedPod.ComponentName},
+			})
+		}
+	}
+	if requeue {
+		return DedicatedPodsProvisioningStatus{ProvisioningStatus: ProvisioningStatus{Requeue: true}}
+	}
+	return DedicatedPodsProvisioningStatus{
+		ProvisioningStatus: ProvisioningStatus{Continue: allReady},
+		PodAdditions:       podAdditions,
+	}
+}
+
+// DeleteDedicatedPods removes the deployments and services created for container components with dedicatedPod set.
+// Returns true once all have been removed.
+func DeleteDedicatedPods(workspace *dw.DevWorkspace, clusterAPI ClusterAPI) (deleted bool, err error) {
+	remaining, err := deleteDedicatedPodObjects(workspace, nil, clusterAPI)
+	return !remaining, err
+}
+
+// deleteDedicatedPodObjects deletes deployments and services for dedicated pods, except those for deployments whose
+// names are in keep. Returns true if any objects were found that are not yet removed.
+func deleteDedicatedPodObjects(workspace *dw.DevWorkspace, keep map[string]bool, clusterAPI ClusterAPI) (remaining bool, err error) {
+	listOpts := []client.ListOption{
+		client.InNamespace(workspace.Namespace),
+		client.MatchingLabels{constants.DevWorkspaceIDLabel: workspace.Status.DevWorkspaceId},
+		client.HasLabels{constants.DevWorkspaceDedicatedPodLabel},
+	}
+	deployments := &appsv1.DeploymentList{}
+	if err := clusterAPI.Client.List(clusterAPI.Ctx, deployments, listOpts...); err != nil {
+		return false, err
+	}
+	services := &corev1.ServiceList{}
+	if err := clusterAPI.Client.List(clusterAPI.Ctx, services, listOpts...); err != nil {
+		return false, err
+	}
+	type object interface {
+		runtime.Object
+		metav1.Object
+	}
+	var toDelete []object
+	for idx := range deployments.Items {
+		toDelete = append(toDelete, &deployments.Items[idx])
+	}
+	for idx := range services.Items {
+		toDelete = append(toDelete, &services.Items[idx])
+	}
+
+	propagationPolicy := metav1.DeletePropagationBackground
+	for _, obj := range toDelete {
+		if keep[obj.GetLabels()[constants.DevWorkspaceDedicatedPodLabel]] {
+			continue
+		}
+		remaining = true
+		if obj.GetDeletionTimestamp() != nil {
+			continue
+		}
+		clusterAPI.Logger.Info("Deleting object for dedicated pod", "name", obj.GetName())
+		err := clusterAPI.Client.Delete(clusterAPI.Ctx, obj, &client.DeleteOptions{PropagationPolicy: &propagationPolicy})
+		if err != nil && !k8sErrors.IsNotFound(err) {
+			return remaining, err
+		}
+	}
+	return remaining, nil
+}
+
+func getSpecDedicatedPodDeployment(workspace *dw.DevWorkspace, dedicatedPod container.DedicatedPod,
+	pullSecrets []corev1.LocalObjectReference, saName string, scheduling config.PodScheduling, securityContexts *SecurityContexts,
+	clusterAPI ClusterAPI) (*appsv1.Deployment, error) {
+	workspaceId := workspace.Status.DevWorkspaceId
+	name := common.DedicatedPodDeploymentName(workspaceId, dedicatedPod.ComponentName)
+	replicas := int32(1)
+	terminationGracePeriod := int64(1)
+
+	creator, present := workspace.Labels[constants.DevWorkspaceCreatorLabel]
+	if !present {
+		return nil, fmt.Errorf("workspace must have creator specified to be run. Recreate it to fix an issue")
+	}
+	podContainer := dedicatedPod.Container.DeepCopy()
+	podContainer.Env = append(podContainer.Env, env.CommonEnvironmentVariables(workspace.Name, workspaceId, workspace.Namespace, creator)...)
+
+	podAnnotations := map[string]string{}
+	for key, value := range dedicatedPod.Annotations {
+		podAnnotations[key] = value
+	}
+	var annotations map[string]string
+	if restrictedAccess, ok := workspace.Annotations[constants.DevWorkspaceRestrictedAccessAnnotation]; ok {
+		annotations = maputils.Append(annotations, constants.DevWorkspaceRestrictedAccessAnnotation, restrictedAccess)
+		podAnnotations[constants.DevWorkspaceRestrictedAccessAnnotation] = restrictedAccess
+	}
+	if len(podAnnotations) == 0 {
+		podAnnotations = nil
+	}
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: workspace.Namespace,
+			Labels: map[string]string{
+				constants.DevWorkspaceIDLabel:           workspaceId,
+				constants.DevWorkspaceNameLabel:         workspace.Name,
+				constants.DevWorkspaceCreatorLabel:      creator,
+				constants.DevWorkspaceDedicatedPodLabel: name,
+			},
+			Annotations: annotations,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					constants.DevWorkspaceDedicatedPodLabel: name,
+				},
+			},
+			Strategy: appsv1.DeploymentStrategy{
+				Type: appsv1.RecreateDeploymentStrategyType,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					// Pods must not have the DevWorkspace ID label, as it is used to select the DevWorkspace's main pod
+					Labels: map[string]string{
+						constants.DevWorkspaceNameLabel:         workspace.Name,
+						constants.DevWorkspaceCreatorLabel:      creator,
+						constants.DevWorkspaceDedicatedPodLabel: name,
+					},
+					Annotations: podAnnotations,
+				},
+				Spec: corev1.PodSpec{
+					Containers:                    []corev1.Container{*podContainer},
+					ImagePullSecrets:              pullSecrets,
+					RestartPolicy:                 "Always",
+					TerminationGracePeriodSeconds: &terminationGracePeriod,
+					ServiceAccountName:            saName,
+				},
+			},
+		},
+	}
+
+	scheduling.ApplyTo(&deployment.Spec.Template.Spec)
+	if err := securityContexts.ApplyTo(&deployment.Spec.Template); err != nil {
+		return nil, err
+	}
+
+	err := controllerutil.SetControllerReference(workspace, deployment, clusterAPI.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	return deployment, nil
+}
+
+// syncDedicatedPodService creates or updates the service that exposes the endpoints of a dedicated pod component to
+// other components in the DevWorkspace, and returns the service once it is in sync. No service is created if the
+// component has no endpoints. Startup fails if a service with the same name exists that was not created for the
+// DevWorkspace.
+func syncDedicatedPodService(workspace *dw.DevWorkspace, dedicatedPod container.DedicatedPod, clusterAPI ClusterAPI) (*corev1.Service, ProvisioningStatus) {
+	deploymentName := common.DedicatedPodDeploymentName(workspace.Status.DevWorkspaceId, dedicatedPod.ComponentName)
+	var ports []corev1.ServicePort
+	exposedPorts := map[string]bool{}
+	for _, endpoint := range dedicatedPod.Endpoints {
+		protocol := corev1.ProtocolTCP
+		if endpoint.Protocol == dw.UDPEndpointProtocol {
+			protocol = corev1.ProtocolUDP
+		}
+		portKey := fmt.Sprintf("%d/%s", endpoint.TargetPort, protocol)
+		if exposedPorts[portKey] {
+			continue
+		}
+		exposedPorts[portKey] = true
+		ports = append(ports, corev1.ServicePort{
+			Name:       common.EndpointName(endpoint.Name),
+			Protocol:   protocol,
+			Port:       int32(endpoint.TargetPort),
+			TargetPort: intstr.FromInt(endpoint.TargetPort),
+		})
+	}
+	if len(ports) == 0 {
+		return nil, ProvisioningStatus{Continue: true}
+	}
+	specService := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      common.DedicatedPodServiceName(workspace.Status.DevWorkspaceId, dedicatedPod.ComponentName),
+			Namespace: workspace.Namespace,
+			Labels: map[string]string{
+				constants.DevWorkspaceIDLabel:           workspace.Status.DevWorkspaceId,
+				constants.DevWorkspaceDedicatedPodLabel: deploymentName,
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{
+				constants.DevWorkspaceDedicatedPodLabel: deploymentName,
+			},
+			Type:  corev1.ServiceTypeClusterIP,
+			Ports: ports,
+		},
+	}
+	if err := controllerutil.SetControllerReference(workspace, specService, clusterAPI.Scheme); err != nil {
+		return nil, ProvisioningStatus{Err: err}
+	}
+
+	clusterService := &corev1.Service{}
+	namespacedName := types.NamespacedName{Name: specService.Name, Namespace: specService.Namespace}
+	err := clusterAPI.Client.Get(clusterAPI.Ctx, namespacedName, clusterService)
+	if err != nil {
+		if !k8sErrors.IsNotFound(err) {
+			return nil, ProvisioningStatus{Err: err}
+		}
+		clusterAPI.Logger.Info("Creating service for dedicated pod", "component", dedicatedPod.ComponentName)
+		if err := clusterAPI.Client.Create(clusterAPI.Ctx, specService); err != nil && !k8sErrors.IsAlreadyExists(err) {
+			return nil, ProvisioningStatus{Err: err}
+		}
+		return nil, ProvisioningStatus{Requeue: true}
+	}
+
+	if clusterService.Labels[constants.DevWorkspaceIDLabel] != workspace.Status.DevWorkspaceId {
+		return nil, ProvisioningStatus{
+			FailStartup: true,
+			Message: fmt.Sprintf("Cannot create service %s for component %s: service already exists and does not belong to this DevWorkspace",
+				specService.Name, dedicatedPod.ComponentName),
+		}
+	}
+	if !cmp.Equal(specService.Spec.Selector, clusterService.Spec.Selector) || !cmp.Equal(specService.Spec.Ports, clusterService.Spec.Ports) {
+		clusterAPI.Logger.Info("Updating service for dedicated pod", "component", dedicatedPod.ComponentName)
+		clusterService.Spec.Selector = specService.Spec.Selector
+		clusterService.Spec.Ports = specService.Spec.Ports
+		if err := clusterAPI.Client.Update(clusterAPI.Ctx, clusterService); err != nil && !k8sErrors.IsConflict(err) {
+			return nil, ProvisioningStatus{Err: err}
+		}
+		return nil, ProvisioningStatus{Requeue: true}
+	}
+	if clusterService.Spec.ClusterIP == "" {
+		// Host aliases for the service cannot be added until it has been assigned an IP
+		return nil, ProvisioningStatus{Requeue: true}
+	}
+	return clusterService, ProvisioningStatus{Continue: true}
+}
+
+// checkFailedDedicatedPods checks whether the pods for a dedicated pod component are in an unrecoverable state, e.g.
+// CrashLoopBackOff. Returns a message describing the failure, or an empty string if no failure is found.
+func checkFailedDedicatedPods(workspace *dw.DevWorkspace, componentName string, clusterAPI ClusterAPI) (string, error) {
+	pods := &corev1.PodList{}
+	err := clusterAPI.Client.List(clusterAPI.Ctx, pods, client.InNamespace(workspace.Namespace), client.MatchingLabels{
+		constants.DevWorkspaceDedicatedPodLabel: common.DedicatedPodDeploymentName(workspace.Status.DevWorkspaceId, componentName),
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, pod := range pods.Items {
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+			if !checkContainerStatusForFailure(&containerStatus) {
+				return fmt.Sprintf("Container %s in dedicated pod has state %s", containerStatus.Name, containerStatus.State.Waiting.Reason), nil
+			}
+		}
+	}
+	return "", nil
+}
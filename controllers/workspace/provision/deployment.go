@@ -26,6 +26,7 @@ import (
 	"github.com/devfile/devworkspace-operator/pkg/config"
 	"github.com/devfile/devworkspace-operator/pkg/constants"
 	"github.com/devfile/devworkspace-operator/pkg/infrastructure"
+	"github.com/devfile/devworkspace-operator/pkg/library/lifecycle"
 
 	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
 
@@ -49,8 +50,13 @@ var ContainerFailureStateReasons = []string{
 	"RunContainerError",
 }
 
+// postStartHookErrorReason is the reason set on a container's waiting state when its postStart lifecycle hook fails
+const postStartHookErrorReason = "PostStartHookError"
+
 type DeploymentProvisioningStatus struct {
 	ProvisioningStatus
+	// PostStartFailed is true if startup failed because a postStart command failed in one of the workspace's containers
+	PostStartFailed bool
 }
 
 var deploymentDiffOpts = cmp.Options{
@@ -73,6 +79,8 @@ func SyncDeploymentToCluster(
 	workspace *dw.DevWorkspace,
 	podAdditions []v1alpha1.PodAdditions,
 	saName string,
+	scheduling config.PodScheduling,
+	securityContexts *SecurityContexts,
 	clusterAPI ClusterAPI) DeploymentProvisioningStatus {
 
 	cmPodAdditions, configmapEnvFromSourceAdditions, err := getDevWorkspaceConfigmaps(workspace.Namespace, clusterAPI.Client)
@@ -101,7 +109,7 @@ func SyncDeploymentToCluster(
 
 	// [design] we have to pass components and routing pod additions separately because we need mountsources from each
 	// component.
-	specDeployment, err := getSpecDeployment(workspace, podAdditions, envFromSourceAdditions, saName, clusterAPI.Scheme)
+	specDeployment, err := getSpecDeployment(workspace, podAdditions, envFromSourceAdditions, saName, scheduling, securityContexts, clusterAPI.Scheme)
 	if err != nil {
 		return DeploymentProvisioningStatus{
 			ProvisioningStatus: ProvisioningStatus{
@@ -110,6 +118,14 @@ func SyncDeploymentToCluster(
 			},
 		}
 	}
+	if err := securityContexts.CheckRestricted(&specDeployment.Spec.Template); err != nil {
+		return DeploymentProvisioningStatus{
+			ProvisioningStatus: ProvisioningStatus{
+				FailStartup: true,
+				Message:     fmt.Sprintf("Namespace %s enforces the restricted Pod Security Standard: %s", workspace.Namespace, err),
+			},
+		}
+	}
 	clusterDeployment, err := getClusterDeployment(specDeployment.Name, workspace.Namespace, clusterAPI.Client)
 	if err != nil {
 		return DeploymentProvisioningStatus{
@@ -119,7 +135,11 @@ func SyncDeploymentToCluster(
 
 	if clusterDeployment == nil {
 		clusterAPI.Logger.Info("Creating deployment...")
-		err := clusterAPI.Client.Create(context.TODO(), specDeployment)
+		clusterObj, err := securityContexts.ToClusterObject(specDeployment, clusterAPI.Scheme)
+		if err != nil {
+			return DeploymentProvisioningStatus{ProvisioningStatus: ProvisioningStatus{Err: err}}
+		}
+		err = clusterAPI.Client.Create(context.TODO(), clusterObj)
 		return DeploymentProvisioningStatus{
 			ProvisioningStatus: ProvisioningStatus{
 				Requeue: true,
@@ -133,7 +153,7 @@ func SyncDeploymentToCluster(
 		clusterDeployment.Spec = specDeployment.Spec
 		err := clusterAPI.Client.Delete(context.TODO(), clusterDeployment)
 		if err != nil {
-			return DeploymentProvisioningStatus{ProvisioningStatus{Err: err}}
+			return DeploymentProvisioningStatus{ProvisioningStatus: ProvisioningStatus{Err: err}}
 		}
 		return DeploymentProvisioningStatus{
 			ProvisioningStatus: ProvisioningStatus{Requeue: true},
@@ -143,12 +163,16 @@ func SyncDeploymentToCluster(
 	if !cmp.Equal(specDeployment, clusterDeployment, deploymentDiffOpts) {
 		clusterAPI.Logger.Info("Updating deployment...")
 		clusterDeployment.Spec = specDeployment.Spec
-		err := clusterAPI.Client.Update(context.TODO(), clusterDeployment)
+		clusterObj, err := securityContexts.ToClusterObject(clusterDeployment, clusterAPI.Scheme)
+		if err != nil {
+			return DeploymentProvisioningStatus{ProvisioningStatus: ProvisioningStatus{Err: err}}
+		}
+		err = clusterAPI.Client.Update(context.TODO(), clusterObj)
 		if err != nil {
 			if k8sErrors.IsConflict(err) {
 				return DeploymentProvisioningStatus{ProvisioningStatus: ProvisioningStatus{Requeue: true}}
 			}
-			return DeploymentProvisioningStatus{ProvisioningStatus{Err: err}}
+			return DeploymentProvisioningStatus{ProvisioningStatus: ProvisioningStatus{Err: err}}
 		}
 		return DeploymentProvisioningStatus{
 			ProvisioningStatus: ProvisioningStatus{Requeue: true},
@@ -164,7 +188,7 @@ func SyncDeploymentToCluster(
 		}
 	}
 
-	failureMsg, checkErr := checkFailedPods(workspace, clusterAPI)
+	failureMsg, postStartFailed, checkErr := checkFailedPods(workspace, clusterAPI)
 	if checkErr != nil {
 		return DeploymentProvisioningStatus{
 			ProvisioningStatus: ProvisioningStatus{
@@ -178,6 +202,7 @@ func SyncDeploymentToCluster(
 			FailStartup: failureMsg != "",
 			Message:     failureMsg,
 		},
+		PostStartFailed: postStartFailed,
 	}
 }
 
@@ -240,6 +265,8 @@ func getSpecDeployment(
 	podAdditionsList []v1alpha1.PodAdditions,
 	envFromSourceAdditions []corev1.EnvFromSource,
 	saName string,
+	scheduling config.PodScheduling,
+	securityContexts *SecurityContexts,
 	scheme *runtime.Scheme) (*appsv1.Deployment, error) {
 	replicas := int32(1)
 	terminationGracePeriod := int64(1)
@@ -248,6 +275,9 @@ func getSpecDeployment(
 	if err != nil {
 		return nil, err
 	}
+	if hasPreStopHooks(podAdditions.Containers) {
+		terminationGracePeriod = config.ControllerCfg.GetPreStopTerminationGracePeriodSeconds()
+	}
 
 	creator := workspace.Labels[constants.DevWorkspaceCreatorLabel]
 	var envVars []corev1.EnvVar
@@ -296,9 +326,9 @@ func getSpecDeployment(
 					Containers:                    podAdditions.Containers,
 					ImagePullSecrets:              podAdditions.PullSecrets,
 					Volumes:                       podAdditions.Volumes,
+					HostAliases:                   podAdditions.HostAliases,
 					RestartPolicy:                 "Always",
 					TerminationGracePeriodSeconds: &terminationGracePeriod,
-					SecurityContext:               GetDevWorkspaceSecurityContext(),
 					ServiceAccountName:            saName,
 					AutomountServiceAccountToken:  nil,
 				},
@@ -306,6 +336,11 @@ func getSpecDeployment(
 		},
 	}
 
+	scheduling.ApplyTo(&deployment.Spec.Template.Spec)
+	if err := securityContexts.ApplyTo(&deployment.Spec.Template); err != nil {
+		return nil, err
+	}
+
 	if needsPVCWorkaround(podAdditions) {
 		// Kubernetes creates directories in a PVC to support subpaths such that only the leaf directory has g+rwx permissions.
 		// This means that mounting the subpath e.g. <workspace-id>/plugins will result in the <workspace-id> directory being
@@ -322,6 +357,13 @@ func getSpecDeployment(
 		}
 	}
 
+	for annotKey, annotVal := range podAdditions.Annotations {
+		deployment.Spec.Template.Annotations = maputils.Append(deployment.Spec.Template.Annotations, annotKey, annotVal)
+	}
+	for labelKey, labelVal := range podAdditions.Labels {
+		deployment.Spec.Template.Labels[labelKey] = labelVal
+	}
+
 	workspaceCreator, present := workspace.Labels[constants.DevWorkspaceCreatorLabel]
 	if present {
 		deployment.Labels[constants.DevWorkspaceCreatorLabel] = workspaceCreator
@@ -420,33 +462,53 @@ func getDevWorkspaceSecrets(namespace string, client runtimeClient.Client) (*v1a
 	return podAdditions, additionalEnvVars, nil
 }
 
-// checkFailedPods check if related pods has unrecoverable states: CrashLoopBackOffReason, ImagePullErr
+// checkFailedPods check if related pods has unrecoverable states: CrashLoopBackOffReason, ImagePullErr, failed postStart
+// commands
 // Returns optional message with detected unrecoverable state details
+//         postStartFailed is true if the detected state is caused by a failed postStart command
 //         error is any happens during check
 func checkFailedPods(workspace *dw.DevWorkspace,
-	clusterAPI ClusterAPI) (stateMsg string, checkFailure error) {
+	clusterAPI ClusterAPI) (stateMsg string, postStartFailed bool, checkFailure error) {
 	podList, err := getPods(workspace, clusterAPI.Client)
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 
 	for _, pod := range podList.Items {
 		for _, containerStatus := range pod.Status.ContainerStatuses {
+			if containerStatus.State.Waiting != nil && containerStatus.State.Waiting.Reason == postStartHookErrorReason {
+				return fmt.Sprintf("PostStart commands in container %s failed; see %s in the container for output",
+					containerStatus.Name, postStartLogPathForContainer(pod, containerStatus.Name)), true, nil
+			}
 			if !checkContainerStatusForFailure(&containerStatus) {
-				return fmt.Sprintf("Container %s has state %s", containerStatus.Name, containerStatus.State.Waiting.Reason), nil
+				return fmt.Sprintf("Container %s has state %s", containerStatus.Name, containerStatus.State.Waiting.Reason), false, nil
 			}
 		}
 		for _, initContainerStatus := range pod.Status.InitContainerStatuses {
 			if !checkContainerStatusForFailure(&initContainerStatus) {
-				return fmt.Sprintf("Init Container %s has state %s", initContainerStatus.Name, initContainerStatus.State.Waiting.Reason), nil
+				return fmt.Sprintf("Init Container %s has state %s", initContainerStatus.Name, initContainerStatus.State.Waiting.Reason), false, nil
 			}
 		}
 	}
-	return "", nil
+	return "", false, nil
+}
+
+// postStartLogPathForContainer returns the path postStart output is written to in a container of a pod, with
+// PROJECTS_ROOT resolved from the container's environment.
+func postStartLogPathForContainer(pod corev1.Pod, containerName string) string {
+	for _, container := range pod.Spec.Containers {
+		if container.Name == containerName {
+			return lifecycle.ResolvedPostStartLogPath(container)
+		}
+	}
+	return lifecycle.ResolvedPostStartLogPath(corev1.Container{Name: containerName})
 }
 
 func mergePodAdditions(toMerge []v1alpha1.PodAdditions) (*v1alpha1.PodAdditions, error) {
-	podAdditions := &v1alpha1.PodAdditions{}
+	podAdditions := &v1alpha1.PodAdditions{
+		Annotations: map[string]string{},
+		Labels:      map[string]string{},
+	}
 
 	// "Set"s to store k8s object names and detect duplicates
 	containerNames := map[string]bool{}
@@ -500,6 +562,7 @@ func mergePodAdditions(toMerge []v1alpha1.PodAdditions) (*v1alpha1.PodAdditions,
 			pullSecretNames[pullSecret.Name] = true
 			podAdditions.PullSecrets = append(podAdditions.PullSecrets, pullSecret)
 		}
+		podAdditions.HostAliases = append(podAdditions.HostAliases, additions.HostAliases...)
 	}
 	return podAdditions, nil
 }
@@ -565,6 +628,17 @@ func needsPVCWorkaround(podAdditions *v1alpha1.PodAdditions) bool {
 	return false
 }
 
+// hasPreStopHooks returns true if any container defines a preStop lifecycle hook, in which case the pod needs time to
+// run the hook before it is terminated.
+func hasPreStopHooks(containers []corev1.Container) bool {
+	for _, container := range containers {
+		if container.Lifecycle != nil && container.Lifecycle.PreStop != nil {
+			return true
+		}
+	}
+	return false
+}
+
 func checkContainerStatusForFailure(containerStatus *corev1.ContainerStatus) (ok bool) {
 	if containerStatus.State.Waiting != nil {
 		for _, failureReason := range ContainerFailureStateReasons {
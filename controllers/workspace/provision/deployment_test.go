@@ -0,0 +1,64 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package provision
+
+import (
+	"context"
+	"testing"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+)
+
+func TestCheckFailedPodsReportsPostStartLogPath(t *testing.T) {
+	workspace := &dw.DevWorkspace{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-workspace", Namespace: "test-namespace"},
+		Status:     dw.DevWorkspaceStatus{DevWorkspaceId: "workspaceid"},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-pod",
+			Namespace: "test-namespace",
+			Labels:    map[string]string{constants.DevWorkspaceIDLabel: "workspaceid"},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "tools", Env: []corev1.EnvVar{{Name: "PROJECTS_ROOT", Value: "/projects"}}},
+			},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name:  "tools",
+					State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: postStartHookErrorReason}},
+				},
+			},
+		},
+	}
+	clusterAPI := ClusterAPI{
+		Client: fake.NewFakeClientWithScheme(scheme.Scheme, pod),
+		Scheme: scheme.Scheme,
+		Ctx:    context.TODO(),
+	}
+	msg, postStartFailed, err := checkFailedPods(workspace, clusterAPI)
+	if assert.NoError(t, err) {
+		assert.True(t, postStartFailed, "Should report postStart failure")
+		assert.Equal(t, "PostStart commands in container tools failed; see /projects/.devworkspace/poststart-tools.log in the container for output", msg)
+	}
+}
@@ -0,0 +1,304 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package provision
+
+import (
+	"fmt"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/devfile/devworkspace-operator/apis/controller/v1alpha1"
+	"github.com/devfile/devworkspace-operator/internal/images"
+	"github.com/devfile/devworkspace-operator/pkg/common"
+	"github.com/devfile/devworkspace-operator/pkg/config"
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+	"github.com/devfile/devworkspace-operator/pkg/library/image"
+	"github.com/devfile/devworkspace-operator/pkg/library/projects"
+)
+
+var imageBuildJobBackoffLimit = int32(0)
+
+// SyncImageBuilds runs a job for each image component that needs to be built, which clones the DevWorkspace's projects,
+// builds the image and pushes it to the registry configured for the controller. The job's pod uses the volumes that
+// the project clone init container in podAdditions mounts, so that projects are cloned to the DevWorkspace's storage.
+//
+// Job pods are scheduled in the same way as the DevWorkspace's pods and use the same security contexts, except that the
+// image builder runs as root. Images therefore cannot be built in namespaces that enforce the restricted Pod Security
+// Standard. Once all jobs have completed, returns a map from the imageName of each image component to the reference of
+// the built image, for substituting into containers with image.SubstituteImages. Built images are referenced by the
+// digest reported by the job's pod, as the tag they are pushed to is reused by subsequent builds.
+func SyncImageBuilds(workspace *dw.DevWorkspace, builds []image.ImageBuild, podAdditions *v1alpha1.PodAdditions,
+	scheduling config.PodScheduling, securityContexts *SecurityContexts, clusterAPI ClusterAPI) (builtImages map[string]string, status ProvisioningStatus) {
+	if securityContexts.Restricted {
+		return nil, ProvisioningStatus{
+			FailStartup: true,
+			Message: fmt.Sprintf("Image components cannot be built: namespace %s enforces the restricted Pod Security Standard, "+
+				"but the image builder must run as root", workspace.Namespace),
+		}
+	}
+	registry := config.ControllerCfg.GetImageBuildRegistry()
+	if registry == nil || *registry == "" {
+		return nil, ProvisioningStatus{
+			FailStartup: true,
+			Message:     "Image components are not supported: no registry is configured for pushing built images",
+		}
+	}
+	builderImage := images.GetImageBuilderImage()
+	if builderImage == "" {
+		return nil, ProvisioningStatus{
+			FailStartup: true,
+			Message:     "Image components are not supported: no image is configured for building images",
+		}
+	}
+	if pushSecret := config.ControllerCfg.GetImageBuildPushSecret(); pushSecret != "" {
+		if msg, err := checkImageBuildPushSecret(workspace, pushSecret, clusterAPI); err != nil {
+			return nil, ProvisioningStatus{Err: err}
+		} else if msg != "" {
+			return nil, ProvisioningStatus{FailStartup: true, Message: msg}
+		}
+	}
+	var projectCloner *corev1.Container
+	for idx, container := range podAdditions.InitContainers {
+		if container.Name == projects.ProjectClonerContainerName {
+			projectCloner = &podAdditions.InitContainers[idx]
+		}
+	}
+	if projectCloner == nil {
+		return nil, ProvisioningStatus{
+			FailStartup: true,
+			Message:     "Image components are built from project sources, but the DevWorkspace does not define any projects",
+		}
+	}
+
+	builtImages = map[string]string{}
+	requeue := false
+	for _, build := range builds {
+		destination := image.GetImageReference(*registry, build.Image.ImageName, workspace.Status.DevWorkspaceId)
+
+		clusterJob, err := getClusterImageBuildJob(workspace, build.ComponentName, clusterAPI)
+		if err != nil {
+			return nil, ProvisioningStatus{Err: err}
+		}
+		if clusterJob == nil {
+			specJob, err := getSpecImageBuildJob(workspace, build, destination, builderImage, projectCloner, podAdditions.Volumes, scheduling, securityContexts, clusterAPI)
+			if err != nil {
+				return nil, ProvisioningStatus{Err: err}
+			}
+			if err := securityContexts.CheckRestricted(&specJob.Spec.Template); err != nil {
+				return nil, ProvisioningStatus{
+					FailStartup: true,
+					Message:     fmt.Sprintf("Namespace %s enforces the restricted Pod Security Standard: %s", workspace.Namespace, err),
+				}
+			}
+			clusterObj, err := securityContexts.ToClusterObject(specJob, clusterAPI.Scheme)
+			if err != nil {
+				return nil, ProvisioningStatus{Err: err}
+			}
+			clusterAPI.Logger.Info("Creating image build job", "component", build.ComponentName)
+			if err := clusterAPI.Client.Create(clusterAPI.Ctx, clusterObj); err != nil && !k8sErrors.IsAlreadyExists(err) {
+				return nil, ProvisioningStatus{Err: err}
+			}
+			requeue = true
+			continue
+		}
+
+		jobDone := false
+		for _, condition := range clusterJob.Status.Conditions {
+			if condition.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch condition.Type {
+			case batchv1.JobComplete:
+				jobDone = true
+			case batchv1.JobFailed:
+				return nil, ProvisioningStatus{
+					FailStartup: true,
+					Message: fmt.Sprintf("Failed to build image component %s (%s): see logs for job %q for details",
+						build.ComponentName, condition.Reason, clusterJob.Name),
+				}
+			}
+		}
+		if !jobDone {
+			requeue = true
+			continue
+		}
+		builderPod, err := getSucceededImageBuildPod(clusterJob, clusterAPI)
+		if err != nil {
+			return nil, ProvisioningStatus{Err: err}
+		}
+		if builderPod == nil {
+			return nil, ProvisioningStatus{
+				FailStartup: true,
+				Message: fmt.Sprintf("Failed to determine image built for component %s: no completed pod found for job %q. "+
+					"Restart the DevWorkspace to build it again", build.ComponentName, clusterJob.Name),
+			}
+		}
+		builtImage, err := image.GetBuiltImageReference(destination, builderPod)
+		if err != nil {
+			return nil, ProvisioningStatus{
+				FailStartup: true,
+				Message: fmt.Sprintf("Failed to determine image built for component %s: %s. Restart the DevWorkspace to build it again",
+					build.ComponentName, err),
+			}
+		}
+		builtImages[build.Image.ImageName] = builtImage
+	}
+	if requeue {
+		return nil, ProvisioningStatus{Requeue: true}
+	}
+	return builtImages, ProvisioningStatus{Continue: true}
+}
+
+// DeleteImageBuildJobs removes all image build jobs for a DevWorkspace, so that images are rebuilt from the current
+// project sources the next time the DevWorkspace is started.
+func DeleteImageBuildJobs(workspace *dw.DevWorkspace, clusterAPI ClusterAPI) error {
+	jobs := &batchv1.JobList{}
+	err := clusterAPI.Client.List(clusterAPI.Ctx, jobs,
+		client.InNamespace(workspace.Namespace),
+		client.MatchingLabels{constants.DevWorkspaceIDLabel: workspace.Status.DevWorkspaceId},
+		client.HasLabels{constants.DevWorkspaceImageComponentLabel})
+	if err != nil {
+		return err
+	}
+	propagationPolicy := metav1.DeletePropagationBackground
+	for idx := range jobs.Items {
+		err := clusterAPI.Client.Delete(clusterAPI.Ctx, &jobs.Items[idx], &client.DeleteOptions{PropagationPolicy: &propagationPolicy})
+		if err != nil && !k8sErrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func getSpecImageBuildJob(workspace *dw.DevWorkspace, build image.ImageBuild, destination, builderImage string,
+	projectCloner *corev1.Container, volumes []corev1.Volume, scheduling config.PodScheduling, securityContexts *SecurityContexts,
+	clusterAPI ClusterAPI) (*batchv1.Job, error) {
+	workspaceId := workspace.Status.DevWorkspaceId
+
+	// Projects are cloned by an init container before the image is built from them
+	cloneContainer := projectCloner.DeepCopy()
+	builderContainer := image.GetBuilderContainer(build, builderImage, destination, config.ControllerCfg.GetImageBuildInsecureRegistry())
+	builderContainer.VolumeMounts = append(builderContainer.VolumeMounts, cloneContainer.VolumeMounts...)
+	builderContainer.Env = append(builderContainer.Env, cloneContainer.Env...)
+
+	mountedVolumes := map[string]bool{}
+	for _, volumeMount := range cloneContainer.VolumeMounts {
+		mountedVolumes[volumeMount.Name] = true
+	}
+	var jobVolumes []corev1.Volume
+	for _, volume := range volumes {
+		if mountedVolumes[volume.Name] {
+			jobVolumes = append(jobVolumes, volume)
+		}
+	}
+	if pushSecret := config.ControllerCfg.GetImageBuildPushSecret(); pushSecret != "" {
+		jobVolumes = append(jobVolumes, image.MountPushSecret(&builderContainer, pushSecret))
+	}
+	imageBuildJobTimeout := config.ControllerCfg.GetImageBuildJobTimeoutSeconds()
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      common.ImageBuildJobName(workspaceId, build.ComponentName),
+			Namespace: workspace.Namespace,
+			Labels: map[string]string{
+				constants.DevWorkspaceIDLabel:             workspaceId,
+				constants.DevWorkspaceNameLabel:           workspace.Name,
+				constants.DevWorkspaceImageComponentLabel: build.ComponentName,
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          &imageBuildJobBackoffLimit,
+			ActiveDeadlineSeconds: &imageBuildJobTimeout,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						constants.DevWorkspaceNameLabel: workspace.Name,
+					},
+				},
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{*cloneContainer},
+					Containers:     []corev1.Container{builderContainer},
+					Volumes:        jobVolumes,
+					RestartPolicy:  corev1.RestartPolicyNever,
+				},
+			},
+		},
+	}
+
+	scheduling.ApplyTo(&job.Spec.Template.Spec)
+	if err := securityContexts.ApplyTo(&job.Spec.Template); err != nil {
+		return nil, err
+	}
+
+	err := controllerutil.SetControllerReference(workspace, job, clusterAPI.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// checkImageBuildPushSecret checks that the secret configured for pushing built images exists in the DevWorkspace's
+// namespace and contains docker registry credentials. If it does not, a message explaining the problem is returned.
+func checkImageBuildPushSecret(workspace *dw.DevWorkspace, secretName string, clusterAPI ClusterAPI) (msg string, err error) {
+	secret := &corev1.Secret{}
+	err = clusterAPI.Client.Get(clusterAPI.Ctx, types.NamespacedName{Name: secretName, Namespace: workspace.Namespace}, secret)
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return fmt.Sprintf("Secret %s for pushing built images does not exist in namespace %s", secretName, workspace.Namespace), nil
+		}
+		return "", err
+	}
+	if _, ok := secret.Data[corev1.DockerConfigJsonKey]; !ok {
+		return fmt.Sprintf("Secret %s for pushing built images must be of type %s", secretName, corev1.SecretTypeDockerConfigJson), nil
+	}
+	return "", nil
+}
+
+// getSucceededImageBuildPod returns the pod of an image build job that completed successfully, which reports the digest
+// of the built image. Returns nil if there is no such pod, e.g. because it was deleted.
+func getSucceededImageBuildPod(job *batchv1.Job, clusterAPI ClusterAPI) (*corev1.Pod, error) {
+	pods := &corev1.PodList{}
+	err := clusterAPI.Client.List(clusterAPI.Ctx, pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name})
+	if err != nil {
+		return nil, err
+	}
+	for idx := range pods.Items {
+		if pods.Items[idx].Status.Phase == corev1.PodSucceeded {
+			return &pods.Items[idx], nil
+		}
+	}
+	return nil, nil
+}
+
+func getClusterImageBuildJob(workspace *dw.DevWorkspace, componentName string, clusterAPI ClusterAPI) (*batchv1.Job, error) {
+	namespacedName := types.NamespacedName{
+		Name:      common.ImageBuildJobName(workspace.Status.DevWorkspaceId, componentName),
+		Namespace: workspace.Namespace,
+	}
+	clusterJob := &batchv1.Job{}
+	err := clusterAPI.Client.Get(clusterAPI.Ctx, namespacedName, clusterJob)
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return clusterJob, nil
+}
@@ -0,0 +1,121 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package provision
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/devfile/devworkspace-operator/apis/controller/v1alpha1"
+	"github.com/devfile/devworkspace-operator/pkg/config"
+	"github.com/devfile/devworkspace-operator/pkg/library/image"
+	"github.com/devfile/devworkspace-operator/pkg/library/projects"
+)
+
+func TestSyncImageBuildsUsesBuiltImageDigest(t *testing.T) {
+	os.Setenv("RELATED_IMAGE_image_builder", "builder:latest")
+	defer os.Unsetenv("RELATED_IMAGE_image_builder")
+	config.SetupConfigForTesting(&corev1.ConfigMap{Data: map[string]string{
+		"devworkspace.image_build.registry": "registry.local:5000",
+	}})
+
+	digest := "sha256:" + strings.Repeat("a", 64)
+	workspace := &dw.DevWorkspace{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-workspace", Namespace: "test-namespace"},
+		Status:     dw.DevWorkspaceStatus{DevWorkspaceId: "workspaceid"},
+	}
+	builds := []image.ImageBuild{{ComponentName: "app-image", Image: image.ImageComponent{ImageName: "app"}}}
+	podAdditions := &v1alpha1.PodAdditions{
+		InitContainers: []corev1.Container{{Name: projects.ProjectClonerContainerName}},
+	}
+	completedJob := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "workspaceid-build-app-image", Namespace: "test-namespace"},
+		Status: batchv1.JobStatus{
+			Conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}},
+		},
+	}
+	builderPod := func(message string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "workspaceid-build-app-image-abcde",
+				Namespace: "test-namespace",
+				Labels:    map[string]string{"job-name": "workspaceid-build-app-image"},
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodSucceeded,
+				ContainerStatuses: []corev1.ContainerStatus{
+					{
+						Name:  "build",
+						State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: message}},
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name                string
+		objs                []runtime.Object
+		expectedBuiltImages map[string]string
+		expectedMessage     string
+	}{
+		{
+			name:                "Refers to built image by digest",
+			objs:                []runtime.Object{completedJob.DeepCopy(), builderPod(digest)},
+			expectedBuiltImages: map[string]string{"app": "registry.local:5000/app@" + digest},
+		},
+		{
+			name: "Fails when builder pod does not report digest",
+			objs: []runtime.Object{completedJob.DeepCopy(), builderPod("build logs")},
+			expectedMessage: `Failed to determine image built for component app-image: image builder in pod workspaceid-build-app-image-abcde ` +
+				`did not report a valid image digest: "build logs". Restart the DevWorkspace to build it again`,
+		},
+		{
+			name: "Fails when builder pod no longer exists",
+			objs: []runtime.Object{completedJob.DeepCopy()},
+			expectedMessage: `Failed to determine image built for component app-image: no completed pod found for job ` +
+				`"workspaceid-build-app-image". Restart the DevWorkspace to build it again`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clusterAPI := ClusterAPI{
+				Client: fake.NewFakeClientWithScheme(scheme.Scheme, tt.objs...),
+				Scheme: scheme.Scheme,
+				Ctx:    context.TODO(),
+			}
+			builtImages, status := SyncImageBuilds(workspace, builds, podAdditions, config.PodScheduling{}, &SecurityContexts{}, clusterAPI)
+			if !assert.NoError(t, status.Err) {
+				return
+			}
+			if tt.expectedMessage != "" {
+				assert.True(t, status.FailStartup, "Should fail DevWorkspace startup")
+				assert.Equal(t, tt.expectedMessage, status.Message)
+				return
+			}
+			assert.True(t, status.Continue, "Should continue once images are built")
+			assert.Equal(t, tt.expectedBuiltImages, builtImages)
+		})
+	}
+}
@@ -0,0 +1,356 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package provision
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/devfile/devworkspace-operator/pkg/config"
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+	"github.com/devfile/devworkspace-operator/pkg/library/kubernetes"
+)
+
+// SyncKubernetesComponents applies objects defined by kubernetes and openshift components to the DevWorkspace's
+// namespace. Objects are labelled with the DevWorkspace ID and the name of the component that defines them, are owned
+// by the DevWorkspace, and have the restricted-access annotation if it is set on the DevWorkspace. Objects previously
+// created for the DevWorkspace that are no longer defined by its components are deleted.
+//
+// As objects are created by the controller, the DevWorkspace's creator must be permitted to create them: objects must
+// be of a kind allowed by the controller configuration and namespaced, and the creator is checked using a
+// SubjectAccessReview before an object is created or updated.
+func SyncKubernetesComponents(workspace *dw.DevWorkspace, objects []kubernetes.ComponentObject, mapper meta.RESTMapper, clusterAPI ClusterAPI) ProvisioningStatus {
+	requeue := false
+	specObjs := map[string]bool{}
+	for _, componentObject := range objects {
+		specObj, err := getSpecComponentObject(workspace, componentObject, clusterAPI)
+		if err != nil {
+			return ProvisioningStatus{FailStartup: true, Message: err.Error()}
+		}
+		description := describeObject(componentObject.ComponentName, specObj)
+		mapping, err := getAllowedMapping(specObj, mapper)
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				return ProvisioningStatus{FailStartup: true, Message: fmt.Sprintf("Cannot create %s: %s", description, err)}
+			}
+			var notAllowed *objectNotAllowedError
+			if errors.As(err, &notAllowed) {
+				return ProvisioningStatus{FailStartup: true, Message: fmt.Sprintf("Cannot create %s: %s", description, notAllowed.reason)}
+			}
+			return ProvisioningStatus{Err: err}
+		}
+		specObjs[objectKey(specObj.GroupVersionKind().GroupKind(), specObj.GetName())] = true
+
+		clusterObj, err := getClusterComponentObject(specObj, clusterAPI)
+		if err != nil {
+			if isUnrecoverableObjectError(err) {
+				return ProvisioningStatus{FailStartup: true, Message: fmt.Sprintf("Failed to read %s: %s", description, err)}
+			}
+			return ProvisioningStatus{Err: err}
+		}
+
+		if clusterObj == nil {
+			if msg, err := checkCreatorAccess(workspace, specObj, mapping, "create", clusterAPI); err != nil {
+				return ProvisioningStatus{Err: err}
+			} else if msg != "" {
+				return ProvisioningStatus{FailStartup: true, Message: fmt.Sprintf("Cannot create %s: %s", description, msg)}
+			}
+			clusterAPI.Logger.Info("Creating object from kubernetes component", "component", componentObject.ComponentName,
+				"kind", specObj.GetKind(), "name", specObj.GetName())
+			err := clusterAPI.Client.Create(clusterAPI.Ctx, specObj)
+			if err != nil && !k8sErrors.IsAlreadyExists(err) {
+				if isUnrecoverableObjectError(err) {
+					return ProvisioningStatus{FailStartup: true, Message: fmt.Sprintf("Failed to create %s: %s", description, err)}
+				}
+				return ProvisioningStatus{Err: err}
+			}
+			requeue = true
+			continue
+		}
+
+		if clusterObj.GetLabels()[constants.DevWorkspaceIDLabel] != workspace.Status.DevWorkspaceId {
+			return ProvisioningStatus{
+				FailStartup: true,
+				Message:     fmt.Sprintf("Cannot create %s: object already exists and does not belong to this DevWorkspace", description),
+			}
+		}
+
+		if !objectMatchesSpec(specObj, clusterObj) {
+			if msg, err := checkCreatorAccess(workspace, specObj, mapping, "update", clusterAPI); err != nil {
+				return ProvisioningStatus{Err: err}
+			} else if msg != "" {
+				return ProvisioningStatus{FailStartup: true, Message: fmt.Sprintf("Cannot update %s: %s", description, msg)}
+			}
+			clusterAPI.Logger.Info("Updating object from kubernetes component", "component", componentObject.ComponentName,
+				"kind", specObj.GetKind(), "name", specObj.GetName())
+			specObj.SetResourceVersion(clusterObj.GetResourceVersion())
+			err := clusterAPI.Client.Update(clusterAPI.Ctx, specObj)
+			if err != nil && !k8sErrors.IsConflict(err) {
+				if isUnrecoverableObjectError(err) {
+					return ProvisioningStatus{FailStartup: true, Message: fmt.Sprintf("Failed to update %s: %s", description, err)}
+				}
+				return ProvisioningStatus{Err: err}
+			}
+			requeue = true
+		}
+	}
+
+	// Remove objects that were created for components that have since been removed or changed
+	clusterObjs, err := listKubernetesComponentObjects(workspace, mapper, clusterAPI)
+	if err != nil {
+		return ProvisioningStatus{Err: err}
+	}
+	for _, clusterObj := range clusterObjs {
+		if specObjs[objectKey(clusterObj.GroupVersionKind().GroupKind(), clusterObj.GetName())] || clusterObj.GetDeletionTimestamp() != nil {
+			continue
+		}
+		clusterAPI.Logger.Info("Deleting object no longer defined by kubernetes components",
+			"component", clusterObj.GetLabels()[constants.DevWorkspaceKubernetesComponentLabel],
+			"kind", clusterObj.GetKind(), "name", clusterObj.GetName())
+		if err := deleteComponentObject(clusterObj, clusterAPI); err != nil {
+			return ProvisioningStatus{Err: err}
+		}
+	}
+	return ProvisioningStatus{Continue: !requeue, Requeue: requeue}
+}
+
+// DeleteKubernetesComponents deletes objects created from kubernetes and openshift components from the DevWorkspace's
+// namespace. Objects are found using the labels applied in SyncKubernetesComponents, so objects are removed even if
+// the components that defined them have since been removed from the DevWorkspace. Returns true once all objects are
+// removed.
+func DeleteKubernetesComponents(workspace *dw.DevWorkspace, mapper meta.RESTMapper, clusterAPI ClusterAPI) (deleted bool, err error) {
+	clusterObjs, err := listKubernetesComponentObjects(workspace, mapper, clusterAPI)
+	if err != nil {
+		return false, err
+	}
+	for _, clusterObj := range clusterObjs {
+		if clusterObj.GetDeletionTimestamp() != nil {
+			continue
+		}
+		clusterAPI.Logger.Info("Deleting object from kubernetes component",
+			"component", clusterObj.GetLabels()[constants.DevWorkspaceKubernetesComponentLabel],
+			"kind", clusterObj.GetKind(), "name", clusterObj.GetName())
+		if err := deleteComponentObject(clusterObj, clusterAPI); err != nil {
+			return false, err
+		}
+	}
+	return len(clusterObjs) == 0, nil
+}
+
+// listKubernetesComponentObjects lists objects created from kubernetes and openshift components for a DevWorkspace.
+// As objects can only be created for allowed kinds, only those kinds are listed; kinds that do not exist on the cluster
+// are skipped.
+func listKubernetesComponentObjects(workspace *dw.DevWorkspace, mapper meta.RESTMapper, clusterAPI ClusterAPI) ([]unstructured.Unstructured, error) {
+	selector, err := labels.Parse(fmt.Sprintf("%s=%s,%s", constants.DevWorkspaceIDLabel, workspace.Status.DevWorkspaceId,
+		constants.DevWorkspaceKubernetesComponentLabel))
+	if err != nil {
+		return nil, err
+	}
+	var allowedKinds []string
+	for allowedKind := range config.ControllerCfg.GetKubernetesComponentsAllowedKinds() {
+		allowedKinds = append(allowedKinds, allowedKind)
+	}
+	sort.Strings(allowedKinds)
+
+	var objs []unstructured.Unstructured
+	for _, allowedKind := range allowedKinds {
+		mapping, err := mapper.RESTMapping(schema.ParseGroupKind(allowedKind))
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return nil, err
+		}
+		if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+			continue
+		}
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(mapping.GroupVersionKind.GroupVersion().WithKind(mapping.GroupVersionKind.Kind + "List"))
+		err = clusterAPI.Client.List(clusterAPI.Ctx, list, client.InNamespace(workspace.Namespace), client.MatchingLabelsSelector{Selector: selector})
+		if err != nil {
+			if k8sErrors.IsForbidden(err) || k8sErrors.IsNotFound(err) {
+				// The controller cannot have created objects of this kind
+				continue
+			}
+			return nil, err
+		}
+		objs = append(objs, list.Items...)
+	}
+	return objs, nil
+}
+
+func deleteComponentObject(obj unstructured.Unstructured, clusterAPI ClusterAPI) error {
+	propagationPolicy := metav1.DeletePropagationBackground
+	err := clusterAPI.Client.Delete(clusterAPI.Ctx, &obj, &client.DeleteOptions{PropagationPolicy: &propagationPolicy})
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// objectNotAllowedError is returned when an object defined by a kubernetes component may not be created by the
+// controller.
+type objectNotAllowedError struct {
+	reason string
+}
+
+func (e *objectNotAllowedError) Error() string {
+	return e.reason
+}
+
+// getAllowedMapping returns the REST mapping for an object defined by a kubernetes component, or an
+// objectNotAllowedError if the object's kind is not in the list of allowed kinds or is cluster-scoped.
+func getAllowedMapping(obj *unstructured.Unstructured, mapper meta.RESTMapper) (*meta.RESTMapping, error) {
+	gvk := obj.GroupVersionKind()
+	kind := gvk.GroupKind().String()
+	if !config.ControllerCfg.GetKubernetesComponentsAllowedKinds()[kind] {
+		return nil, &objectNotAllowedError{reason: fmt.Sprintf("kind %s is not allowed in kubernetes components", kind)}
+	}
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, err
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return nil, &objectNotAllowedError{reason: fmt.Sprintf("kind %s is cluster-scoped", kind)}
+	}
+	return mapping, nil
+}
+
+// checkCreatorAccess uses a SubjectAccessReview to check that the DevWorkspace's creator may perform verb on an
+// object. If they may not, a message explaining why is returned.
+func checkCreatorAccess(workspace *dw.DevWorkspace, obj *unstructured.Unstructured, mapping *meta.RESTMapping, verb string, clusterAPI ClusterAPI) (msg string, err error) {
+	username := workspace.Annotations[constants.DevWorkspaceCreatorUsernameAnnotation]
+	if username == "" {
+		return fmt.Sprintf("DevWorkspace does not have annotation %s and must be recreated to deploy kubernetes components",
+			constants.DevWorkspaceCreatorUsernameAnnotation), nil
+	}
+	var groups []string
+	if groupsJSON, ok := workspace.Annotations[constants.DevWorkspaceCreatorGroupsAnnotation]; ok {
+		if err := json.Unmarshal([]byte(groupsJSON), &groups); err != nil {
+			return fmt.Sprintf("failed to read annotation %s: %s", constants.DevWorkspaceCreatorGroupsAnnotation, err), nil
+		}
+	}
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: obj.GetNamespace(),
+				Verb:      verb,
+				Group:     mapping.Resource.Group,
+				Version:   mapping.Resource.Version,
+				Resource:  mapping.Resource.Resource,
+				Name:      obj.GetName(),
+			},
+			User:   username,
+			Groups: groups,
+			UID:    workspace.Labels[constants.DevWorkspaceCreatorLabel],
+		},
+	}
+	if err := clusterAPI.Client.Create(clusterAPI.Ctx, review); err != nil {
+		return "", err
+	}
+	if !review.Status.Allowed {
+		return fmt.Sprintf("DevWorkspace creator %s is not permitted to %s %s", username, verb, mapping.Resource.GroupResource()), nil
+	}
+	return "", nil
+}
+
+func objectKey(groupKind schema.GroupKind, name string) string {
+	return fmt.Sprintf("%s/%s", groupKind, name)
+}
+
+func getSpecComponentObject(workspace *dw.DevWorkspace, componentObject kubernetes.ComponentObject, clusterAPI ClusterAPI) (*unstructured.Unstructured, error) {
+	obj := componentObject.Object.DeepCopy()
+	if namespace := obj.GetNamespace(); namespace != "" && namespace != workspace.Namespace {
+		return nil, fmt.Errorf("%s must be in the DevWorkspace's namespace, but specifies namespace %s",
+			describeObject(componentObject.ComponentName, obj), namespace)
+	}
+	obj.SetNamespace(workspace.Namespace)
+
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[constants.DevWorkspaceIDLabel] = workspace.Status.DevWorkspaceId
+	labels[constants.DevWorkspaceKubernetesComponentLabel] = componentObject.ComponentName
+	obj.SetLabels(labels)
+
+	if restrictedAccess, ok := workspace.Annotations[constants.DevWorkspaceRestrictedAccessAnnotation]; ok {
+		annotations := obj.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[constants.DevWorkspaceRestrictedAccessAnnotation] = restrictedAccess
+		obj.SetAnnotations(annotations)
+	}
+
+	if err := controllerutil.SetControllerReference(workspace, obj, clusterAPI.Scheme); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func getClusterComponentObject(specObj *unstructured.Unstructured, clusterAPI ClusterAPI) (*unstructured.Unstructured, error) {
+	clusterObj := &unstructured.Unstructured{}
+	clusterObj.SetGroupVersionKind(specObj.GroupVersionKind())
+	namespacedName := types.NamespacedName{
+		Name:      specObj.GetName(),
+		Namespace: specObj.GetNamespace(),
+	}
+	err := clusterAPI.Client.Get(clusterAPI.Ctx, namespacedName, clusterObj)
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return clusterObj, nil
+}
+
+// objectMatchesSpec returns true if all fields set in specObj (other than metadata) as well as its labels, annotations
+// and owner references are set to the same values in clusterObj. Fields set by the cluster (e.g. defaults and status)
+// are ignored.
+func objectMatchesSpec(specObj, clusterObj *unstructured.Unstructured) bool {
+	specContent := specObj.DeepCopy().Object
+	clusterContent := clusterObj.DeepCopy().Object
+	delete(specContent, "metadata")
+	delete(clusterContent, "metadata")
+	return equality.Semantic.DeepDerivative(specContent, clusterContent) &&
+		equality.Semantic.DeepDerivative(specObj.GetLabels(), clusterObj.GetLabels()) &&
+		equality.Semantic.DeepDerivative(specObj.GetAnnotations(), clusterObj.GetAnnotations()) &&
+		equality.Semantic.DeepDerivative(specObj.GetOwnerReferences(), clusterObj.GetOwnerReferences())
+}
+
+// isUnrecoverableObjectError returns true for errors that will not be resolved by retrying, e.g. when the object is
+// invalid or the controller is not permitted to manage objects of its type.
+func isUnrecoverableObjectError(err error) bool {
+	return k8sErrors.IsInvalid(err) || k8sErrors.IsForbidden(err) || k8sErrors.IsBadRequest(err) || meta.IsNoMatchError(err)
+}
+
+func describeObject(componentName string, obj *unstructured.Unstructured) string {
+	return fmt.Sprintf("%s %s from component %s", obj.GetKind(), obj.GetName(), componentName)
+}
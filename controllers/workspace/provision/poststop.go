@@ -0,0 +1,174 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package provision
+
+import (
+	"fmt"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/devfile/devworkspace-operator/pkg/common"
+	"github.com/devfile/devworkspace-operator/pkg/config"
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+	"github.com/devfile/devworkspace-operator/pkg/library/lifecycle"
+)
+
+var postStopJobBackoffLimit = int32(0)
+
+// SyncPostStopJob runs the commands bound to the postStop event of a stopped DevWorkspace in a job. The job's pod uses
+// the volumes, service account, pull secrets and scheduling configuration of the DevWorkspace's (scaled down) deployment,
+// so that commands have access to the same storage as the DevWorkspace, and the same security contexts as its pods. The
+// DevWorkspace passed in must be flattened.
+//
+// Returns done=true once the job has completed or failed (including when it times out), or if the DevWorkspace defines
+// no postStop commands. If the job failed or cannot be run, failureMsg describes why.
+func SyncPostStopJob(workspace *dw.DevWorkspace, deployment *appsv1.Deployment, securityContexts *SecurityContexts,
+	clusterAPI ClusterAPI) (done bool, failureMsg string, err error) {
+	containers, err := lifecycle.GetPostStopContainers(workspace.Spec.Template.DevWorkspaceTemplateSpecContent, deployment.Spec.Template.Spec.Containers)
+	if err != nil {
+		return true, fmt.Sprintf("Could not run postStop commands: %s", err), nil
+	}
+	if len(containers) == 0 {
+		return true, "", nil
+	}
+
+	clusterJob, err := getClusterPostStopJob(workspace, clusterAPI)
+	if err != nil {
+		return false, "", err
+	}
+	if clusterJob == nil {
+		specJob, err := getSpecPostStopJob(workspace, deployment, containers, securityContexts, clusterAPI)
+		if err != nil {
+			return false, "", err
+		}
+		if err := securityContexts.CheckRestricted(&specJob.Spec.Template); err != nil {
+			return true, fmt.Sprintf("Could not run postStop commands: namespace %s enforces the restricted Pod Security Standard: %s", workspace.Namespace, err), nil
+		}
+		clusterObj, err := securityContexts.ToClusterObject(specJob, clusterAPI.Scheme)
+		if err != nil {
+			return false, "", err
+		}
+		clusterAPI.Logger.Info("Creating postStop job")
+		if err := clusterAPI.Client.Create(clusterAPI.Ctx, clusterObj); err != nil && !k8sErrors.IsAlreadyExists(err) {
+			return false, "", err
+		}
+		return false, "", nil
+	}
+
+	for _, condition := range clusterJob.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return true, "", nil
+		case batchv1.JobFailed:
+			return true, fmt.Sprintf("PostStop job failed (%s): see logs for job %q for details", condition.Reason, clusterJob.Name), nil
+		}
+	}
+	return false, "", nil
+}
+
+// DeletePostStopJob removes the postStop job for a DevWorkspace, if present, so that postStop commands are run again
+// the next time the DevWorkspace is stopped.
+func DeletePostStopJob(workspace *dw.DevWorkspace, clusterAPI ClusterAPI) error {
+	clusterJob, err := getClusterPostStopJob(workspace, clusterAPI)
+	if err != nil || clusterJob == nil {
+		return err
+	}
+	propagationPolicy := metav1.DeletePropagationBackground
+	err = clusterAPI.Client.Delete(clusterAPI.Ctx, clusterJob, &client.DeleteOptions{PropagationPolicy: &propagationPolicy})
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func getSpecPostStopJob(workspace *dw.DevWorkspace, deployment *appsv1.Deployment, containers []corev1.Container,
+	securityContexts *SecurityContexts, clusterAPI ClusterAPI) (*batchv1.Job, error) {
+	workspaceId := workspace.Status.DevWorkspaceId
+	podSpec := deployment.Spec.Template.Spec
+	// Commands are run in the order of the postStop event: all but the last are run as init containers
+	lastIdx := len(containers) - 1
+	postStopJobTimeout := config.ControllerCfg.GetPostStopJobTimeoutSeconds()
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      common.PostStopJobName(workspaceId),
+			Namespace: workspace.Namespace,
+			Labels: map[string]string{
+				constants.DevWorkspaceIDLabel:   workspaceId,
+				constants.DevWorkspaceNameLabel: workspace.Name,
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          &postStopJobBackoffLimit,
+			ActiveDeadlineSeconds: &postStopJobTimeout,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						constants.DevWorkspaceNameLabel: workspace.Name,
+					},
+				},
+				Spec: corev1.PodSpec{
+					InitContainers:     containers[:lastIdx],
+					Containers:         containers[lastIdx:],
+					ImagePullSecrets:   podSpec.ImagePullSecrets,
+					Volumes:            podSpec.Volumes,
+					RestartPolicy:      corev1.RestartPolicyNever,
+					ServiceAccountName: podSpec.ServiceAccountName,
+					// Job pods are scheduled in the same way as the DevWorkspace's pod
+					NodeSelector:              podSpec.NodeSelector,
+					Tolerations:               podSpec.Tolerations,
+					Affinity:                  podSpec.Affinity,
+					PriorityClassName:         podSpec.PriorityClassName,
+					RuntimeClassName:          podSpec.RuntimeClassName,
+					TopologySpreadConstraints: podSpec.TopologySpreadConstraints,
+				},
+			},
+		},
+	}
+
+	if err := securityContexts.ApplyTo(&job.Spec.Template); err != nil {
+		return nil, err
+	}
+
+	err := controllerutil.SetControllerReference(workspace, job, clusterAPI.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func getClusterPostStopJob(workspace *dw.DevWorkspace, clusterAPI ClusterAPI) (*batchv1.Job, error) {
+	namespacedName := types.NamespacedName{
+		Name:      common.PostStopJobName(workspace.Status.DevWorkspaceId),
+		Namespace: workspace.Namespace,
+	}
+	clusterJob := &batchv1.Job{}
+	err := clusterAPI.Client.Get(clusterAPI.Ctx, namespacedName, clusterJob)
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return clusterJob, nil
+}
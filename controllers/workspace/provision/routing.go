@@ -16,11 +16,13 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
 
 	"github.com/devfile/devworkspace-operator/apis/controller/v1alpha1"
 	maputils "github.com/devfile/devworkspace-operator/internal/map"
+	"github.com/devfile/devworkspace-operator/pkg/common"
 	"github.com/devfile/devworkspace-operator/pkg/config"
 	"github.com/devfile/devworkspace-operator/pkg/constants"
 
@@ -38,6 +40,9 @@ type RoutingProvisioningStatus struct {
 	ProvisioningStatus
 	PodAdditions     *v1alpha1.PodAdditions
 	ExposedEndpoints map[string]v1alpha1.ExposedEndpointList
+	// RequeueAfter is set while the routing is not ready, to ensure the routing's claim is checked
+	// even if the routing is never updated.
+	RequeueAfter time.Duration
 }
 
 var routingDiffOpts = cmp.Options{
@@ -61,6 +66,14 @@ var routingDiffOpts = cmp.Options{
 		"ObjectMeta.ManagedFields"),
 }
 
+// SyncRoutingToCluster creates or updates the DevWorkspaceRouting for a DevWorkspace and reports its status. The
+// routing is handled by the controller for its routing class, which must either
+//
+//   - claim the routing by setting status.claim, and renew the claim's heartbeat while the routing is not ready, or
+//   - set status.phase without claiming the routing, in which case it is implicitly claimed and its claim never expires.
+//
+// If the routing is neither claimed nor has a phase set within the routing claim timeout, or an explicit claim is not
+// renewed within the timeout, the DevWorkspace fails as no controller handles the routing class.
 func SyncRoutingToCluster(
 	workspace *dw.DevWorkspace,
 	clusterAPI ClusterAPI) RoutingProvisioningStatus {
@@ -106,6 +119,39 @@ func SyncRoutingToCluster(
 		}
 	}
 
+	claimTimeout := config.ControllerCfg.GetRoutingClaimTimeout()
+	claim := clusterRouting.Status.Claim
+	// Routing controllers that predate claims only set the routing's phase. A phase set without a claim is treated as
+	// an implicit claim that never expires, as such controllers do not renew a heartbeat.
+	implicitlyClaimed := claim == nil && clusterRouting.Status.Phase != ""
+	var requeueAfter time.Duration
+	if clusterRouting.Status.Phase != v1alpha1.RoutingReady && !implicitlyClaimed {
+		// A routing controller claims a DevWorkspaceRouting and renews its claim while the routing is not ready. If
+		// no controller does so within the timeout, it's likely that no controller handles the routing class.
+		lastHeartbeat := clusterRouting.CreationTimestamp.Time
+		if claim != nil && claim.HeartbeatTime.After(lastHeartbeat) {
+			lastHeartbeat = claim.HeartbeatTime.Time
+		}
+		sinceHeartbeat := time.Since(lastHeartbeat)
+		requeueAfter = claimTimeout - sinceHeartbeat
+		if sinceHeartbeat > claimTimeout {
+			return RoutingProvisioningStatus{
+				ProvisioningStatus: ProvisioningStatus{
+					FailStartup: true,
+					Message:     fmt.Sprintf("no controller handles routing class %s", clusterRouting.Spec.RoutingClass),
+				},
+			}
+		}
+		if claim == nil {
+			return RoutingProvisioningStatus{
+				ProvisioningStatus: ProvisioningStatus{
+					Message: fmt.Sprintf("Waiting for a controller to handle routing class %s", clusterRouting.Spec.RoutingClass),
+				},
+				RequeueAfter: requeueAfter,
+			}
+		}
+	}
+
 	if clusterRouting.Status.Phase == v1alpha1.RoutingFailed {
 		return RoutingProvisioningStatus{
 			ProvisioningStatus: ProvisioningStatus{FailStartup: true, Message: clusterRouting.Status.Message},
@@ -118,6 +164,8 @@ func SyncRoutingToCluster(
 				Requeue:  false,
 				Message:  clusterRouting.Status.Message,
 			},
+			// Recheck once the claim would expire, in case the routing controller stops renewing it
+			RequeueAfter: requeueAfter,
 		}
 	}
 
@@ -135,6 +183,7 @@ func getSpecRouting(
 	scheme *runtime.Scheme) (*v1alpha1.DevWorkspaceRouting, error) {
 
 	endpoints := map[string]v1alpha1.EndpointList{}
+	var machinePodSelectors map[string]map[string]string
 	for _, component := range workspace.Spec.Template.Components {
 		if component.Container == nil {
 			continue
@@ -142,6 +191,15 @@ func getSpecRouting(
 		componentEndpoints := component.Container.Endpoints
 		if len(componentEndpoints) > 0 {
 			endpoints[component.Name] = append(endpoints[component.Name], componentEndpoints...)
+			// Endpoints of dedicated pod components are served by the component's own deployment, see SyncDedicatedPods
+			if component.Container.DedicatedPod {
+				if machinePodSelectors == nil {
+					machinePodSelectors = map[string]map[string]string{}
+				}
+				machinePodSelectors[component.Name] = map[string]string{
+					constants.DevWorkspaceDedicatedPodLabel: common.DedicatedPodDeploymentName(workspace.Status.DevWorkspaceId, component.Name),
+				}
+			}
 		}
 	}
 
@@ -165,7 +223,7 @@ func getSpecRouting(
 
 	routing := &v1alpha1.DevWorkspaceRouting{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      fmt.Sprintf("routing-%s", workspace.Status.DevWorkspaceId),
+			Name:      common.DevWorkspaceRoutingName(workspace.Status.DevWorkspaceId),
 			Namespace: workspace.Namespace,
 			Labels: map[string]string{
 				constants.DevWorkspaceIDLabel: workspace.Status.DevWorkspaceId,
@@ -179,6 +237,7 @@ func getSpecRouting(
 			PodSelector: map[string]string{
 				constants.DevWorkspaceIDLabel: workspace.Status.DevWorkspaceId,
 			},
+			MachinePodSelectors: machinePodSelectors,
 		},
 	}
 	err := controllerutil.SetControllerReference(workspace, routing, scheme)
@@ -0,0 +1,208 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package provision
+
+import (
+	"fmt"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+	"github.com/devfile/devworkspace-operator/pkg/infrastructure"
+	"github.com/devfile/devworkspace-operator/pkg/library/overrides"
+)
+
+// seccompProfileRuntimeDefault is the seccompProfile type for the container runtime's default profile
+const seccompProfileRuntimeDefault = "RuntimeDefault"
+
+// SecurityContexts are the security contexts applied to the pods of a DevWorkspace and their containers
+type SecurityContexts struct {
+	// Pod is the security context of the pod
+	Pod *corev1.PodSecurityContext
+	// Container is the default security context of containers. Fields set in the security context of a container
+	// replace those in the default.
+	Container *corev1.SecurityContext
+	// SeccompProfileType is the type of the seccomp profile of the pod, or the empty string if none is set. The Kubernetes
+	// API version used by the controller predates the seccompProfile field of security contexts, so the profile is
+	// added when the pod's deployment or job is converted with ToClusterObject.
+	SeccompProfileType string
+	// Restricted is true if pods must satisfy the restricted Pod Security Standard, as it is enforced in the namespace.
+	// On OpenShift, security context constraints are responsible for making pods satisfy the standard.
+	Restricted bool
+}
+
+// GetDefaultSecurityContexts returns the security contexts for pods in a namespace. If the namespace enforces the
+// restricted Pod Security Standard, the security contexts satisfy it: containers drop all capabilities and cannot
+// escalate privileges, and, on Kubernetes, pods use the runtime's default seccomp profile.
+func GetDefaultSecurityContexts(namespace string, clusterAPI ClusterAPI) (*SecurityContexts, error) {
+	securityContexts := &SecurityContexts{
+		Pod: GetDevWorkspaceSecurityContext(),
+	}
+	restricted, err := isRestrictedNamespace(namespace, clusterAPI)
+	if err != nil {
+		return nil, err
+	}
+	if !restricted {
+		return securityContexts, nil
+	}
+	securityContexts.Restricted = !infrastructure.IsOpenShift()
+	if securityContexts.Restricted {
+		securityContexts.SeccompProfileType = seccompProfileRuntimeDefault
+	}
+	allowPrivilegeEscalation := false
+	securityContexts.Container = &corev1.SecurityContext{
+		AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+		Capabilities: &corev1.Capabilities{
+			Drop: []corev1.Capability{"ALL"},
+		},
+	}
+	return securityContexts, nil
+}
+
+// GetSecurityContexts returns the security contexts for a DevWorkspace's pods, which are the defaults for its namespace
+// with the overrides defined by the DevWorkspace's constants.PodOverridesAttribute attribute applied. Overrides must be
+// checked with overrides.CheckAllowedOverrides before calling this function.
+func GetSecurityContexts(workspace *dw.DevWorkspace, clusterAPI ClusterAPI) (*SecurityContexts, error) {
+	securityContexts, err := GetDefaultSecurityContexts(workspace.Namespace, clusterAPI)
+	if err != nil {
+		return nil, err
+	}
+	podOverrides, err := overrides.GetPodOverrides(&workspace.Spec.Template)
+	if err != nil {
+		return nil, err
+	}
+	if podOverrides != nil {
+		securityContexts.Pod, err = overrides.MergePodSecurityContext(securityContexts.Pod, podOverrides.Spec.SecurityContext)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return securityContexts, nil
+}
+
+// ApplyTo sets the security context of a pod template, and merges the security context of each of its containers and
+// init containers over the default container security context.
+func (s *SecurityContexts) ApplyTo(template *corev1.PodTemplateSpec) error {
+	podSpec := &template.Spec
+	podSpec.SecurityContext = s.Pod
+	for _, containers := range [][]corev1.Container{podSpec.InitContainers, podSpec.Containers} {
+		for idx, container := range containers {
+			securityContext, err := overrides.MergeContainerSecurityContext(s.Container, container.SecurityContext)
+			if err != nil {
+				return err
+			}
+			containers[idx].SecurityContext = securityContext
+		}
+	}
+	return nil
+}
+
+// ToClusterObject returns the object that should be created or updated on the cluster for a deployment or job whose
+// pod template was passed to ApplyTo. If a seccomp profile is required, the object is converted to an unstructured
+// object with the seccompProfile field of its pod security context set; otherwise, obj is returned unchanged.
+func (s *SecurityContexts) ToClusterObject(obj runtime.Object, scheme *runtime.Scheme) (runtime.Object, error) {
+	if s.SeccompProfileType == "" {
+		return obj, nil
+	}
+	gvk, err := apiutil.GVKForObject(obj, scheme)
+	if err != nil {
+		return nil, err
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	clusterObj := &unstructured.Unstructured{Object: content}
+	clusterObj.SetGroupVersionKind(gvk)
+	err = unstructured.SetNestedField(clusterObj.Object, s.SeccompProfileType, "spec", "template", "spec", "securityContext", "seccompProfile", "type")
+	if err != nil {
+		return nil, err
+	}
+	return clusterObj, nil
+}
+
+// CheckRestricted verifies that a pod template satisfies the restricted Pod Security Standard, if it is required, so
+// that DevWorkspaces whose overrides violate it fail to start with a clear error instead of waiting for pods that are
+// never created. The seccomp profile is not checked, as it is always set by ToClusterObject and cannot be overridden.
+func (s *SecurityContexts) CheckRestricted(template *corev1.PodTemplateSpec) error {
+	if !s.Restricted {
+		return nil
+	}
+	podSpec := &template.Spec
+	podSecurity := podSpec.SecurityContext
+	if podSecurity == nil {
+		podSecurity = &corev1.PodSecurityContext{}
+	}
+	for _, containers := range [][]corev1.Container{podSpec.InitContainers, podSpec.Containers} {
+		for _, container := range containers {
+			containerSecurity := container.SecurityContext
+			if containerSecurity == nil {
+				containerSecurity = &corev1.SecurityContext{}
+			}
+			violation := func(reason string) error {
+				return fmt.Errorf("container %s does not satisfy the restricted Pod Security Standard: %s", container.Name, reason)
+			}
+
+			runAsNonRoot := podSecurity.RunAsNonRoot
+			if containerSecurity.RunAsNonRoot != nil {
+				runAsNonRoot = containerSecurity.RunAsNonRoot
+			}
+			if runAsNonRoot == nil || !*runAsNonRoot {
+				return violation("runAsNonRoot must be true")
+			}
+			runAsUser := podSecurity.RunAsUser
+			if containerSecurity.RunAsUser != nil {
+				runAsUser = containerSecurity.RunAsUser
+			}
+			if runAsUser != nil && *runAsUser == 0 {
+				return violation("runAsUser must not be 0")
+			}
+			if containerSecurity.Privileged != nil && *containerSecurity.Privileged {
+				return violation("privileged must not be true")
+			}
+			if containerSecurity.AllowPrivilegeEscalation == nil || *containerSecurity.AllowPrivilegeEscalation {
+				return violation("allowPrivilegeEscalation must be false")
+			}
+			dropsAll := false
+			if capabilities := containerSecurity.Capabilities; capabilities != nil {
+				for _, capability := range capabilities.Drop {
+					if capability == "ALL" {
+						dropsAll = true
+					}
+				}
+				for _, capability := range capabilities.Add {
+					if capability != "NET_BIND_SERVICE" {
+						return violation(fmt.Sprintf("capability %s must not be added", capability))
+					}
+				}
+			}
+			if !dropsAll {
+				return violation("capabilities must drop ALL")
+			}
+		}
+	}
+	return nil
+}
+
+func isRestrictedNamespace(namespace string, clusterAPI ClusterAPI) (bool, error) {
+	ns := &corev1.Namespace{}
+	if err := clusterAPI.Client.Get(clusterAPI.Ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		return false, fmt.Errorf("failed to read namespace %s: %w", namespace, err)
+	}
+	return ns.Labels[constants.PodSecurityEnforceLabel] == constants.PodSecurityRestrictedLevel, nil
+}
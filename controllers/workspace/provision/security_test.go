@@ -0,0 +1,276 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package provision
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+)
+
+func TestCheckRestricted(t *testing.T) {
+	boolPtr := func(b bool) *bool { return &b }
+	int64Ptr := func(i int64) *int64 { return &i }
+	restrictedContainer := func() *corev1.SecurityContext {
+		return &corev1.SecurityContext{
+			AllowPrivilegeEscalation: boolPtr(false),
+			Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+		}
+	}
+	restrictedPod := &corev1.PodSecurityContext{RunAsUser: int64Ptr(1234), RunAsNonRoot: boolPtr(true)}
+
+	tests := []struct {
+		name        string
+		restricted  bool
+		pod         *corev1.PodSecurityContext
+		container   *corev1.SecurityContext
+		expectedErr string
+	}{
+		{
+			name:       "Allows any pod when restricted standard is not enforced",
+			restricted: false,
+			pod:        nil,
+			container:  &corev1.SecurityContext{Privileged: boolPtr(true)},
+		},
+		{
+			name:       "Allows pod that satisfies restricted standard",
+			restricted: true,
+			pod:        restrictedPod,
+			container:  restrictedContainer(),
+		},
+		{
+			name:       "Allows runAsNonRoot set on container",
+			restricted: true,
+			pod:        nil,
+			container: func() *corev1.SecurityContext {
+				sc := restrictedContainer()
+				sc.RunAsNonRoot = boolPtr(true)
+				return sc
+			}(),
+		},
+		{
+			name:       "Allows adding NET_BIND_SERVICE capability",
+			restricted: true,
+			pod:        restrictedPod,
+			container: func() *corev1.SecurityContext {
+				sc := restrictedContainer()
+				sc.Capabilities.Add = []corev1.Capability{"NET_BIND_SERVICE"}
+				return sc
+			}(),
+		},
+		{
+			name:        "Rejects missing runAsNonRoot",
+			restricted:  true,
+			pod:         nil,
+			container:   restrictedContainer(),
+			expectedErr: "container tools does not satisfy the restricted Pod Security Standard: runAsNonRoot must be true",
+		},
+		{
+			name:       "Rejects container runAsNonRoot false overriding pod",
+			restricted: true,
+			pod:        restrictedPod,
+			container: func() *corev1.SecurityContext {
+				sc := restrictedContainer()
+				sc.RunAsNonRoot = boolPtr(false)
+				return sc
+			}(),
+			expectedErr: "container tools does not satisfy the restricted Pod Security Standard: runAsNonRoot must be true",
+		},
+		{
+			name:       "Rejects running as root user",
+			restricted: true,
+			pod:        restrictedPod,
+			container: func() *corev1.SecurityContext {
+				sc := restrictedContainer()
+				sc.RunAsUser = int64Ptr(0)
+				return sc
+			}(),
+			expectedErr: "container tools does not satisfy the restricted Pod Security Standard: runAsUser must not be 0",
+		},
+		{
+			name:       "Rejects privileged container",
+			restricted: true,
+			pod:        restrictedPod,
+			container: func() *corev1.SecurityContext {
+				sc := restrictedContainer()
+				sc.Privileged = boolPtr(true)
+				return sc
+			}(),
+			expectedErr: "container tools does not satisfy the restricted Pod Security Standard: privileged must not be true",
+		},
+		{
+			name:       "Rejects missing allowPrivilegeEscalation",
+			restricted: true,
+			pod:        restrictedPod,
+			container: func() *corev1.SecurityContext {
+				sc := restrictedContainer()
+				sc.AllowPrivilegeEscalation = nil
+				return sc
+			}(),
+			expectedErr: "container tools does not satisfy the restricted Pod Security Standard: allowPrivilegeEscalation must be false",
+		},
+		{
+			name:       "Rejects capabilities that do not drop ALL",
+			restricted: true,
+			pod:        restrictedPod,
+			container: func() *corev1.SecurityContext {
+				sc := restrictedContainer()
+				sc.Capabilities.Drop = []corev1.Capability{"NET_RAW"}
+				return sc
+			}(),
+			expectedErr: "container tools does not satisfy the restricted Pod Security Standard: capabilities must drop ALL",
+		},
+		{
+			name:       "Rejects added capabilities other than NET_BIND_SERVICE",
+			restricted: true,
+			pod:        restrictedPod,
+			container: func() *corev1.SecurityContext {
+				sc := restrictedContainer()
+				sc.Capabilities.Add = []corev1.Capability{"SYS_ADMIN"}
+				return sc
+			}(),
+			expectedErr: "container tools does not satisfy the restricted Pod Security Standard: capability SYS_ADMIN must not be added",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			securityContexts := &SecurityContexts{Restricted: tt.restricted}
+			template := &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					SecurityContext: tt.pod,
+					Containers:      []corev1.Container{{Name: "tools", SecurityContext: tt.container}},
+				},
+			}
+			err := securityContexts.CheckRestricted(template)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckRestrictedChecksInitContainers(t *testing.T) {
+	nonRoot := true
+	securityContexts := &SecurityContexts{Restricted: true}
+	template := &corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{
+			SecurityContext: &corev1.PodSecurityContext{RunAsNonRoot: &nonRoot},
+			InitContainers:  []corev1.Container{{Name: "project-clone"}},
+		},
+	}
+	assert.EqualError(t, securityContexts.CheckRestricted(template),
+		"container project-clone does not satisfy the restricted Pod Security Standard: allowPrivilegeEscalation must be false")
+}
+
+func TestIsRestrictedNamespace(t *testing.T) {
+	tests := []struct {
+		name        string
+		namespace   *corev1.Namespace
+		expected    bool
+		expectedErr bool
+	}{
+		{
+			name: "Namespace enforcing restricted standard is restricted",
+			namespace: &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:   "test-namespace",
+				Labels: map[string]string{constants.PodSecurityEnforceLabel: "restricted"},
+			}},
+			expected: true,
+		},
+		{
+			name: "Namespace enforcing baseline standard is not restricted",
+			namespace: &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:   "test-namespace",
+				Labels: map[string]string{constants.PodSecurityEnforceLabel: "baseline"},
+			}},
+			expected: false,
+		},
+		{
+			name: "Namespace only warning about restricted standard is not restricted",
+			namespace: &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:   "test-namespace",
+				Labels: map[string]string{"pod-security.kubernetes.io/warn": "restricted"},
+			}},
+			expected: false,
+		},
+		{
+			name:      "Namespace without labels is not restricted",
+			namespace: &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test-namespace"}},
+			expected:  false,
+		},
+		{
+			name:        "Returns error when namespace cannot be read",
+			namespace:   nil,
+			expectedErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var objs []runtime.Object
+			if tt.namespace != nil {
+				objs = append(objs, tt.namespace)
+			}
+			clusterAPI := ClusterAPI{
+				Client: fake.NewFakeClientWithScheme(scheme.Scheme, objs...),
+				Scheme: scheme.Scheme,
+				Ctx:    context.TODO(),
+			}
+			restricted, err := isRestrictedNamespace("test-namespace", clusterAPI)
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.expected, restricted)
+			}
+		})
+	}
+}
+
+func TestToClusterObject(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-deployment", Namespace: "test-namespace"},
+	}
+
+	unchanged, err := (&SecurityContexts{}).ToClusterObject(deployment, scheme.Scheme)
+	if assert.NoError(t, err) {
+		assert.Same(t, deployment, unchanged, "Should not convert object when no seccomp profile is required")
+	}
+
+	clusterObj, err := (&SecurityContexts{SeccompProfileType: "RuntimeDefault"}).ToClusterObject(deployment, scheme.Scheme)
+	if !assert.NoError(t, err) {
+		return
+	}
+	u, ok := clusterObj.(*unstructured.Unstructured)
+	if !assert.True(t, ok, "Should convert object to unstructured") {
+		return
+	}
+	assert.Equal(t, appsv1.SchemeGroupVersion.WithKind("Deployment"), u.GroupVersionKind())
+	assert.Equal(t, "test-deployment", u.GetName())
+	profileType, _, err := unstructured.NestedString(u.Object, "spec", "template", "spec", "securityContext", "seccompProfile", "type")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "RuntimeDefault", profileType)
+	}
+}
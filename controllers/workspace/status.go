@@ -19,6 +19,7 @@ import (
 	"net/http"
 	"net/url"
 	"sort"
+	"strings"
 
 	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
 	"github.com/devfile/devworkspace-operator/apis/controller/v1alpha1"
@@ -86,25 +87,30 @@ func syncConditions(workspaceStatus *dw.DevWorkspaceStatus, currentStatus *curre
 
 	// Set of conditions already set on the workspace
 	existingConditions := map[dw.DevWorkspaceConditionType]bool{}
-	for idx, workspaceCondition := range workspaceStatus.Conditions {
-		existingConditions[workspaceCondition.Type] = true
-
+	var syncedConditions []dw.DevWorkspaceCondition
+	for _, workspaceCondition := range workspaceStatus.Conditions {
 		currCondition, ok := currentStatus.conditions[workspaceCondition.Type]
 		if !ok {
+			if optionalConditions[workspaceCondition.Type] {
+				// Optional conditions only apply while they are observed; drop them from status once they are not
+				continue
+			}
 			// Didn't observe this condition this time; set status to unknown
-			workspaceStatus.Conditions[idx].LastTransitionTime = currTransitionTime
-			workspaceStatus.Conditions[idx].Status = corev1.ConditionUnknown
-			workspaceStatus.Conditions[idx].Message = ""
-			continue
-		}
-
-		// Update condition if needed
-		if workspaceCondition.Status != currCondition.Status || workspaceCondition.Message != currCondition.Message {
-			workspaceStatus.Conditions[idx].LastTransitionTime = currTransitionTime
-			workspaceStatus.Conditions[idx].Status = currCondition.Status
-			workspaceStatus.Conditions[idx].Message = currCondition.Message
+			workspaceCondition.LastTransitionTime = currTransitionTime
+			workspaceCondition.Status = corev1.ConditionUnknown
+			workspaceCondition.Message = ""
+		} else if workspaceCondition.Status != currCondition.Status || workspaceCondition.Reason != currCondition.Reason ||
+			workspaceCondition.Message != currCondition.Message {
+			// Update condition if needed
+			workspaceCondition.LastTransitionTime = currTransitionTime
+			workspaceCondition.Status = currCondition.Status
+			workspaceCondition.Reason = currCondition.Reason
+			workspaceCondition.Message = currCondition.Message
 		}
+		existingConditions[workspaceCondition.Type] = true
+		syncedConditions = append(syncedConditions, workspaceCondition)
 	}
+	workspaceStatus.Conditions = syncedConditions
 
 	// Check for conditions we need to add
 	for condType, cond := range currentStatus.conditions {
@@ -116,6 +122,7 @@ func syncConditions(workspaceStatus *dw.DevWorkspaceStatus, currentStatus *curre
 			LastTransitionTime: currTransitionTime,
 			Type:               condType,
 			Status:             cond.Status,
+			Reason:             cond.Reason,
 			Message:            cond.Message,
 		})
 	}
@@ -137,7 +144,7 @@ func syncWorkspaceMainURL(workspace *dw.DevWorkspace, exposedEndpoints map[strin
 	return false, err
 }
 
-func checkServerStatus(workspace *dw.DevWorkspace) (ok bool, err error) {
+func checkServerStatus(workspace *dw.DevWorkspace, exposedEndpoints map[string]v1alpha1.ExposedEndpointList) (ok bool, err error) {
 	mainUrl := workspace.Status.MainUrl
 	if mainUrl == "" {
 		// Support DevWorkspaces that do not specify an mainUrl
@@ -147,7 +154,11 @@ func checkServerStatus(workspace *dw.DevWorkspace) (ok bool, err error) {
 	if err != nil {
 		return false, err
 	}
-	healthz.Path = healthz.Path + "healthz"
+	if healthPath := getMainEndpointHealthCheckPath(exposedEndpoints); healthPath != "" {
+		healthz.Path = strings.TrimRight(healthz.Path, "/") + "/" + strings.TrimLeft(healthPath, "/")
+	} else {
+		healthz.Path = healthz.Path + "healthz"
+	}
 
 	resp, err := healthHttpClient.Get(healthz.String())
 	if err != nil {
@@ -165,6 +176,19 @@ func checkServerStatus(workspace *dw.DevWorkspace) (ok bool, err error) {
 	return ok, nil
 }
 
+// getMainEndpointHealthCheckPath returns the health check path defined in the main endpoint's attributes, or an empty
+// string if it is not defined.
+func getMainEndpointHealthCheckPath(exposedEndpoints map[string]v1alpha1.ExposedEndpointList) string {
+	for _, endpoints := range exposedEndpoints {
+		for _, endpoint := range endpoints {
+			if endpoint.Attributes.GetString(string(v1alpha1.TypeEndpointAttribute), nil) == string(v1alpha1.MainEndpointType) {
+				return endpoint.Attributes.GetString(string(v1alpha1.HealthCheckPathAttribute), nil)
+			}
+		}
+	}
+	return ""
+}
+
 func getMainUrl(exposedEndpoints map[string]v1alpha1.ExposedEndpointList) string {
 	for _, endpoints := range exposedEndpoints {
 		for _, endpoint := range endpoints {
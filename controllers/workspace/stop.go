@@ -0,0 +1,171 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package controllers
+
+import (
+	"fmt"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/devfile/devworkspace-operator/controllers/workspace/provision"
+	"github.com/devfile/devworkspace-operator/pkg/config"
+	"github.com/devfile/devworkspace-operator/pkg/library/flatten"
+	"github.com/devfile/devworkspace-operator/pkg/library/overrides"
+	"github.com/devfile/devworkspace-operator/pkg/provision/importlock"
+)
+
+// needsStopCleanup returns true if a DevWorkspace that is being stopped may have postStop commands to run or
+// resources from kubernetes components to delete. DevWorkspaces that are already stopped are skipped.
+func needsStopCleanup(workspace *dw.DevWorkspace) bool {
+	switch workspace.Status.Phase {
+	case dw.DevWorkspaceStatusStopped, dw.DevWorkspaceStatusFailed:
+		return false
+	default:
+		return true
+	}
+}
+
+// syncPostStopCondition carries over the PostStopCommandsSucceeded condition while a DevWorkspace is stopping or stopped.
+// When a DevWorkspace that reached the Running phase is first stopped, the condition is set to pending to record that
+// its postStop commands should be run; DevWorkspaces that are stopped before they start do not run postStop commands.
+func syncPostStopCondition(workspace *dw.DevWorkspace, status *currentStatus) {
+	if postStopCondition := getConditionByType(workspace.Status.Conditions, PostStopCommandsSucceeded); postStopCondition != nil {
+		status.setCondition(PostStopCommandsSucceeded, *postStopCondition)
+		return
+	}
+	if workspace.Status.Phase == dw.DevWorkspaceStatusRunning {
+		status.setCondition(PostStopCommandsSucceeded, dw.DevWorkspaceCondition{
+			Status:  corev1.ConditionUnknown,
+			Reason:  PostStopPendingReason,
+			Message: "Waiting for postStop commands to run",
+		})
+	}
+}
+
+// shouldRunPostStop returns true if postStop commands should be run when stopping a DevWorkspace, i.e. if
+// syncPostStopCondition recorded them as pending. Commands are only run for DevWorkspaces that were running;
+// DevWorkspaces that failed to start or whose postStop commands have already run are skipped.
+func shouldRunPostStop(status *currentStatus) bool {
+	postStopCondition, ok := status.conditions[PostStopCommandsSucceeded]
+	return ok && postStopCondition.Reason == PostStopPendingReason
+}
+
+// cleanUpStoppedWorkspace runs postStop commands and deletes image build jobs, dedicated pods and resources created from
+// kubernetes components once a DevWorkspace's deployment has been scaled to zero. Deployment may be nil if the
+// DevWorkspace has no deployment, in which case postStop commands are not run. Returns true once cleanup is complete.
+func (r *DevWorkspaceReconciler) cleanUpStoppedWorkspace(workspace *dw.DevWorkspace, deployment *appsv1.Deployment,
+	clusterAPI provision.ClusterAPI, status *currentStatus, logger logr.Logger) (done bool, err error) {
+	// Images are rebuilt from the current project sources each time the DevWorkspace starts
+	if err := provision.DeleteImageBuildJobs(workspace, clusterAPI); err != nil {
+		return false, err
+	}
+	if deleted, err := provision.DeleteDedicatedPods(workspace, clusterAPI); err != nil || !deleted {
+		return false, err
+	}
+	if !needsStopCleanup(workspace) {
+		return true, nil
+	}
+	if shouldRunPostStop(status) {
+		done, err := r.runPostStopCommands(workspace, deployment, clusterAPI, status, logger)
+		if err != nil || !done {
+			return false, err
+		}
+	}
+
+	deleted, err := provision.DeleteKubernetesComponents(workspace, r.restMapper, clusterAPI)
+	if err != nil {
+		return false, err
+	}
+	if !deleted {
+		logger.Info("Waiting on resources from kubernetes components to be deleted")
+	}
+	return deleted, nil
+}
+
+// flattenStoppingWorkspace resolves the parent and plugins of a DevWorkspace that is being stopped, using its import
+// lock so that the result matches the content it was running with.
+func (r *DevWorkspaceReconciler) flattenStoppingWorkspace(workspace *dw.DevWorkspace, clusterAPI provision.ClusterAPI) (*dw.DevWorkspace, error) {
+	importLock, err := importlock.GetImportLock(workspace, clusterAPI)
+	if err != nil {
+		return nil, err
+	}
+	flattenHelpers := flatten.ResolverTools{
+		WorkspaceNamespace:        workspace.Namespace,
+		Context:                   clusterAPI.Ctx,
+		K8sClient:                 r.Client,
+		InternalRegistry:          r.internalRegistry,
+		HttpClient:                r.httpClient,
+		OCIClient:                 r.ociClient,
+		ImportLock:                importLock,
+		ResolvedImports:           flatten.ImportLock{},
+		PinKubernetesImports:      true,
+		OutdatedKubernetesImports: map[string]bool{},
+	}
+	flattenedWorkspace, _, err := flatten.ResolveDevWorkspace(&workspace.Spec.Template, flattenHelpers)
+	if err != nil {
+		return nil, err
+	}
+	flattened := workspace.DeepCopy()
+	flattened.Spec.Template = *flattenedWorkspace
+	return flattened, nil
+}
+
+// runPostStopCommands runs the commands bound to the postStop event of a DevWorkspace whose deployment has been scaled
+// to zero, and records the result in the PostStopCommandsSucceeded condition. The condition is removed if the
+// DevWorkspace does not define postStop commands. Returns true once the commands have completed, failed or timed out,
+// or cannot be run; failures are recorded in the condition but do not prevent the DevWorkspace from stopping.
+func (r *DevWorkspaceReconciler) runPostStopCommands(workspace *dw.DevWorkspace, deployment *appsv1.Deployment,
+	clusterAPI provision.ClusterAPI, status *currentStatus, logger logr.Logger) (done bool, err error) {
+	flattened, err := r.flattenStoppingWorkspace(workspace, clusterAPI)
+	if err != nil {
+		msg := fmt.Sprintf("Skipping postStop commands: error processing devfile: %s", err)
+		logger.Info(msg)
+		status.setConditionFalse(PostStopCommandsSucceeded, msg)
+		return true, nil
+	}
+	events := flattened.Spec.Template.Events
+	if events == nil || len(events.PostStop) == 0 {
+		delete(status.conditions, PostStopCommandsSucceeded)
+		return true, nil
+	}
+	if deployment == nil {
+		status.setConditionFalse(PostStopCommandsSucceeded, "Could not run postStop commands: DevWorkspace deployment not found")
+		return true, nil
+	}
+	if err := overrides.CheckAllowedOverrides(&flattened.Spec.Template, config.ControllerCfg.GetAllowedSecurityContextOverrides()); err != nil {
+		status.setConditionFalse(PostStopCommandsSucceeded, fmt.Sprintf("Could not run postStop commands: %s", err))
+		return true, nil
+	}
+	securityContexts, err := provision.GetSecurityContexts(flattened, clusterAPI)
+	if err != nil {
+		return false, err
+	}
+	done, failureMsg, err := provision.SyncPostStopJob(flattened, deployment, securityContexts, clusterAPI)
+	if err != nil {
+		return false, err
+	}
+	if !done {
+		logger.Info("Waiting on postStop commands to complete")
+		return false, nil
+	}
+	if failureMsg != "" {
+		logger.Info(failureMsg)
+		status.setConditionFalse(PostStopCommandsSucceeded, failureMsg)
+	} else {
+		status.setConditionTrue(PostStopCommandsSucceeded, "PostStop commands completed")
+	}
+	return true, nil
+}
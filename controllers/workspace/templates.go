@@ -0,0 +1,138 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+)
+
+// templateRefsIndex is the name of the field index on DevWorkspaces that stores the namespace/name of all
+// DevWorkspaceTemplates referenced by kubernetes imports in the DevWorkspace's parent and plugins, including nested imports.
+const templateRefsIndex = "spec.template.kubernetesImports"
+
+// indexTemplateRefs returns the namespace/name of DevWorkspaceTemplates referenced by a DevWorkspace. Templates
+// referenced by other templates are only known once the DevWorkspace is flattened, and are read from the
+// DevWorkspaceKubernetesImportsAnnotation recorded along with the import lock.
+func indexTemplateRefs(obj runtime.Object) []string {
+	workspace, ok := obj.(*dw.DevWorkspace)
+	if !ok {
+		return nil
+	}
+	refs := map[string]bool{}
+	addRef := func(ref *dw.KubernetesCustomResourceImportReference) {
+		namespace := ref.Namespace
+		if namespace == "" {
+			namespace = workspace.Namespace
+		}
+		refs[fmt.Sprintf("%s/%s", namespace, ref.Name)] = true
+	}
+	if parent := workspace.Spec.Template.Parent; parent != nil && parent.Kubernetes != nil {
+		addRef(parent.Kubernetes)
+	}
+	for _, component := range workspace.Spec.Template.Components {
+		if component.Plugin != nil && component.Plugin.Kubernetes != nil {
+			addRef(component.Plugin.Kubernetes)
+		}
+	}
+	if imports := workspace.Annotations[constants.DevWorkspaceKubernetesImportsAnnotation]; imports != "" {
+		for _, ref := range strings.Split(imports, ",") {
+			refs[ref] = true
+		}
+	}
+	var indexed []string
+	for ref := range refs {
+		indexed = append(indexed, ref)
+	}
+	sort.Strings(indexed)
+	return indexed
+}
+
+// dwtRelatedWorkspacesHandler enqueues reconciles for all DevWorkspaces that reference a DevWorkspaceTemplate when the
+// template is changed.
+func (r *DevWorkspaceReconciler) dwtRelatedWorkspacesHandler() handler.EventHandler {
+	dwtToDW := func(mapObj handler.MapObject) []reconcile.Request {
+		workspaces := &dw.DevWorkspaceList{}
+		templateRef := fmt.Sprintf("%s/%s", mapObj.Meta.GetNamespace(), mapObj.Meta.GetName())
+		if err := r.List(context.TODO(), workspaces, client.MatchingFields{templateRefsIndex: templateRef}); err != nil {
+			r.Log.Error(err, "Failed to list DevWorkspaces referencing DevWorkspaceTemplate", "template", templateRef)
+			return nil
+		}
+		var requests []reconcile.Request
+		for _, workspace := range workspaces.Items {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      workspace.Name,
+					Namespace: workspace.Namespace,
+				},
+			})
+		}
+		return requests
+	}
+	return &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(dwtToDW)}
+}
+
+// getTemplateUpdatePolicy reads the template update policy for a DevWorkspace from its attributes. If the attribute is
+// not set, the 'auto' policy is used.
+func getTemplateUpdatePolicy(workspace *dw.DevWorkspace) (string, error) {
+	attrs := workspace.Spec.Template.Attributes
+	if !attrs.Exists(constants.DevWorkspaceTemplateUpdatePolicyAttr) {
+		return constants.TemplateUpdatePolicyAuto, nil
+	}
+	var err error
+	policy := attrs.GetString(constants.DevWorkspaceTemplateUpdatePolicyAttr, &err)
+	if err != nil {
+		return "", fmt.Errorf("failed to read attribute %s: %w", constants.DevWorkspaceTemplateUpdatePolicyAttr, err)
+	}
+	switch policy {
+	case constants.TemplateUpdatePolicyAuto, constants.TemplateUpdatePolicyManual:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unsupported value for attribute %s: %s (supported values are '%s' and '%s')",
+			constants.DevWorkspaceTemplateUpdatePolicyAttr, policy, constants.TemplateUpdatePolicyAuto, constants.TemplateUpdatePolicyManual)
+	}
+}
+
+// formatInvalidLockedImportsMessage returns the message for the ImportLockVerified condition when the locked content of
+// imported parents or plugins does not match their recorded digests.
+func formatInvalidLockedImportsMessage(invalid map[string]bool) string {
+	var imports []string
+	for importPath := range invalid {
+		imports = append(imports, importPath)
+	}
+	sort.Strings(imports)
+	return fmt.Sprintf("Locked content does not match recorded digest for %s; content was fetched from its source instead. "+
+		"Set annotation %s=true to update the lock", strings.Join(imports, ", "), constants.DevWorkspaceUpdateImportsAnnotation)
+}
+
+// formatOutdatedTemplatesMessage returns the message for the TemplatesUpToDate condition when referenced
+// DevWorkspaceTemplates have changed since the DevWorkspace was started.
+func formatOutdatedTemplatesMessage(outdated map[string]bool) string {
+	var templates []string
+	for template := range outdated {
+		templates = append(templates, template)
+	}
+	sort.Strings(templates)
+	return fmt.Sprintf("DevWorkspaceTemplates updated: %s. Restart DevWorkspace to apply changes", strings.Join(templates, ", "))
+}
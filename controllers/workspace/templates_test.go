@@ -0,0 +1,93 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package controllers
+
+import (
+	"testing"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+)
+
+func TestIndexTemplateRefs(t *testing.T) {
+	kubernetesPlugin := func(name, namespace string) dw.Component {
+		return dw.Component{
+			Name: name,
+			ComponentUnion: dw.ComponentUnion{
+				Plugin: &dw.PluginComponent{
+					ImportReference: dw.ImportReference{
+						ImportReferenceUnion: dw.ImportReferenceUnion{
+							Kubernetes: &dw.KubernetesCustomResourceImportReference{Name: name, Namespace: namespace},
+						},
+					},
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name         string
+		parent       *dw.Parent
+		components   []dw.Component
+		annotations  map[string]string
+		expectedRefs []string
+	}{
+		{
+			name:         "Indexes nothing for DevWorkspace without kubernetes imports",
+			components:   []dw.Component{{Name: "tools", ComponentUnion: dw.ComponentUnion{Container: &dw.ContainerComponent{}}}},
+			expectedRefs: nil,
+		},
+		{
+			name: "Indexes parent and plugins referenced in spec",
+			parent: &dw.Parent{
+				ImportReference: dw.ImportReference{
+					ImportReferenceUnion: dw.ImportReferenceUnion{
+						Kubernetes: &dw.KubernetesCustomResourceImportReference{Name: "parent-template"},
+					},
+				},
+			},
+			components:   []dw.Component{kubernetesPlugin("plugin-template", "other-namespace")},
+			expectedRefs: []string{"other-namespace/plugin-template", "test-namespace/parent-template"},
+		},
+		{
+			name:       "Indexes nested imports recorded in annotation",
+			components: []dw.Component{kubernetesPlugin("plugin-template", "")},
+			annotations: map[string]string{
+				constants.DevWorkspaceKubernetesImportsAnnotation: "other-namespace/nested-template,test-namespace/plugin-template",
+			},
+			expectedRefs: []string{"other-namespace/nested-template", "test-namespace/plugin-template"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspace := &dw.DevWorkspace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-workspace",
+					Namespace:   "test-namespace",
+					Annotations: tt.annotations,
+				},
+				Spec: dw.DevWorkspaceSpec{
+					Template: dw.DevWorkspaceTemplateSpec{
+						Parent: tt.parent,
+						DevWorkspaceTemplateSpecContent: dw.DevWorkspaceTemplateSpecContent{
+							Components: tt.components,
+						},
+					},
+				},
+			}
+			assert.Equal(t, tt.expectedRefs, indexTemplateRefs(workspace))
+		})
+	}
+}
@@ -13,6 +13,8 @@
 # is a file, combined.yaml, which stores all the objects involved in deploying
 # the operator, and a subfolder, objects, which stores separate yaml files for
 # each object in combined.yaml, with the name <object-name>.<object-kind>.yaml
+# Objects for the tunnel server used by the tunnel routing class, which is optional,
+# are stored separately in tunnel-server.yaml
 #
 # Accepts parameter `--use-defaults`, which will generate static files based on
 # default environment variables. Otherwise, current environment variables are
@@ -118,6 +120,7 @@ KUBERNETES_DIR="${OUTPUT_DIR}/kubernetes"
 OPENSHIFT_DIR="${OUTPUT_DIR}/openshift"
 OLM_DIR="${OUTPUT_DIR}/olm"
 COMBINED_FILENAME="combined.yaml"
+TUNNEL_SERVER_FILENAME="tunnel-server.yaml"
 OBJECTS_DIR="objects"
 
 KUSTOMIZE_VER=4.0.5
@@ -177,6 +180,18 @@ ${KUSTOMIZE} build "${SCRIPT_DIR}/templates/service-ca" \
   > "${OPENSHIFT_DIR}/${COMBINED_FILENAME}"
 echo "File saved to ${OPENSHIFT_DIR}/${COMBINED_FILENAME}"
 
+echo "Generating tunnel server config for Kubernetes"
+${KUSTOMIZE} build "${SCRIPT_DIR}/templates/tunnel-server/cert-manager" \
+  | envsubst "$SUBST_VARS" \
+  > "${KUBERNETES_DIR}/${TUNNEL_SERVER_FILENAME}"
+echo "File saved to ${KUBERNETES_DIR}/${TUNNEL_SERVER_FILENAME}"
+
+echo "Generating tunnel server config for OpenShift"
+${KUSTOMIZE} build "${SCRIPT_DIR}/templates/tunnel-server/service-ca" \
+  | envsubst "$SUBST_VARS" \
+  > "${OPENSHIFT_DIR}/${TUNNEL_SERVER_FILENAME}"
+echo "File saved to ${OPENSHIFT_DIR}/${TUNNEL_SERVER_FILENAME}"
+
 if $GEN_OLM; then
   echo "Generating base deployment files for OLM"
   export NAMESPACE=openshift-operators
@@ -3,6 +3,7 @@ module github.com/devfile/devworkspace-operator
 go 1.15
 
 require (
+	github.com/Masterminds/semver/v3 v3.1.1
 	github.com/devfile/api/v2 v2.0.0-20210713124824-03e023e7078b
 	github.com/go-git/go-git/v5 v5.2.0
 	github.com/go-logr/logr v0.1.0
@@ -11,6 +12,7 @@ require (
 	github.com/onsi/ginkgo v1.12.1
 	github.com/onsi/gomega v1.10.1
 	github.com/openshift/api v0.0.0-20200205133042-34f0ec8dab87
+	github.com/prometheus/client_golang v1.0.0
 	github.com/redhat-cop/operator-utils v0.1.0
 	github.com/stretchr/testify v1.6.1
 	go.uber.org/zap v1.16.0 // indirect
@@ -22,7 +24,7 @@ require (
 	google.golang.org/protobuf v1.25.0 // indirect
 	gopkg.in/yaml.v3 v3.0.0-20200605160147-a5ece683394c // indirect
 	k8s.io/api v0.18.8
-	k8s.io/apiextensions-apiserver v0.18.8 // indirect
+	k8s.io/apiextensions-apiserver v0.18.8
 	k8s.io/apimachinery v0.18.8
 	k8s.io/client-go v0.18.8
 	sigs.k8s.io/controller-runtime v0.6.3
@@ -40,6 +40,7 @@ const (
 	asyncStorageServerImageEnvVar  = "RELATED_IMAGE_async_storage_server"
 	asyncStorageSidecarImageEnvVar = "RELATED_IMAGE_async_storage_sidecar"
 	projectCloneImageEnvVar        = "RELATED_IMAGE_project_clone"
+	imageBuilderImageEnvVar        = "RELATED_IMAGE_image_builder"
 )
 
 // GetWebhookServerImage returns the image reference for the webhook server image. Returns
@@ -102,6 +103,22 @@ func GetProjectClonerImage() string {
 	return val
 }
 
+// GetImageBuilderImage returns the image reference for the image used to build image components. The image must
+// accept the arguments of the kaniko executor (e.g. --dockerfile, --context, --destination). Returns the empty string
+// if environment variable RELATED_IMAGE_image_builder is not defined; this is checked on startup by
+// ValidateImageBuilderImage.
+func GetImageBuilderImage() string {
+	return os.Getenv(imageBuilderImageEnvVar)
+}
+
+// ValidateImageBuilderImage returns an error if the image used to build image components is not configured.
+func ValidateImageBuilderImage() error {
+	if GetImageBuilderImage() == "" {
+		return fmt.Errorf("environment variable %s is not set", imageBuilderImageEnvVar)
+	}
+	return nil
+}
+
 // FillPluginEnvVars replaces plugin devworkspaceTemplate .spec.components[].container.image environment
 // variables of the form ${RELATED_IMAGE_*} with values from environment variables with the same name.
 //
@@ -33,3 +33,14 @@ func Equal(a, b map[string]string) bool {
 	}
 	return true
 }
+
+// Subset returns whether every key-value pair in sub is also present in super. A nil map is
+// treated as empty.
+func Subset(sub, super map[string]string) bool {
+	for k, v := range sub {
+		if superVal, ok := super[k]; !ok || superVal != v {
+			return false
+		}
+	}
+	return true
+}
@@ -21,6 +21,7 @@ import (
 
 	"github.com/devfile/devworkspace-operator/controllers/controller/devworkspacerouting"
 	"github.com/devfile/devworkspace-operator/controllers/controller/devworkspacerouting/solvers"
+	"github.com/devfile/devworkspace-operator/internal/images"
 	"github.com/devfile/devworkspace-operator/pkg/config"
 	"github.com/devfile/devworkspace-operator/pkg/infrastructure"
 	"github.com/devfile/devworkspace-operator/pkg/webhook"
@@ -109,11 +110,22 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err = validateImageBuilder(); err != nil {
+		setupLog.Error(err, "Image builds are enabled but cannot be run")
+		os.Exit(1)
+	}
+
+	solverGetter := &solvers.SolverGetter{}
+	if err = validateDefaultRoutingClass(solverGetter); err != nil {
+		setupLog.Error(err, "invalid controller configuration")
+		os.Exit(1)
+	}
+
 	if err = (&devworkspacerouting.DevWorkspaceRoutingReconciler{
 		Client:       mgr.GetClient(),
 		Log:          ctrl.Log.WithName("controllers").WithName("DevWorkspaceRouting"),
 		Scheme:       mgr.GetScheme(),
-		SolverGetter: &solvers.SolverGetter{},
+		SolverGetter: solverGetter,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "DevWorkspaceRouting")
 		os.Exit(1)
@@ -186,3 +198,32 @@ func setupControllerConfig(mgr ctrl.Manager) error {
 	}
 	return nil
 }
+
+// validateDefaultRoutingClass checks that the default routing class is handled either by the routing solvers
+// registered in this controller or by an external routing controller listed in the controller configuration;
+// otherwise, all DevWorkspaces that do not specify a routing class would fail to start.
+func validateDefaultRoutingClass(solverGetter solvers.RoutingSolverGetter) error {
+	defaultRoutingClass := controllerv1alpha1.DevWorkspaceRoutingClass(config.ControllerCfg.GetDefaultRoutingClass())
+	if solverGetter.HasSolver(defaultRoutingClass) {
+		return nil
+	}
+	for _, externalClass := range config.ControllerCfg.GetExternalRoutingClasses() {
+		if externalClass == string(defaultRoutingClass) {
+			return nil
+		}
+	}
+	return fmt.Errorf("default routing class %q is not supported by any registered routing solver and is not listed in "+
+		"the external routing classes of the controller configuration", defaultRoutingClass)
+}
+
+// validateImageBuilder checks that an image for building image components is configured if a registry for pushing
+// built images is configured. If no registry is configured, image components are not supported and no image is needed.
+func validateImageBuilder() error {
+	if registry := config.ControllerCfg.GetImageBuildRegistry(); registry == nil || *registry == "" {
+		return nil
+	}
+	if err := images.ValidateImageBuilderImage(); err != nil {
+		return fmt.Errorf("invalid image builder configuration: %w", err)
+	}
+	return nil
+}
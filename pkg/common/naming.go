@@ -31,6 +31,16 @@ func ServiceName(workspaceId string) string {
 	return fmt.Sprintf("%s-%s", workspaceId, "service")
 }
 
+// EndpointServiceName returns the name of a service that exposes only a single endpoint, e.g. a NodePort service
+// for a tcp endpoint.
+func EndpointServiceName(workspaceId, endpointName string, endpointPort int) string {
+	name := fmt.Sprintf("%s-%s-%d", workspaceId, endpointName, endpointPort)
+	if len(name) > 63 {
+		name = strings.TrimSuffix(name[:63], "-")
+	}
+	return name
+}
+
 func ServiceAccountName(workspaceId string) string {
 	return fmt.Sprintf("%s-%s", workspaceId, "sa")
 }
@@ -76,3 +86,48 @@ func PVCCleanupJobName(workspaceId string) string {
 func MetadataConfigMapName(workspaceId string) string {
 	return fmt.Sprintf("%s-metadata", workspaceId)
 }
+
+func ImportLockConfigMapName(workspaceId string) string {
+	return fmt.Sprintf("%s-import-lock", workspaceId)
+}
+
+func DevWorkspaceRoutingName(workspaceId string) string {
+	return fmt.Sprintf("routing-%s", workspaceId)
+}
+
+func PostStopJobName(workspaceId string) string {
+	return fmt.Sprintf("%s-poststop", workspaceId)
+}
+
+func ImageBuildJobName(workspaceId, componentName string) string {
+	name := fmt.Sprintf("%s-build-%s", workspaceId, componentName)
+	if len(name) > 63 {
+		name = strings.TrimSuffix(name[:63], "-")
+	}
+	return name
+}
+
+// DedicatedPodDeploymentName returns the name of the deployment for a container component with dedicatedPod set
+func DedicatedPodDeploymentName(workspaceId, componentName string) string {
+	name := fmt.Sprintf("%s-%s", workspaceId, componentName)
+	if len(name) > 63 {
+		name = strings.TrimSuffix(name[:63], "-")
+	}
+	return name
+}
+
+// DedicatedPodServiceName returns the name of the service that exposes a dedicated pod component's endpoints to other
+// components in the DevWorkspace
+func DedicatedPodServiceName(workspaceId, componentName string) string {
+	return DedicatedPodDeploymentName(workspaceId, componentName)
+}
+
+// MachineServiceName returns the name of the service created by DevWorkspaceRouting for the endpoints of a machine
+// that does not run in the DevWorkspace's main pod
+func MachineServiceName(workspaceId, machineName string) string {
+	name := fmt.Sprintf("%s-%s-service", workspaceId, machineName)
+	if len(name) > 63 {
+		name = strings.TrimSuffix(name[:63], "-")
+	}
+	return name
+}
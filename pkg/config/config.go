@@ -15,8 +15,12 @@ package config
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"strconv"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/devfile/devworkspace-operator/pkg/constants"
 	"github.com/devfile/devworkspace-operator/pkg/infrastructure"
@@ -26,6 +30,7 @@ import (
 	routeV1 "github.com/openshift/api/route/v1"
 	corev1 "k8s.io/api/core/v1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -69,6 +74,118 @@ func (wc *ControllerConfig) GetExperimentalFeaturesEnabled() bool {
 	return wc.GetPropertyOrDefault(experimentalFeaturesEnabled, defaultExperimentalFeaturesEnabled) == "true"
 }
 
+// GetExternalRoutingClasses returns the routing classes that are handled by external routing controllers.
+func (wc *ControllerConfig) GetExternalRoutingClasses() []string {
+	var classes []string
+	for _, class := range strings.Split(wc.GetPropertyOrDefault(routingExternalClasses, ""), ",") {
+		if class = strings.TrimSpace(class); class != "" {
+			classes = append(classes, class)
+		}
+	}
+	return classes
+}
+
+// GetRoutingClaimTimeout returns how long a DevWorkspaceRouting may remain unclaimed by any routing controller, or
+// without its claim being renewed, before the DevWorkspace is failed.
+func (wc *ControllerConfig) GetRoutingClaimTimeout() time.Duration {
+	return wc.getDurationPropertyOrDefault(routingClaimTimeout, defaultRoutingClaimTimeout)
+}
+
+// GetRoutingHeartbeatInterval returns how often a routing controller renews its claim on a DevWorkspaceRouting. The
+// interval is a fraction of the claim timeout, so that a claim is renewed several times before it expires.
+func (wc *ControllerConfig) GetRoutingHeartbeatInterval() time.Duration {
+	return wc.GetRoutingClaimTimeout() / 3
+}
+
+// GetRemoteResourcesCacheTTL returns the maximum duration for which fetched plugins and parents are cached.
+func (wc *ControllerConfig) GetRemoteResourcesCacheTTL() time.Duration {
+	return wc.getDurationPropertyOrDefault(remoteResourcesCacheTTL, defaultRemoteResourcesCacheTTL)
+}
+
+// GetRemoteResourcesMaxSize returns the maximum size in bytes of a fetched plugin or parent.
+func (wc *ControllerConfig) GetRemoteResourcesMaxSize() int64 {
+	maxSize, err := resource.ParseQuantity(wc.GetPropertyOrDefault(remoteResourcesMaxSize, defaultRemoteResourcesMaxSize))
+	if err != nil {
+		// Property is checked in Validate(); default is always parseable
+		maxSize = resource.MustParse(defaultRemoteResourcesMaxSize)
+	}
+	return maxSize.Value()
+}
+
+// GetRemoteResourcesRequestTimeout returns the timeout for a single request when fetching a plugin or parent.
+func (wc *ControllerConfig) GetRemoteResourcesRequestTimeout() time.Duration {
+	return wc.getDurationPropertyOrDefault(remoteResourcesRequestTimeout, defaultRemoteResourcesRequestTimeout)
+}
+
+// GetRemoteResourcesMaxRetries returns the number of times a failed request for a plugin or parent is retried.
+func (wc *ControllerConfig) GetRemoteResourcesMaxRetries() int {
+	retries, err := strconv.Atoi(wc.GetPropertyOrDefault(remoteResourcesMaxRetries, defaultRemoteResourcesMaxRetries))
+	if err != nil || retries < 0 {
+		// Property is checked in Validate(); default is always parseable
+		retries, _ = strconv.Atoi(defaultRemoteResourcesMaxRetries)
+	}
+	return retries
+}
+
+// GetRemoteResourcesCacheMaxEntries returns the maximum number of fetched plugins and parents kept in the cache.
+func (wc *ControllerConfig) GetRemoteResourcesCacheMaxEntries() int {
+	maxEntries, err := strconv.Atoi(wc.GetPropertyOrDefault(remoteResourcesCacheMaxEntries, defaultRemoteResourcesCacheMaxEntries))
+	if err != nil || maxEntries < 0 {
+		// Property is checked in Validate(); default is always parseable
+		maxEntries, _ = strconv.Atoi(defaultRemoteResourcesCacheMaxEntries)
+	}
+	return maxEntries
+}
+
+func (wc *ControllerConfig) getDurationPropertyOrDefault(name, defaultValue string) time.Duration {
+	duration, err := time.ParseDuration(wc.GetPropertyOrDefault(name, defaultValue))
+	if err != nil {
+		// Properties are checked in Validate(); defaults are always parseable
+		duration, _ = time.ParseDuration(defaultValue)
+	}
+	return duration
+}
+
+// GetEndpointProbesEnabled returns true if the reachability of exposed endpoints should be probed by the
+// DevWorkspaceRouting controller.
+func (wc *ControllerConfig) GetEndpointProbesEnabled() bool {
+	return wc.GetPropertyOrDefault(endpointProbesEnabled, defaultEndpointProbesEnabled) == "true"
+}
+
+// GetImageBuildRegistry returns the registry that images built from image components are pushed to, or nil if
+// building images is not configured.
+func (wc *ControllerConfig) GetImageBuildRegistry() *string {
+	return wc.GetProperty(imageBuildRegistry)
+}
+
+// GetImageBuildInsecureRegistry returns true if images built from image components may be pushed to an insecure
+// registry.
+func (wc *ControllerConfig) GetImageBuildInsecureRegistry() bool {
+	return wc.GetPropertyOrDefault(imageBuildInsecureRegistry, defaultImageBuildInsecureRegistry) == "true"
+}
+
+// GetPreStopTerminationGracePeriodSeconds returns the termination grace period for DevWorkspaces that define preStop
+// commands
+func (wc *ControllerConfig) GetPreStopTerminationGracePeriodSeconds() int64 {
+	return int64(wc.getDurationPropertyOrDefault(preStopTerminationGracePeriod, defaultPreStopTerminationGracePeriod).Seconds())
+}
+
+// GetPostStopJobTimeoutSeconds returns how long a postStop job may run before it is stopped
+func (wc *ControllerConfig) GetPostStopJobTimeoutSeconds() int64 {
+	return int64(wc.getDurationPropertyOrDefault(postStopJobTimeout, defaultPostStopJobTimeout).Seconds())
+}
+
+// GetImageBuildPushSecret returns the name of the secret used to push built images, or the empty string if images
+// are pushed without credentials
+func (wc *ControllerConfig) GetImageBuildPushSecret() string {
+	return wc.GetPropertyOrDefault(imageBuildPushSecret, "")
+}
+
+// GetImageBuildJobTimeoutSeconds returns how long a job building an image component may run before it is stopped
+func (wc *ControllerConfig) GetImageBuildJobTimeoutSeconds() int64 {
+	return int64(wc.getDurationPropertyOrDefault(imageBuildJobTimeout, defaultImageBuildJobTimeout).Seconds())
+}
+
 func (wc *ControllerConfig) GetPVCStorageClassName() *string {
 	return wc.GetProperty(workspacePVCStorageClassName)
 }
@@ -77,6 +194,146 @@ func (wc *ControllerConfig) GetSidecarPullPolicy() string {
 	return wc.GetPropertyOrDefault(sidecarPullPolicy, defaultSidecarPullPolicy)
 }
 
+// ContainerResourcePolicy defines the default resource requirements of containers in DevWorkspaces and the maximum
+// resource requirements they may set. All values are Kubernetes quantities; empty values are not applied.
+type ContainerResourcePolicy struct {
+	DefaultMemoryLimit   string
+	DefaultMemoryRequest string
+	DefaultCpuLimit      string
+	DefaultCpuRequest    string
+	MaxMemoryLimit       string
+	MaxCpuLimit          string
+	// MaxLimitRequestRatio is the maximum ratio of limit to request for memory and CPU, e.g. "4"
+	MaxLimitRequestRatio string
+}
+
+// WithOverrides returns a copy of the policy where defaults that are set in overrides replace those in the policy.
+// Maximums in overrides can only tighten the policy: the lower of the two values is used. Values in overrides that
+// cannot be parsed are used as-is, so that they are reported by Validate.
+func (p ContainerResourcePolicy) WithOverrides(overrides *ContainerResourcePolicy) ContainerResourcePolicy {
+	if overrides == nil {
+		return p
+	}
+	override := func(value *string, overrideValue string) {
+		if overrideValue != "" {
+			*value = overrideValue
+		}
+	}
+	overrideMaxQuantity := func(value *string, overrideValue string) {
+		if overrideValue == "" {
+			return
+		}
+		current, err := resource.ParseQuantity(*value)
+		if err != nil {
+			*value = overrideValue
+			return
+		}
+		if overrideQuantity, err := resource.ParseQuantity(overrideValue); err != nil || overrideQuantity.Cmp(current) < 0 {
+			*value = overrideValue
+		}
+	}
+	overrideMaxRatio := func(value *string, overrideValue string) {
+		if overrideValue == "" {
+			return
+		}
+		current, err := strconv.ParseFloat(*value, 64)
+		if err != nil {
+			*value = overrideValue
+			return
+		}
+		if overrideRatio, err := strconv.ParseFloat(overrideValue, 64); err != nil || overrideRatio < current {
+			*value = overrideValue
+		}
+	}
+	override(&p.DefaultMemoryLimit, overrides.DefaultMemoryLimit)
+	override(&p.DefaultMemoryRequest, overrides.DefaultMemoryRequest)
+	override(&p.DefaultCpuLimit, overrides.DefaultCpuLimit)
+	override(&p.DefaultCpuRequest, overrides.DefaultCpuRequest)
+	overrideMaxQuantity(&p.MaxMemoryLimit, overrides.MaxMemoryLimit)
+	overrideMaxQuantity(&p.MaxCpuLimit, overrides.MaxCpuLimit)
+	overrideMaxRatio(&p.MaxLimitRequestRatio, overrides.MaxLimitRequestRatio)
+	return p
+}
+
+// Validate checks that all values in the policy can be parsed, and that default limits do not exceed maximum limits.
+func (p ContainerResourcePolicy) Validate() error {
+	quantities := []struct {
+		name  string
+		value string
+	}{
+		{"default memory limit", p.DefaultMemoryLimit},
+		{"default memory request", p.DefaultMemoryRequest},
+		{"default CPU limit", p.DefaultCpuLimit},
+		{"default CPU request", p.DefaultCpuRequest},
+		{"maximum memory limit", p.MaxMemoryLimit},
+		{"maximum CPU limit", p.MaxCpuLimit},
+	}
+	for _, quantity := range quantities {
+		if quantity.value == "" {
+			continue
+		}
+		if _, err := resource.ParseQuantity(quantity.value); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", quantity.name, quantity.value, err)
+		}
+	}
+	if p.MaxLimitRequestRatio != "" {
+		if ratio, err := strconv.ParseFloat(p.MaxLimitRequestRatio, 64); err != nil || ratio < 1 {
+			return fmt.Errorf("invalid maximum limit to request ratio %q: must be a number greater than or equal to 1", p.MaxLimitRequestRatio)
+		}
+	}
+	exceedsMax := func(value, max string) bool {
+		if value == "" || max == "" {
+			return false
+		}
+		valueQuantity, maxQuantity := resource.MustParse(value), resource.MustParse(max)
+		return valueQuantity.Cmp(maxQuantity) > 0
+	}
+	if exceedsMax(p.DefaultMemoryLimit, p.MaxMemoryLimit) {
+		return fmt.Errorf("default memory limit %s exceeds maximum memory limit %s", p.DefaultMemoryLimit, p.MaxMemoryLimit)
+	}
+	if exceedsMax(p.DefaultCpuLimit, p.MaxCpuLimit) {
+		return fmt.Errorf("default CPU limit %s exceeds maximum CPU limit %s", p.DefaultCpuLimit, p.MaxCpuLimit)
+	}
+	return nil
+}
+
+// GetContainerResourcePolicy returns the resource policy for containers in DevWorkspaces. Defaults that are not
+// configured fall back to the defaults in the constants package.
+func (wc *ControllerConfig) GetContainerResourcePolicy() ContainerResourcePolicy {
+	return ContainerResourcePolicy{
+		DefaultMemoryLimit:   wc.GetPropertyOrDefault(sidecarDefaultMemoryLimit, constants.SidecarDefaultMemoryLimit),
+		DefaultMemoryRequest: wc.GetPropertyOrDefault(sidecarDefaultMemoryRequest, constants.SidecarDefaultMemoryRequest),
+		DefaultCpuLimit:      wc.GetPropertyOrDefault(sidecarDefaultCpuLimit, constants.SidecarDefaultCpuLimit),
+		DefaultCpuRequest:    wc.GetPropertyOrDefault(sidecarDefaultCpuRequest, constants.SidecarDefaultCpuRequest),
+		MaxMemoryLimit:       wc.GetPropertyOrDefault(sidecarMaxMemoryLimit, ""),
+		MaxCpuLimit:          wc.GetPropertyOrDefault(sidecarMaxCpuLimit, ""),
+		MaxLimitRequestRatio: wc.GetPropertyOrDefault(sidecarMaxLimitRequestRatio, ""),
+	}
+}
+
+// GetAllowedSecurityContextOverrides returns the security context fields that DevWorkspaces may override.
+func (wc *ControllerConfig) GetAllowedSecurityContextOverrides() []string {
+	var fields []string
+	for _, field := range strings.Split(wc.GetPropertyOrDefault(securityContextOverridesAllowed, ""), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// GetKubernetesComponentsAllowedKinds returns the kinds of objects that kubernetes and openshift components may create,
+// in the format Kind.group
+func (wc *ControllerConfig) GetKubernetesComponentsAllowedKinds() map[string]bool {
+	kinds := map[string]bool{}
+	for _, kind := range strings.Split(wc.GetPropertyOrDefault(kubernetesComponentsAllowedKinds, defaultKubernetesComponentsAllowedKinds), ",") {
+		if kind = strings.TrimSpace(kind); kind != "" {
+			kinds[kind] = true
+		}
+	}
+	return kinds
+}
+
 func (wc *ControllerConfig) GetTlsInsecureSkipVerify() string {
 	return wc.GetPropertyOrDefault(tlsInsecureSkipVerify, defaultTlsInsecureSkipVerify)
 }
@@ -97,7 +354,81 @@ func (wc *ControllerConfig) GetPropertyOrDefault(name string, defaultValue strin
 	return defaultValue
 }
 
+// HostnameTemplateData is the data available when executing the routing hostname template.
+type HostnameTemplateData struct {
+	// Endpoint is the name of the endpoint, sanitized to be usable in a hostname
+	Endpoint string
+	// Workspace is the DevWorkspace ID
+	Workspace string
+	// Namespace is the namespace of the DevWorkspace
+	Namespace string
+	// Port is the target port of the endpoint
+	Port int
+}
+
+// GetRoutingNodePortHost returns the host that clients use to connect to endpoints exposed via NodePort services, or
+// the empty string if none is configured.
+func (wc *ControllerConfig) GetRoutingNodePortHost() string {
+	return wc.GetPropertyOrDefault(routingNodePortHost, "")
+}
+
+// GetRoutingHostnameTemplate returns the parsed hostname template for endpoints, or nil if no template is configured.
+func (wc *ControllerConfig) GetRoutingHostnameTemplate() (*template.Template, error) {
+	templateStr := wc.GetProperty(routingHostnameTemplate)
+	if templateStr == nil || *templateStr == "" {
+		return nil, nil
+	}
+	return template.New("hostname").Option("missingkey=error").Parse(*templateStr)
+}
+
 func (wc *ControllerConfig) Validate() error {
+	durationProperties := map[string]string{
+		routingClaimTimeout:           defaultRoutingClaimTimeout,
+		remoteResourcesCacheTTL:       defaultRemoteResourcesCacheTTL,
+		remoteResourcesRequestTimeout: defaultRemoteResourcesRequestTimeout,
+		preStopTerminationGracePeriod: defaultPreStopTerminationGracePeriod,
+		postStopJobTimeout:            defaultPostStopJobTimeout,
+		imageBuildJobTimeout:          defaultImageBuildJobTimeout,
+	}
+	for property, defaultValue := range durationProperties {
+		if _, err := time.ParseDuration(wc.GetPropertyOrDefault(property, defaultValue)); err != nil {
+			return fmt.Errorf("invalid %s: %w", property, err)
+		}
+	}
+	if wc.GetPreStopTerminationGracePeriodSeconds() < 1 {
+		return fmt.Errorf("invalid %s: must be at least 1s", preStopTerminationGracePeriod)
+	}
+	if wc.GetPostStopJobTimeoutSeconds() < 1 {
+		return fmt.Errorf("invalid %s: must be at least 1s", postStopJobTimeout)
+	}
+	if wc.GetImageBuildJobTimeoutSeconds() < 1 {
+		return fmt.Errorf("invalid %s: must be at least 1s", imageBuildJobTimeout)
+	}
+	if _, err := resource.ParseQuantity(wc.GetPropertyOrDefault(remoteResourcesMaxSize, defaultRemoteResourcesMaxSize)); err != nil {
+		return fmt.Errorf("invalid %s: %w", remoteResourcesMaxSize, err)
+	}
+	if retries, err := strconv.Atoi(wc.GetPropertyOrDefault(remoteResourcesMaxRetries, defaultRemoteResourcesMaxRetries)); err != nil || retries < 0 {
+		return fmt.Errorf("invalid %s: must be a non-negative integer", remoteResourcesMaxRetries)
+	}
+	if maxEntries, err := strconv.Atoi(wc.GetPropertyOrDefault(remoteResourcesCacheMaxEntries, defaultRemoteResourcesCacheMaxEntries)); err != nil || maxEntries < 0 {
+		return fmt.Errorf("invalid %s: must be a non-negative integer", remoteResourcesCacheMaxEntries)
+	}
+	if err := wc.GetContainerResourcePolicy().Validate(); err != nil {
+		return fmt.Errorf("invalid container resource configuration: %w", err)
+	}
+	if _, _, err := wc.GetPodScheduling(); err != nil {
+		return err
+	}
+	hostnameTemplate, err := wc.GetRoutingHostnameTemplate()
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", routingHostnameTemplate, err)
+	}
+	if hostnameTemplate != nil {
+		testData := HostnameTemplateData{Endpoint: "endpoint", Workspace: "workspaceid", Namespace: "namespace", Port: 8080}
+		if err := hostnameTemplate.Execute(ioutil.Discard, testData); err != nil {
+			return fmt.Errorf("invalid %s: %w", routingHostnameTemplate, err)
+		}
+	}
 	return nil
 }
 
@@ -213,6 +544,31 @@ func WatchControllerConfig(mgr manager.Manager) error {
 	return err
 }
 
+// LoadControllerConfig reads the controller's config map, as identified by the ConfigMapNameEnvVar and ConfigMapNamespaceEnvVar
+// environment variables, into ControllerCfg. Unlike WatchControllerConfig, the config map is not created or modified, so
+// this is suitable for components that run separately from the controller, such as the webhook server. If the config map
+// does not exist, the default configuration is used.
+func LoadControllerConfig(nonCachedClient client.Client) error {
+	if configMapName := os.Getenv(ConfigMapNameEnvVar); configMapName != "" {
+		ConfigMapReference.Name = configMapName
+	}
+	ConfigMapReference.Namespace = os.Getenv(ConfigMapNamespaceEnvVar)
+	if ConfigMapReference.Namespace == "" {
+		return fmt.Errorf("you should set the namespace of the controller config map through the '%s' environment variable", ConfigMapNamespaceEnvVar)
+	}
+
+	configMap := &corev1.ConfigMap{}
+	err := nonCachedClient.Get(context.TODO(), ConfigMapReference, configMap)
+	if err != nil {
+		if !k8sErrors.IsNotFound(err) {
+			return err
+		}
+		buildDefaultConfigMap(configMap)
+	}
+	ControllerCfg.update(configMap)
+	return nil
+}
+
 func SetupConfigForTesting(cm *corev1.ConfigMap) {
 	ControllerCfg.update(cm)
 }
@@ -0,0 +1,71 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerResourcePolicyWithOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    ContainerResourcePolicy
+		overrides *ContainerResourcePolicy
+		expected  ContainerResourcePolicy
+	}{
+		{
+			name:     "Returns policy when there are no overrides",
+			policy:   ContainerResourcePolicy{DefaultMemoryLimit: "128Mi", MaxMemoryLimit: "1Gi"},
+			expected: ContainerResourcePolicy{DefaultMemoryLimit: "128Mi", MaxMemoryLimit: "1Gi"},
+		},
+		{
+			name:      "Overrides defaults",
+			policy:    ContainerResourcePolicy{DefaultMemoryLimit: "128Mi", DefaultCpuRequest: "100m"},
+			overrides: &ContainerResourcePolicy{DefaultMemoryLimit: "2Gi", DefaultCpuLimit: "1"},
+			expected:  ContainerResourcePolicy{DefaultMemoryLimit: "2Gi", DefaultCpuLimit: "1", DefaultCpuRequest: "100m"},
+		},
+		{
+			name:      "Lowers maximums",
+			policy:    ContainerResourcePolicy{MaxMemoryLimit: "4Gi", MaxCpuLimit: "2", MaxLimitRequestRatio: "10"},
+			overrides: &ContainerResourcePolicy{MaxMemoryLimit: "1Gi", MaxCpuLimit: "500m", MaxLimitRequestRatio: "4"},
+			expected:  ContainerResourcePolicy{MaxMemoryLimit: "1Gi", MaxCpuLimit: "500m", MaxLimitRequestRatio: "4"},
+		},
+		{
+			name:      "Does not raise maximums",
+			policy:    ContainerResourcePolicy{MaxMemoryLimit: "1Gi", MaxCpuLimit: "500m", MaxLimitRequestRatio: "4"},
+			overrides: &ContainerResourcePolicy{MaxMemoryLimit: "4Gi", MaxCpuLimit: "2", MaxLimitRequestRatio: "10"},
+			expected:  ContainerResourcePolicy{MaxMemoryLimit: "1Gi", MaxCpuLimit: "500m", MaxLimitRequestRatio: "4"},
+		},
+		{
+			name:      "Sets maximums that are not set in policy",
+			policy:    ContainerResourcePolicy{},
+			overrides: &ContainerResourcePolicy{MaxMemoryLimit: "4Gi", MaxCpuLimit: "2", MaxLimitRequestRatio: "10"},
+			expected:  ContainerResourcePolicy{MaxMemoryLimit: "4Gi", MaxCpuLimit: "2", MaxLimitRequestRatio: "10"},
+		},
+		{
+			name:      "Uses invalid maximums in overrides so they are reported",
+			policy:    ContainerResourcePolicy{MaxMemoryLimit: "1Gi", MaxLimitRequestRatio: "4"},
+			overrides: &ContainerResourcePolicy{MaxMemoryLimit: "invalid", MaxLimitRequestRatio: "invalid"},
+			expected:  ContainerResourcePolicy{MaxMemoryLimit: "invalid", MaxLimitRequestRatio: "invalid"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := tt.policy.WithOverrides(tt.overrides)
+			assert.Equal(t, tt.expected, actual, "Should merge overrides into policy")
+		})
+	}
+}
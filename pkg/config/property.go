@@ -17,6 +17,20 @@ const (
 	sidecarPullPolicy        = "devworkspace.sidecar.image_pull_policy"
 	defaultSidecarPullPolicy = "Always"
 
+	// Default resource requirements for containers in DevWorkspaces that do not specify them, as Kubernetes quantities.
+	// Setting a property to an empty value means no default is applied, e.g. to defer to a LimitRange in the namespace.
+	sidecarDefaultMemoryLimit   = "devworkspace.sidecar.default_memory_limit"
+	sidecarDefaultMemoryRequest = "devworkspace.sidecar.default_memory_request"
+	sidecarDefaultCpuLimit      = "devworkspace.sidecar.default_cpu_limit"
+	sidecarDefaultCpuRequest    = "devworkspace.sidecar.default_cpu_request"
+	// Maximum resource limits allowed for containers in DevWorkspaces, as Kubernetes quantities. Containers that do
+	// not specify a limit (and have no default) use the maximum as their limit.
+	sidecarMaxMemoryLimit = "devworkspace.sidecar.max_memory_limit"
+	sidecarMaxCpuLimit    = "devworkspace.sidecar.max_cpu_limit"
+	// sidecarMaxLimitRequestRatio is the maximum ratio of limit to request allowed for the memory and CPU of containers
+	// in DevWorkspaces, e.g. "4". Default requests are raised as necessary to satisfy the ratio.
+	sidecarMaxLimitRequestRatio = "devworkspace.sidecar.max_limit_request_ratio"
+
 	// workspacePVCName config property handles the PVC name that should be created and used for all workspaces within one kubernetes namespace
 	workspacePVCName        = "devworkspace.pvc.name"
 	defaultWorkspacePVCName = "claim-devworkspace"
@@ -32,6 +46,93 @@ const (
 	// is supposed to be used by embedded routing solvers only
 	RoutingSuffix = "devworkspace.routing.cluster_host_suffix"
 
+	// routingNodePortHost is the hostname or IP address of the cluster's nodes that clients use to connect to public
+	// tcp and udp endpoints exposed via NodePort services, e.g. the address of a node or of a load balancer in front of
+	// the nodes. Must be set for such endpoints to be exposed.
+	routingNodePortHost = "devworkspace.routing.node_port_host"
+
+	// routingHostnameTemplate is a Go text/template used by embedded routing solvers to compute the hostname
+	// for each exposed endpoint, e.g. "{{.Endpoint}}-{{.Workspace}}.{{.Namespace}}.apps.example.com".
+	// See HostnameTemplateData for available fields. If unset, hostnames are generated from the RoutingSuffix.
+	routingHostnameTemplate = "devworkspace.routing.hostname_template"
+
+	// routingExternalClasses is a comma-separated list of routing classes handled by external routing controllers,
+	// e.g. "che,custom". The default routing class must either be handled by this controller or be listed here.
+	routingExternalClasses = "devworkspace.routing.external_classes"
+
+	// routingClaimTimeout defines how long the DevWorkspace controller waits for a routing controller to claim a
+	// DevWorkspaceRouting, or to renew its claim, before failing the DevWorkspace. Must be a valid Go duration, e.g. "5m"
+	routingClaimTimeout        = "devworkspace.routing.claim_timeout"
+	defaultRoutingClaimTimeout = "5m"
+
+	// endpointProbesEnabled defines whether the DevWorkspaceRouting controller should probe exposed endpoints
+	// and report their reachability in the DevWorkspaceRouting status
+	endpointProbesEnabled        = "devworkspace.routing.endpoint_probes_enabled"
+	defaultEndpointProbesEnabled = "false"
+
+	// Plugins and parents referenced by URI or registry ID are cached to avoid fetching them on every reconcile.
+	// Responses are cached for at most remoteResourcesCacheTTL, or less if the server specifies a shorter max-age.
+	remoteResourcesCacheTTL        = "devworkspace.remote_resources.cache_ttl"
+	defaultRemoteResourcesCacheTTL = "5m"
+	// remoteResourcesMaxSize is the maximum size of a fetched plugin or parent, as a Kubernetes quantity (e.g. "1Mi")
+	remoteResourcesMaxSize        = "devworkspace.remote_resources.max_size"
+	defaultRemoteResourcesMaxSize = "1Mi"
+	// remoteResourcesRequestTimeout is the timeout for a single request when fetching a plugin or parent
+	remoteResourcesRequestTimeout        = "devworkspace.remote_resources.request_timeout"
+	defaultRemoteResourcesRequestTimeout = "10s"
+	// remoteResourcesMaxRetries is the number of times a failed request is retried on subsequent reconciles, with
+	// exponential backoff
+	remoteResourcesMaxRetries        = "devworkspace.remote_resources.max_retries"
+	defaultRemoteResourcesMaxRetries = "3"
+	// remoteResourcesCacheMaxEntries is the maximum number of fetched plugins and parents kept in the cache
+	remoteResourcesCacheMaxEntries        = "devworkspace.remote_resources.cache_max_entries"
+	defaultRemoteResourcesCacheMaxEntries = "500"
+
+	// imageBuildRegistry is the registry that images built from image components are pushed to, e.g.
+	// "registry.example.com/devworkspaces". Image components are not supported if it is unset.
+	imageBuildRegistry = "devworkspace.image_build.registry"
+	// imageBuildInsecureRegistry defines whether images may be pushed to imageBuildRegistry over plain HTTP or without
+	// verifying its TLS certificate. It's insecure and should be used only for testing, e.g. with a local registry
+	imageBuildInsecureRegistry        = "devworkspace.image_build.insecure_registry"
+	defaultImageBuildInsecureRegistry = "false"
+	// imageBuildPushSecret is the name of a Secret of type kubernetes.io/dockerconfigjson, in the DevWorkspace's
+	// namespace, that provides credentials for pushing built images to imageBuildRegistry. If unset, images are pushed
+	// without credentials.
+	imageBuildPushSecret = "devworkspace.image_build.push_secret"
+	// imageBuildJobTimeout is how long a job building an image component may run before it is stopped and the
+	// DevWorkspace fails to start. Must be a valid Go duration, e.g. "30m"
+	imageBuildJobTimeout        = "devworkspace.image_build.job_timeout"
+	defaultImageBuildJobTimeout = "30m"
+
+	// preStopTerminationGracePeriod is the termination grace period used for DevWorkspaces that define preStop
+	// commands, to give the commands time to complete before containers are killed. Must be a valid Go duration, e.g. "1m"
+	preStopTerminationGracePeriod        = "devworkspace.lifecycle.prestop_termination_grace_period"
+	defaultPreStopTerminationGracePeriod = "60s"
+	// postStopJobTimeout is how long a DevWorkspace's postStop job may run before it is stopped. Stopping the
+	// DevWorkspace completes once the job finishes or times out. Must be a valid Go duration, e.g. "5m"
+	postStopJobTimeout        = "devworkspace.lifecycle.poststop_job_timeout"
+	defaultPostStopJobTimeout = "5m"
+
+	// kubernetesComponentsAllowedKinds is a comma-separated list of the kinds of objects that kubernetes and openshift
+	// components may create, in the format Kind.group (or Kind for the core group), e.g. "ConfigMap,Deployment.apps".
+	// Cluster-scoped kinds are never allowed.
+	kubernetesComponentsAllowedKinds        = "devworkspace.kubernetes_components.allowed_kinds"
+	defaultKubernetesComponentsAllowedKinds = "ConfigMap,Secret,Service,PersistentVolumeClaim,Pod,Deployment.apps,Job.batch,Ingress.extensions,Route.route.openshift.io"
+
+	// podScheduling is the default scheduling configuration for DevWorkspace pods, as YAML or JSON with the fields
+	// nodeSelector, tolerations, affinity, priorityClassName, runtimeClassName and topologySpreadConstraints. Namespaces
+	// and DevWorkspaces may override it.
+	podScheduling = "devworkspace.pod.scheduling"
+	// podSchedulingEnforced has the same format as podScheduling, but fields set in it cannot be overridden by
+	// namespaces or DevWorkspaces. Namespaces may also enforce scheduling for their DevWorkspaces, but as users can
+	// usually edit their namespace's configuration, only this property can enforce scheduling against users.
+	podSchedulingEnforced = "devworkspace.pod.scheduling_enforced"
+
+	// securityContextOverridesAllowed is a comma-separated list of security context fields that DevWorkspaces may
+	// override through the controller.devfile.io/pod-overrides and controller.devfile.io/container-overrides
+	// attributes, using their JSON names, e.g. "runAsUser,runAsGroup,fsGroup". By default, no fields may be overridden.
+	securityContextOverridesAllowed = "devworkspace.pod.security_context_overrides_allowed"
+
 	experimentalFeaturesEnabled        = "devworkspace.experimental_features_enabled"
 	defaultExperimentalFeaturesEnabled = "false"
 
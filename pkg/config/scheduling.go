@@ -0,0 +1,96 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package config
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// PodScheduling defines how the pods of a DevWorkspace are scheduled. Fields use the same format as the corresponding
+// fields of a Kubernetes PodSpec.
+type PodScheduling struct {
+	NodeSelector              map[string]string                 `json:"nodeSelector,omitempty"`
+	Tolerations               []corev1.Toleration               `json:"tolerations,omitempty"`
+	Affinity                  *corev1.Affinity                  `json:"affinity,omitempty"`
+	PriorityClassName         string                            `json:"priorityClassName,omitempty"`
+	RuntimeClassName          *string                           `json:"runtimeClassName,omitempty"`
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+}
+
+// WithOverrides returns a copy of the scheduling configuration where fields that are set in overrides replace those
+// in the configuration. Fields are replaced as a whole, e.g. tolerations in overrides replace all tolerations rather
+// than being added to them.
+func (s PodScheduling) WithOverrides(overrides *PodScheduling) PodScheduling {
+	if overrides == nil {
+		return s
+	}
+	if overrides.NodeSelector != nil {
+		s.NodeSelector = overrides.NodeSelector
+	}
+	if overrides.Tolerations != nil {
+		s.Tolerations = overrides.Tolerations
+	}
+	if overrides.Affinity != nil {
+		s.Affinity = overrides.Affinity
+	}
+	if overrides.PriorityClassName != "" {
+		s.PriorityClassName = overrides.PriorityClassName
+	}
+	if overrides.RuntimeClassName != nil {
+		s.RuntimeClassName = overrides.RuntimeClassName
+	}
+	if overrides.TopologySpreadConstraints != nil {
+		s.TopologySpreadConstraints = overrides.TopologySpreadConstraints
+	}
+	return s
+}
+
+// ApplyTo sets the scheduling fields of podSpec according to the scheduling configuration.
+func (s PodScheduling) ApplyTo(podSpec *corev1.PodSpec) {
+	podSpec.NodeSelector = s.NodeSelector
+	podSpec.Tolerations = s.Tolerations
+	podSpec.Affinity = s.Affinity
+	podSpec.PriorityClassName = s.PriorityClassName
+	podSpec.RuntimeClassName = s.RuntimeClassName
+	podSpec.TopologySpreadConstraints = s.TopologySpreadConstraints
+}
+
+// ParsePodScheduling reads scheduling configuration from a YAML or JSON string. Returns nil if value is empty.
+func ParsePodScheduling(value string) (*PodScheduling, error) {
+	if value == "" {
+		return nil, nil
+	}
+	scheduling := &PodScheduling{}
+	if err := yaml.UnmarshalStrict([]byte(value), scheduling); err != nil {
+		return nil, fmt.Errorf("failed to parse pod scheduling configuration: %w", err)
+	}
+	return scheduling, nil
+}
+
+// GetPodScheduling returns the default scheduling configuration for DevWorkspace pods, which may be overridden by
+// namespaces and DevWorkspaces, and the enforced scheduling configuration, which takes precedence over all other
+// configuration. Either may be nil if not configured.
+func (wc *ControllerConfig) GetPodScheduling() (defaults, enforced *PodScheduling, err error) {
+	defaults, err = ParsePodScheduling(wc.GetPropertyOrDefault(podScheduling, ""))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s: %w", podScheduling, err)
+	}
+	enforced, err = ParsePodScheduling(wc.GetPropertyOrDefault(podSchedulingEnforced, ""))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s: %w", podSchedulingEnforced, err)
+	}
+	return defaults, enforced, nil
+}
@@ -0,0 +1,152 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestPodSchedulingWithOverrides(t *testing.T) {
+	runtimeClass := "test-runtime-class"
+	overrideRuntimeClass := "override-runtime-class"
+	tests := []struct {
+		name      string
+		base      PodScheduling
+		overrides *PodScheduling
+		expected  PodScheduling
+	}{
+		{
+			name:     "Returns configuration when there are no overrides",
+			base:     PodScheduling{NodeSelector: map[string]string{"zone": "a"}, PriorityClassName: "low"},
+			expected: PodScheduling{NodeSelector: map[string]string{"zone": "a"}, PriorityClassName: "low"},
+		},
+		{
+			name:      "Keeps fields that are not set in overrides",
+			base:      PodScheduling{NodeSelector: map[string]string{"zone": "a"}, RuntimeClassName: &runtimeClass},
+			overrides: &PodScheduling{PriorityClassName: "high"},
+			expected:  PodScheduling{NodeSelector: map[string]string{"zone": "a"}, RuntimeClassName: &runtimeClass, PriorityClassName: "high"},
+		},
+		{
+			name: "Replaces fields as a whole",
+			base: PodScheduling{
+				NodeSelector: map[string]string{"zone": "a", "disk": "ssd"},
+				Tolerations:  []corev1.Toleration{{Key: "base", Operator: corev1.TolerationOpExists}},
+			},
+			overrides: &PodScheduling{
+				NodeSelector: map[string]string{"zone": "b"},
+				Tolerations:  []corev1.Toleration{{Key: "override", Operator: corev1.TolerationOpExists}},
+			},
+			expected: PodScheduling{
+				NodeSelector: map[string]string{"zone": "b"},
+				Tolerations:  []corev1.Toleration{{Key: "override", Operator: corev1.TolerationOpExists}},
+			},
+		},
+		{
+			name: "Overrides all fields",
+			base: PodScheduling{
+				NodeSelector:              map[string]string{"zone": "a"},
+				Tolerations:               []corev1.Toleration{{Key: "base"}},
+				Affinity:                  &corev1.Affinity{PodAffinity: &corev1.PodAffinity{}},
+				PriorityClassName:         "low",
+				RuntimeClassName:          &runtimeClass,
+				TopologySpreadConstraints: []corev1.TopologySpreadConstraint{{MaxSkew: 1, TopologyKey: "zone"}},
+			},
+			overrides: &PodScheduling{
+				NodeSelector:              map[string]string{"zone": "b"},
+				Tolerations:               []corev1.Toleration{{Key: "override"}},
+				Affinity:                  &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{}},
+				PriorityClassName:         "high",
+				RuntimeClassName:          &overrideRuntimeClass,
+				TopologySpreadConstraints: []corev1.TopologySpreadConstraint{{MaxSkew: 2, TopologyKey: "node"}},
+			},
+			expected: PodScheduling{
+				NodeSelector:              map[string]string{"zone": "b"},
+				Tolerations:               []corev1.Toleration{{Key: "override"}},
+				Affinity:                  &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{}},
+				PriorityClassName:         "high",
+				RuntimeClassName:          &overrideRuntimeClass,
+				TopologySpreadConstraints: []corev1.TopologySpreadConstraint{{MaxSkew: 2, TopologyKey: "node"}},
+			},
+		},
+		{
+			name:      "Empty values in overrides are ignored",
+			base:      PodScheduling{NodeSelector: map[string]string{"zone": "a"}, PriorityClassName: "low"},
+			overrides: &PodScheduling{},
+			expected:  PodScheduling{NodeSelector: map[string]string{"zone": "a"}, PriorityClassName: "low"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := tt.base.WithOverrides(tt.overrides)
+			assert.Equal(t, tt.expected, actual, "Should merge overrides into scheduling configuration")
+		})
+	}
+}
+
+func TestPodSchedulingApplyTo(t *testing.T) {
+	runtimeClass := "test-runtime-class"
+	tests := []struct {
+		name       string
+		scheduling PodScheduling
+		podSpec    corev1.PodSpec
+		expected   corev1.PodSpec
+	}{
+		{
+			name: "Sets scheduling fields",
+			scheduling: PodScheduling{
+				NodeSelector:              map[string]string{"zone": "a"},
+				Tolerations:               []corev1.Toleration{{Key: "test", Operator: corev1.TolerationOpExists}},
+				Affinity:                  &corev1.Affinity{PodAffinity: &corev1.PodAffinity{}},
+				PriorityClassName:         "high",
+				RuntimeClassName:          &runtimeClass,
+				TopologySpreadConstraints: []corev1.TopologySpreadConstraint{{MaxSkew: 1, TopologyKey: "zone"}},
+			},
+			podSpec: corev1.PodSpec{ServiceAccountName: "test-sa"},
+			expected: corev1.PodSpec{
+				ServiceAccountName:        "test-sa",
+				NodeSelector:              map[string]string{"zone": "a"},
+				Tolerations:               []corev1.Toleration{{Key: "test", Operator: corev1.TolerationOpExists}},
+				Affinity:                  &corev1.Affinity{PodAffinity: &corev1.PodAffinity{}},
+				PriorityClassName:         "high",
+				RuntimeClassName:          &runtimeClass,
+				TopologySpreadConstraints: []corev1.TopologySpreadConstraint{{MaxSkew: 1, TopologyKey: "zone"}},
+			},
+		},
+		{
+			name:       "Clears scheduling fields that are not configured",
+			scheduling: PodScheduling{PriorityClassName: "high"},
+			podSpec: corev1.PodSpec{
+				ServiceAccountName: "test-sa",
+				NodeSelector:       map[string]string{"zone": "a"},
+				Tolerations:        []corev1.Toleration{{Key: "test"}},
+				RuntimeClassName:   &runtimeClass,
+			},
+			expected: corev1.PodSpec{
+				ServiceAccountName: "test-sa",
+				PriorityClassName:  "high",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			podSpec := tt.podSpec.DeepCopy()
+			tt.scheduling.ApplyTo(podSpec)
+			assert.Equal(t, tt.expected, *podSpec, "Should apply scheduling configuration to pod spec")
+		})
+	}
+}
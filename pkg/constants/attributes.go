@@ -21,4 +21,54 @@ const (
 	// EndpointURLAttribute is an attribute added to endpoints to denote the endpoint on the cluster that
 	// was created to route to this endpoint
 	EndpointURLAttribute = "controller.devfile.io/endpoint-url"
+	// RegistryAuthSecretAttribute is an attribute on plugin components that specifies the name of the registry auth
+	// secret to use when fetching the plugin. When applied to a DevWorkspace, the secret is used for fetching its
+	// parent. The secret must be in the DevWorkspace's namespace and have the DevWorkspaceRegistryAuthSecretLabel.
+	RegistryAuthSecretAttribute = "controller.devfile.io/registry-auth-secret"
+	// RegistryVersionAttribute is an attribute on plugin components that specifies the version of a plugin resolved by
+	// ID from a devfile registry. Exact versions, 'latest', and semver ranges (e.g. '^1.2') are supported. When applied
+	// to a DevWorkspace, the version is used for resolving its parent.
+	RegistryVersionAttribute = "controller.devfile.io/registry-version"
+	// DeployByDefaultAttribute is an attribute on kubernetes and openshift components that controls whether the
+	// component's resources are applied when the DevWorkspace starts. If true, resources are always applied. If false,
+	// resources are only applied if the component is referenced by an apply command in the preStart event. If unset,
+	// resources are applied unless the component is only referenced by apply commands outside the preStart event.
+	DeployByDefaultAttribute = "controller.devfile.io/deploy-by-default"
+	// PodAnnotationsAttribute is an attribute on container components that defines annotations (a map of string keys to
+	// string values) to add to the pod the component runs in. Annotations from all components in a pod are merged, and
+	// components may not set different values for the same annotation.
+	PodAnnotationsAttribute = "controller.devfile.io/pod-annotations"
+	// EnvVarSourcesAttribute is an attribute on container components that defines environment variables whose values are
+	// read from keys in Secrets or ConfigMaps in the DevWorkspace's namespace. It is a map from the name of an environment
+	// variable to a source with either a 'secretKeyRef' or a 'configMapKeyRef', using the same format as Kubernetes
+	// containers, e.g.
+	//
+	//   controller.devfile.io/env-var-sources:
+	//     DB_PASSWORD:
+	//       secretKeyRef:
+	//         name: db-credentials
+	//         key: password
+	//
+	// Environment variables defined this way take precedence over those with the same name in the component's env.
+	EnvVarSourcesAttribute = "controller.devfile.io/env-var-sources"
+	// PodSchedulingAttribute is an attribute on a DevWorkspace that defines how its pods are scheduled, with the fields
+	// nodeSelector, tolerations, affinity, priorityClassName, runtimeClassName and topologySpreadConstraints in the same
+	// format as a Kubernetes PodSpec, e.g.
+	//
+	//   controller.devfile.io/pod-scheduling:
+	//     nodeSelector:
+	//       node-role.example.com/dev: "true"
+	//     runtimeClassName: gvisor
+	//
+	// Fields set in the attribute override the defaults configured for the controller and the DevWorkspace's namespace,
+	// but not the fields those configurations enforce.
+	PodSchedulingAttribute = "controller.devfile.io/pod-scheduling"
+	// PodOverridesAttribute is an attribute on a DevWorkspace that overrides fields of the DevWorkspace's pods, using the
+	// same format as a Kubernetes PodTemplateSpec. Only spec.securityContext is supported; see package
+	// pkg/library/overrides.
+	PodOverridesAttribute = "controller.devfile.io/pod-overrides"
+	// ContainerOverridesAttribute is an attribute on container components that overrides fields of the component's
+	// container, using the same format as a Kubernetes container. Only securityContext is supported; see package
+	// pkg/library/overrides.
+	ContainerOverridesAttribute = "controller.devfile.io/container-overrides"
 )
@@ -69,4 +69,11 @@ const (
 	// EphemeralStorageClassType defines the 'ephemeral' storage policy: all volumes are allocated as emptyDir volumes and
 	// so do not require cleanup. When a DevWorkspace is stopped, all local changes are lost.
 	EphemeralStorageClassType = "ephemeral"
+
+	// Constants describing policies for applying changes to DevWorkspaceTemplates referenced by DevWorkspaces
+	// TemplateUpdatePolicyAuto applies changes to referenced DevWorkspaceTemplates to running DevWorkspaces immediately.
+	TemplateUpdatePolicyAuto = "auto"
+	// TemplateUpdatePolicyManual keeps running DevWorkspaces on the content of referenced DevWorkspaceTemplates they
+	// were started with; changes are applied when the DevWorkspace is restarted.
+	TemplateUpdatePolicyManual = "manual"
 )
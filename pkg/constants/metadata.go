@@ -20,6 +20,15 @@ const (
 	// DevWorkspaceCreatorLabel is the label key for storing the UID of the user who created the workspace
 	DevWorkspaceCreatorLabel = "controller.devfile.io/creator"
 
+	// DevWorkspaceCreatorUsernameAnnotation is the annotation key for storing the username of the user who created the
+	// workspace. It is set by the webhook server and is used to check that the creator may create the resources defined
+	// by kubernetes and openshift components.
+	DevWorkspaceCreatorUsernameAnnotation = "controller.devfile.io/creator-username"
+
+	// DevWorkspaceCreatorGroupsAnnotation is the annotation key for storing the groups of the user who created the
+	// workspace, as a JSON list. It is set by the webhook server along with DevWorkspaceCreatorUsernameAnnotation.
+	DevWorkspaceCreatorGroupsAnnotation = "controller.devfile.io/creator-groups"
+
 	// DevWorkspaceNameLabel is the label key to store workspace name
 	DevWorkspaceNameLabel = "controller.devfile.io/devworkspace_name"
 
@@ -43,6 +52,18 @@ const (
 	// Operator also propagates it to the devworkspace-related objects to perform authorization.
 	DevWorkspaceRestrictedAccessAnnotation = "controller.devfile.io/restricted-access"
 
+	// DevWorkspaceKubernetesComponentLabel is the label key used to store the name of the kubernetes or openshift
+	// component in a DevWorkspace that a resource was created from
+	DevWorkspaceKubernetesComponentLabel = "controller.devfile.io/kubernetes-component"
+
+	// DevWorkspaceImageComponentLabel is the label key used to store the name of the image component in a DevWorkspace
+	// that an image build job was created for
+	DevWorkspaceImageComponentLabel = "controller.devfile.io/image-component"
+
+	// DevWorkspaceDedicatedPodLabel is the label key used to identify the deployment, pods and service created for a
+	// container component with dedicatedPod set. Its value is the name of the component's deployment.
+	DevWorkspaceDedicatedPodLabel = "controller.devfile.io/dedicated-pod"
+
 	// DevWorkspaceStopReasonAnnotation marks the reason why the devworkspace was stopped; when a devworkspace is restarted
 	// this annotation will be cleared
 	DevWorkspaceStopReasonAnnotation = "controller.devfile.io/stopped-by"
@@ -73,12 +94,70 @@ const (
 	// as opposed to a service created to support the devworkspace itself.
 	DevWorkspaceDiscoverableServiceAnnotation = "controller.devfile.io/discoverable-service"
 
+	// DevWorkspaceMachineNameAnnotation marks a service created by DevWorkspaceRouting for the endpoints of a machine that
+	// does not run in the devworkspace's main pod. Its value is the name of the machine.
+	DevWorkspaceMachineNameAnnotation = "controller.devfile.io/machine-name"
+
+	// DevWorkspaceManagedAnnotationsAnnotation lists the keys of the annotations set by the DevWorkspaceRouting controller
+	// on an ingress, route or service, as a comma-separated list. It is used to remove annotations that are no longer
+	// defined for an endpoint, while preserving annotations added by others.
+	DevWorkspaceManagedAnnotationsAnnotation = "controller.devfile.io/managed-annotations"
+
 	// PullSecretLabel marks the intention that secret should be used as pull secret for devworkspaces withing namespace
 	// Only secrets with 'true' value will be mount as pull secret
 	// Should be assigned to secrets with type docker config types (kubernetes.io/dockercfg and kubernetes.io/dockerconfigjson)
 	DevWorkspacePullSecretLabel = "controller.devfile.io/devworkspace_pullsecret"
 
+	// DevWorkspaceRegistryAuthSecretLabel marks a secret as containing credentials for fetching plugins and parents
+	// from registries that require authentication. Only secrets with 'true' value are used. A secret is used for
+	// requests to the hosts listed in its DevWorkspaceRegistryHostsAnnotation, or when referenced explicitly by the
+	// RegistryAuthSecretAttribute.
+	DevWorkspaceRegistryAuthSecretLabel = "controller.devfile.io/registry-auth-secret"
+
+	// DevWorkspaceRegistryHostsAnnotation is a comma-separated list of hosts (e.g. 'registry.example.com:8443') that a
+	// registry auth secret applies to.
+	DevWorkspaceRegistryHostsAnnotation = "controller.devfile.io/registry-hosts"
+
+	// DevWorkspaceImportLockAnnotation records the sha256 digest of the content of each plugin and parent resolved from a
+	// URI or registry for a DevWorkspace, as a JSON object keyed by import path. The content itself is stored in a
+	// configmap owned by the DevWorkspace and is reused on restarts, so that changes at the source do not affect the
+	// DevWorkspace until an update is requested.
+	DevWorkspaceImportLockAnnotation = "controller.devfile.io/import-lock"
+
+	// DevWorkspaceKubernetesImportsAnnotation records the namespace/name of all DevWorkspaceTemplates resolved via
+	// kubernetes imports for a DevWorkspace, as a comma-separated list. Unlike the DevWorkspace's spec, it includes
+	// templates imported by other plugins and parents, so that changes to them can be detected.
+	DevWorkspaceKubernetesImportsAnnotation = "controller.devfile.io/kubernetes-imports"
+
+	// DevWorkspaceUpdateImportsAnnotation requests that plugins and parents are fetched again from their sources
+	// instead of using the locked content when set to "true". The annotation is removed once the lock is updated.
+	DevWorkspaceUpdateImportsAnnotation = "controller.devfile.io/update-imports"
+
+	// InternalRegistryPluginLabel marks a DevWorkspaceTemplate in the operator's namespace as a plugin in the internal
+	// registry. Only DevWorkspaceTemplates with 'true' value are used. Plugins defined this way take precedence over
+	// plugins with the same ID that are built into the controller image.
+	InternalRegistryPluginLabel = "controller.devfile.io/internal-registry-plugin"
+
+	// InternalRegistryPluginIDAnnotation defines the ID (e.g. 'redhat-developer/web-terminal/latest') of a plugin
+	// defined via InternalRegistryPluginLabel. If the annotation is not present, the DevWorkspaceTemplate's name is
+	// used as the ID.
+	InternalRegistryPluginIDAnnotation = "controller.devfile.io/plugin-id"
+
+	// DevWorkspaceTemplateUpdatePolicyAttr defines how changes to DevWorkspaceTemplates referenced by a DevWorkspace
+	// via kubernetes imports are applied while the DevWorkspace is running.
+	// Supported options:
+	// - "auto"  : Changes are applied immediately (default)
+	// - "manual": Changes are applied when the DevWorkspace is restarted
+	DevWorkspaceTemplateUpdatePolicyAttr = "controller.devfile.io/template-update-policy"
+
 	// NamespacedConfigLabelKey is a label applied to configmaps to mark them as a configuration for all DevWorkspaces in
 	// the current namespace.
 	NamespacedConfigLabelKey = "controller.devfile.io/namespaced-config"
+
+	// PodSecurityEnforceLabel is the namespace label that defines which Pod Security Standard is enforced by the Pod
+	// Security admission controller for pods in the namespace.
+	PodSecurityEnforceLabel = "pod-security.kubernetes.io/enforce"
+	// PodSecurityRestrictedLevel is the value of PodSecurityEnforceLabel for namespaces that enforce the restricted
+	// Pod Security Standard.
+	PodSecurityRestrictedLevel = "restricted"
 )
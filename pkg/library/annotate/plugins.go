@@ -22,28 +22,47 @@ import (
 // AddSourceAttributesForTemplate adds an attribute 'controller.devfile.io/imported-by=sourceID' to all elements of
 // a plugin that support attributes.
 func AddSourceAttributesForTemplate(sourceID string, template *dw.DevWorkspaceTemplateSpec) {
+	addSourceAttributes(sourceID, template, true)
+}
+
+// AddSourceAttributesForParent adds an attribute 'controller.devfile.io/imported-by=sourceID' to all elements of
+// a parent that support attributes. Elements that already have the attribute (e.g. components imported by a plugin
+// within the parent) keep their existing value.
+func AddSourceAttributesForParent(sourceID string, template *dw.DevWorkspaceTemplateSpec) {
+	addSourceAttributes(sourceID, template, false)
+}
+
+func addSourceAttributes(sourceID string, template *dw.DevWorkspaceTemplateSpec, overwrite bool) {
 	for idx, component := range template.Components {
 		if component.Attributes == nil {
 			template.Components[idx].Attributes = attributes.Attributes{}
 		}
-		template.Components[idx].Attributes.PutString(constants.PluginSourceAttribute, sourceID)
+		if overwrite || !template.Components[idx].Attributes.Exists(constants.PluginSourceAttribute) {
+			template.Components[idx].Attributes.PutString(constants.PluginSourceAttribute, sourceID)
+		}
 	}
 	for idx, command := range template.Commands {
 		if command.Attributes == nil {
 			template.Commands[idx].Attributes = attributes.Attributes{}
 		}
-		template.Commands[idx].Attributes.PutString(constants.PluginSourceAttribute, sourceID)
+		if overwrite || !template.Commands[idx].Attributes.Exists(constants.PluginSourceAttribute) {
+			template.Commands[idx].Attributes.PutString(constants.PluginSourceAttribute, sourceID)
+		}
 	}
 	for idx, project := range template.Projects {
 		if project.Attributes == nil {
 			template.Projects[idx].Attributes = attributes.Attributes{}
 		}
-		template.Projects[idx].Attributes.PutString(constants.PluginSourceAttribute, sourceID)
+		if overwrite || !template.Projects[idx].Attributes.Exists(constants.PluginSourceAttribute) {
+			template.Projects[idx].Attributes.PutString(constants.PluginSourceAttribute, sourceID)
+		}
 	}
 	for idx, project := range template.StarterProjects {
 		if project.Attributes == nil {
 			template.StarterProjects[idx].Attributes = attributes.Attributes{}
 		}
-		template.StarterProjects[idx].Attributes.PutString(constants.PluginSourceAttribute, sourceID)
+		if overwrite || !template.StarterProjects[idx].Attributes.Exists(constants.PluginSourceAttribute) {
+			template.StarterProjects[idx].Attributes.PutString(constants.PluginSourceAttribute, sourceID)
+		}
 	}
 }
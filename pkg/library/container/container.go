@@ -26,13 +26,17 @@ import (
 
 	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
 	"github.com/devfile/devworkspace-operator/apis/controller/v1alpha1"
+	"github.com/devfile/devworkspace-operator/pkg/config"
 	"github.com/devfile/devworkspace-operator/pkg/library/flatten"
 	"github.com/devfile/devworkspace-operator/pkg/library/lifecycle"
 )
 
 // GetKubeContainersFromDevfile converts container components in a DevWorkspace into Kubernetes containers.
 // If a DevWorkspace container is an init container (i.e. is bound to a preStart event), it will be returned as an
-// init container.
+// init container. Exec commands bound to the postStart and preStop events are added as lifecycle hooks on the container
+// they refer to. Container components with dedicatedPod set are not included; see GetDedicatedPods. Annotations defined
+// by the constants.PodAnnotationsAttribute attribute on container components are added to the PodAdditions. Resource
+// requirements that are not specified by container components are defaulted, and checked, according to resourcePolicy.
 //
 // This function also provisions volume mounts on containers as follows:
 // - Container component's volume mounts are provisioned with the mount path and name specified in the devworkspace
@@ -40,7 +44,7 @@ import (
 // rewritten as Volumes are added to PodAdditions, in order to support e.g. using one PVC to hold all volumes
 //
 // Note: Requires DevWorkspace to be flattened (i.e. the DevWorkspace contains no Parent or Components of type Plugin)
-func GetKubeContainersFromDevfile(workspace *dw.DevWorkspaceTemplateSpec) (*v1alpha1.PodAdditions, error) {
+func GetKubeContainersFromDevfile(workspace *dw.DevWorkspaceTemplateSpec, resourcePolicy config.ContainerResourcePolicy) (*v1alpha1.PodAdditions, error) {
 	if !flatten.DevWorkspaceIsFlattened(workspace) {
 		return nil, fmt.Errorf("devfile is not flattened")
 	}
@@ -51,20 +55,34 @@ func GetKubeContainersFromDevfile(workspace *dw.DevWorkspaceTemplateSpec) (*v1al
 		return nil, err
 	}
 
+	annotations := map[string]string{}
 	for _, component := range mainComponents {
-		if component.Container == nil {
+		if component.Container == nil || component.Container.DedicatedPod {
 			continue
 		}
-		k8sContainer, err := convertContainerToK8s(component)
+		k8sContainer, err := convertContainerToK8s(component, resourcePolicy)
 		if err != nil {
 			return nil, err
 		}
 		handleMountSources(k8sContainer, component.Container)
 		podAdditions.Containers = append(podAdditions.Containers, *k8sContainer)
+		if err := addPodAnnotations(component, annotations); err != nil {
+			return nil, err
+		}
+	}
+	if len(annotations) > 0 {
+		podAdditions.Annotations = annotations
+	}
+
+	if err := lifecycle.AddPostStartLifecycleHooks(workspace.DevWorkspaceTemplateSpecContent, podAdditions.Containers); err != nil {
+		return nil, err
+	}
+	if err := lifecycle.AddPreStopLifecycleHooks(workspace.DevWorkspaceTemplateSpecContent, podAdditions.Containers); err != nil {
+		return nil, err
 	}
 
 	for _, container := range initContainers {
-		k8sContainer, err := convertContainerToK8s(container)
+		k8sContainer, err := convertContainerToK8s(container, resourcePolicy)
 		if err != nil {
 			return nil, err
 		}
@@ -32,11 +32,14 @@ type testCase struct {
 	Name   string                       `json:"name,omitempty"`
 	Input  *dw.DevWorkspaceTemplateSpec `json:"input,omitempty"`
 	Output testOutput                   `json:"output,omitempty"`
+	// ResourcePolicy overrides the controller's container resource policy for the test case
+	ResourcePolicy *config.ContainerResourcePolicy `json:"resourcePolicy,omitempty"`
 }
 
 type testOutput struct {
-	PodAdditions *v1alpha1.PodAdditions `json:"podAdditions,omitempty"`
-	ErrRegexp    *string                `json:"errRegexp,omitempty"`
+	PodAdditions  *v1alpha1.PodAdditions `json:"podAdditions,omitempty"`
+	DedicatedPods []DedicatedPod         `json:"dedicatedPods,omitempty"`
+	ErrRegexp     *string                `json:"errRegexp,omitempty"`
 }
 
 var testControllerCfg = &corev1.ConfigMap{
@@ -86,7 +89,12 @@ func TestGetKubeContainersFromDevfile(t *testing.T) {
 		t.Run(tt.Name, func(t *testing.T) {
 			// sanity check that file is read correctly.
 			assert.True(t, len(tt.Input.Components) > 0, "Input defines no components")
-			gotPodAdditions, err := GetKubeContainersFromDevfile(tt.Input)
+			resourcePolicy := config.ControllerCfg.GetContainerResourcePolicy().WithOverrides(tt.ResourcePolicy)
+			gotPodAdditions, err := GetKubeContainersFromDevfile(tt.Input, resourcePolicy)
+			var gotDedicatedPods []DedicatedPod
+			if err == nil {
+				gotDedicatedPods, err = GetDedicatedPods(tt.Input, resourcePolicy)
+			}
 			if tt.Output.ErrRegexp != nil && assert.Error(t, err) {
 				assert.Regexp(t, *tt.Output.ErrRegexp, err.Error(), "Error message should match")
 			} else {
@@ -95,6 +103,8 @@ func TestGetKubeContainersFromDevfile(t *testing.T) {
 				}
 				assert.True(t, cmp.Equal(tt.Output.PodAdditions, gotPodAdditions),
 					"PodAdditions should match expected output: \n%s", cmp.Diff(tt.Output.PodAdditions, gotPodAdditions))
+				assert.True(t, cmp.Equal(tt.Output.DedicatedPods, gotDedicatedPods),
+					"Dedicated pods should match expected output: \n%s", cmp.Diff(tt.Output.DedicatedPods, gotDedicatedPods))
 			}
 		})
 	}
@@ -14,28 +14,37 @@ package container
 
 import (
 	"fmt"
+	"math"
+	"sort"
+	"strconv"
 
 	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
 	dwEnv "github.com/devfile/devworkspace-operator/controllers/workspace/env"
 
 	"github.com/devfile/devworkspace-operator/pkg/config"
 	"github.com/devfile/devworkspace-operator/pkg/constants"
+	"github.com/devfile/devworkspace-operator/pkg/library/overrides"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 )
 
-func convertContainerToK8s(devfileComponent dw.Component) (*v1.Container, error) {
+func convertContainerToK8s(devfileComponent dw.Component, resourcePolicy config.ContainerResourcePolicy) (*v1.Container, error) {
 	if devfileComponent.Container == nil {
 		return nil, fmt.Errorf("cannot get k8s container from non-container component")
 	}
 	devfileContainer := devfileComponent.Container
 
-	containerResources, err := devfileResourcesToContainerResources(devfileContainer)
+	containerResources, err := devfileResourcesToContainerResources(devfileContainer, resourcePolicy)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get resources for container %s: %s", devfileComponent.Name, err)
 	}
 
+	env, err := devfileEnvToContainerEnv(devfileComponent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get environment variables for container %s: %w", devfileComponent.Name, err)
+	}
+
 	container := &v1.Container{
 		Name:            devfileComponent.Name,
 		Image:           devfileContainer.Image,
@@ -43,101 +52,175 @@ func convertContainerToK8s(devfileComponent dw.Component) (*v1.Container, error)
 		Args:            devfileContainer.Args,
 		Resources:       *containerResources,
 		Ports:           devfileEndpointsToContainerPorts(devfileContainer.Endpoints),
-		Env:             devfileEnvToContainerEnv(devfileComponent.Name, devfileContainer.Env),
+		Env:             env,
 		VolumeMounts:    devfileVolumeMountsToContainerVolumeMounts(devfileContainer.VolumeMounts),
 		ImagePullPolicy: v1.PullPolicy(config.ControllerCfg.GetSidecarPullPolicy()),
 	}
 
+	containerOverrides, err := overrides.GetContainerOverrides(devfileComponent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read overrides for container %s: %w", devfileComponent.Name, err)
+	}
+	if containerOverrides != nil {
+		container.SecurityContext = containerOverrides.SecurityContext
+	}
+
 	return container, nil
 }
 
 func devfileEndpointsToContainerPorts(endpoints []dw.Endpoint) []v1.ContainerPort {
 	var containerPorts []v1.ContainerPort
-	exposedPorts := map[int]bool{}
+	type exposedPort struct {
+		port     int
+		protocol v1.Protocol
+	}
+	exposedPorts := map[exposedPort]bool{}
 	for _, endpoint := range endpoints {
-		if exposedPorts[endpoint.TargetPort] {
+		protocol := v1.ProtocolTCP
+		if endpoint.Protocol == dw.UDPEndpointProtocol {
+			protocol = v1.ProtocolUDP
+		}
+		key := exposedPort{endpoint.TargetPort, protocol}
+		if exposedPorts[key] {
 			continue
 		}
 		containerPorts = append(containerPorts, v1.ContainerPort{
 			// Use meaningless name for port since endpoint.Name does not match requirements for ContainerPort name
 			Name:          fmt.Sprintf("%d-%s", endpoint.TargetPort, endpoint.Protocol),
 			ContainerPort: int32(endpoint.TargetPort),
-			Protocol:      v1.ProtocolTCP,
+			Protocol:      protocol,
 		})
-		exposedPorts[endpoint.TargetPort] = true
+		exposedPorts[key] = true
 	}
 	return containerPorts
 }
 
-func devfileResourcesToContainerResources(devfileContainer *dw.ContainerComponent) (*v1.ResourceRequirements, error) {
+func devfileResourcesToContainerResources(devfileContainer *dw.ContainerComponent, policy config.ContainerResourcePolicy) (*v1.ResourceRequirements, error) {
 	limits := v1.ResourceList{}
 	requests := v1.ResourceList{}
 
-	memLimit := devfileContainer.MemoryLimit
-	if memLimit == "" {
-		memLimit = constants.SidecarDefaultMemoryLimit
+	var maxRatio float64
+	if policy.MaxLimitRequestRatio != "" {
+		ratio, err := strconv.ParseFloat(policy.MaxLimitRequestRatio, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse maximum limit to request ratio %q: %w", policy.MaxLimitRequestRatio, err)
+		}
+		maxRatio = ratio
 	}
-	memLimitQuantity, err := resource.ParseQuantity(memLimit)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse memory limit %q: %w", memLimit, err)
+
+	memory := resourceRequirement{
+		name:           "memory",
+		limit:          devfileContainer.MemoryLimit,
+		request:        devfileContainer.MemoryRequest,
+		defaultLimit:   policy.DefaultMemoryLimit,
+		defaultRequest: policy.DefaultMemoryRequest,
+		maxLimit:       policy.MaxMemoryLimit,
+		maxRatio:       maxRatio,
+	}
+	if err := memory.addTo(v1.ResourceMemory, limits, requests); err != nil {
+		return nil, err
 	}
-	limits[v1.ResourceMemory] = memLimitQuantity
 
-	memReq := devfileContainer.MemoryRequest
-	if memReq == "" {
-		memReq = constants.SidecarDefaultMemoryRequest
+	cpu := resourceRequirement{
+		name:           "CPU",
+		limit:          devfileContainer.CpuLimit,
+		request:        devfileContainer.CpuRequest,
+		defaultLimit:   policy.DefaultCpuLimit,
+		defaultRequest: policy.DefaultCpuRequest,
+		maxLimit:       policy.MaxCpuLimit,
+		maxRatio:       maxRatio,
 	}
-	memReqQuantity, err := resource.ParseQuantity(memReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse memory request %q: %w", memReq, err)
+	if err := cpu.addTo(v1.ResourceCPU, limits, requests); err != nil {
+		return nil, err
 	}
-	requests[v1.ResourceMemory] = memReqQuantity
 
-	if memLimitQuantity.Cmp(memReqQuantity) < 0 {
-		if devfileContainer.MemoryRequest != "" {
-			return nil, fmt.Errorf("container resources are invalid: memory limit (%s) is less than request (%s)", memLimit, devfileContainer.MemoryRequest)
-		} else {
-			// No value was supplied; the issue is that the default value is greater than supplied limit. To resolve this, reuse limit as request
-			requests[v1.ResourceMemory] = memLimitQuantity
-		}
+	return &v1.ResourceRequirements{
+		Limits:   limits,
+		Requests: requests,
+	}, nil
+}
+
+// resourceRequirement is the limit and request for a single resource of a container, as specified in the devfile,
+// along with the defaults and maximums that apply to it.
+type resourceRequirement struct {
+	name           string
+	limit          string
+	request        string
+	defaultLimit   string
+	defaultRequest string
+	maxLimit       string
+	maxRatio       float64
+}
+
+// addTo computes the limit and request for the resource and adds them to limits and requests. Values that are not
+// specified in the devfile use the defaults; if no limit is specified or defaulted, the maximum limit is used. Default
+// requests are raised if necessary to not exceed the limit or the maximum limit to request ratio, but values specified
+// in the devfile that violate these constraints result in an error.
+func (r resourceRequirement) addTo(resourceName v1.ResourceName, limits, requests v1.ResourceList) error {
+	limit := r.limit
+	if limit == "" {
+		limit = r.defaultLimit
+	}
+	if limit == "" {
+		limit = r.maxLimit
+	}
+	request := r.request
+	if request == "" {
+		request = r.defaultRequest
 	}
 
-	cpuLimit := devfileContainer.CpuLimit
-	if cpuLimit == "" {
-		cpuLimit = constants.SidecarDefaultCpuLimit
+	var limitQuantity, requestQuantity *resource.Quantity
+	if limit != "" {
+		parsed, err := resource.ParseQuantity(limit)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s limit %q: %w", r.name, limit, err)
+		}
+		limitQuantity = &parsed
 	}
-	if cpuLimit != "" {
-		cpuLimitQuantity, err := resource.ParseQuantity(cpuLimit)
+	if request != "" {
+		parsed, err := resource.ParseQuantity(request)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse cpu limit %q: %w", cpuLimit, err)
+			return fmt.Errorf("failed to parse %s request %q: %w", r.name, request, err)
 		}
-		limits[v1.ResourceCPU] = cpuLimitQuantity
+		requestQuantity = &parsed
 	}
 
-	cpuReq := devfileContainer.CpuRequest
-	if cpuReq == "" {
-		cpuReq = constants.SidecarDefaultCpuRequest
-	}
-	if cpuReq != "" {
-		cpuReqQuantity, err := resource.ParseQuantity(cpuReq)
+	if limitQuantity != nil && r.maxLimit != "" {
+		maxLimitQuantity, err := resource.ParseQuantity(r.maxLimit)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse cpu request %q: %w", cpuReq, err)
+			return fmt.Errorf("failed to parse maximum %s limit %q: %w", r.name, r.maxLimit, err)
+		}
+		if limitQuantity.Cmp(maxLimitQuantity) > 0 {
+			return fmt.Errorf("container resources are invalid: %s limit (%s) exceeds the maximum allowed (%s)", r.name, limit, r.maxLimit)
 		}
-		requests[v1.ResourceCPU] = cpuReqQuantity
 	}
 
-	if parsedCPULimit, ok := limits[v1.ResourceCPU]; ok {
-		if parsedCPUReq, ok := requests[v1.ResourceCPU]; ok {
-			if parsedCPULimit.Cmp(parsedCPUReq) < 0 {
-				return nil, fmt.Errorf("container resources are invalid: CPU limit (%s) is less than request (%s)", cpuLimit, cpuReq)
+	if limitQuantity != nil && requestQuantity != nil {
+		if limitQuantity.Cmp(*requestQuantity) < 0 {
+			if r.request != "" {
+				return fmt.Errorf("container resources are invalid: %s limit (%s) is less than request (%s)", r.name, limit, request)
 			}
+			// No value was supplied; the issue is that the default value is greater than supplied limit. To resolve this, reuse limit as request
+			requestQuantity = limitQuantity
+		}
+		if r.maxRatio > 0 && float64(limitQuantity.MilliValue()) > r.maxRatio*float64(requestQuantity.MilliValue()) {
+			if r.request != "" {
+				return fmt.Errorf("container resources are invalid: ratio of %s limit (%s) to request (%s) exceeds the maximum allowed (%s)",
+					r.name, limit, request, strconv.FormatFloat(r.maxRatio, 'f', -1, 64))
+			}
+			// Raise the default request to the smallest value that satisfies the ratio
+			minRequest := int64(math.Ceil(float64(limitQuantity.MilliValue()) / r.maxRatio))
+			requestQuantity = resource.NewMilliQuantity(minRequest, limitQuantity.Format)
 		}
 	}
 
-	return &v1.ResourceRequirements{
-		Limits:   limits,
-		Requests: requests,
-	}, nil
+	if limitQuantity != nil {
+		limits[resourceName] = *limitQuantity
+	}
+	if requestQuantity != nil {
+		requests[resourceName] = *requestQuantity
+	}
+	return nil
 }
 
 func devfileVolumeMountsToContainerVolumeMounts(devfileVolumeMounts []dw.VolumeMount) []v1.VolumeMount {
@@ -156,19 +239,97 @@ func devfileVolumeMountsToContainerVolumeMounts(devfileVolumeMounts []dw.VolumeM
 	return volumeMounts
 }
 
-func devfileEnvToContainerEnv(componentName string, devfileEnvVars []dw.EnvVar) []v1.EnvVar {
+// envVarSource is the source of an environment variable defined in the constants.EnvVarSourcesAttribute attribute
+type envVarSource struct {
+	SecretKeyRef    *v1.SecretKeySelector    `json:"secretKeyRef,omitempty"`
+	ConfigMapKeyRef *v1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+}
+
+// devfileEnvToContainerEnv converts the env of a container component to Kubernetes environment variables, including
+// variables read from Secrets and ConfigMaps according to the constants.EnvVarSourcesAttribute attribute.
+func devfileEnvToContainerEnv(devfileComponent dw.Component) ([]v1.EnvVar, error) {
 	var env = []v1.EnvVar{
 		{
 			Name:  dwEnv.DevWorkspaceComponentName,
-			Value: componentName,
+			Value: devfileComponent.Name,
 		},
 	}
 
-	for _, devfileEnv := range devfileEnvVars {
+	for _, devfileEnv := range devfileComponent.Container.Env {
 		env = append(env, v1.EnvVar{
 			Name:  devfileEnv.Name,
 			Value: devfileEnv.Value,
 		})
 	}
-	return env
+
+	if !devfileComponent.Attributes.Exists(constants.EnvVarSourcesAttribute) {
+		return env, nil
+	}
+	sources := map[string]envVarSource{}
+	if err := devfileComponent.Attributes.GetInto(constants.EnvVarSourcesAttribute, &sources); err != nil {
+		return nil, fmt.Errorf("failed to read attribute %s: %w", constants.EnvVarSourcesAttribute, err)
+	}
+	var names []string
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		valueFrom, err := getEnvVarSource(name, sources[name])
+		if err != nil {
+			return nil, err
+		}
+		envVar := v1.EnvVar{Name: name, ValueFrom: valueFrom}
+		replaced := false
+		for idx := range env {
+			if env[idx].Name == name {
+				env[idx] = envVar
+				replaced = true
+			}
+		}
+		if !replaced {
+			env = append(env, envVar)
+		}
+	}
+	return env, nil
+}
+
+func getEnvVarSource(name string, source envVarSource) (*v1.EnvVarSource, error) {
+	switch {
+	case source.SecretKeyRef != nil && source.ConfigMapKeyRef != nil:
+		return nil, fmt.Errorf("environment variable %s must define only one of secretKeyRef and configMapKeyRef", name)
+	case source.SecretKeyRef != nil:
+		if source.SecretKeyRef.Name == "" || source.SecretKeyRef.Key == "" {
+			return nil, fmt.Errorf("secretKeyRef for environment variable %s must define name and key", name)
+		}
+		return &v1.EnvVarSource{SecretKeyRef: source.SecretKeyRef}, nil
+	case source.ConfigMapKeyRef != nil:
+		if source.ConfigMapKeyRef.Name == "" || source.ConfigMapKeyRef.Key == "" {
+			return nil, fmt.Errorf("configMapKeyRef for environment variable %s must define name and key", name)
+		}
+		return &v1.EnvVarSource{ConfigMapKeyRef: source.ConfigMapKeyRef}, nil
+	default:
+		return nil, fmt.Errorf("environment variable %s must define secretKeyRef or configMapKeyRef", name)
+	}
+}
+
+// addPodAnnotations merges the annotations defined by the constants.PodAnnotationsAttribute attribute on a container
+// component into annotations. Returns an error if the component sets a different value for an annotation that is
+// already present.
+func addPodAnnotations(devfileComponent dw.Component, annotations map[string]string) error {
+	if !devfileComponent.Attributes.Exists(constants.PodAnnotationsAttribute) {
+		return nil
+	}
+	componentAnnotations := map[string]string{}
+	if err := devfileComponent.Attributes.GetInto(constants.PodAnnotationsAttribute, &componentAnnotations); err != nil {
+		return fmt.Errorf("failed to read attribute %s on component %s: %w", constants.PodAnnotationsAttribute, devfileComponent.Name, err)
+	}
+	for key, value := range componentAnnotations {
+		if existing, ok := annotations[key]; ok && existing != value {
+			return fmt.Errorf("component %s sets pod annotation %s to %q, but it is already set to %q by another component",
+				devfileComponent.Name, key, value, existing)
+		}
+		annotations[key] = value
+	}
+	return nil
 }
@@ -0,0 +1,74 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package container
+
+import (
+	"fmt"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/devfile/devworkspace-operator/pkg/config"
+	"github.com/devfile/devworkspace-operator/pkg/library/flatten"
+)
+
+// DedicatedPod is a container component that runs in its own pod, separate from the DevWorkspace's main pod
+type DedicatedPod struct {
+	// ComponentName is the name of the container component
+	ComponentName string `json:"componentName"`
+	// Container is the Kubernetes container for the component
+	Container corev1.Container `json:"container"`
+	// Annotations are annotations to add to the pod, as defined by the constants.PodAnnotationsAttribute attribute
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Endpoints are the endpoints of the component, which are exposed by a service for the pod
+	Endpoints []dw.Endpoint `json:"endpoints,omitempty"`
+}
+
+// GetDedicatedPods converts container components in a DevWorkspace that have dedicatedPod set into containers that
+// should each be run in a separate pod. As storage is only provisioned for the DevWorkspace's main pod, components
+// with dedicatedPod set cannot mount volumes or project sources. Resource requirements are handled according to
+// resourcePolicy, as in GetKubeContainersFromDevfile.
+//
+// Note: Requires DevWorkspace to be flattened (i.e. the DevWorkspace contains no Parent or Components of type Plugin)
+func GetDedicatedPods(workspace *dw.DevWorkspaceTemplateSpec, resourcePolicy config.ContainerResourcePolicy) ([]DedicatedPod, error) {
+	if !flatten.DevWorkspaceIsFlattened(workspace) {
+		return nil, fmt.Errorf("devfile is not flattened")
+	}
+	var dedicatedPods []DedicatedPod
+	for _, component := range workspace.Components {
+		if component.Container == nil || !component.Container.DedicatedPod {
+			continue
+		}
+		if len(component.Container.VolumeMounts) > 0 || HasMountSources(component.Container) {
+			return nil, fmt.Errorf("component %s has dedicatedPod set and cannot mount volumes or project sources", component.Name)
+		}
+		k8sContainer, err := convertContainerToK8s(component, resourcePolicy)
+		if err != nil {
+			return nil, err
+		}
+		annotations := map[string]string{}
+		if err := addPodAnnotations(component, annotations); err != nil {
+			return nil, err
+		}
+		if len(annotations) == 0 {
+			annotations = nil
+		}
+		dedicatedPods = append(dedicatedPods, DedicatedPod{
+			ComponentName: component.Name,
+			Container:     *k8sContainer,
+			Annotations:   annotations,
+			Endpoints:     component.Container.Endpoints,
+		})
+	}
+	return dedicatedPods, nil
+}
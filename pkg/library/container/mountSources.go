@@ -23,12 +23,11 @@ import (
 // HasMountSources evaluates whether project sources should be mounted in the given container component.
 // MountSources is by default true for non-plugin components, unless they have dedicatedPod set
 // TODO:
-// - Support dedicatedPod field
 // - Find way to track is container component comes from plugin
 func HasMountSources(devfileContainer *dw.ContainerComponent) bool {
 	var mountSources bool
 	if devfileContainer.MountSources == nil {
-		mountSources = true
+		mountSources = !devfileContainer.DedicatedPod
 	} else {
 		mountSources = *devfileContainer.MountSources
 	}
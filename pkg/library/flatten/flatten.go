@@ -16,11 +16,11 @@ import (
 	"context"
 	"fmt"
 	"net/url"
-	"path"
 	"strings"
 
 	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
 	"github.com/devfile/api/v2/pkg/utils/overriding"
+	"github.com/devfile/api/v2/pkg/validation/variables"
 	"github.com/devfile/devworkspace-operator/pkg/library/annotate"
 	registry "github.com/devfile/devworkspace-operator/pkg/library/flatten/internal_registry"
 	"github.com/devfile/devworkspace-operator/pkg/library/flatten/network"
@@ -46,22 +46,44 @@ type ResolverTools struct {
 	K8sClient          client.Client
 	InternalRegistry   registry.InternalRegistry
 	HttpClient         network.HTTPGetter
+	OCIClient          *network.OCIClient
+	// ImportLock, if set, provides content for plugins and parents previously fetched from URIs and registries, which
+	// is used instead of fetching them again.
+	ImportLock ImportLock
+	// ResolvedImports, if non-nil, is populated with the content of all plugins and parents resolved from URIs and
+	// registries, and can be used as the ImportLock for subsequent resolutions.
+	ResolvedImports ImportLock
+	// PinKubernetesImports, if true, causes DevWorkspaceTemplates referenced via kubernetes imports to be resolved from
+	// the ImportLock when they have changed on the cluster since they were locked. Otherwise, the current content of
+	// DevWorkspaceTemplates is always used.
+	PinKubernetesImports bool
+	// OutdatedKubernetesImports, if non-nil, is populated with the namespace/name of DevWorkspaceTemplates that changed
+	// on the cluster but were resolved from the ImportLock due to PinKubernetesImports.
+	OutdatedKubernetesImports map[string]bool
+	// InvalidLockedImports, if non-nil, is populated with the import paths of entries in the ImportLock whose content
+	// does not match their digest. Such imports are fetched again instead of being resolved from the ImportLock.
+	InvalidLockedImports map[string]bool
 }
 
 // ResolveDevWorkspace takes a devworkspace and returns a "resolved" version of it -- i.e. one where all plugins and parents
-// are inlined as components.
-func ResolveDevWorkspace(workspace *dw.DevWorkspaceTemplateSpec, tooling ResolverTools) (*dw.DevWorkspaceTemplateSpec, error) {
+// are inlined as components and references to variables (e.g. '{{ var }}') are replaced by their values. If the
+// DevWorkspace references variables that are not defined, warnings listing the invalid references are returned.
+func ResolveDevWorkspace(workspace *dw.DevWorkspaceTemplateSpec, tooling ResolverTools) (*dw.DevWorkspaceTemplateSpec, *variables.VariableWarning, error) {
 	// Web terminals get default container components if they do not specify one
 	if err := web_terminal.AddDefaultContainerIfNeeded(workspace); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	resolutionCtx := &resolutionContextTree{}
 	resolvedDW, err := recursiveResolve(workspace, tooling, resolutionCtx)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return resolvedDW, nil
+	warnings := variables.ValidateAndReplaceGlobalVariable(resolvedDW)
+	if hasVariableWarnings(warnings) {
+		return resolvedDW, &warnings, nil
+	}
+	return resolvedDW, nil, nil
 }
 
 func recursiveResolve(workspace *dw.DevWorkspaceTemplateSpec, tooling ResolverTools, resolveCtx *resolutionContextTree) (*dw.DevWorkspaceTemplateSpec, error) {
@@ -71,15 +93,29 @@ func recursiveResolve(workspace *dw.DevWorkspaceTemplateSpec, tooling ResolverTo
 
 	resolvedParent := &dw.DevWorkspaceTemplateSpecContent{}
 	if workspace.Parent != nil {
-		resolvedParentSpec, err := resolveParentComponent(workspace.Parent, tooling)
+		parentOpts, err := getImportOptions(workspace.Attributes)
 		if err != nil {
 			return nil, err
 		}
-		if !DevWorkspaceIsFlattened(resolvedParentSpec) {
-			// TODO: implemenent this
-			return nil, fmt.Errorf("parents containing plugins or parents are not supported")
+		parentOpts.importPath = resolveCtx.importPath("parent")
+		parentSpec, err := resolveParentComponent(workspace.Parent, parentOpts, tooling)
+		if err != nil {
+			return nil, err
+		}
+		newCtx := resolveCtx.addParent(workspace.Parent)
+		if err := newCtx.hasCycle(); err != nil {
+			return nil, err
 		}
-		annotate.AddSourceAttributesForTemplate("parent", resolvedParentSpec)
+		resolvedParentSpec, err := recursiveResolve(parentSpec, tooling, newCtx)
+		if err != nil {
+			return nil, err
+		}
+		// Overrides are applied after the parent is flattened, as they may refer to elements the parent inherits
+		// from its own parent or plugins.
+		if err := applyParentOverrides(workspace.Parent, resolvedParentSpec); err != nil {
+			return nil, err
+		}
+		annotate.AddSourceAttributesForParent("parent", resolvedParentSpec)
 		resolvedParent = &resolvedParentSpec.DevWorkspaceTemplateSpecContent
 	}
 	resolvedContent := &dw.DevWorkspaceTemplateSpecContent{}
@@ -88,7 +124,6 @@ func recursiveResolve(workspace *dw.DevWorkspaceTemplateSpec, tooling ResolverTo
 	resolvedContent.Commands = workspace.Commands
 	resolvedContent.Events = workspace.Events
 	resolvedContent.Attributes = workspace.Attributes
-	resolvedContent.Variables = workspace.Variables
 
 	var pluginSpecContents []*dw.DevWorkspaceTemplateSpecContent
 	for _, component := range workspace.Components {
@@ -96,7 +131,12 @@ func recursiveResolve(workspace *dw.DevWorkspaceTemplateSpec, tooling ResolverTo
 			// No action necessary
 			resolvedContent.Components = append(resolvedContent.Components, component)
 		} else {
-			pluginComponent, err := resolvePluginComponent(component.Name, component.Plugin, tooling)
+			pluginOpts, err := getImportOptions(component.Attributes)
+			if err != nil {
+				return nil, fmt.Errorf("invalid plugin component %s: %w", component.Name, err)
+			}
+			pluginOpts.importPath = resolveCtx.importPath(component.Name)
+			pluginComponent, err := resolvePluginComponent(component.Name, component.Plugin, pluginOpts, tooling)
 			if err != nil {
 				return nil, err
 			}
@@ -109,12 +149,17 @@ func recursiveResolve(workspace *dw.DevWorkspaceTemplateSpec, tooling ResolverTo
 			if err != nil {
 				return nil, err
 			}
+			if err := applyPluginOverrides(component.Plugin, resolvedPlugin); err != nil {
+				return nil, err
+			}
 
 			annotate.AddSourceAttributesForTemplate(component.Name, resolvedPlugin)
 			pluginSpecContents = append(pluginSpecContents, &resolvedPlugin.DevWorkspaceTemplateSpecContent)
 		}
 	}
 
+	resolvedContent.Variables = mergeVariables(workspace.Variables, resolvedParent, pluginSpecContents)
+
 	resolvedContent, err := overriding.MergeDevWorkspaceTemplateSpec(resolvedContent, resolvedParent, pluginSpecContents...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to merge DevWorkspace parents/plugins: %w", err)
@@ -125,68 +170,80 @@ func recursiveResolve(workspace *dw.DevWorkspaceTemplateSpec, tooling ResolverTo
 	}, nil
 }
 
-// resolveParentComponent resolves the parent DevWorkspaceTemplateSpec that a parent reference refers to.
-func resolveParentComponent(parent *dw.Parent, tools ResolverTools) (resolvedParent *dw.DevWorkspaceTemplateSpec, err error) {
+// resolveParentComponent resolves the parent DevWorkspaceTemplateSpec that a parent reference refers to. The returned
+// spec is not flattened and parent overrides are not applied.
+func resolveParentComponent(parent *dw.Parent, opts importOptions, tools ResolverTools) (resolvedParent *dw.DevWorkspaceTemplateSpec, err error) {
 	switch {
 	case parent.Kubernetes != nil:
 		// Search in default namespace if namespace ref is unset
 		if parent.Kubernetes.Namespace == "" {
 			parent.Kubernetes.Namespace = tools.WorkspaceNamespace
 		}
-		resolvedParent, err = resolveElementByKubernetesImport("parent", parent.Kubernetes, tools)
+		resolvedParent, err = resolveKubernetesImportWithLock("parent", parent.Kubernetes, opts, tools)
 	case parent.Uri != "":
-		resolvedParent, err = resolveElementByURI("parent", parent.Uri, tools)
+		resolvedParent, err = resolveElementByURI("parent", parent.Uri, opts, tools)
 	case parent.Id != "":
-		resolvedParent, err = resolveElementById("parent", parent.Id, parent.RegistryUrl, tools)
+		resolvedParent, err = resolveElementById("parent", parent.Id, parent.RegistryUrl, opts, tools)
 	default:
 		err = fmt.Errorf("devfile parent does not define any resources")
 	}
 	if err != nil {
 		return nil, err
 	}
-	if parent.Components != nil || parent.Commands != nil || parent.Projects != nil || parent.StarterProjects != nil {
-		overrideSpec, err := overriding.OverrideDevWorkspaceTemplateSpec(&resolvedParent.DevWorkspaceTemplateSpecContent, parent.ParentOverrides)
+	return resolvedParent, nil
+}
 
-		if err != nil {
-			return nil, err
-		}
-		resolvedParent.DevWorkspaceTemplateSpecContent = *overrideSpec
+// applyParentOverrides applies the overrides defined in a parent reference to the (flattened) parent spec.
+func applyParentOverrides(parent *dw.Parent, resolvedParent *dw.DevWorkspaceTemplateSpec) error {
+	if parent.Components == nil && parent.Commands == nil && parent.Projects == nil && parent.StarterProjects == nil {
+		return nil
 	}
-	return resolvedParent, nil
+	overrideSpec, err := overriding.OverrideDevWorkspaceTemplateSpec(&resolvedParent.DevWorkspaceTemplateSpecContent, parent.ParentOverrides)
+	if err != nil {
+		return err
+	}
+	resolvedParent.DevWorkspaceTemplateSpecContent = *overrideSpec
+	return nil
 }
 
 // resolvePluginComponent resolves the DevWorkspaceTemplateSpec that a plugin component refers to. The name parameter is
-// used to construct meaningful error messages (e.g. issue resolving plugin 'name')
+// used to construct meaningful error messages (e.g. issue resolving plugin 'name'). The returned spec is not flattened
+// and plugin overrides are not applied.
 func resolvePluginComponent(
 	name string,
 	plugin *dw.PluginComponent,
+	opts importOptions,
 	tools ResolverTools) (resolvedPlugin *dw.DevWorkspaceTemplateSpec, err error) {
 	switch {
 	case plugin.Kubernetes != nil:
-		resolvedPlugin, err = resolveElementByKubernetesImport(name, plugin.Kubernetes, tools)
+		resolvedPlugin, err = resolveKubernetesImportWithLock(name, plugin.Kubernetes, opts, tools)
 	case plugin.Uri != "":
-		resolvedPlugin, err = resolveElementByURI(name, plugin.Uri, tools)
+		resolvedPlugin, err = resolveElementByURI(name, plugin.Uri, opts, tools)
 	case plugin.Id != "":
-		resolvedPlugin, err = resolveElementById(name, plugin.Id, plugin.RegistryUrl, tools)
+		resolvedPlugin, err = resolveElementById(name, plugin.Id, plugin.RegistryUrl, opts, tools)
 	default:
 		err = fmt.Errorf("plugin %s does not define any resources", name)
 	}
 	if err != nil {
 		return nil, err
 	}
+	return resolvedPlugin, nil
+}
 
-	if plugin.Components != nil || plugin.Commands != nil {
-		overrideSpec, err := overriding.OverrideDevWorkspaceTemplateSpec(&resolvedPlugin.DevWorkspaceTemplateSpecContent, dw.PluginOverrides{
-			Components: plugin.Components,
-			Commands:   plugin.Commands,
-		})
-
-		if err != nil {
-			return nil, err
-		}
-		resolvedPlugin.DevWorkspaceTemplateSpecContent = *overrideSpec
+// applyPluginOverrides applies the overrides defined in a plugin component to the (flattened) plugin spec.
+func applyPluginOverrides(plugin *dw.PluginComponent, resolvedPlugin *dw.DevWorkspaceTemplateSpec) error {
+	if plugin.Components == nil && plugin.Commands == nil {
+		return nil
 	}
-	return resolvedPlugin, nil
+	overrideSpec, err := overriding.OverrideDevWorkspaceTemplateSpec(&resolvedPlugin.DevWorkspaceTemplateSpecContent, dw.PluginOverrides{
+		Components: plugin.Components,
+		Commands:   plugin.Commands,
+	})
+	if err != nil {
+		return err
+	}
+	resolvedPlugin.DevWorkspaceTemplateSpecContent = *overrideSpec
+	return nil
 }
 
 // resolveElementByKubernetesImport resolves a plugin specified by a Kubernetes reference.
@@ -236,6 +293,7 @@ func resolveElementById(
 	name string,
 	id string,
 	registryUrl string,
+	opts importOptions,
 	tools ResolverTools) (resolvedPlugin *dw.DevWorkspaceTemplateSpec, err error) {
 
 	// Check internal registry for plugins that do not specify a registry
@@ -258,17 +316,26 @@ func resolveElementById(
 		return nil, fmt.Errorf("cannot resolve resources by id: no HTTP client provided")
 	}
 
-	pluginURL, err := url.Parse(registryUrl)
+	parsedRegistryURL, err := url.Parse(registryUrl)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse registry URL for component %s: %w", name, err)
 	}
-	pluginURL.Path = path.Join(pluginURL.Path, id)
 
-	dwt, err := network.FetchDevWorkspaceTemplate(pluginURL.String(), tools.HttpClient)
-	if err != nil {
-		return nil, fmt.Errorf("failed to resolve component %s from registry %s: %w", name, registryUrl, err)
+	source := fmt.Sprintf("%s#%s", registryUrl, id)
+	if opts.version != "" {
+		source = fmt.Sprintf("%s@%s", source, opts.version)
 	}
-	return dwt, nil
+	return resolveWithLock(opts.importPath, source, tools, func() (*dw.DevWorkspaceTemplateSpec, []byte, error) {
+		httpClient, err := getHttpClientForLocation(parsedRegistryURL.String(), opts.authSecret, tools)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to resolve component %s from registry %s: %w", name, registryUrl, err)
+		}
+		dwt, raw, err := network.FetchDevWorkspaceTemplateFromRegistry(parsedRegistryURL, id, opts.version, httpClient)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to resolve component %s from registry %s: %w", name, registryUrl, err)
+		}
+		return dwt, raw, nil
+	})
 }
 
 // resolveElementByURI resolves a plugin defined by URI. The name parameter is used to construct meaningful
@@ -276,17 +343,28 @@ func resolveElementById(
 func resolveElementByURI(
 	name string,
 	uri string,
+	opts importOptions,
 	tools ResolverTools) (resolvedPlugin *dw.DevWorkspaceTemplateSpec, err error) {
 
-	if tools.HttpClient == nil {
-		return nil, fmt.Errorf("cannot resolve resources by id: no HTTP client provided")
-	}
+	return resolveWithLock(opts.importPath, uri, tools, func() (*dw.DevWorkspaceTemplateSpec, []byte, error) {
+		if network.IsOCIReference(uri) {
+			return resolveElementByOCIReference(name, uri, opts, tools)
+		}
 
-	dwt, err := network.FetchDevWorkspaceTemplate(uri, tools.HttpClient)
-	if err != nil {
-		return nil, fmt.Errorf("failed to resolve component %s by URI: %w", name, err)
-	}
-	return dwt, nil
+		if tools.HttpClient == nil {
+			return nil, nil, fmt.Errorf("cannot resolve resources by id: no HTTP client provided")
+		}
+
+		httpClient, err := getHttpClientForLocation(uri, opts.authSecret, tools)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to resolve component %s by URI: %w", name, err)
+		}
+		dwt, raw, err := network.FetchDevWorkspaceTemplate(uri, httpClient)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to resolve component %s by URI: %w", name, err)
+		}
+		return dwt, raw, nil
+	})
 }
 
 // canImportDW returns true if a DevWorkspace in dwNamespace is allowed to reference the provided DevWorkspaceTemplate
@@ -37,7 +37,7 @@ func TestResolveDevWorkspaceKubernetesReference(t *testing.T) {
 				WorkspaceNamespace: "test-ignored",
 				K8sClient:          testClient,
 			}
-			outputWorkspace, err := ResolveDevWorkspace(tt.Input.DevWorkspace, testResolverTools)
+			outputWorkspace, _, err := ResolveDevWorkspace(tt.Input.DevWorkspace, testResolverTools)
 			if tt.Output.ErrRegexp != nil && assert.Error(t, err) {
 				assert.Regexp(t, *tt.Output.ErrRegexp, err.Error(), "Error message should match")
 			} else {
@@ -67,7 +67,7 @@ func TestResolveDevWorkspaceInternalRegistry(t *testing.T) {
 				Context:          context.Background(),
 				InternalRegistry: testRegistry,
 			}
-			outputWorkspace, err := ResolveDevWorkspace(tt.Input.DevWorkspace, testResolverTools)
+			outputWorkspace, _, err := ResolveDevWorkspace(tt.Input.DevWorkspace, testResolverTools)
 			if tt.Output.ErrRegexp != nil && assert.Error(t, err) {
 				assert.Regexp(t, *tt.Output.ErrRegexp, err.Error(), "Error message should match")
 			} else {
@@ -98,7 +98,7 @@ func TestResolveDevWorkspacePluginRegistry(t *testing.T) {
 				Context:    context.Background(),
 				HttpClient: testHttpGetter,
 			}
-			outputWorkspace, err := ResolveDevWorkspace(tt.Input.DevWorkspace, testResolverTools)
+			outputWorkspace, _, err := ResolveDevWorkspace(tt.Input.DevWorkspace, testResolverTools)
 			if tt.Output.ErrRegexp != nil && assert.Error(t, err) {
 				assert.Regexp(t, *tt.Output.ErrRegexp, err.Error(), "Error message should match")
 			} else {
@@ -129,7 +129,7 @@ func TestResolveDevWorkspacePluginURI(t *testing.T) {
 				Context:    context.Background(),
 				HttpClient: testHttpGetter,
 			}
-			outputWorkspace, err := ResolveDevWorkspace(tt.Input.DevWorkspace, testResolverTools)
+			outputWorkspace, _, err := ResolveDevWorkspace(tt.Input.DevWorkspace, testResolverTools)
 			if tt.Output.ErrRegexp != nil && assert.Error(t, err) {
 				assert.Regexp(t, *tt.Output.ErrRegexp, err.Error(), "Error message should match")
 			} else {
@@ -165,7 +165,7 @@ func TestResolveDevWorkspaceParents(t *testing.T) {
 				K8sClient:          testK8sClient,
 				HttpClient:         testHttpGetter,
 			}
-			outputWorkspace, err := ResolveDevWorkspace(tt.Input.DevWorkspace, testResolverTools)
+			outputWorkspace, _, err := ResolveDevWorkspace(tt.Input.DevWorkspace, testResolverTools)
 			if tt.Output.ErrRegexp != nil && assert.Error(t, err) {
 				assert.Regexp(t, *tt.Output.ErrRegexp, err.Error(), "Error message should match")
 			} else {
@@ -203,7 +203,7 @@ func TestResolveDevWorkspaceMissingDefaults(t *testing.T) {
 				K8sClient:  testK8sClient,
 				HttpClient: testHttpGetter,
 			}
-			outputWorkspace, err := ResolveDevWorkspace(tt.Input.DevWorkspace, testResolverTools)
+			outputWorkspace, _, err := ResolveDevWorkspace(tt.Input.DevWorkspace, testResolverTools)
 			if tt.Output.ErrRegexp != nil && assert.Error(t, err) {
 				assert.Regexp(t, *tt.Output.ErrRegexp, err.Error(), "Error message should match")
 			} else {
@@ -239,7 +239,7 @@ func TestResolveDevWorkspaceAnnotations(t *testing.T) {
 				HttpClient:         testHttpGetter,
 				WorkspaceNamespace: "default-namespace",
 			}
-			outputWorkspace, err := ResolveDevWorkspace(tt.Input.DevWorkspace, testResolverTools)
+			outputWorkspace, _, err := ResolveDevWorkspace(tt.Input.DevWorkspace, testResolverTools)
 			if tt.Output.ErrRegexp != nil && assert.Error(t, err) {
 				assert.Regexp(t, *tt.Output.ErrRegexp, err.Error(), "Error message should match")
 			} else {
@@ -275,7 +275,7 @@ func TestResolveDevWorkspaceTemplateNamespaceRestriction(t *testing.T) {
 				HttpClient:         testHttpGetter,
 				WorkspaceNamespace: "test-namespace",
 			}
-			outputWorkspace, err := ResolveDevWorkspace(tt.Input.DevWorkspace, testResolverTools)
+			outputWorkspace, _, err := ResolveDevWorkspace(tt.Input.DevWorkspace, testResolverTools)
 			if tt.Output.ErrRegexp != nil && assert.Error(t, err) {
 				assert.Regexp(t, *tt.Output.ErrRegexp, err.Error(), "Error message should match")
 			} else {
@@ -289,3 +289,47 @@ func TestResolveDevWorkspaceTemplateNamespaceRestriction(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveDevWorkspaceVariables(t *testing.T) {
+	tests := testutil.LoadAllTestsOrPanic(t, "testdata/variables")
+	testutil.SetupControllerCfg()
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			// sanity check: input defines components
+			assert.True(t, len(tt.Input.DevWorkspace.Components) > 0, "Test case defines workspace with no components")
+			testHttpGetter := &testutil.FakeHTTPGetter{
+				DevfileResources:      tt.Input.DevfileResources,
+				DevWorkspaceResources: tt.Input.DevWorkspaceResources,
+				Errors:                tt.Input.Errors,
+			}
+			testK8sClient := &testutil.FakeK8sClient{
+				DevWorkspaceResources: tt.Input.DevWorkspaceResources,
+				Errors:                tt.Input.Errors,
+			}
+			testResolverTools := ResolverTools{
+				Context:            context.Background(),
+				WorkspaceNamespace: "test-ignored",
+				K8sClient:          testK8sClient,
+				HttpClient:         testHttpGetter,
+			}
+			outputWorkspace, warnings, err := ResolveDevWorkspace(tt.Input.DevWorkspace, testResolverTools)
+			if tt.Output.ErrRegexp != nil && assert.Error(t, err) {
+				assert.Regexp(t, *tt.Output.ErrRegexp, err.Error(), "Error message should match")
+				return
+			}
+			if !assert.NoError(t, err, "Should not return error") {
+				return
+			}
+			if tt.Output.WarningRegexp != nil {
+				if assert.NotNil(t, warnings, "Should return warnings") {
+					assert.Regexp(t, *tt.Output.WarningRegexp, FormatVariablesWarning(warnings), "Warning message should match")
+				}
+			} else {
+				assert.Nil(t, warnings, "Should not return warnings")
+			}
+			assert.Truef(t, cmp.Equal(tt.Output.DevWorkspace, outputWorkspace, testutil.WorkspaceTemplateDiffOpts),
+				"DevWorkspace should match expected output:\n%s",
+				cmp.Diff(tt.Output.DevWorkspace, outputWorkspace, testutil.WorkspaceTemplateDiffOpts))
+		})
+	}
+}
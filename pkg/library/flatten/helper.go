@@ -17,6 +17,9 @@ import (
 	"reflect"
 
 	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	"github.com/devfile/api/v2/pkg/attributes"
+
+	"github.com/devfile/devworkspace-operator/pkg/constants"
 )
 
 // resolutionContextTree is a recursive structure representing information about the devworkspace that is
@@ -25,6 +28,7 @@ type resolutionContextTree struct {
 	componentName   string
 	importReference dw.ImportReference
 	plugins         []*resolutionContextTree
+	devfileParent   *resolutionContextTree
 	parentNode      *resolutionContextTree
 }
 
@@ -38,6 +42,26 @@ func (t *resolutionContextTree) addPlugin(name string, plugin *dw.PluginComponen
 	return newNode
 }
 
+func (t *resolutionContextTree) addParent(parent *dw.Parent) *resolutionContextTree {
+	newNode := &resolutionContextTree{
+		componentName:   "parent",
+		importReference: parent.ImportReference,
+		parentNode:      t,
+	}
+	t.devfileParent = newNode
+	return newNode
+}
+
+// importPath returns the path of imports leading to an element named name that is imported by this node, e.g.
+// 'parent/my-plugin' for a plugin imported by the DevWorkspace's parent.
+func (t *resolutionContextTree) importPath(name string) string {
+	path := name
+	for node := t; node.parentNode != nil; node = node.parentNode {
+		path = node.componentName + "/" + path
+	}
+	return path
+}
+
 func (t *resolutionContextTree) hasCycle() error {
 	var seenRefs []dw.ImportReference
 	currNode := t
@@ -64,3 +88,32 @@ func formatImportCycle(end *resolutionContextTree) string {
 	}
 	return cycle
 }
+
+// importOptions are settings for fetching a plugin or parent, read from attributes on the plugin component or on the
+// DevWorkspace that defines the parent.
+type importOptions struct {
+	// authSecret is the name of the registry auth secret to use, if any
+	authSecret string
+	// version is the version constraint used when resolving a plugin or parent by ID from a devfile registry
+	version string
+	// importPath identifies the plugin or parent in an ImportLock
+	importPath string
+}
+
+func getImportOptions(attrs attributes.Attributes) (importOptions, error) {
+	opts := importOptions{}
+	var err error
+	if attrs.Exists(constants.RegistryAuthSecretAttribute) {
+		opts.authSecret = attrs.GetString(constants.RegistryAuthSecretAttribute, &err)
+		if err != nil {
+			return importOptions{}, fmt.Errorf("failed to read attribute %s: %w", constants.RegistryAuthSecretAttribute, err)
+		}
+	}
+	if attrs.Exists(constants.RegistryVersionAttribute) {
+		opts.version = attrs.GetString(constants.RegistryVersionAttribute, &err)
+		if err != nil {
+			return importOptions{}, fmt.Errorf("failed to read attribute %s: %w", constants.RegistryVersionAttribute, err)
+		}
+	}
+	return opts, nil
+}
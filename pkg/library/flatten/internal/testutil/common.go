@@ -81,6 +81,8 @@ type TestPluginError struct {
 type TestOutput struct {
 	DevWorkspace *dw.DevWorkspaceTemplateSpec `json:"devworkspace,omitempty"`
 	ErrRegexp    *string                      `json:"errRegexp,omitempty"`
+	// WarningRegexp, if set, must match the formatted warnings for undefined variables
+	WarningRegexp *string `json:"warningRegexp,omitempty"`
 }
 
 func LoadTestCaseOrPanic(t *testing.T, testFilepath string) TestCase {
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
 	"sigs.k8s.io/yaml"
@@ -72,5 +73,12 @@ func (reg *FakeHTTPGetter) Get(location string) (*http.Response, error) {
 		}
 		return nil, errors.New(err.Message)
 	}
+	if strings.HasSuffix(location, "/index") {
+		// Test registries do not serve an index unless one is defined explicitly
+		return &http.Response{
+			StatusCode: http.StatusNotFound,
+			Body:       &fakeRespBody{bytes.NewBuffer([]byte{})},
+		}, nil
+	}
 	return nil, fmt.Errorf("test does not define entry for plugin at URL %s", location)
 }
@@ -15,6 +15,7 @@ package testutil
 import (
 	"errors"
 	"fmt"
+	"sort"
 
 	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
 )
@@ -39,3 +40,12 @@ func (reg *FakeInternalRegistry) ReadPluginFromInternalRegistry(pluginID string)
 	}
 	return nil, fmt.Errorf("test does not define entry for plugin %s", pluginID)
 }
+
+func (reg *FakeInternalRegistry) ListPlugins() ([]string, error) {
+	var pluginIDs []string
+	for pluginID := range reg.Plugins {
+		pluginIDs = append(pluginIDs, pluginID)
+	}
+	sort.Strings(pluginIDs)
+	return pluginIDs, nil
+}
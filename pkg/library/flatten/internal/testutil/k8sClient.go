@@ -18,6 +18,7 @@ import (
 	"fmt"
 
 	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -48,3 +49,11 @@ func (client *FakeK8sClient) Get(_ context.Context, namespacedName client.Object
 	}
 	return fmt.Errorf("test does not define an entry for plugin %s", namespacedName.Name)
 }
+
+func (client *FakeK8sClient) List(_ context.Context, list runtime.Object, _ ...client.ListOption) error {
+	if _, ok := list.(*corev1.SecretList); !ok {
+		return fmt.Errorf("called List() in fake client with non-SecretList")
+	}
+	// Tests do not define registry auth secrets
+	return nil
+}
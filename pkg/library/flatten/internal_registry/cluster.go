@@ -0,0 +1,120 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+)
+
+// ClusterInternalRegistry is an internal registry backed by DevWorkspaceTemplates in a namespace (normally the
+// operator's namespace) that have the InternalRegistryPluginLabel. Plugins not defined on the cluster are read
+// from the Fallback registry, if set, which allows admins to add or override plugins built into the controller image.
+type ClusterInternalRegistry struct {
+	Client    client.Client
+	Namespace string
+	Fallback  InternalRegistry
+}
+
+var _ InternalRegistry = (*ClusterInternalRegistry)(nil)
+
+// IsInInternalRegistry checks if pluginID is defined on the cluster or in the fallback registry
+func (reg *ClusterInternalRegistry) IsInInternalRegistry(pluginID string) bool {
+	plugins, err := reg.getClusterPlugins()
+	if err != nil {
+		log.Error(err, "Failed to read plugins from cluster internal registry")
+	} else if _, ok := plugins[pluginID]; ok {
+		return true
+	}
+	return reg.Fallback != nil && reg.Fallback.IsInInternalRegistry(pluginID)
+}
+
+// ReadPluginFromInternalRegistry returns the plugin with ID pluginID. Plugins defined on the cluster take precedence
+// over plugins in the fallback registry.
+func (reg *ClusterInternalRegistry) ReadPluginFromInternalRegistry(pluginID string) (*dw.DevWorkspaceTemplate, error) {
+	plugins, err := reg.getClusterPlugins()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugins from cluster: %w", err)
+	}
+	if plugin, ok := plugins[pluginID]; ok {
+		return plugin.DeepCopy(), nil
+	}
+	if reg.Fallback == nil {
+		return nil, fmt.Errorf("plugin %s not found in internal registry", pluginID)
+	}
+	return reg.Fallback.ReadPluginFromInternalRegistry(pluginID)
+}
+
+// ListPlugins returns the IDs of all plugins defined on the cluster or in the fallback registry
+func (reg *ClusterInternalRegistry) ListPlugins() ([]string, error) {
+	plugins, err := reg.getClusterPlugins()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugins from cluster: %w", err)
+	}
+	pluginIDs := map[string]bool{}
+	for pluginID := range plugins {
+		pluginIDs[pluginID] = true
+	}
+	if reg.Fallback != nil {
+		fallbackIDs, err := reg.Fallback.ListPlugins()
+		if err != nil {
+			return nil, err
+		}
+		for _, pluginID := range fallbackIDs {
+			pluginIDs[pluginID] = true
+		}
+	}
+	var sortedIDs []string
+	for pluginID := range pluginIDs {
+		sortedIDs = append(sortedIDs, pluginID)
+	}
+	sort.Strings(sortedIDs)
+	return sortedIDs, nil
+}
+
+// getClusterPlugins returns the DevWorkspaceTemplates labelled as internal registry plugins, keyed by plugin ID. If
+// multiple DevWorkspaceTemplates define the same ID, the first by name is used.
+func (reg *ClusterInternalRegistry) getClusterPlugins() (map[string]*dw.DevWorkspaceTemplate, error) {
+	plugins := map[string]*dw.DevWorkspaceTemplate{}
+	if reg.Client == nil || reg.Namespace == "" {
+		return plugins, nil
+	}
+	templates := &dw.DevWorkspaceTemplateList{}
+	err := reg.Client.List(context.TODO(), templates,
+		client.InNamespace(reg.Namespace),
+		client.MatchingLabels{constants.InternalRegistryPluginLabel: "true"})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(templates.Items, func(i, j int) bool {
+		return templates.Items[i].Name < templates.Items[j].Name
+	})
+	for idx, template := range templates.Items {
+		pluginID := template.Name
+		if id, ok := template.Annotations[constants.InternalRegistryPluginIDAnnotation]; ok && id != "" {
+			pluginID = id
+		}
+		if _, exists := plugins[pluginID]; exists {
+			log.Info(fmt.Sprintf("Ignoring DevWorkspaceTemplate %s: plugin %s is already defined", template.Name, pluginID))
+			continue
+		}
+		plugins[pluginID] = &templates.Items[idx]
+	}
+	return plugins, nil
+}
@@ -0,0 +1,153 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package registry
+
+import (
+	"fmt"
+	"testing"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(dw.AddToScheme(scheme))
+}
+
+type fakeRegistry struct {
+	plugins map[string]*dw.DevWorkspaceTemplate
+}
+
+func (reg *fakeRegistry) IsInInternalRegistry(pluginID string) bool {
+	_, ok := reg.plugins[pluginID]
+	return ok
+}
+
+func (reg *fakeRegistry) ReadPluginFromInternalRegistry(pluginID string) (*dw.DevWorkspaceTemplate, error) {
+	if plugin, ok := reg.plugins[pluginID]; ok {
+		return plugin, nil
+	}
+	return nil, fmt.Errorf("plugin %s not found", pluginID)
+}
+
+func (reg *fakeRegistry) ListPlugins() ([]string, error) {
+	var pluginIDs []string
+	for pluginID := range reg.plugins {
+		pluginIDs = append(pluginIDs, pluginID)
+	}
+	return pluginIDs, nil
+}
+
+func testTemplate(name, namespace, pluginID string, labelled bool) *dw.DevWorkspaceTemplate {
+	template := &dw.DevWorkspaceTemplate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Labels:      map[string]string{},
+			Annotations: map[string]string{},
+		},
+		Spec: dw.DevWorkspaceTemplateSpec{
+			DevWorkspaceTemplateSpecContent: dw.DevWorkspaceTemplateSpecContent{
+				Components: []dw.Component{{Name: name}},
+			},
+		},
+	}
+	if labelled {
+		template.Labels[constants.InternalRegistryPluginLabel] = "true"
+	}
+	if pluginID != "" {
+		template.Annotations[constants.InternalRegistryPluginIDAnnotation] = pluginID
+	}
+	return template
+}
+
+func TestClusterInternalRegistry(t *testing.T) {
+	fallback := &fakeRegistry{
+		plugins: map[string]*dw.DevWorkspaceTemplate{
+			"builtin/plugin/latest":  testTemplate("builtin", "", "", false),
+			"override/plugin/latest": testTemplate("builtin-override", "", "", false),
+		},
+	}
+	reg := &ClusterInternalRegistry{
+		Client: fake.NewFakeClientWithScheme(scheme,
+			testTemplate("cluster-plugin", "operator-ns", "", true),
+			testTemplate("override", "operator-ns", "override/plugin/latest", true),
+			testTemplate("override-duplicate", "operator-ns", "override/plugin/latest", true),
+			testTemplate("unlabelled", "operator-ns", "unlabelled/plugin/latest", false),
+			testTemplate("other-namespace", "other-ns", "other/plugin/latest", true),
+		),
+		Namespace: "operator-ns",
+		Fallback:  fallback,
+	}
+
+	tests := []struct {
+		name              string
+		pluginID          string
+		expectedComponent string
+	}{
+		{
+			name:              "Reads plugin from cluster using template name as ID",
+			pluginID:          "cluster-plugin",
+			expectedComponent: "cluster-plugin",
+		},
+		{
+			name:              "Plugins on cluster take precedence over fallback",
+			pluginID:          "override/plugin/latest",
+			expectedComponent: "override",
+		},
+		{
+			name:              "Reads plugin from fallback when not on cluster",
+			pluginID:          "builtin/plugin/latest",
+			expectedComponent: "builtin",
+		},
+		{
+			name:     "Ignores templates without label",
+			pluginID: "unlabelled/plugin/latest",
+		},
+		{
+			name:     "Ignores templates outside of namespace",
+			pluginID: "other/plugin/latest",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.expectedComponent == "" {
+				assert.False(t, reg.IsInInternalRegistry(tt.pluginID), "Plugin should not be in registry")
+				_, err := reg.ReadPluginFromInternalRegistry(tt.pluginID)
+				assert.Error(t, err, "Should return error for plugin not in registry")
+				return
+			}
+			assert.True(t, reg.IsInInternalRegistry(tt.pluginID), "Plugin should be in registry")
+			plugin, err := reg.ReadPluginFromInternalRegistry(tt.pluginID)
+			if assert.NoError(t, err, "Should read plugin") {
+				assert.Equal(t, tt.expectedComponent, plugin.Spec.Components[0].Name, "Should read expected plugin")
+			}
+		})
+	}
+
+	t.Run("Lists plugins from cluster and fallback", func(t *testing.T) {
+		pluginIDs, err := reg.ListPlugins()
+		if assert.NoError(t, err, "Should list plugins") {
+			assert.Equal(t, []string{"builtin/plugin/latest", "cluster-plugin", "override/plugin/latest"}, pluginIDs,
+				"Should list plugins from cluster and fallback")
+		}
+	})
+}
@@ -0,0 +1,51 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package registry
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// PluginListPath is the path on the metrics server where the list of internal registry plugins is served
+const PluginListPath = "/internal-registry/plugins"
+
+// PluginList is the response served by the plugin list handler
+type PluginList struct {
+	Plugins []string `json:"plugins"`
+}
+
+// NewPluginListHandler returns an http.Handler that serves the IDs of all plugins available in
+// the internal registry as JSON, for use by clients that need to present available plugins.
+func NewPluginListHandler(reg InternalRegistry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		pluginIDs, err := reg.ListPlugins()
+		if err != nil {
+			log.Error(err, "Failed to list internal registry plugins")
+			http.Error(w, "failed to list plugins", http.StatusInternalServerError)
+			return
+		}
+		if pluginIDs == nil {
+			pluginIDs = []string{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(PluginList{Plugins: pluginIDs}); err != nil {
+			log.Error(err, "Failed to write internal registry plugin list")
+		}
+	})
+}
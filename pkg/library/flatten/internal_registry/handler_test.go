@@ -0,0 +1,73 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package registry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPluginListHandler(t *testing.T) {
+	tests := []struct {
+		name            string
+		plugins         []string
+		method          string
+		expectedStatus  int
+		expectedPlugins []string
+	}{
+		{
+			name:            "Lists plugins",
+			plugins:         []string{"test-plugin"},
+			method:          http.MethodGet,
+			expectedStatus:  http.StatusOK,
+			expectedPlugins: []string{"test-plugin"},
+		},
+		{
+			name:            "Returns empty list when registry is empty",
+			method:          http.MethodGet,
+			expectedStatus:  http.StatusOK,
+			expectedPlugins: []string{},
+		},
+		{
+			name:           "Rejects methods other than GET",
+			plugins:        []string{"test-plugin"},
+			method:         http.MethodPost,
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := &fakeRegistry{plugins: map[string]*dw.DevWorkspaceTemplate{}}
+			for _, pluginID := range tt.plugins {
+				reg.plugins[pluginID] = &dw.DevWorkspaceTemplate{}
+			}
+			recorder := httptest.NewRecorder()
+			NewPluginListHandler(reg).ServeHTTP(recorder, httptest.NewRequest(tt.method, PluginListPath, nil))
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code, "Should return expected status")
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			list := PluginList{}
+			if assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &list), "Should return valid JSON") {
+				assert.Equal(t, tt.expectedPlugins, list.Plugins, "Should list plugins in registry")
+			}
+		})
+	}
+}
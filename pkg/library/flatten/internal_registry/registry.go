@@ -17,6 +17,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 
 	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
 	"github.com/devfile/devworkspace-operator/internal/images"
@@ -36,6 +37,8 @@ var log = logf.Log.WithName("registry")
 type InternalRegistry interface {
 	IsInInternalRegistry(pluginID string) bool
 	ReadPluginFromInternalRegistry(pluginID string) (*dw.DevWorkspaceTemplate, error)
+	// ListPlugins returns the IDs of all plugins available in the internal registry, in sorted order
+	ListPlugins() ([]string, error)
 }
 
 type InternalRegistryImpl struct{}
@@ -67,6 +70,33 @@ func (_ *InternalRegistryImpl) ReadPluginFromInternalRegistry(pluginID string) (
 	return resolvedPlugin, nil
 }
 
+// ListPlugins returns the IDs of all plugins in the internal registry directory
+func (_ *InternalRegistryImpl) ListPlugins() ([]string, error) {
+	var pluginIDs []string
+	err := filepath.Walk(RegistryDirectory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || info.Name() != "devworkspacetemplate.yaml" {
+			return nil
+		}
+		pluginID, err := filepath.Rel(RegistryDirectory, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		pluginIDs = append(pluginIDs, filepath.ToSlash(pluginID))
+		return nil
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	sort.Strings(pluginIDs)
+	return pluginIDs, nil
+}
+
 func getPluginPath(pluginID string) string {
 	return filepath.Join(RegistryDirectory, pluginID, "devworkspacetemplate.yaml")
 }
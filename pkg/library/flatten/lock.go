@@ -0,0 +1,188 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package flatten
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/devfile/devworkspace-operator/pkg/library/flatten/network"
+)
+
+// ImportLock records the content of plugins and parents fetched from URIs and registries, keyed by import path.
+// The import path of a plugin or parent imported directly by a DevWorkspace is the value of the
+// controller.devfile.io/imported-by attribute on the elements it contributes (i.e. the plugin component's name, or
+// 'parent'); nested imports are keyed by the path of imports leading to them, e.g. 'parent/my-plugin'.
+type ImportLock map[string]LockedImport
+
+// LockedImport is the content of a plugin or parent at the time it was first resolved.
+type LockedImport struct {
+	// Source identifies the reference the content was fetched from, e.g. the plugin's URI. If the reference changes,
+	// the locked content is not used.
+	Source string `json:"source"`
+	// Digest is the sha256 digest of Raw, in the form sha256:<hex>
+	Digest string `json:"digest"`
+	// Raw is the devfile, DevWorkspace, or DevWorkspaceTemplate as fetched from Source. For DevWorkspaceTemplates
+	// referenced via kubernetes imports, it is the serialized DevWorkspaceTemplate.
+	Raw string `json:"raw"`
+}
+
+// kubernetesImportSourcePrefix prefixes the Source of DevWorkspaceTemplates resolved via kubernetes imports, which is
+// followed by the template's namespace/name.
+const kubernetesImportSourcePrefix = "kubernetes:"
+
+// KubernetesImports returns the sorted namespace/name of all DevWorkspaceTemplates resolved via kubernetes imports,
+// including templates imported by other plugins and parents.
+func (l ImportLock) KubernetesImports() []string {
+	templates := map[string]bool{}
+	for _, locked := range l {
+		if strings.HasPrefix(locked.Source, kubernetesImportSourcePrefix) {
+			templates[strings.TrimPrefix(locked.Source, kubernetesImportSourcePrefix)] = true
+		}
+	}
+	var refs []string
+	for template := range templates {
+		refs = append(refs, template)
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// Digests returns the digest of each locked import, keyed by import path.
+func (l ImportLock) Digests() map[string]string {
+	digests := map[string]string{}
+	for importPath, locked := range l {
+		digests[importPath] = locked.Digest
+	}
+	return digests
+}
+
+// fetchFunc fetches a plugin or parent, returning its template spec and the content it was parsed from.
+type fetchFunc func() (spec *dw.DevWorkspaceTemplateSpec, raw []byte, err error)
+
+// resolveWithLock returns the locked content for importPath if it was fetched from source, and otherwise calls fetch.
+// The content used is recorded in tools.ResolvedImports, if set.
+//
+// If the locked content does not match its digest, the content is fetched again and importPath is recorded in
+// tools.InvalidLockedImports. The invalid entry is kept in tools.ResolvedImports so that the problem is reported until
+// the user requests updating imports.
+func resolveWithLock(importPath string, source string, tools ResolverTools, fetch fetchFunc) (*dw.DevWorkspaceTemplateSpec, error) {
+	if locked, ok := tools.ImportLock[importPath]; ok && locked.Source == source && locked.Raw != "" {
+		if digestContent([]byte(locked.Raw)) == locked.Digest {
+			content, err := network.ParseDevWorkspaceTemplate([]byte(locked.Raw), source)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read locked content for %s: %w", importPath, err)
+			}
+			if tools.ResolvedImports != nil {
+				tools.ResolvedImports[importPath] = locked
+			}
+			return content, nil
+		}
+		resolved, _, err := fetch()
+		if err != nil {
+			return nil, err
+		}
+		if tools.InvalidLockedImports != nil {
+			tools.InvalidLockedImports[importPath] = true
+		}
+		if tools.ResolvedImports != nil {
+			tools.ResolvedImports[importPath] = locked
+		}
+		return resolved, nil
+	}
+
+	resolved, raw, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	if tools.ResolvedImports != nil {
+		tools.ResolvedImports[importPath] = LockedImport{
+			Source: source,
+			Digest: digestContent(raw),
+			Raw:    string(raw),
+		}
+	}
+	return resolved, nil
+}
+
+func digestContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// serializeTemplate returns the content of a DevWorkspaceTemplate resolved from the cluster, in a form that can be
+// read by network.ParseDevWorkspaceTemplate.
+func serializeTemplate(spec *dw.DevWorkspaceTemplateSpec) ([]byte, error) {
+	template := &dw.DevWorkspaceTemplate{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "DevWorkspaceTemplate",
+			APIVersion: dw.SchemeGroupVersion.String(),
+		},
+		Spec: *spec,
+	}
+	content, err := yaml.Marshal(template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize DevWorkspaceTemplate: %w", err)
+	}
+	return content, nil
+}
+
+// resolveKubernetesImportWithLock resolves a DevWorkspaceTemplate referenced via a kubernetes import. The current content
+// of the DevWorkspaceTemplate is used and recorded in tools.ResolvedImports, unless tools.PinKubernetesImports is set
+// and the DevWorkspaceTemplate changed since it was locked; in that case, the locked content is used and the
+// DevWorkspaceTemplate is recorded in tools.OutdatedKubernetesImports.
+func resolveKubernetesImportWithLock(
+	name string,
+	kubeReference *dw.KubernetesCustomResourceImportReference,
+	opts importOptions,
+	tools ResolverTools) (*dw.DevWorkspaceTemplateSpec, error) {
+
+	current, err := resolveElementByKubernetesImport(name, kubeReference, tools)
+	if err != nil {
+		return nil, err
+	}
+	currentContent, err := serializeTemplate(current)
+	if err != nil {
+		return nil, err
+	}
+	fetch := func() (*dw.DevWorkspaceTemplateSpec, []byte, error) {
+		return current, currentContent, nil
+	}
+
+	namespace := kubeReference.Namespace
+	if namespace == "" {
+		namespace = tools.WorkspaceNamespace
+	}
+	templateName := fmt.Sprintf("%s/%s", namespace, kubeReference.Name)
+	source := kubernetesImportSourcePrefix + templateName
+
+	if locked, ok := tools.ImportLock[opts.importPath]; ok && tools.PinKubernetesImports && locked.Source == source {
+		if digestContent(currentContent) != locked.Digest {
+			if tools.OutdatedKubernetesImports != nil {
+				tools.OutdatedKubernetesImports[templateName] = true
+			}
+			return resolveWithLock(opts.importPath, source, tools, fetch)
+		}
+	}
+
+	unpinnedTools := tools
+	unpinnedTools.ImportLock = nil
+	return resolveWithLock(opts.importPath, source, unpinnedTools, fetch)
+}
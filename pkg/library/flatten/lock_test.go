@@ -0,0 +1,236 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package flatten
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/devfile/devworkspace-operator/pkg/library/flatten/internal/testutil"
+)
+
+func pluginDevfile(image string) dw.Devfile {
+	devfile := dw.Devfile{}
+	devfile.SchemaVersion = "2.0.0"
+	devfile.Components = []dw.Component{
+		{
+			Name: "plugin-container",
+			ComponentUnion: dw.ComponentUnion{
+				Container: &dw.ContainerComponent{
+					Container: dw.Container{Image: image},
+				},
+			},
+		},
+	}
+	return devfile
+}
+
+func workspaceWithPlugin(uri string) *dw.DevWorkspaceTemplateSpec {
+	return &dw.DevWorkspaceTemplateSpec{
+		DevWorkspaceTemplateSpecContent: dw.DevWorkspaceTemplateSpecContent{
+			Components: []dw.Component{
+				{
+					Name: "test-plugin",
+					ComponentUnion: dw.ComponentUnion{
+						Plugin: &dw.PluginComponent{
+							ImportReference: dw.ImportReference{
+								ImportReferenceUnion: dw.ImportReferenceUnion{Uri: uri},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestResolveDevWorkspaceWithImportLock(t *testing.T) {
+	testutil.SetupControllerCfg()
+	httpGetter := &testutil.FakeHTTPGetter{
+		DevfileResources: map[string]dw.Devfile{
+			"https://my-plugin.io/test":  pluginDevfile("image:v1"),
+			"https://my-plugin.io/other": pluginDevfile("other-image"),
+		},
+	}
+	tools := ResolverTools{
+		Context:         context.Background(),
+		HttpClient:      httpGetter,
+		ResolvedImports: ImportLock{},
+	}
+
+	resolved, _, err := ResolveDevWorkspace(workspaceWithPlugin("https://my-plugin.io/test"), tools)
+	if !assert.NoError(t, err, "Should resolve DevWorkspace") {
+		return
+	}
+	assert.Equal(t, "image:v1", resolved.Components[0].Container.Image, "Should use fetched content")
+	lock := tools.ResolvedImports
+	if !assert.Contains(t, lock, "test-plugin", "Should lock plugin by imported-by name") {
+		return
+	}
+	assert.Equal(t, "https://my-plugin.io/test", lock["test-plugin"].Source, "Should record source of plugin")
+	assert.Regexp(t, "^sha256:[a-f0-9]{64}$", lock["test-plugin"].Digest, "Should record digest of plugin")
+	fetched, err := yaml.Marshal(pluginDevfile("image:v1"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, string(fetched), lock["test-plugin"].Raw, "Should record content as fetched")
+		assert.Equal(t, digestContent(fetched), lock["test-plugin"].Digest, "Should record digest of fetched content")
+	}
+
+	// Plugin changes at its source
+	httpGetter.DevfileResources["https://my-plugin.io/test"] = pluginDevfile("image:v2")
+
+	t.Run("Uses locked content", func(t *testing.T) {
+		tools.ImportLock = lock
+		tools.ResolvedImports = ImportLock{}
+		resolved, _, err := ResolveDevWorkspace(workspaceWithPlugin("https://my-plugin.io/test"), tools)
+		if assert.NoError(t, err, "Should resolve DevWorkspace") {
+			assert.Equal(t, "image:v1", resolved.Components[0].Container.Image, "Should use locked content")
+			assert.Equal(t, lock, tools.ResolvedImports, "Should keep lock unchanged")
+		}
+	})
+
+	t.Run("Fetches content when source changes", func(t *testing.T) {
+		tools.ImportLock = lock
+		tools.ResolvedImports = ImportLock{}
+		resolved, _, err := ResolveDevWorkspace(workspaceWithPlugin("https://my-plugin.io/other"), tools)
+		if assert.NoError(t, err, "Should resolve DevWorkspace") {
+			assert.Equal(t, "other-image", resolved.Components[0].Container.Image, "Should use fetched content")
+			assert.Equal(t, "https://my-plugin.io/other", tools.ResolvedImports["test-plugin"].Source, "Should update lock")
+		}
+	})
+
+	t.Run("Fetches content when lock is empty", func(t *testing.T) {
+		tools.ImportLock = ImportLock{}
+		tools.ResolvedImports = ImportLock{}
+		resolved, _, err := ResolveDevWorkspace(workspaceWithPlugin("https://my-plugin.io/test"), tools)
+		if assert.NoError(t, err, "Should resolve DevWorkspace") {
+			assert.Equal(t, "image:v2", resolved.Components[0].Container.Image, "Should use fetched content")
+			assert.NotEqual(t, lock["test-plugin"].Digest, tools.ResolvedImports["test-plugin"].Digest, "Should update digest")
+		}
+	})
+
+	t.Run("Fetches content when locked content does not match digest", func(t *testing.T) {
+		tampered := lock["test-plugin"]
+		tampered.Raw = strings.Replace(tampered.Raw, "image:v1", "tampered-image", 1)
+		tools.ImportLock = ImportLock{"test-plugin": tampered}
+		tools.ResolvedImports = ImportLock{}
+		tools.InvalidLockedImports = map[string]bool{}
+		resolved, _, err := ResolveDevWorkspace(workspaceWithPlugin("https://my-plugin.io/test"), tools)
+		if assert.NoError(t, err, "Should resolve DevWorkspace") {
+			assert.Equal(t, "image:v2", resolved.Components[0].Container.Image, "Should use fetched content")
+			assert.Equal(t, map[string]bool{"test-plugin": true}, tools.InvalidLockedImports, "Should report invalid locked import")
+			assert.Equal(t, tampered, tools.ResolvedImports["test-plugin"], "Should keep invalid lock entry until update is requested")
+		}
+	})
+}
+
+func TestResolveDevWorkspacePinKubernetesImports(t *testing.T) {
+	testutil.SetupControllerCfg()
+	template := func(image string) dw.DevWorkspaceTemplate {
+		return dw.DevWorkspaceTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-template", Namespace: "test-namespace"},
+			Spec: dw.DevWorkspaceTemplateSpec{
+				DevWorkspaceTemplateSpecContent: pluginDevfile(image).DevWorkspaceTemplateSpecContent,
+			},
+		}
+	}
+	workspace := func() *dw.DevWorkspaceTemplateSpec {
+		return &dw.DevWorkspaceTemplateSpec{
+			DevWorkspaceTemplateSpecContent: dw.DevWorkspaceTemplateSpecContent{
+				Components: []dw.Component{
+					{
+						Name: "test-plugin",
+						ComponentUnion: dw.ComponentUnion{
+							Plugin: &dw.PluginComponent{
+								ImportReference: dw.ImportReference{
+									ImportReferenceUnion: dw.ImportReferenceUnion{
+										Kubernetes: &dw.KubernetesCustomResourceImportReference{Name: "test-template"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	k8sClient := &testutil.FakeK8sClient{
+		DevWorkspaceResources: map[string]dw.DevWorkspaceTemplate{
+			"test-template": template("image:v1"),
+		},
+	}
+	tools := ResolverTools{
+		Context:            context.Background(),
+		WorkspaceNamespace: "test-namespace",
+		K8sClient:          k8sClient,
+		ResolvedImports:    ImportLock{},
+	}
+
+	_, _, err := ResolveDevWorkspace(workspace(), tools)
+	if !assert.NoError(t, err, "Should resolve DevWorkspace") {
+		return
+	}
+	lock := tools.ResolvedImports
+	assert.Equal(t, "kubernetes:test-namespace/test-template", lock["test-plugin"].Source, "Should record source of template")
+
+	// Template is updated on the cluster
+	k8sClient.DevWorkspaceResources["test-template"] = template("image:v2")
+
+	t.Run("Uses locked content when pinned", func(t *testing.T) {
+		tools.ImportLock = lock
+		tools.ResolvedImports = ImportLock{}
+		tools.PinKubernetesImports = true
+		tools.OutdatedKubernetesImports = map[string]bool{}
+		resolved, _, err := ResolveDevWorkspace(workspace(), tools)
+		if assert.NoError(t, err, "Should resolve DevWorkspace") {
+			assert.Equal(t, "image:v1", resolved.Components[0].Container.Image, "Should use locked content")
+			assert.Equal(t, lock, tools.ResolvedImports, "Should keep lock unchanged")
+			assert.Equal(t, map[string]bool{"test-namespace/test-template": true}, tools.OutdatedKubernetesImports,
+				"Should report template as outdated")
+		}
+	})
+
+	t.Run("Uses current content when not pinned", func(t *testing.T) {
+		tools.ImportLock = lock
+		tools.ResolvedImports = ImportLock{}
+		tools.PinKubernetesImports = false
+		tools.OutdatedKubernetesImports = map[string]bool{}
+		resolved, _, err := ResolveDevWorkspace(workspace(), tools)
+		if assert.NoError(t, err, "Should resolve DevWorkspace") {
+			assert.Equal(t, "image:v2", resolved.Components[0].Container.Image, "Should use current content")
+			assert.NotEqual(t, lock["test-plugin"].Digest, tools.ResolvedImports["test-plugin"].Digest, "Should update lock")
+			assert.Empty(t, tools.OutdatedKubernetesImports, "Should not report outdated templates")
+		}
+	})
+}
+
+func TestImportLockKubernetesImports(t *testing.T) {
+	lock := ImportLock{
+		"parent":                 {Source: "kubernetes:test-namespace/parent-template"},
+		"parent/nested-plugin":   {Source: "kubernetes:other-namespace/plugin-template"},
+		"test-plugin":            {Source: "kubernetes:test-namespace/parent-template"},
+		"uri-plugin":             {Source: "https://example.com/devfile.yaml"},
+		"uri-plugin/kube-plugin": {Source: "kubernetes:test-namespace/nested-template"},
+	}
+	assert.Equal(t, []string{
+		"other-namespace/plugin-template",
+		"test-namespace/nested-template",
+		"test-namespace/parent-template",
+	}, lock.KubernetesImports(), "Should return sorted, deduplicated templates from all import paths")
+	assert.Empty(t, ImportLock{}.KubernetesImports(), "Should return no templates for empty lock")
+}
@@ -0,0 +1,152 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package network
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Keys read from secrets used to authenticate with registries. Any combination of keys may be present; a bearer
+// token takes precedence over basic auth.
+const (
+	// RegistryAuthTokenKey is the key for a bearer token sent in the Authorization header
+	RegistryAuthTokenKey = "token"
+	// RegistryAuthUsernameKey and RegistryAuthPasswordKey are the keys for basic auth credentials
+	RegistryAuthUsernameKey = "username"
+	RegistryAuthPasswordKey = "password"
+	// RegistryAuthClientCertKey and RegistryAuthClientKeyKey are the keys for a PEM-encoded client certificate and
+	// private key used for TLS client authentication
+	RegistryAuthClientCertKey = corev1.TLSCertKey
+	RegistryAuthClientKeyKey  = corev1.TLSPrivateKeyKey
+	// RegistryAuthCAKey is the key for a PEM-encoded CA bundle used to verify the registry's certificate, in addition
+	// to the system CAs
+	RegistryAuthCAKey = "ca.crt"
+)
+
+// RegistryAuth holds credentials used when fetching resources from a registry that requires authentication.
+type RegistryAuth struct {
+	BearerToken        string
+	Username           string
+	Password           string
+	ClientCertificates []tls.Certificate
+	RootCAs            *x509.CertPool
+	// caBundle is the PEM-encoded CA bundle RootCAs was read from, used to identify the credentials
+	caBundle []byte
+}
+
+// AuthenticatingHTTPGetter is an HTTPGetter that can provide HTTPGetters that authenticate requests.
+type AuthenticatingHTTPGetter interface {
+	HTTPGetter
+	// WithAuth returns an HTTPGetter that authenticates requests to host using the provided credentials.
+	WithAuth(auth *RegistryAuth, host string) HTTPGetter
+}
+
+// RegistryAuthFromSecret reads registry credentials from a secret. An error is returned if the secret does not
+// contain any credentials or they cannot be parsed.
+func RegistryAuthFromSecret(secret *corev1.Secret) (*RegistryAuth, error) {
+	auth := &RegistryAuth{
+		BearerToken: string(secret.Data[RegistryAuthTokenKey]),
+		Username:    string(secret.Data[RegistryAuthUsernameKey]),
+		Password:    string(secret.Data[RegistryAuthPasswordKey]),
+	}
+	cert, hasCert := secret.Data[RegistryAuthClientCertKey]
+	key, hasKey := secret.Data[RegistryAuthClientKeyKey]
+	if hasCert != hasKey {
+		return nil, fmt.Errorf("secret %s must define both %s and %s to use a client certificate", secret.Name, RegistryAuthClientCertKey, RegistryAuthClientKeyKey)
+	}
+	if hasCert {
+		clientCert, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse client certificate in secret %s: %w", secret.Name, err)
+		}
+		auth.ClientCertificates = []tls.Certificate{clientCert}
+	}
+	if ca, ok := secret.Data[RegistryAuthCAKey]; ok {
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("failed to parse CA bundle in secret %s", secret.Name)
+		}
+		auth.RootCAs = rootCAs
+		auth.caBundle = ca
+	}
+	if auth.BearerToken == "" && auth.Username == "" && auth.ClientCertificates == nil && auth.RootCAs == nil {
+		return nil, fmt.Errorf("secret %s does not contain registry credentials", secret.Name)
+	}
+	return auth, nil
+}
+
+// cacheKey returns a key identifying the credentials in auth when used for requests to host. Getters that
+// authenticate with the same credentials may share cached responses.
+func (auth *RegistryAuth) cacheKey(host string) string {
+	hash := sha256.New()
+	for _, field := range []string{host, auth.BearerToken, auth.Username, auth.Password} {
+		hash.Write([]byte(field))
+		hash.Write([]byte{0})
+	}
+	for _, cert := range auth.ClientCertificates {
+		for _, der := range cert.Certificate {
+			hash.Write(der)
+		}
+		hash.Write([]byte{0})
+	}
+	hash.Write(auth.caBundle)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// transport returns a RoundTripper that applies the credentials in auth to requests to host. Credentials are not
+// sent if a request is redirected to a different host.
+func (auth *RegistryAuth) transport(host string) http.RoundTripper {
+	return &authRoundTripper{auth: auth, host: host, base: auth.tlsTransport()}
+}
+
+// tlsTransport returns a transport that uses the client certificates and CAs in auth, if any.
+func (auth *RegistryAuth) tlsTransport() *http.Transport {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	if auth.ClientCertificates != nil || auth.RootCAs != nil {
+		base.TLSClientConfig = &tls.Config{
+			Certificates: auth.ClientCertificates,
+			RootCAs:      auth.RootCAs,
+		}
+	}
+	return base
+}
+
+type authRoundTripper struct {
+	auth *RegistryAuth
+	host string
+	base http.RoundTripper
+}
+
+func (rt *authRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != rt.host || (rt.auth.BearerToken == "" && rt.auth.Username == "") {
+		return rt.base.RoundTrip(req)
+	}
+	// RoundTrippers must not modify the original request
+	req = req.Clone(req.Context())
+	if rt.auth.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+rt.auth.BearerToken)
+	} else {
+		req.SetBasicAuth(rt.auth.Username, rt.auth.Password)
+	}
+	return rt.base.RoundTrip(req)
+}
@@ -0,0 +1,120 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package network
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRegistryAuth(t *testing.T) {
+	tests := []struct {
+		name               string
+		secretData         map[string]string
+		redirect           bool
+		expectedAuthHeader string
+		expectedErr        string
+	}{
+		{
+			name:               "Sends bearer token",
+			secretData:         map[string]string{RegistryAuthTokenKey: "test-token"},
+			expectedAuthHeader: "Bearer test-token",
+		},
+		{
+			name:               "Sends basic auth",
+			secretData:         map[string]string{RegistryAuthUsernameKey: "user", RegistryAuthPasswordKey: "pass"},
+			expectedAuthHeader: "Basic dXNlcjpwYXNz",
+		},
+		{
+			name:               "Does not send credentials when redirected to other host",
+			secretData:         map[string]string{RegistryAuthTokenKey: "test-token"},
+			redirect:           true,
+			expectedAuthHeader: "",
+		},
+		{
+			name:        "Fails when secret has no credentials",
+			secretData:  map[string]string{},
+			expectedErr: "secret test-secret does not contain registry credentials",
+		},
+		{
+			name:        "Fails when client certificate has no key",
+			secretData:  map[string]string{RegistryAuthClientCertKey: "cert"},
+			expectedErr: "secret test-secret must define both tls.crt and tls.key to use a client certificate",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var receivedAuthHeader string
+			target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				receivedAuthHeader = r.Header.Get("Authorization")
+				w.Write([]byte("plugin"))
+			}))
+			defer target.Close()
+			registry := target
+			if tt.redirect {
+				// Requests to 127.0.0.1 and localhost are treated as different hosts
+				redirectURL, _ := url.Parse(target.URL)
+				redirectURL.Host = "localhost:" + redirectURL.Port()
+				registry = httptest.NewServer(http.RedirectHandler(redirectURL.String(), http.StatusFound))
+				defer registry.Close()
+			}
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-secret"},
+				Data:       map[string][]byte{},
+			}
+			for k, v := range tt.secretData {
+				secret.Data[k] = []byte(v)
+			}
+			auth, err := RegistryAuthFromSecret(secret)
+			if tt.expectedErr != "" {
+				if assert.Error(t, err, "Should return error") {
+					assert.Equal(t, tt.expectedErr, err.Error(), "Should return expected error")
+				}
+				return
+			}
+			if !assert.NoError(t, err, "Should read credentials from secret") {
+				return
+			}
+
+			registryURL, _ := url.Parse(registry.URL)
+			getter := NewCachingHTTPGetter(CacheOptions{}).WithAuth(auth, registryURL.Host)
+			resp, err := getter.Get(registry.URL)
+			if !assert.NoError(t, err, "Should not return error") {
+				return
+			}
+			resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode, "Should return status OK")
+			assert.Equal(t, tt.expectedAuthHeader, receivedAuthHeader, "Should send expected Authorization header")
+		})
+	}
+}
+
+func TestWithAuthReusesGetters(t *testing.T) {
+	getter := NewCachingHTTPGetter(CacheOptions{})
+	auth := &RegistryAuth{BearerToken: "test-token"}
+	authGetter := getter.WithAuth(auth, "registry.example.com")
+	assert.Same(t, authGetter, getter.WithAuth(&RegistryAuth{BearerToken: "test-token"}, "registry.example.com"),
+		"Should reuse getter for same host and credentials")
+	assert.NotSame(t, authGetter, getter.WithAuth(&RegistryAuth{BearerToken: "rotated-token"}, "registry.example.com"),
+		"Should not reuse getter for different credentials")
+	assert.NotSame(t, authGetter, getter.WithAuth(auth, "other.example.com"),
+		"Should not reuse getter for different host")
+}
@@ -0,0 +1,354 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package network
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const defaultRetryBackoff = 500 * time.Millisecond
+
+var (
+	cacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "devworkspace_remote_resource_cache_hits_total",
+		Help: "Number of requests for remote plugins and parents served from the cache, including revalidated and stale responses",
+	})
+	cacheMisses = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "devworkspace_remote_resource_cache_misses_total",
+		Help: "Number of requests for remote plugins and parents that required fetching the full resource",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(cacheHits, cacheMisses)
+}
+
+// CacheOptions configures a CachingHTTPGetter.
+type CacheOptions struct {
+	// TTL is the maximum duration a response is cached for. Servers may request a shorter duration via the
+	// Cache-Control max-age directive.
+	TTL time.Duration
+	// MaxBodySize is the maximum size in bytes of a response body. Larger responses result in an error.
+	MaxBodySize int64
+	// RequestTimeout is the timeout for a single request.
+	RequestTimeout time.Duration
+	// MaxRetries is the number of times a request is retried if it fails due to a network error or the server
+	// responds with a 5xx or 429 status. Requests are not retried within a call to Get; instead a NotReadyError
+	// is returned so that the caller can retry later.
+	MaxRetries int
+	// RetryBackoff is the delay suggested before the first retry; the delay is doubled for each subsequent retry.
+	// If unset, defaults to 500ms.
+	RetryBackoff time.Duration
+	// MaxEntries is the maximum number of responses stored in the cache. When the cache is full, expired responses
+	// are evicted first, followed by the responses closest to expiring. If zero, the cache is unbounded.
+	MaxEntries int
+}
+
+// NotReadyError is returned by CachingHTTPGetter when a request failed due to a transient issue and should be
+// retried after RequeueAfter.
+type NotReadyError struct {
+	// Message is a user-friendly string explaining why the error occurred
+	Message string
+	// RequeueAfter represents how long to wait before retrying the request
+	RequeueAfter time.Duration
+}
+
+func (e *NotReadyError) Error() string {
+	return e.Message
+}
+
+// CachingHTTPGetter is an HTTPGetter that caches successful responses in memory. Cached responses are revalidated
+// using ETag and Last-Modified headers once they expire, and Cache-Control no-store, no-cache and max-age directives
+// are honoured. If a resource cannot be fetched, a previously cached response is returned instead of an error, so
+// that an unavailable server does not prevent DevWorkspaces from starting.
+type CachingHTTPGetter struct {
+	client  *http.Client
+	options CacheOptions
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	// failures tracks the number of consecutive failed requests for locations that are not cached
+	failures map[string]int
+	// authGetters stores the getters returned by WithAuth, keyed by host and credentials
+	authGetters map[string]*CachingHTTPGetter
+	now         func() time.Time
+}
+
+var _ AuthenticatingHTTPGetter = (*CachingHTTPGetter)(nil)
+
+type cacheEntry struct {
+	body         []byte
+	header       http.Header
+	etag         string
+	lastModified string
+	expires      time.Time
+}
+
+// NewCachingHTTPGetter returns a CachingHTTPGetter configured with the provided options.
+func NewCachingHTTPGetter(options CacheOptions) *CachingHTTPGetter {
+	if options.RetryBackoff == 0 {
+		options.RetryBackoff = defaultRetryBackoff
+	}
+	return &CachingHTTPGetter{
+		client:      &http.Client{Timeout: options.RequestTimeout},
+		options:     options,
+		entries:     map[string]*cacheEntry{},
+		failures:    map[string]int{},
+		authGetters: map[string]*CachingHTTPGetter{},
+		now:         time.Now,
+	}
+}
+
+// WithAuth returns a CachingHTTPGetter with the same options as g that authenticates requests to host. The returned
+// getter does not share g's cache, as authenticated responses must not be served to other users, and always
+// revalidates cached responses. Calls to WithAuth with the same host and credentials return the same getter, so that
+// its cache and connections are reused across reconciles.
+func (g *CachingHTTPGetter) WithAuth(auth *RegistryAuth, host string) HTTPGetter {
+	key := auth.cacheKey(host)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if authGetter, ok := g.authGetters[key]; ok {
+		return authGetter
+	}
+	if g.options.MaxEntries > 0 && len(g.authGetters) >= g.options.MaxEntries {
+		// Getters for rotated credentials are never used again; drop an arbitrary getter to bound memory usage
+		for existingKey := range g.authGetters {
+			delete(g.authGetters, existingKey)
+			break
+		}
+	}
+	options := g.options
+	options.TTL = 0
+	authGetter := NewCachingHTTPGetter(options)
+	authGetter.client.Transport = auth.transport(host)
+	authGetter.now = g.now
+	g.authGetters[key] = authGetter
+	return authGetter
+}
+
+func (g *CachingHTTPGetter) Get(location string) (*http.Response, error) {
+	entry := g.getEntry(location)
+	if entry != nil && g.now().Before(entry.expires) {
+		cacheHits.Inc()
+		return entry.toResponse(), nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil {
+		if entry.etag != "" {
+			req.Header.Set("If-None-Match", entry.etag)
+		}
+		if entry.lastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.lastModified)
+		}
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil || isRetryableStatus(resp.StatusCode) {
+		if entry != nil {
+			// Serve stale content rather than failing
+			if resp != nil {
+				resp.Body.Close()
+			}
+			cacheHits.Inc()
+			return entry.toResponse(), nil
+		}
+		if retryAfter, retry := g.recordFailure(location); retry {
+			var reason string
+			if err != nil {
+				reason = err.Error()
+			} else {
+				reason = fmt.Sprintf("got status %d", resp.StatusCode)
+				resp.Body.Close()
+			}
+			return nil, &NotReadyError{
+				Message:      fmt.Sprintf("request to %s failed, retrying in %s: %s", location, retryAfter, reason),
+				RequeueAfter: retryAfter,
+			}
+		}
+		return resp, err
+	}
+	g.clearFailures(location)
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && entry != nil:
+		resp.Body.Close()
+		cacheHits.Inc()
+		if noStore, expires := g.getExpiry(resp.Header); !noStore {
+			g.setEntry(location, &cacheEntry{
+				body:         entry.body,
+				header:       entry.header,
+				etag:         entry.etag,
+				lastModified: entry.lastModified,
+				expires:      expires,
+			})
+		}
+		return entry.toResponse(), nil
+	case resp.StatusCode == http.StatusOK:
+		defer resp.Body.Close()
+		cacheMisses.Inc()
+		body, err := g.readBody(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response from %s: %w", location, err)
+		}
+		newEntry := &cacheEntry{
+			body:         body,
+			header:       resp.Header,
+			etag:         resp.Header.Get("ETag"),
+			lastModified: resp.Header.Get("Last-Modified"),
+		}
+		noStore, expires := g.getExpiry(resp.Header)
+		if noStore {
+			g.deleteEntry(location)
+		} else {
+			newEntry.expires = expires
+			g.setEntry(location, newEntry)
+		}
+		return newEntry.toResponse(), nil
+	default:
+		// Resource is no longer available (e.g. 404); do not serve it from the cache
+		cacheMisses.Inc()
+		g.deleteEntry(location)
+		return resp, nil
+	}
+}
+
+// recordFailure records a failed request for location and returns how long to wait before retrying it. If the
+// request has already been retried MaxRetries times, retry is false and the failure count is reset.
+func (g *CachingHTTPGetter) recordFailure(location string) (retryAfter time.Duration, retry bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	attempts := g.failures[location]
+	if attempts >= g.options.MaxRetries {
+		delete(g.failures, location)
+		return 0, false
+	}
+	g.failures[location] = attempts + 1
+	return g.options.RetryBackoff << attempts, true
+}
+
+func (g *CachingHTTPGetter) clearFailures(location string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.failures, location)
+}
+
+func (g *CachingHTTPGetter) readBody(body io.Reader) ([]byte, error) {
+	if g.options.MaxBodySize <= 0 {
+		return ioutil.ReadAll(body)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(body, g.options.MaxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > g.options.MaxBodySize {
+		return nil, fmt.Errorf("response exceeds maximum size of %d bytes", g.options.MaxBodySize)
+	}
+	return data, nil
+}
+
+// getExpiry determines whether a response may be stored and when it should next be revalidated, based on its
+// Cache-Control header and the configured TTL.
+func (g *CachingHTTPGetter) getExpiry(header http.Header) (noStore bool, expires time.Time) {
+	ttl := g.options.TTL
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-store":
+			return true, time.Time{}
+		case directive == "no-cache":
+			ttl = 0
+		case strings.HasPrefix(directive, "max-age="):
+			maxAge, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+			if err == nil && time.Duration(maxAge)*time.Second < ttl {
+				ttl = time.Duration(maxAge) * time.Second
+			}
+		}
+	}
+	return false, g.now().Add(ttl)
+}
+
+func (g *CachingHTTPGetter) getEntry(location string) *cacheEntry {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.entries[location]
+}
+
+func (g *CachingHTTPGetter) setEntry(location string, entry *cacheEntry) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, exists := g.entries[location]; !exists && g.options.MaxEntries > 0 {
+		g.evict(len(g.entries) - g.options.MaxEntries + 1)
+	}
+	g.entries[location] = entry
+}
+
+// evict removes count entries from the cache, preferring expired entries and otherwise removing the entries that
+// expire soonest. Must be called with g.mu held.
+func (g *CachingHTTPGetter) evict(count int) {
+	if count <= 0 {
+		return
+	}
+	now := g.now()
+	for location, entry := range g.entries {
+		if count == 0 {
+			return
+		}
+		if !now.Before(entry.expires) {
+			delete(g.entries, location)
+			count--
+		}
+	}
+	for ; count > 0; count-- {
+		oldest := ""
+		for location, entry := range g.entries {
+			if oldest == "" || entry.expires.Before(g.entries[oldest].expires) {
+				oldest = location
+			}
+		}
+		delete(g.entries, oldest)
+	}
+}
+
+func (g *CachingHTTPGetter) deleteEntry(location string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.entries, location)
+}
+
+func (e *cacheEntry) toResponse() *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", http.StatusOK, http.StatusText(http.StatusOK)),
+		StatusCode:    http.StatusOK,
+		Header:        e.header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+	}
+}
+
+func isRetryableStatus(statusCode int) bool {
+	return statusCode >= 500 || statusCode == http.StatusTooManyRequests
+}
@@ -0,0 +1,253 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package network
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testResponse struct {
+	status       int
+	cacheControl string
+	etag         string
+	body         string
+}
+
+func TestCachingHTTPGetter(t *testing.T) {
+	tests := []struct {
+		name string
+		// responses are returned by the server in order, one per request
+		responses []testResponse
+		// advance is how far the clock is moved forward between calls to Get
+		advance      time.Duration
+		getCount     int
+		expectedBody string
+		expectedErr  bool
+		// expectedNotReady is the RequeueAfter of the expected NotReadyError, if any
+		expectedNotReady time.Duration
+		expectedStatus   int
+		expectedFetches  int
+	}{
+		{
+			name:            "Serves fresh responses from cache",
+			responses:       []testResponse{{status: 200, body: "plugin-v1"}},
+			getCount:        3,
+			expectedBody:    "plugin-v1",
+			expectedFetches: 1,
+		},
+		{
+			name: "Revalidates expired responses with ETag",
+			responses: []testResponse{
+				{status: 200, etag: `"v1"`, body: "plugin-v1"},
+				{status: 304, etag: `"v1"`},
+			},
+			advance:         10 * time.Minute,
+			getCount:        2,
+			expectedBody:    "plugin-v1",
+			expectedFetches: 2,
+		},
+		{
+			name: "Respects max-age shorter than TTL",
+			responses: []testResponse{
+				{status: 200, cacheControl: "max-age=10", body: "plugin-v1"},
+				{status: 200, body: "plugin-v2"},
+			},
+			advance:         time.Minute,
+			getCount:        2,
+			expectedBody:    "plugin-v2",
+			expectedFetches: 2,
+		},
+		{
+			name: "Does not store no-store responses",
+			responses: []testResponse{
+				{status: 200, cacheControl: "no-store", body: "plugin-v1"},
+				{status: 200, cacheControl: "no-store", body: "plugin-v2"},
+			},
+			getCount:        2,
+			expectedBody:    "plugin-v2",
+			expectedFetches: 2,
+		},
+		{
+			name: "Serves stale response when server fails",
+			responses: []testResponse{
+				{status: 200, body: "plugin-v1"},
+				{status: 503},
+			},
+			advance:         10 * time.Minute,
+			getCount:        2,
+			expectedBody:    "plugin-v1",
+			expectedFetches: 2,
+		},
+		{
+			name:             "Returns NotReadyError on server errors",
+			responses:        []testResponse{{status: 503}},
+			getCount:         1,
+			expectedNotReady: time.Second,
+			expectedFetches:  1,
+		},
+		{
+			name: "Backs off exponentially between retries",
+			responses: []testResponse{
+				{status: 503}, {status: 429},
+			},
+			getCount:         2,
+			expectedNotReady: 2 * time.Second,
+			expectedFetches:  2,
+		},
+		{
+			name: "Retries on server errors",
+			responses: []testResponse{
+				{status: 503}, {status: 429},
+				{status: 200, body: "plugin-v1"},
+			},
+			getCount:        3,
+			expectedBody:    "plugin-v1",
+			expectedFetches: 3,
+		},
+		{
+			name: "Returns status when retries are exhausted",
+			responses: []testResponse{
+				{status: 503}, {status: 503}, {status: 503},
+			},
+			getCount:        3,
+			expectedStatus:  503,
+			expectedFetches: 3,
+		},
+		{
+			name: "Does not serve removed resources from cache",
+			responses: []testResponse{
+				{status: 200, body: "plugin-v1"},
+				{status: 404},
+			},
+			advance:         10 * time.Minute,
+			getCount:        2,
+			expectedStatus:  404,
+			expectedFetches: 2,
+		},
+		{
+			name:            "Fails on responses exceeding maximum size",
+			responses:       []testResponse{{status: 200, body: "this response is too large"}},
+			getCount:        1,
+			expectedErr:     true,
+			expectedFetches: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetches := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if fetches >= len(tt.responses) {
+					t.Errorf("Unexpected request %d", fetches+1)
+					http.Error(w, "unexpected request", http.StatusBadRequest)
+					return
+				}
+				resp := tt.responses[fetches]
+				fetches++
+				if resp.etag != "" {
+					w.Header().Set("ETag", resp.etag)
+				}
+				if resp.cacheControl != "" {
+					w.Header().Set("Cache-Control", resp.cacheControl)
+				}
+				if resp.status == http.StatusNotModified {
+					assert.Equal(t, resp.etag, r.Header.Get("If-None-Match"), "Should send ETag when revalidating")
+				}
+				w.WriteHeader(resp.status)
+				w.Write([]byte(resp.body))
+			}))
+			defer server.Close()
+
+			now := time.Now()
+			getter := NewCachingHTTPGetter(CacheOptions{
+				TTL:          5 * time.Minute,
+				MaxBodySize:  20,
+				MaxRetries:   2,
+				RetryBackoff: time.Second,
+			})
+			getter.now = func() time.Time { return now }
+
+			var resp *http.Response
+			var err error
+			for i := 0; i < tt.getCount; i++ {
+				if i > 0 {
+					now = now.Add(tt.advance)
+				}
+				resp, err = getter.Get(server.URL)
+			}
+
+			assert.Equal(t, tt.expectedFetches, fetches, "Should make expected number of requests")
+			if tt.expectedNotReady != 0 {
+				var notReadyErr *NotReadyError
+				if assert.True(t, errors.As(err, &notReadyErr), "Should return NotReadyError") {
+					assert.Equal(t, tt.expectedNotReady, notReadyErr.RequeueAfter, "Should request retry after backoff")
+				}
+				return
+			}
+			if tt.expectedErr {
+				assert.Error(t, err, "Should return error")
+				return
+			}
+			if !assert.NoError(t, err, "Should not return error") {
+				return
+			}
+			defer resp.Body.Close()
+			if tt.expectedStatus != 0 {
+				assert.Equal(t, tt.expectedStatus, resp.StatusCode, "Should return status from server")
+				return
+			}
+			assert.Equal(t, http.StatusOK, resp.StatusCode, "Should return status OK")
+			body, err := ioutil.ReadAll(resp.Body)
+			assert.NoError(t, err, "Should read response body")
+			assert.Equal(t, tt.expectedBody, string(body), "Should return expected body")
+		})
+	}
+}
+
+func TestCachingHTTPGetterEvictsEntries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/short-lived" {
+			w.Header().Set("Cache-Control", "max-age=10")
+		}
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	getter := NewCachingHTTPGetter(CacheOptions{
+		TTL:        5 * time.Minute,
+		MaxEntries: 2,
+	})
+	getter.now = func() time.Time { return now }
+
+	for _, path := range []string{"/long-lived", "/short-lived", "/new"} {
+		resp, err := getter.Get(fmt.Sprintf("%s%s", server.URL, path))
+		if !assert.NoError(t, err, "Should not return error") {
+			return
+		}
+		resp.Body.Close()
+		now = now.Add(time.Second)
+	}
+
+	assert.Len(t, getter.entries, 2, "Should not store more than MaxEntries responses")
+	assert.Contains(t, getter.entries, server.URL+"/long-lived", "Should keep response that expires last")
+	assert.Contains(t, getter.entries, server.URL+"/new", "Should store new response")
+}
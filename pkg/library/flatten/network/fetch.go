@@ -25,20 +25,31 @@ type HTTPGetter interface {
 	Get(location string) (*http.Response, error)
 }
 
-func FetchDevWorkspaceTemplate(location string, httpClient HTTPGetter) (*dw.DevWorkspaceTemplateSpec, error) {
+// FetchDevWorkspaceTemplate fetches a devfile, DevWorkspace, or DevWorkspaceTemplate from location and returns its
+// template spec, along with the content as fetched.
+func FetchDevWorkspaceTemplate(location string, httpClient HTTPGetter) (*dw.DevWorkspaceTemplateSpec, []byte, error) {
 	resp, err := httpClient.Get(location)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch file from %s: %w", location, err)
+		return nil, nil, fmt.Errorf("failed to fetch file from %s: %w", location, err)
 	}
 	defer resp.Body.Close() // ignoring error because what would we even do?
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("could not fetch file from %s: got status %d", location, resp.StatusCode)
+		return nil, nil, fmt.Errorf("could not fetch file from %s: got status %d", location, resp.StatusCode)
 	}
 	bytes, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("could not read data from %s: %w", location, err)
+		return nil, nil, fmt.Errorf("could not read data from %s: %w", location, err)
 	}
+	spec, err := ParseDevWorkspaceTemplate(bytes, location)
+	if err != nil {
+		return nil, nil, err
+	}
+	return spec, bytes, nil
+}
 
+// ParseDevWorkspaceTemplate reads a devfile, DevWorkspace, or DevWorkspaceTemplate fetched from location and returns
+// its template spec.
+func ParseDevWorkspaceTemplate(bytes []byte, location string) (*dw.DevWorkspaceTemplateSpec, error) {
 	devfile := &dw.Devfile{}
 	if err := yaml.Unmarshal(bytes, devfile); err != nil {
 		return nil, fmt.Errorf("could not unmarshal devfile from response: %w", err)
@@ -0,0 +1,473 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package network
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// OCIScheme is the URI scheme for plugins and parents published as OCI artifacts, e.g.
+	// oci://registry.example.com/org/stack:1.0.0 or oci://registry.example.com/org/stack@sha256:<digest>
+	OCIScheme = "oci"
+	// DevfileLayerMediaType is the media type of the layer containing the devfile in a devfile stack artifact
+	DevfileLayerMediaType = "application/vnd.devfileio.devfile.layer.v1"
+
+	ociManifestMediaType    = "application/vnd.oci.image.manifest.v1+json"
+	dockerManifestMediaType = "application/vnd.docker.distribution.manifest.v2+json"
+	ociTitleAnnotation      = "org.opencontainers.image.title"
+	defaultOCITag           = "latest"
+)
+
+var ociDigestRegexp = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// OCIReference identifies an artifact in an OCI registry.
+type OCIReference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	// Digest pins the artifact's manifest. If set, Tag is ignored.
+	Digest string
+}
+
+// RegistryCredentials are used to authenticate with an OCI registry.
+type RegistryCredentials struct {
+	Username string
+	Password string
+	// Auth holds credentials read from a registry auth secret, if any. A bearer token is sent with every request
+	// instead of answering the registry's authentication challenge, and client certificates and CAs are used when
+	// connecting to the registry.
+	Auth *RegistryAuth
+}
+
+// RegistryCredentialsFromAuth returns credentials for an OCI registry that use the registry auth in auth.
+func RegistryCredentialsFromAuth(auth *RegistryAuth) *RegistryCredentials {
+	return &RegistryCredentials{
+		Username: auth.Username,
+		Password: auth.Password,
+		Auth:     auth,
+	}
+}
+
+// OCIClient fetches devfiles published as OCI artifacts.
+type OCIClient struct {
+	Client *http.Client
+	// MaxBodySize is the maximum size in bytes of a manifest or devfile layer. If zero, size is not limited.
+	MaxBodySize int64
+
+	mu sync.Mutex
+	// authClients stores clients configured with TLS credentials from registry auth secrets, keyed by registry and
+	// credentials
+	authClients map[string]*http.Client
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociManifest struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	MediaType     string          `json:"mediaType,omitempty"`
+	Layers        []ociDescriptor `json:"layers"`
+}
+
+// IsOCIReference returns true if uri refers to an OCI artifact.
+func IsOCIReference(uri string) bool {
+	return strings.HasPrefix(uri, OCIScheme+"://")
+}
+
+// ParseOCIReference parses a URI of the form oci://<registry>/<repository>[:<tag>|@<digest>]. If neither tag nor
+// digest is specified, the tag 'latest' is used.
+func ParseOCIReference(uri string) (OCIReference, error) {
+	if !IsOCIReference(uri) {
+		return OCIReference{}, fmt.Errorf("invalid OCI reference %s: must start with %s://", uri, OCIScheme)
+	}
+	remainder := strings.TrimPrefix(uri, OCIScheme+"://")
+	slashIdx := strings.Index(remainder, "/")
+	if slashIdx <= 0 || slashIdx == len(remainder)-1 {
+		return OCIReference{}, fmt.Errorf("invalid OCI reference %s: expected %s://<registry>/<repository>", uri, OCIScheme)
+	}
+	ref := OCIReference{Registry: remainder[:slashIdx]}
+	repository := remainder[slashIdx+1:]
+	if atIdx := strings.Index(repository, "@"); atIdx >= 0 {
+		ref.Digest = repository[atIdx+1:]
+		repository = repository[:atIdx]
+		if !ociDigestRegexp.MatchString(ref.Digest) {
+			return OCIReference{}, fmt.Errorf("invalid OCI reference %s: unsupported digest %s", uri, ref.Digest)
+		}
+	} else if colonIdx := strings.LastIndex(repository, ":"); colonIdx > strings.LastIndex(repository, "/") {
+		ref.Tag = repository[colonIdx+1:]
+		repository = repository[:colonIdx]
+	} else {
+		ref.Tag = defaultOCITag
+	}
+	if repository == "" || ref.Tag == "" && ref.Digest == "" {
+		return OCIReference{}, fmt.Errorf("invalid OCI reference %s", uri)
+	}
+	ref.Repository = repository
+	return ref, nil
+}
+
+func (ref OCIReference) String() string {
+	if ref.Digest != "" {
+		return fmt.Sprintf("%s://%s/%s@%s", OCIScheme, ref.Registry, ref.Repository, ref.Digest)
+	}
+	return fmt.Sprintf("%s://%s/%s:%s", OCIScheme, ref.Registry, ref.Repository, ref.Tag)
+}
+
+// GetRegistryCredentialsFromPullSecret reads credentials for registry from a secret of type kubernetes.io/dockercfg
+// or kubernetes.io/dockerconfigjson. If the secret does not contain credentials for registry, nil is returned.
+func GetRegistryCredentialsFromPullSecret(secret *corev1.Secret, registry string) (*RegistryCredentials, error) {
+	type dockerAuth struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Auth     string `json:"auth"`
+	}
+	var auths map[string]dockerAuth
+	switch secret.Type {
+	case corev1.SecretTypeDockerConfigJson:
+		config := struct {
+			Auths map[string]dockerAuth `json:"auths"`
+		}{}
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config); err != nil {
+			return nil, fmt.Errorf("failed to parse pull secret %s: %w", secret.Name, err)
+		}
+		auths = config.Auths
+	case corev1.SecretTypeDockercfg:
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigKey], &auths); err != nil {
+			return nil, fmt.Errorf("failed to parse pull secret %s: %w", secret.Name, err)
+		}
+	default:
+		return nil, nil
+	}
+
+	for server, auth := range auths {
+		// Servers may be specified as URLs, e.g. https://registry.example.com/v1/
+		server = strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+		server = strings.SplitN(server, "/", 2)[0]
+		if server != registry {
+			continue
+		}
+		if auth.Username != "" || auth.Password != "" {
+			return &RegistryCredentials{Username: auth.Username, Password: auth.Password}, nil
+		}
+		decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode auth for %s in pull secret %s: %w", server, secret.Name, err)
+		}
+		parts := strings.SplitN(string(decoded), ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid auth for %s in pull secret %s", server, secret.Name)
+		}
+		return &RegistryCredentials{Username: parts[0], Password: parts[1]}, nil
+	}
+	return nil, nil
+}
+
+// FetchDevWorkspaceTemplate pulls the devfile layer of the artifact referenced by ref and returns its content as a
+// DevWorkspaceTemplateSpec, along with the content of the layer. If credentials are provided, they are used to authenticate with the registry. If ref
+// specifies a digest, the artifact's manifest is verified against it; the devfile layer is always verified against
+// the digest in the manifest.
+func (c *OCIClient) FetchDevWorkspaceTemplate(ref OCIReference, credentials *RegistryCredentials) (*dw.DevWorkspaceTemplateSpec, []byte, error) {
+	session := &ociSession{client: c, httpClient: c.httpClient(), ref: ref, credentials: credentials}
+	if credentials != nil && credentials.Auth != nil {
+		session.httpClient = c.authClient(ref.Registry, credentials.Auth)
+		if credentials.Auth.BearerToken != "" {
+			session.authorization = "Bearer " + credentials.Auth.BearerToken
+		}
+	}
+
+	manifestRef := ref.Tag
+	if ref.Digest != "" {
+		manifestRef = ref.Digest
+	}
+	manifestBytes, err := session.get("manifests/"+manifestRef, ociManifestMediaType+", "+dockerManifestMediaType)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch manifest for %s: %w", ref, err)
+	}
+	if ref.Digest != "" {
+		if err := verifyDigest(manifestBytes, ref.Digest); err != nil {
+			return nil, nil, fmt.Errorf("failed to verify manifest for %s: %w", ref, err)
+		}
+	}
+	manifest := &ociManifest{}
+	if err := json.Unmarshal(manifestBytes, manifest); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse manifest for %s: %w", ref, err)
+	}
+
+	layer, err := getDevfileLayer(manifest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", ref, err)
+	}
+	if !ociDigestRegexp.MatchString(layer.Digest) {
+		return nil, nil, fmt.Errorf("failed to read %s: unsupported layer digest %s", ref, layer.Digest)
+	}
+	devfileBytes, err := session.get("blobs/"+layer.Digest, "")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch devfile layer for %s: %w", ref, err)
+	}
+	if err := verifyDigest(devfileBytes, layer.Digest); err != nil {
+		return nil, nil, fmt.Errorf("failed to verify devfile layer for %s: %w", ref, err)
+	}
+	spec, err := ParseDevWorkspaceTemplate(devfileBytes, ref.String())
+	if err != nil {
+		return nil, nil, err
+	}
+	return spec, devfileBytes, nil
+}
+
+// getDevfileLayer finds the layer containing the devfile in a manifest. Layers are matched by media type, or by
+// title for artifacts pushed with generic tooling.
+func getDevfileLayer(manifest *ociManifest) (*ociDescriptor, error) {
+	for idx, layer := range manifest.Layers {
+		if layer.MediaType == DevfileLayerMediaType {
+			return &manifest.Layers[idx], nil
+		}
+	}
+	for idx, layer := range manifest.Layers {
+		title := layer.Annotations[ociTitleAnnotation]
+		if title == "devfile.yaml" || title == ".devfile.yaml" {
+			return &manifest.Layers[idx], nil
+		}
+	}
+	return nil, fmt.Errorf("artifact does not contain a devfile layer")
+}
+
+func verifyDigest(data []byte, digest string) error {
+	sum := sha256.Sum256(data)
+	if actual := "sha256:" + hex.EncodeToString(sum[:]); actual != digest {
+		return fmt.Errorf("digest mismatch: expected %s, got %s", digest, actual)
+	}
+	return nil
+}
+
+// ociSession tracks authorization for requests to a single repository. Registries respond to unauthorized requests
+// with a challenge describing how to authenticate, which is resolved once and reused for subsequent requests.
+type ociSession struct {
+	client        *OCIClient
+	httpClient    *http.Client
+	ref           OCIReference
+	credentials   *RegistryCredentials
+	authorization string
+}
+
+func (s *ociSession) get(path, accept string) ([]byte, error) {
+	resp, err := s.do(path, accept)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && s.authorization == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if s.authorization, err = s.authorize(challenge); err != nil {
+			return nil, err
+		}
+		if resp, err = s.do(path, accept); err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("registry returned status %d", resp.StatusCode)
+	}
+	return s.readBody(resp.Body)
+}
+
+func (s *ociSession) do(path, accept string) (*http.Response, error) {
+	reqURL := (&url.URL{Scheme: "https", Host: s.ref.Registry, Path: fmt.Sprintf("/v2/%s/%s", s.ref.Repository, path)}).String()
+	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	// The Authorization header is not forwarded if the registry redirects to a different host (e.g. blob storage)
+	if s.authorization != "" {
+		req.Header.Set("Authorization", s.authorization)
+	}
+	return s.httpClient.Do(req)
+}
+
+// authorize resolves a WWW-Authenticate challenge into an Authorization header value. For bearer challenges, a token
+// is requested from the challenge's realm, using basic auth if credentials are available.
+func (s *ociSession) authorize(challenge string) (string, error) {
+	scheme, params := parseAuthChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if s.credentials == nil {
+			return "", fmt.Errorf("registry %s requires authentication and no pull secret or registry auth secret is available", s.ref.Registry)
+		}
+		return "Basic " + basicAuth(s.credentials), nil
+	case "bearer":
+		return s.getBearerToken(params)
+	default:
+		return "", fmt.Errorf("registry %s returned unsupported authentication challenge %q", s.ref.Registry, challenge)
+	}
+}
+
+func (s *ociSession) getBearerToken(params map[string]string) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Host == "" {
+		return "", fmt.Errorf("registry %s returned invalid token realm %q", s.ref.Registry, params["realm"])
+	}
+	query := realm.Query()
+	if service, ok := params["service"]; ok {
+		query.Set("service", service)
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = fmt.Sprintf("repository:%s:pull", s.ref.Repository)
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if s.credentials != nil {
+		req.Header.Set("Authorization", "Basic "+basicAuth(s.credentials))
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get token for registry %s: %w", s.ref.Registry, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get token for registry %s: got status %d", s.ref.Registry, resp.StatusCode)
+	}
+	body, err := s.readBody(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	tokenResp := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return "", fmt.Errorf("failed to parse token response from registry %s: %w", s.ref.Registry, err)
+	}
+	token := tokenResp.Token
+	if token == "" {
+		token = tokenResp.AccessToken
+	}
+	if token == "" {
+		return "", fmt.Errorf("registry %s did not return a token", s.ref.Registry)
+	}
+	return "Bearer " + token, nil
+}
+
+func (s *ociSession) readBody(body io.Reader) ([]byte, error) {
+	maxSize := s.client.MaxBodySize
+	if maxSize <= 0 {
+		return ioutil.ReadAll(body)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("response exceeds maximum size of %d bytes", maxSize)
+	}
+	return data, nil
+}
+
+func (c *OCIClient) httpClient() *http.Client {
+	if c.Client == nil {
+		return http.DefaultClient
+	}
+	return c.Client
+}
+
+// authClient returns a client that uses the client certificates and CAs in auth when connecting to registry. Clients
+// are reused for the same registry and credentials.
+func (c *OCIClient) authClient(registry string, auth *RegistryAuth) *http.Client {
+	if auth.ClientCertificates == nil && auth.RootCAs == nil {
+		return c.httpClient()
+	}
+	key := auth.cacheKey(registry)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if client, ok := c.authClients[key]; ok {
+		return client
+	}
+	if c.authClients == nil {
+		c.authClients = map[string]*http.Client{}
+	}
+	client := &http.Client{
+		Timeout:   c.httpClient().Timeout,
+		Transport: auth.tlsTransport(),
+	}
+	c.authClients[key] = client
+	return client
+}
+
+// parseAuthChallenge parses a WWW-Authenticate header of the form <scheme> key1="value1",key2="value2"
+func parseAuthChallenge(challenge string) (scheme string, params map[string]string) {
+	params = map[string]string{}
+	parts := strings.SplitN(strings.TrimSpace(challenge), " ", 2)
+	scheme = parts[0]
+	if len(parts) < 2 {
+		return scheme, params
+	}
+	for _, param := range splitChallengeParams(parts[1]) {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		params[strings.ToLower(strings.TrimSpace(kv[0]))] = strings.Trim(strings.TrimSpace(kv[1]), `"`)
+	}
+	return scheme, params
+}
+
+// splitChallengeParams splits challenge parameters on commas that are not within quotes, as scopes may contain
+// commas (e.g. repository:org/stack:pull,push)
+func splitChallengeParams(params string) []string {
+	var result []string
+	inQuotes := false
+	start := 0
+	for idx, char := range params {
+		switch char {
+		case '"':
+			inQuotes = !inQuotes
+		case ',':
+			if !inQuotes {
+				result = append(result, params[start:idx])
+				start = idx + 1
+			}
+		}
+	}
+	return append(result, params[start:])
+}
+
+func basicAuth(credentials *RegistryCredentials) string {
+	return base64.StdEncoding.EncodeToString([]byte(credentials.Username + ":" + credentials.Password))
+}
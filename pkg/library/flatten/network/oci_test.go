@@ -0,0 +1,276 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package network
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	testRegistryUser     = "test-user"
+	testRegistryPassword = "test-password"
+	testRegistryToken    = "test-registry-token"
+	testDevfile          = `
+schemaVersion: 2.1.0
+metadata:
+  name: test-stack
+components:
+  - name: tools
+    container:
+      image: quay.io/test/tools:latest
+`
+)
+
+type testArtifact struct {
+	manifest []byte
+	blobs    map[string][]byte
+}
+
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func newTestArtifact(t *testing.T, layerMediaType string, layerAnnotations map[string]string, servedContent string) testArtifact {
+	layerDigest := digestOf([]byte(testDevfile))
+	manifest, err := json.Marshal(ociManifest{
+		SchemaVersion: 2,
+		MediaType:     ociManifestMediaType,
+		Layers: []ociDescriptor{{
+			MediaType:   layerMediaType,
+			Digest:      layerDigest,
+			Size:        int64(len(testDevfile)),
+			Annotations: layerAnnotations,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal manifest: %s", err)
+	}
+	return testArtifact{
+		manifest: manifest,
+		blobs:    map[string][]byte{layerDigest: []byte(servedContent)},
+	}
+}
+
+// testRegistry serves artifacts using token authentication. Tokens are issued only for requests using testRegistryUser
+// and testRegistryPassword. Manifests are served for any tag or digest, so that digest verification can be tested.
+func testRegistry(artifacts map[string]testArtifact) *httptest.Server {
+	mux := http.NewServeMux()
+	var registryURL string
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != testRegistryUser || password != testRegistryPassword {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"token": testRegistryToken})
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testRegistryToken {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test-registry"`, registryURL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		path := strings.TrimPrefix(r.URL.Path, "/v2/")
+		for repository, artifact := range artifacts {
+			switch {
+			case strings.HasPrefix(path, repository+"/manifests/"):
+				w.Header().Set("Content-Type", ociManifestMediaType)
+				w.Write(artifact.manifest)
+				return
+			case strings.HasPrefix(path, repository+"/blobs/"):
+				if blob, ok := artifact.blobs[strings.TrimPrefix(path, repository+"/blobs/")]; ok {
+					w.Write(blob)
+					return
+				}
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	server := httptest.NewTLSServer(mux)
+	registryURL = server.URL
+	return server
+}
+
+func TestOCIClient(t *testing.T) {
+	artifacts := map[string]testArtifact{
+		"org/stack":            newTestArtifact(t, DevfileLayerMediaType, nil, testDevfile),
+		"org/titled-stack":     newTestArtifact(t, "application/octet-stream", map[string]string{ociTitleAnnotation: "devfile.yaml"}, testDevfile),
+		"org/tampered-stack":   newTestArtifact(t, DevfileLayerMediaType, nil, "tampered content"),
+		"org/not-devfile-repo": newTestArtifact(t, "application/octet-stream", nil, testDevfile),
+	}
+	stackDigest := digestOf(artifacts["org/stack"].manifest)
+	otherDigest := digestOf([]byte("other manifest"))
+
+	tests := []struct {
+		name   string
+		uri    string
+		noAuth bool
+		// authSecretData, if set, is used as a registry auth secret instead of the pull secret. The secret always
+		// includes the test registry's CA, as the registry auth secret's TLS settings replace the default client's.
+		authSecretData map[string]string
+		expectedErr    string
+	}{
+		{
+			name: "Pulls devfile by tag",
+			uri:  "oci://%s/org/stack:1.0.0",
+		},
+		{
+			name: "Pulls devfile by digest",
+			uri:  "oci://%s/org/stack@" + stackDigest,
+		},
+		{
+			name: "Pulls devfile layer identified by title",
+			uri:  "oci://%s/org/titled-stack",
+		},
+		{
+			name:           "Pulls devfile using token from registry auth secret",
+			uri:            "oci://%s/org/stack:1.0.0",
+			authSecretData: map[string]string{RegistryAuthTokenKey: testRegistryToken},
+		},
+		{
+			name: "Pulls devfile using basic auth from registry auth secret",
+			uri:  "oci://%s/org/stack:1.0.0",
+			authSecretData: map[string]string{
+				RegistryAuthUsernameKey: testRegistryUser,
+				RegistryAuthPasswordKey: testRegistryPassword,
+			},
+		},
+		{
+			name:           "Fails with invalid token from registry auth secret",
+			uri:            "oci://%s/org/stack:1.0.0",
+			authSecretData: map[string]string{RegistryAuthTokenKey: "invalid-token"},
+			expectedErr:    "failed to fetch manifest for oci://%s/org/stack:1.0.0: registry returned status 401",
+		},
+		{
+			name:        "Fails when manifest does not match digest",
+			uri:         "oci://%s/org/stack@" + otherDigest,
+			expectedErr: "failed to verify manifest for oci://%s/org/stack@" + otherDigest + ": digest mismatch",
+		},
+		{
+			name:        "Fails when devfile layer does not match digest",
+			uri:         "oci://%s/org/tampered-stack:1.0.0",
+			expectedErr: "failed to verify devfile layer for oci://%s/org/tampered-stack:1.0.0: digest mismatch",
+		},
+		{
+			name:        "Fails when artifact has no devfile layer",
+			uri:         "oci://%s/org/not-devfile-repo:1.0.0",
+			expectedErr: "failed to read oci://%s/org/not-devfile-repo:1.0.0: artifact does not contain a devfile layer",
+		},
+		{
+			name:        "Fails without credentials",
+			uri:         "oci://%s/org/stack:1.0.0",
+			noAuth:      true,
+			expectedErr: "failed to fetch manifest for oci://%s/org/stack:1.0.0: failed to get token for registry %[1]s: got status 401",
+		},
+	}
+
+	registry := testRegistry(artifacts)
+	defer registry.Close()
+	registryHost := strings.TrimPrefix(registry.URL, "https://")
+	pullSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pull-secret"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			corev1.DockerConfigJsonKey: []byte(fmt.Sprintf(`{"auths": {"%s": {"username": "%s", "password": "%s"}}}`,
+				registryHost, testRegistryUser, testRegistryPassword)),
+		},
+	}
+	ociClient := &OCIClient{Client: registry.Client()}
+	registryCA := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: registry.Certificate().Raw})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := ParseOCIReference(fmt.Sprintf(tt.uri, registryHost))
+			if !assert.NoError(t, err, "Should parse OCI reference") {
+				return
+			}
+			var credentials *RegistryCredentials
+			client := ociClient
+			switch {
+			case tt.authSecretData != nil:
+				secret := &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-auth-secret"},
+					Data:       map[string][]byte{RegistryAuthCAKey: registryCA},
+				}
+				for k, v := range tt.authSecretData {
+					secret.Data[k] = []byte(v)
+				}
+				auth, err := RegistryAuthFromSecret(secret)
+				if !assert.NoError(t, err, "Should read credentials from registry auth secret") {
+					return
+				}
+				credentials = RegistryCredentialsFromAuth(auth)
+				client = &OCIClient{}
+			case !tt.noAuth:
+				credentials, err = GetRegistryCredentialsFromPullSecret(pullSecret, ref.Registry)
+				if !assert.NoError(t, err, "Should read credentials from pull secret") || !assert.NotNil(t, credentials, "Should find credentials for registry") {
+					return
+				}
+			}
+			dwt, _, err := client.FetchDevWorkspaceTemplate(ref, credentials)
+			if tt.expectedErr != "" {
+				if assert.Error(t, err, "Should return error") {
+					assert.Contains(t, err.Error(), fmt.Sprintf(tt.expectedErr, registryHost), "Should return expected error")
+				}
+				return
+			}
+			if !assert.NoError(t, err, "Should not return error") {
+				return
+			}
+			if assert.Len(t, dwt.Components, 1, "Should read components from devfile") {
+				assert.Equal(t, "quay.io/test/tools:latest", dwt.Components[0].Container.Image, "Should read container image from devfile")
+			}
+		})
+	}
+}
+
+func TestParseOCIReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	tests := []struct {
+		uri         string
+		expected    OCIReference
+		expectedErr bool
+	}{
+		{uri: "oci://registry.example.com/org/stack:1.0.0", expected: OCIReference{Registry: "registry.example.com", Repository: "org/stack", Tag: "1.0.0"}},
+		{uri: "oci://registry.example.com:5000/stack", expected: OCIReference{Registry: "registry.example.com:5000", Repository: "stack", Tag: "latest"}},
+		{uri: "oci://registry.example.com/org/stack@" + digest, expected: OCIReference{Registry: "registry.example.com", Repository: "org/stack", Digest: digest}},
+		{uri: "oci://registry.example.com/org/stack@sha256:invalid", expectedErr: true},
+		{uri: "oci://registry.example.com", expectedErr: true},
+		{uri: "https://registry.example.com/org/stack", expectedErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			ref, err := ParseOCIReference(tt.uri)
+			if tt.expectedErr {
+				assert.Error(t, err, "Should return error")
+				return
+			}
+			if assert.NoError(t, err, "Should not return error") {
+				assert.Equal(t, tt.expected, ref, "Should parse reference")
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+)
+
+// LatestVersion can be used to request the most recent version of a resource in a devfile registry
+const LatestVersion = "latest"
+
+// registryIndexEntry is an entry in the index served by a devfile registry at /index
+type registryIndexEntry struct {
+	Name string `json:"name"`
+	// Version is the default version of the entry
+	Version  string `json:"version"`
+	Versions []struct {
+		Version string `json:"version"`
+		Default bool   `json:"default"`
+	} `json:"versions"`
+}
+
+// FetchDevWorkspaceTemplateFromRegistry resolves id in the index of the devfile registry at registryURL and fetches
+// the version matching the version constraint from /devfiles/<id>/<version>. If version is empty, the default version
+// is used. Version constraints may be exact versions, 'latest', or semver ranges such as '^1.2'.
+//
+// If the registry does not serve an index, the resource is fetched from <registryURL>/<id> instead, and specifying a
+// version is an error.
+func FetchDevWorkspaceTemplateFromRegistry(registryURL *url.URL, id, version string, httpClient HTTPGetter) (*dw.DevWorkspaceTemplateSpec, []byte, error) {
+	index, err := fetchRegistryIndex(registryURL, httpClient)
+	if err != nil {
+		return nil, nil, err
+	}
+	if index == nil {
+		if version != "" {
+			return nil, nil, fmt.Errorf("cannot resolve version %s of %s: registry does not provide an index", version, id)
+		}
+		return FetchDevWorkspaceTemplate(joinURLPath(registryURL, id), httpClient)
+	}
+
+	var entry *registryIndexEntry
+	for idx := range index {
+		if index[idx].Name == id {
+			entry = &index[idx]
+			break
+		}
+	}
+	if entry == nil {
+		return nil, nil, fmt.Errorf("%s not found in registry index", id)
+	}
+
+	if len(entry.Versions) == 0 {
+		// Registry does not support multiple versions
+		if version != "" && version != LatestVersion && version != entry.Version {
+			if entry.Version == "" {
+				return nil, nil, fmt.Errorf("cannot resolve version %s of %s: registry does not provide versions", version, id)
+			}
+			constraint, err := semver.NewConstraint(version)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid version constraint %q: %w", version, err)
+			}
+			if parsed, err := semver.NewVersion(entry.Version); err != nil || !constraint.Check(parsed) {
+				return nil, nil, fmt.Errorf("no version of %s matches %s; available versions: %s", id, version, entry.Version)
+			}
+		}
+		return FetchDevWorkspaceTemplate(joinURLPath(registryURL, "devfiles", id), httpClient)
+	}
+
+	resolvedVersion, err := resolveVersion(entry, version)
+	if err != nil {
+		return nil, nil, err
+	}
+	return FetchDevWorkspaceTemplate(joinURLPath(registryURL, "devfiles", id, resolvedVersion), httpClient)
+}
+
+// fetchRegistryIndex fetches the index of a devfile registry. If the registry does not serve an index (i.e. responds
+// with status 404), nil is returned.
+func fetchRegistryIndex(registryURL *url.URL, httpClient HTTPGetter) ([]registryIndexEntry, error) {
+	indexURL := joinURLPath(registryURL, "index")
+	resp, err := httpClient.Get(indexURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch registry index from %s: %w", indexURL, err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		break
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("could not fetch registry index from %s: got status %d", indexURL, resp.StatusCode)
+	}
+	bytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read registry index from %s: %w", indexURL, err)
+	}
+	var index []registryIndexEntry
+	if err := json.Unmarshal(bytes, &index); err != nil {
+		return nil, fmt.Errorf("could not parse registry index from %s: %w", indexURL, err)
+	}
+	return index, nil
+}
+
+// resolveVersion returns the version of entry matching the version constraint. If version is empty, the default
+// version is returned; if version is 'latest' or a range, the highest matching version is returned.
+func resolveVersion(entry *registryIndexEntry, version string) (string, error) {
+	var available []string
+	for _, v := range entry.Versions {
+		if version == "" && v.Default {
+			return v.Version, nil
+		}
+		if v.Version == version {
+			return v.Version, nil
+		}
+		available = append(available, v.Version)
+	}
+	if version == "" {
+		if entry.Version != "" {
+			return entry.Version, nil
+		}
+		version = LatestVersion
+	}
+
+	constraintStr := version
+	if version == LatestVersion {
+		constraintStr = "*"
+	}
+	constraint, err := semver.NewConstraint(constraintStr)
+	if err != nil {
+		return "", fmt.Errorf("invalid version constraint %q: %w", version, err)
+	}
+	var best *semver.Version
+	for _, v := range available {
+		parsed, err := semver.NewVersion(v)
+		if err != nil {
+			// Ignore versions that do not follow semver
+			continue
+		}
+		if constraint.Check(parsed) && (best == nil || parsed.GreaterThan(best)) {
+			best = parsed
+		}
+	}
+	if best == nil {
+		sortVersions(available)
+		return "", fmt.Errorf("no version of %s matches %s; available versions: %s", entry.Name, version, strings.Join(available, ", "))
+	}
+	return best.Original(), nil
+}
+
+// sortVersions sorts versions in ascending order. Versions that do not follow semver are sorted lexically after
+// all semver versions.
+func sortVersions(versions []string) {
+	sort.Slice(versions, func(i, j int) bool {
+		vi, errI := semver.NewVersion(versions[i])
+		vj, errJ := semver.NewVersion(versions[j])
+		switch {
+		case errI == nil && errJ == nil:
+			return vi.LessThan(vj)
+		case errI == nil || errJ == nil:
+			return errI == nil
+		default:
+			return versions[i] < versions[j]
+		}
+	})
+}
+
+func joinURLPath(base *url.URL, elements ...string) string {
+	joined := *base
+	joined.Path = path.Join(append([]string{base.Path}, elements...)...)
+	return joined.String()
+}
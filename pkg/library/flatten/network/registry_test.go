@@ -0,0 +1,228 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package network
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testRegistryIndex = `[
+  {
+    "name": "java-maven",
+    "version": "1.1.0",
+    "versions": [
+      {"version": "1.0.0"},
+      {"version": "1.1.0", "default": true},
+      {"version": "1.2.3"},
+      {"version": "2.0.0"},
+      {"version": "2.1.0-rc1"}
+    ]
+  },
+  {
+    "name": "nodejs",
+    "version": "2.1.1"
+  }
+]`
+
+func testDevfileWithImage(image string) string {
+	return fmt.Sprintf(`
+schemaVersion: 2.1.0
+metadata:
+  name: test-stack
+components:
+  - name: tools
+    container:
+      image: %s
+`, image)
+}
+
+func TestFetchDevWorkspaceTemplateFromRegistry(t *testing.T) {
+	tests := []struct {
+		name          string
+		id            string
+		version       string
+		noIndex       bool
+		indexStatus   int
+		expectedImage string
+		expectedErr   string
+	}{
+		{
+			name:          "Resolves default version",
+			id:            "java-maven",
+			expectedImage: "java-maven:1.1.0",
+		},
+		{
+			name:          "Resolves exact version",
+			id:            "java-maven",
+			version:       "1.0.0",
+			expectedImage: "java-maven:1.0.0",
+		},
+		{
+			name:          "Resolves latest version",
+			id:            "java-maven",
+			version:       "latest",
+			expectedImage: "java-maven:2.0.0",
+		},
+		{
+			name:          "Resolves caret range",
+			id:            "java-maven",
+			version:       "^1.1",
+			expectedImage: "java-maven:1.2.3",
+		},
+		{
+			name:          "Resolves tilde range",
+			id:            "java-maven",
+			version:       "~1.1.0",
+			expectedImage: "java-maven:1.1.0",
+		},
+		{
+			name:          "Resolves prerelease when requested explicitly",
+			id:            "java-maven",
+			version:       ">=2.1.0-rc1",
+			expectedImage: "java-maven:2.1.0-rc1",
+		},
+		{
+			name:        "Lists available versions when no version matches",
+			id:          "java-maven",
+			version:     "^3.0",
+			expectedErr: "no version of java-maven matches ^3.0; available versions: 1.0.0, 1.1.0, 1.2.3, 2.0.0, 2.1.0-rc1",
+		},
+		{
+			name:          "Resolves entry without versions",
+			id:            "nodejs",
+			version:       "^2",
+			expectedImage: "nodejs",
+		},
+		{
+			name:        "Fails when entry is not in index",
+			id:          "python",
+			expectedErr: "python not found in registry index",
+		},
+		{
+			name:        "Fails when registry index cannot be fetched",
+			id:          "java-maven",
+			indexStatus: http.StatusServiceUnavailable,
+			expectedErr: "could not fetch registry index from %s/registry/index: got status 503",
+		},
+		{
+			name:          "Falls back to path when registry has no index",
+			id:            "java-maven",
+			noIndex:       true,
+			expectedImage: "legacy-java-maven",
+		},
+		{
+			name:        "Fails to resolve version when registry has no index",
+			id:          "java-maven",
+			version:     "1.0.0",
+			noIndex:     true,
+			expectedErr: "cannot resolve version 1.0.0 of java-maven: registry does not provide an index",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			if !tt.noIndex {
+				mux.HandleFunc("/registry/index", func(w http.ResponseWriter, r *http.Request) {
+					if tt.indexStatus != 0 {
+						w.WriteHeader(tt.indexStatus)
+						return
+					}
+					w.Write([]byte(testRegistryIndex))
+				})
+			}
+			for _, version := range []string{"1.0.0", "1.1.0", "1.2.3", "2.0.0", "2.1.0-rc1"} {
+				devfile := testDevfileWithImage("java-maven:" + version)
+				mux.HandleFunc("/registry/devfiles/java-maven/"+version, func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte(devfile))
+				})
+			}
+			mux.HandleFunc("/registry/devfiles/nodejs", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(testDevfileWithImage("nodejs")))
+			})
+			mux.HandleFunc("/registry/java-maven", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(testDevfileWithImage("legacy-java-maven")))
+			})
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			registryURL, _ := url.Parse(server.URL + "/registry")
+			dwt, _, err := FetchDevWorkspaceTemplateFromRegistry(registryURL, tt.id, tt.version, http.DefaultClient)
+			if tt.expectedErr != "" {
+				if assert.Error(t, err, "Should return error") {
+					expectedErr := tt.expectedErr
+					if strings.Contains(expectedErr, "%s") {
+						expectedErr = fmt.Sprintf(expectedErr, server.URL)
+					}
+					assert.Equal(t, expectedErr, err.Error(), "Should return expected error")
+				}
+				return
+			}
+			if !assert.NoError(t, err, "Should not return error") {
+				return
+			}
+			if assert.Len(t, dwt.Components, 1, "Should read components from devfile") {
+				assert.Equal(t, tt.expectedImage, dwt.Components[0].Container.Image, "Should resolve expected devfile")
+			}
+		})
+	}
+}
+
+func TestResolveVersion(t *testing.T) {
+	entry := &registryIndexEntry{Name: "test-stack"}
+	for _, version := range []string{"0.2.3", "0.2.9", "0.3.0", "1.0.0-alpha", "1.0.0-beta", "1.0.0", "1.2.3", "1.2.9", "1.3.0", "2.0.0-rc1", "2.0.0", "3.1.0"} {
+		entry.Versions = append(entry.Versions, struct {
+			Version string `json:"version"`
+			Default bool   `json:"default"`
+		}{Version: version})
+	}
+	tests := []struct {
+		constraint  string
+		expected    string
+		expectedErr bool
+	}{
+		{constraint: "1.2.3", expected: "1.2.3"},
+		{constraint: "latest", expected: "3.1.0"},
+		{constraint: "^1.2", expected: "1.3.0"},
+		{constraint: "^0.2.3", expected: "0.2.9"},
+		{constraint: "~1.2.3", expected: "1.2.9"},
+		{constraint: "1.x", expected: "1.3.0"},
+		{constraint: ">=1.0.0 <2.0.0", expected: "1.3.0"},
+		{constraint: ">=1.0.0, <2.0.0", expected: "1.3.0"},
+		{constraint: "<=1.2", expected: "1.2.9"},
+		{constraint: "^1.0 || ^3.0", expected: "3.1.0"},
+		{constraint: "2.0.0-rc1", expected: "2.0.0-rc1"},
+		{constraint: ">=1.0.0-beta", expected: "3.1.0"},
+		{constraint: "^4.0", expectedErr: true},
+		{constraint: "not-a-version", expectedErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			version, err := resolveVersion(entry, tt.constraint)
+			if tt.expectedErr {
+				assert.Error(t, err, "Should return error")
+				return
+			}
+			if assert.NoError(t, err, "Should not return error") {
+				assert.Equal(t, tt.expected, version, "Should resolve highest matching version")
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package flatten
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+	"github.com/devfile/devworkspace-operator/pkg/library/flatten/network"
+)
+
+// resolveElementByOCIReference resolves a plugin or parent published as an OCI artifact, returning its template spec
+// and the content of its devfile layer. The name parameter is used to construct meaningful error messages (e.g. issue
+// resolving plugin 'name')
+func resolveElementByOCIReference(
+	name string,
+	uri string,
+	opts importOptions,
+	tools ResolverTools) (resolvedPlugin *dw.DevWorkspaceTemplateSpec, raw []byte, err error) {
+
+	if tools.OCIClient == nil {
+		return nil, nil, fmt.Errorf("cannot resolve resources from OCI registries: no OCI client provided")
+	}
+	ref, err := network.ParseOCIReference(uri)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve component %s: %w", name, err)
+	}
+	credentials, err := getRegistryCredentials(ref.Registry, opts.authSecret, tools)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve component %s: %w", name, err)
+	}
+	dwt, raw, err := tools.OCIClient.FetchDevWorkspaceTemplate(ref, credentials)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve component %s from OCI registry: %w", name, err)
+	}
+	return dwt, raw, nil
+}
+
+// getRegistryCredentials returns credentials for registry. Registry auth secrets (i.e. secrets with the
+// DevWorkspaceRegistryAuthSecretLabel) take precedence; if authSecretName is set, that secret must be used. Otherwise,
+// credentials are read from the pull secrets in the DevWorkspace's namespace (i.e. secrets with the
+// DevWorkspacePullSecretLabel). Returns nil if no secret applies to registry.
+func getRegistryCredentials(registry, authSecretName string, tools ResolverTools) (*network.RegistryCredentials, error) {
+	auth, err := getRegistryAuth(&url.URL{Host: registry}, authSecretName, tools)
+	if err != nil {
+		return nil, err
+	}
+	if auth != nil {
+		return network.RegistryCredentialsFromAuth(auth), nil
+	}
+	if tools.K8sClient == nil || tools.WorkspaceNamespace == "" {
+		return nil, nil
+	}
+	secrets := &corev1.SecretList{}
+	err = tools.K8sClient.List(tools.Context, secrets,
+		client.InNamespace(tools.WorkspaceNamespace),
+		client.MatchingLabels{constants.DevWorkspacePullSecretLabel: "true"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pull secrets: %w", err)
+	}
+	sort.Slice(secrets.Items, func(i, j int) bool {
+		return secrets.Items[i].Name < secrets.Items[j].Name
+	})
+	for idx := range secrets.Items {
+		credentials, err := network.GetRegistryCredentialsFromPullSecret(&secrets.Items[idx], registry)
+		if err != nil {
+			return nil, err
+		}
+		if credentials != nil {
+			return credentials, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,106 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package flatten
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+	"github.com/devfile/devworkspace-operator/pkg/library/flatten/network"
+)
+
+// getHttpClientForLocation returns the HTTPGetter to be used for fetching location. If a registry auth secret in the
+// DevWorkspace's namespace applies to location, a getter that authenticates with the secret's credentials is returned;
+// otherwise, the HttpClient from tools is used. If authSecretName is set, only the secret with that name is considered.
+func getHttpClientForLocation(location, authSecretName string, tools ResolverTools) (network.HTTPGetter, error) {
+	locationURL, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse URL %s: %w", location, err)
+	}
+	auth, err := getRegistryAuth(locationURL, authSecretName, tools)
+	if err != nil || auth == nil {
+		return tools.HttpClient, err
+	}
+	authGetter, ok := tools.HttpClient.(network.AuthenticatingHTTPGetter)
+	if !ok {
+		return nil, fmt.Errorf("cannot use registry auth secret: HTTP client does not support authentication")
+	}
+	return authGetter.WithAuth(auth, locationURL.Host), nil
+}
+
+// getRegistryAuth reads credentials for locationURL from the registry auth secrets in the DevWorkspace's namespace.
+// If authSecretName is set, only the secret with that name is considered and an error is returned if it cannot be
+// used. Returns nil if no secret applies to locationURL.
+func getRegistryAuth(locationURL *url.URL, authSecretName string, tools ResolverTools) (*network.RegistryAuth, error) {
+	if tools.K8sClient == nil || tools.WorkspaceNamespace == "" {
+		if authSecretName != "" {
+			return nil, fmt.Errorf("cannot read registry auth secret %s: no kubernetes client provided", authSecretName)
+		}
+		return nil, nil
+	}
+
+	secrets := &corev1.SecretList{}
+	err := tools.K8sClient.List(tools.Context, secrets,
+		client.InNamespace(tools.WorkspaceNamespace),
+		client.MatchingLabels{constants.DevWorkspaceRegistryAuthSecretLabel: "true"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list registry auth secrets: %w", err)
+	}
+	sort.Slice(secrets.Items, func(i, j int) bool {
+		return secrets.Items[i].Name < secrets.Items[j].Name
+	})
+
+	var authSecret *corev1.Secret
+	for idx, secret := range secrets.Items {
+		if authSecretName != "" {
+			if secret.Name != authSecretName {
+				continue
+			}
+			if _, ok := secret.Annotations[constants.DevWorkspaceRegistryHostsAnnotation]; ok && !secretAppliesToHost(&secret, locationURL) {
+				return nil, fmt.Errorf("registry auth secret %s does not apply to host %s", authSecretName, locationURL.Host)
+			}
+			authSecret = &secrets.Items[idx]
+			break
+		}
+		// Secrets that do not list hosts are only used when referenced explicitly, to avoid sending credentials to
+		// arbitrary hosts.
+		if secretAppliesToHost(&secret, locationURL) {
+			authSecret = &secrets.Items[idx]
+			break
+		}
+	}
+	if authSecret == nil {
+		if authSecretName != "" {
+			return nil, fmt.Errorf("registry auth secret %s not found; secrets must have label %s=true",
+				authSecretName, constants.DevWorkspaceRegistryAuthSecretLabel)
+		}
+		return nil, nil
+	}
+	return network.RegistryAuthFromSecret(authSecret)
+}
+
+func secretAppliesToHost(secret *corev1.Secret, locationURL *url.URL) bool {
+	for _, host := range strings.Split(secret.Annotations[constants.DevWorkspaceRegistryHostsAnnotation], ",") {
+		host = strings.TrimSpace(host)
+		if host != "" && (host == locationURL.Host || host == locationURL.Hostname()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package flatten
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+	"github.com/devfile/devworkspace-operator/pkg/library/flatten/network"
+)
+
+const testNamespace = "test-namespace"
+
+type testAuthSecret struct {
+	name  string
+	token string
+	// hosts is the value of the registry hosts annotation; 'test-server' is replaced by the test server's host
+	hosts      string
+	unlabelled bool
+}
+
+func (s testAuthSecret) toSecret(serverHost string) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.name,
+			Namespace: testNamespace,
+		},
+		Data: map[string][]byte{
+			network.RegistryAuthTokenKey: []byte(s.token),
+		},
+	}
+	if !s.unlabelled {
+		secret.Labels = map[string]string{
+			constants.DevWorkspaceRegistryAuthSecretLabel: "true",
+		}
+	}
+	if s.hosts != "" {
+		secret.Annotations = map[string]string{
+			constants.DevWorkspaceRegistryHostsAnnotation: strings.ReplaceAll(s.hosts, "test-server", serverHost),
+		}
+	}
+	return secret
+}
+
+func TestGetHttpClientForLocation(t *testing.T) {
+	tests := []struct {
+		name           string
+		secrets        []testAuthSecret
+		authSecretName string
+		// expectedToken is the bearer token expected to be sent; if empty, the default client should be returned
+		expectedToken string
+		expectedErr   string
+	}{
+		{
+			name: "Uses default client when there are no secrets",
+		},
+		{
+			name:          "Uses secret for matching host",
+			secrets:       []testAuthSecret{{name: "test-secret", token: "test-token", hosts: "other.example.com, test-server"}},
+			expectedToken: "test-token",
+		},
+		{
+			name:          "Matches hostname without port",
+			secrets:       []testAuthSecret{{name: "test-secret", token: "test-token", hosts: "127.0.0.1"}},
+			expectedToken: "test-token",
+		},
+		{
+			name:    "Does not use secret for other hosts",
+			secrets: []testAuthSecret{{name: "test-secret", token: "test-token", hosts: "other.example.com"}},
+		},
+		{
+			name:    "Does not use secret without hosts unless referenced",
+			secrets: []testAuthSecret{{name: "test-secret", token: "test-token"}},
+		},
+		{
+			name: "Uses first matching secret by name",
+			secrets: []testAuthSecret{
+				{name: "secret-b", token: "token-b", hosts: "test-server"},
+				{name: "secret-a", token: "token-a", hosts: "test-server"},
+			},
+			expectedToken: "token-a",
+		},
+		{
+			name: "Uses referenced secret",
+			secrets: []testAuthSecret{
+				{name: "secret-a", token: "token-a", hosts: "test-server"},
+				{name: "secret-b", token: "token-b"},
+			},
+			authSecretName: "secret-b",
+			expectedToken:  "token-b",
+		},
+		{
+			name:           "Fails when referenced secret does not apply to host",
+			secrets:        []testAuthSecret{{name: "test-secret", token: "test-token", hosts: "other.example.com"}},
+			authSecretName: "test-secret",
+			expectedErr:    "registry auth secret test-secret does not apply to host test-server",
+		},
+		{
+			name:           "Fails when referenced secret does not exist",
+			authSecretName: "test-secret",
+			expectedErr:    "registry auth secret test-secret not found; secrets must have label controller.devfile.io/registry-auth-secret=true",
+		},
+		{
+			name:           "Fails when referenced secret is not labelled",
+			secrets:        []testAuthSecret{{name: "test-secret", token: "test-token", unlabelled: true}},
+			authSecretName: "test-secret",
+			expectedErr:    "registry auth secret test-secret not found; secrets must have label controller.devfile.io/registry-auth-secret=true",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var receivedAuthHeader string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				receivedAuthHeader = r.Header.Get("Authorization")
+			}))
+			defer server.Close()
+			serverHost := server.Listener.Addr().String()
+
+			var objs []runtime.Object
+			for _, secret := range tt.secrets {
+				objs = append(objs, secret.toSecret(serverHost))
+			}
+			defaultClient := network.NewCachingHTTPGetter(network.CacheOptions{})
+			tools := ResolverTools{
+				Context:            context.Background(),
+				WorkspaceNamespace: testNamespace,
+				K8sClient:          fake.NewFakeClientWithScheme(scheme.Scheme, objs...),
+				HttpClient:         defaultClient,
+			}
+
+			httpClient, err := getHttpClientForLocation(server.URL+"/plugin.yaml", tt.authSecretName, tools)
+			if tt.expectedErr != "" {
+				if assert.Error(t, err, "Should return error") {
+					assert.Equal(t, strings.ReplaceAll(tt.expectedErr, "test-server", serverHost), err.Error(), "Should return expected error")
+				}
+				return
+			}
+			if !assert.NoError(t, err, "Should not return error") {
+				return
+			}
+			if tt.expectedToken == "" {
+				assert.Same(t, defaultClient, httpClient, "Should use default client")
+				return
+			}
+			resp, err := httpClient.Get(server.URL + "/plugin.yaml")
+			if !assert.NoError(t, err, "Should not return error") {
+				return
+			}
+			resp.Body.Close()
+			assert.Equal(t, "Bearer "+tt.expectedToken, receivedAuthHeader, "Should send token from secret")
+
+			sameClient, err := getHttpClientForLocation(server.URL+"/other-plugin.yaml", tt.authSecretName, tools)
+			if assert.NoError(t, err, "Should not return error") {
+				assert.Same(t, httpClient, sameClient, "Should reuse client for same host and credentials")
+			}
+		})
+	}
+}
+
+func TestGetHttpClientForLocationWithoutClient(t *testing.T) {
+	defaultClient := network.NewCachingHTTPGetter(network.CacheOptions{})
+	tools := ResolverTools{HttpClient: defaultClient}
+	httpClient, err := getHttpClientForLocation("https://registry.example.com/plugin.yaml", "", tools)
+	if assert.NoError(t, err, "Should not return error") {
+		assert.Same(t, defaultClient, httpClient, "Should use default client")
+	}
+	_, err = getHttpClientForLocation("https://registry.example.com/plugin.yaml", "test-secret", tools)
+	assert.EqualError(t, err, "cannot read registry auth secret test-secret: no kubernetes client provided")
+}
@@ -0,0 +1,76 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package flatten
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	"github.com/devfile/api/v2/pkg/validation/variables"
+)
+
+// mergeVariables returns the variables defined by the parent and plugins of a DevWorkspace, with variables defined in
+// the DevWorkspace itself taking precedence. Variables defined in plugins take precedence over those defined in the
+// parent. Variables are removed from parent and plugin contents, as they would otherwise conflict when merging.
+func mergeVariables(
+	workspaceVariables map[string]string,
+	parent *dw.DevWorkspaceTemplateSpecContent,
+	plugins []*dw.DevWorkspaceTemplateSpecContent) map[string]string {
+
+	merged := map[string]string{}
+	for _, content := range append([]*dw.DevWorkspaceTemplateSpecContent{parent}, plugins...) {
+		for name, value := range content.Variables {
+			merged[name] = value
+		}
+		content.Variables = nil
+	}
+	for name, value := range workspaceVariables {
+		merged[name] = value
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
+
+// hasVariableWarnings returns whether any invalid variable references are recorded in warnings
+func hasVariableWarnings(warnings variables.VariableWarning) bool {
+	return len(warnings.Components) > 0 || len(warnings.Commands) > 0 ||
+		len(warnings.Projects) > 0 || len(warnings.StarterProjects) > 0
+}
+
+// FormatVariablesWarning returns a human-readable description of the invalid variable references in warnings,
+// suitable for use in a status condition.
+func FormatVariablesWarning(warnings *variables.VariableWarning) string {
+	if warnings == nil {
+		return ""
+	}
+	var messages []string
+	addMessages := func(elementType string, invalidRefs map[string][]string) {
+		var names []string
+		for name := range invalidRefs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			messages = append(messages, fmt.Sprintf("%s %s: %s", elementType, name, strings.Join(invalidRefs[name], ", ")))
+		}
+	}
+	addMessages("component", warnings.Components)
+	addMessages("command", warnings.Commands)
+	addMessages("project", warnings.Projects)
+	addMessages("starter project", warnings.StarterProjects)
+	return fmt.Sprintf("DevWorkspace references undefined variables: %s", strings.Join(messages, "; "))
+}
@@ -0,0 +1,294 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+// Package image contains library functions for building the images described by image components in a DevWorkspace.
+//
+// As the devfile API does not yet define image components, they are represented as custom components with component
+// class 'image', whose embedded resource describes the image:
+//
+//	components:
+//	  - name: app-image
+//	    custom:
+//	      componentClass: image
+//	      embeddedResource:
+//	        apiVersion: controller.devfile.io/v1alpha1
+//	        kind: ImageComponent
+//	        imageName: app
+//	        dockerfile:
+//	          uri: app/Dockerfile
+//	          buildContext: app
+//	          args: ["GO_VERSION=1.15"]
+//
+// Image components are built when they are referenced by an apply command. Container components whose image is the
+// image component's imageName use the built image instead.
+package image
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/devfile/devworkspace-operator/apis/controller/v1alpha1"
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+	"github.com/devfile/devworkspace-operator/pkg/library/flatten"
+)
+
+// ImageComponentClass is the component class of custom components that describe an image to build
+const ImageComponentClass = "image"
+
+const builderContainerName = "build"
+
+const (
+	// pushSecretVolumeName is the name of the volume used to mount registry credentials into the builder container
+	pushSecretVolumeName = "image-push-secret"
+	// builderDockerConfigDir is the directory the kaniko executor reads registry credentials (config.json) from
+	builderDockerConfigDir = "/kaniko/.docker"
+)
+
+// imageDigestRegexp matches the digest written by the builder container, e.g. sha256:<hex>
+var imageDigestRegexp = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+var (
+	// builderUser is the user the builder container runs as, as the kaniko executor must run as root
+	builderUser         = int64(0)
+	builderRunAsNonRoot = false
+)
+
+// ImageComponent is the content of the embedded resource of an image component
+type ImageComponent struct {
+	metav1.TypeMeta `json:",inline"`
+	// ImageName is the name of the image. Container components that use this name as their image use the built image.
+	ImageName string `json:"imageName"`
+	// Dockerfile describes how to build the image
+	Dockerfile *DockerfileImage `json:"dockerfile"`
+}
+
+// DockerfileImage describes an image built from a Dockerfile in the DevWorkspace's project sources
+type DockerfileImage struct {
+	// Uri is the path of the Dockerfile, relative to the projects root
+	Uri string `json:"uri"`
+	// BuildContext is the path of the build context, relative to the projects root. Defaults to the projects root.
+	BuildContext string `json:"buildContext,omitempty"`
+	// Args are build arguments passed to the build, in the form KEY=VALUE
+	Args []string `json:"args,omitempty"`
+}
+
+// ImageBuild is an image component that should be built when the DevWorkspace starts
+type ImageBuild struct {
+	// ComponentName is the name of the image component
+	ComponentName string `json:"componentName"`
+	// Image is the image described by the component
+	Image ImageComponent `json:"image"`
+}
+
+// IsImageComponent returns true if a component is a custom component with component class ImageComponentClass
+func IsImageComponent(component dw.Component) bool {
+	return component.Custom != nil && component.Custom.ComponentClass == ImageComponentClass
+}
+
+// GetImagesToBuild returns the image components in a DevWorkspace that are referenced by apply commands and should
+// therefore be built when the DevWorkspace starts.
+//
+// Note: Requires DevWorkspace to be flattened (i.e. the DevWorkspace contains no Parent or Components of type Plugin)
+func GetImagesToBuild(workspace *dw.DevWorkspaceTemplateSpec) ([]ImageBuild, error) {
+	if !flatten.DevWorkspaceIsFlattened(workspace) {
+		return nil, fmt.Errorf("devfile is not flattened")
+	}
+
+	appliedComponents := map[string]bool{}
+	for _, command := range workspace.Commands {
+		if command.Apply != nil {
+			appliedComponents[command.Apply.Component] = true
+		}
+	}
+
+	var builds []ImageBuild
+	imageNames := map[string]string{}
+	for _, component := range workspace.Components {
+		if !IsImageComponent(component) || !appliedComponents[component.Name] {
+			continue
+		}
+		image, err := ReadImageComponent(component)
+		if err != nil {
+			return nil, fmt.Errorf("invalid image component %s: %w", component.Name, err)
+		}
+		if other, ok := imageNames[image.ImageName]; ok {
+			return nil, fmt.Errorf("image components %s and %s both define image %s", other, component.Name, image.ImageName)
+		}
+		imageNames[image.ImageName] = component.Name
+		builds = append(builds, ImageBuild{ComponentName: component.Name, Image: *image})
+	}
+	return builds, nil
+}
+
+// GetImageReference returns the reference that an image is pushed to after it is built. The image is pushed to the
+// given registry and tagged with the DevWorkspace's ID; any tag in imageName is ignored. As the tag is reused by every
+// build for the DevWorkspace, containers should refer to the built image by digest using GetBuiltImageReference.
+func GetImageReference(registry, imageName, workspaceId string) string {
+	return fmt.Sprintf("%s/%s:%s", strings.TrimSuffix(registry, "/"), trimTag(imageName), workspaceId)
+}
+
+// GetBuiltImageReference returns the reference of the image pushed to destination by a builder container, in the form
+// <repository>@<digest>. The digest is read from the builder container's termination message; an error is returned if
+// the pod has no successfully terminated builder container or if its termination message is not a sha256 digest.
+func GetBuiltImageReference(destination string, builderPod *corev1.Pod) (string, error) {
+	for _, status := range builderPod.Status.ContainerStatuses {
+		if status.Name != builderContainerName {
+			continue
+		}
+		if status.State.Terminated == nil || status.State.Terminated.ExitCode != 0 {
+			return "", fmt.Errorf("image builder in pod %s has not completed successfully", builderPod.Name)
+		}
+		digest := strings.TrimSpace(status.State.Terminated.Message)
+		if !imageDigestRegexp.MatchString(digest) {
+			return "", fmt.Errorf("image builder in pod %s did not report a valid image digest: %q", builderPod.Name, digest)
+		}
+		return fmt.Sprintf("%s@%s", trimTag(destination), digest), nil
+	}
+	return "", fmt.Errorf("pod %s does not contain an image builder", builderPod.Name)
+}
+
+// trimTag removes the tag, if any, from an image reference
+func trimTag(image string) string {
+	if idx := strings.LastIndex(image, ":"); idx > strings.LastIndex(image, "/") {
+		return image[:idx]
+	}
+	return image
+}
+
+// GetBuilderContainer returns a container that builds an image component from the DevWorkspace's project sources and
+// pushes it to destination. The builder image must accept the arguments of the kaniko executor. The container does not
+// mount any volumes; the caller is responsible for mounting project sources at constants.DefaultProjectsSourcesRoot.
+// The digest of the pushed image is written to the container's termination message, to be read with
+// GetBuiltImageReference.
+//
+// The kaniko executor unpacks the file system of the base image over its own, so the container runs as root. Builds
+// therefore cannot run in namespaces that enforce the restricted Pod Security Standard.
+func GetBuilderContainer(build ImageBuild, builderImage, destination string, insecureRegistry bool) corev1.Container {
+	dockerfile := build.Image.Dockerfile
+	args := []string{
+		"--dockerfile=" + path.Join(constants.DefaultProjectsSourcesRoot, dockerfile.Uri),
+		"--context=dir://" + path.Join(constants.DefaultProjectsSourcesRoot, dockerfile.BuildContext),
+		"--destination=" + destination,
+		"--digest-file=" + corev1.TerminationMessagePathDefault,
+	}
+	for _, arg := range dockerfile.Args {
+		args = append(args, "--build-arg="+arg)
+	}
+	if insecureRegistry {
+		args = append(args, "--insecure", "--skip-tls-verify")
+	}
+	return corev1.Container{
+		Name:                     builderContainerName,
+		Image:                    builderImage,
+		Args:                     args,
+		ImagePullPolicy:          corev1.PullIfNotPresent,
+		TerminationMessagePath:   corev1.TerminationMessagePathDefault,
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+		SecurityContext: &corev1.SecurityContext{
+			RunAsUser:    &builderUser,
+			RunAsNonRoot: &builderRunAsNonRoot,
+		},
+	}
+}
+
+// MountPushSecret mounts a Secret of type kubernetes.io/dockerconfigjson into a builder container returned by
+// GetBuilderContainer, so that the credentials it contains are used when pushing the built image. Returns the volume
+// that must be added to the builder container's pod.
+func MountPushSecret(builderContainer *corev1.Container, secretName string) corev1.Volume {
+	builderContainer.VolumeMounts = append(builderContainer.VolumeMounts, corev1.VolumeMount{
+		Name:      pushSecretVolumeName,
+		MountPath: builderDockerConfigDir,
+		ReadOnly:  true,
+	})
+	builderContainer.Env = append(builderContainer.Env, corev1.EnvVar{
+		Name:  "DOCKER_CONFIG",
+		Value: builderDockerConfigDir,
+	})
+	return corev1.Volume{
+		Name: pushSecretVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: secretName,
+				Items: []corev1.KeyToPath{
+					{Key: corev1.DockerConfigJsonKey, Path: "config.json"},
+				},
+			},
+		},
+	}
+}
+
+// SubstituteImages replaces the image of containers and init containers in podAdditions according to builtImages, which
+// maps the imageName of image components to the reference of the built image.
+func SubstituteImages(podAdditions *v1alpha1.PodAdditions, builtImages map[string]string) {
+	for idx, container := range podAdditions.Containers {
+		if builtImage, ok := builtImages[container.Image]; ok {
+			podAdditions.Containers[idx].Image = builtImage
+		}
+	}
+	for idx, container := range podAdditions.InitContainers {
+		if builtImage, ok := builtImages[container.Image]; ok {
+			podAdditions.InitContainers[idx].Image = builtImage
+		}
+	}
+}
+
+// ReadImageComponent reads the image described by an image component's embedded resource and checks that it can be
+// built by the controller.
+func ReadImageComponent(component dw.Component) (*ImageComponent, error) {
+	raw := component.Custom.EmbeddedResource.Raw
+	if len(raw) == 0 && component.Custom.EmbeddedResource.Object != nil {
+		var err error
+		raw, err = json.Marshal(component.Custom.EmbeddedResource.Object)
+		if err != nil {
+			return nil, err
+		}
+	}
+	image := &ImageComponent{}
+	if err := json.Unmarshal(raw, image); err != nil {
+		return nil, fmt.Errorf("failed to read embedded resource: %w", err)
+	}
+	if image.ImageName == "" {
+		return nil, fmt.Errorf("imageName is required")
+	}
+	if image.Dockerfile == nil {
+		return nil, fmt.Errorf("only images built from a dockerfile are supported")
+	}
+	if image.Dockerfile.Uri == "" {
+		return nil, fmt.Errorf("dockerfile uri is required")
+	}
+	if err := checkRelativePath(image.Dockerfile.Uri); err != nil {
+		return nil, fmt.Errorf("invalid dockerfile uri: %w", err)
+	}
+	if err := checkRelativePath(image.Dockerfile.BuildContext); err != nil {
+		return nil, fmt.Errorf("invalid dockerfile buildContext: %w", err)
+	}
+	return image, nil
+}
+
+// checkRelativePath verifies that a path refers to a location within the projects root
+func checkRelativePath(location string) error {
+	if parsed, err := url.Parse(location); err == nil && parsed.Scheme != "" {
+		return fmt.Errorf("%s must be a path relative to the projects root", location)
+	}
+	if path.IsAbs(location) || strings.HasPrefix(path.Clean(location), "..") {
+		return fmt.Errorf("%s must be a path relative to the projects root", location)
+	}
+	return nil
+}
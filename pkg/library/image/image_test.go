@@ -0,0 +1,202 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package image
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/devfile/devworkspace-operator/apis/controller/v1alpha1"
+)
+
+type testCase struct {
+	Name   string                       `json:"name,omitempty"`
+	Input  *dw.DevWorkspaceTemplateSpec `json:"input,omitempty"`
+	Output testOutput                   `json:"output,omitempty"`
+}
+
+type testOutput struct {
+	Images    []ImageBuild `json:"images,omitempty"`
+	ErrRegexp *string      `json:"errRegexp,omitempty"`
+}
+
+func loadAllTestCasesOrPanic(t *testing.T, fromDir string) []testCase {
+	files, err := ioutil.ReadDir(fromDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests []testCase
+	for _, file := range files {
+		bytes, err := ioutil.ReadFile(filepath.Join(fromDir, file.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var test testCase
+		if err := yaml.Unmarshal(bytes, &test); err != nil {
+			t.Fatal(err)
+		}
+		t.Log(fmt.Sprintf("Read file:\n%+v\n\n", test))
+		tests = append(tests, test)
+	}
+	return tests
+}
+
+func TestGetImagesToBuild(t *testing.T) {
+	tests := loadAllTestCasesOrPanic(t, "testdata")
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			images, err := GetImagesToBuild(tt.Input)
+			if tt.Output.ErrRegexp != nil && assert.Error(t, err) {
+				assert.Regexp(t, *tt.Output.ErrRegexp, err.Error(), "Error message should match")
+			} else {
+				if !assert.NoError(t, err, "Should not return error") {
+					return
+				}
+				assert.Equal(t, tt.Output.Images, images, "Should build expected images")
+			}
+		})
+	}
+}
+
+func TestGetImageReference(t *testing.T) {
+	assert.Equal(t, "registry.local:5000/app:workspace1234", GetImageReference("registry.local:5000", "app", "workspace1234"))
+	assert.Equal(t, "registry.local:5000/ns/app:workspace1234", GetImageReference("registry.local:5000/", "ns/app:latest", "workspace1234"))
+}
+
+func TestGetBuiltImageReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	builderPod := func(state corev1.ContainerState) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "build-pod"},
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{{Name: "build", State: state}},
+			},
+		}
+	}
+	tests := []struct {
+		name          string
+		pod           *corev1.Pod
+		expectedImage string
+		expectedErr   string
+	}{
+		{
+			name:          "Uses digest from termination message",
+			pod:           builderPod(corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: digest + "\n"}}),
+			expectedImage: "registry.local:5000/app@" + digest,
+		},
+		{
+			name:        "Rejects builder that has not completed",
+			pod:         builderPod(corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}),
+			expectedErr: "image builder in pod build-pod has not completed successfully",
+		},
+		{
+			name:        "Rejects failed builder",
+			pod:         builderPod(corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Message: digest}}),
+			expectedErr: "image builder in pod build-pod has not completed successfully",
+		},
+		{
+			name:        "Rejects termination message that is not a digest",
+			pod:         builderPod(corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: "build logs"}}),
+			expectedErr: `image builder in pod build-pod did not report a valid image digest: "build logs"`,
+		},
+		{
+			name:        "Rejects pod without builder container",
+			pod:         &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "build-pod"}},
+			expectedErr: "pod build-pod does not contain an image builder",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builtImage, err := GetBuiltImageReference("registry.local:5000/app:workspace1234", tt.pod)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.expectedImage, builtImage)
+			}
+		})
+	}
+}
+
+func TestGetBuilderContainer(t *testing.T) {
+	build := ImageBuild{
+		ComponentName: "app-image",
+		Image: ImageComponent{
+			ImageName: "app",
+			Dockerfile: &DockerfileImage{
+				Uri:          "app/Dockerfile",
+				BuildContext: "app",
+				Args:         []string{"GO_VERSION=1.15"},
+			},
+		},
+	}
+	container := GetBuilderContainer(build, "builder:latest", "registry.local/app:workspace1234", true)
+	assert.Equal(t, "builder:latest", container.Image)
+	assert.Equal(t, []string{
+		"--dockerfile=/projects/app/Dockerfile",
+		"--context=dir:///projects/app",
+		"--destination=registry.local/app:workspace1234",
+		"--digest-file=/dev/termination-log",
+		"--build-arg=GO_VERSION=1.15",
+		"--insecure",
+		"--skip-tls-verify",
+	}, container.Args)
+	if assert.NotNil(t, container.SecurityContext, "Should set security context") {
+		assert.Equal(t, int64(0), *container.SecurityContext.RunAsUser, "Should run builder as root")
+		assert.False(t, *container.SecurityContext.RunAsNonRoot, "Should allow builder to run as root")
+	}
+}
+
+func TestMountPushSecret(t *testing.T) {
+	container := corev1.Container{
+		Name:         "build",
+		VolumeMounts: []corev1.VolumeMount{{Name: "projects", MountPath: "/projects"}},
+	}
+	volume := MountPushSecret(&container, "push-secret")
+	if assert.NotNil(t, volume.Secret, "Should use secret volume") {
+		assert.Equal(t, "push-secret", volume.Secret.SecretName)
+		assert.Equal(t, []corev1.KeyToPath{{Key: ".dockerconfigjson", Path: "config.json"}}, volume.Secret.Items,
+			"Should mount docker config as config.json")
+	}
+	assert.Equal(t, []corev1.VolumeMount{
+		{Name: "projects", MountPath: "/projects"},
+		{Name: volume.Name, MountPath: "/kaniko/.docker", ReadOnly: true},
+	}, container.VolumeMounts, "Should mount secret in kaniko docker config directory")
+	assert.Equal(t, []corev1.EnvVar{{Name: "DOCKER_CONFIG", Value: "/kaniko/.docker"}}, container.Env)
+}
+
+func TestSubstituteImages(t *testing.T) {
+	podAdditions := &v1alpha1.PodAdditions{
+		Containers: []corev1.Container{
+			{Name: "tools", Image: "app"},
+			{Name: "other", Image: "quay.io/example/other:latest"},
+		},
+		InitContainers: []corev1.Container{
+			{Name: "init", Image: "app"},
+		},
+	}
+	SubstituteImages(podAdditions, map[string]string{"app": "registry.local/app:workspace1234"})
+	assert.Equal(t, "registry.local/app:workspace1234", podAdditions.Containers[0].Image)
+	assert.Equal(t, "quay.io/example/other:latest", podAdditions.Containers[1].Image)
+	assert.Equal(t, "registry.local/app:workspace1234", podAdditions.InitContainers[0].Image)
+}
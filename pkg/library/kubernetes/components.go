@@ -0,0 +1,172 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+// Package kubernetes contains library functions for converting DevWorkspace kubernetes and openshift components into
+// the Kubernetes objects they define.
+package kubernetes
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sYaml "k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+	"github.com/devfile/devworkspace-operator/pkg/library/flatten"
+	"github.com/devfile/devworkspace-operator/pkg/library/flatten/network"
+	"github.com/devfile/devworkspace-operator/pkg/library/lifecycle"
+)
+
+// ComponentObject is a Kubernetes object defined by a kubernetes or openshift component
+type ComponentObject struct {
+	// ComponentName is the name of the component that defines the object
+	ComponentName string
+	// Object is the object defined in the component's manifest
+	Object *unstructured.Unstructured
+}
+
+// GetComponentsToDeploy returns the kubernetes and openshift components in a DevWorkspace whose resources should be
+// applied to the cluster when the DevWorkspace starts, according to constants.DeployByDefaultAttribute.
+//
+// Note: Requires DevWorkspace to be flattened (i.e. the DevWorkspace contains no Parent or Components of type Plugin)
+func GetComponentsToDeploy(workspace *dw.DevWorkspaceTemplateSpec) ([]dw.Component, error) {
+	if !flatten.DevWorkspaceIsFlattened(workspace) {
+		return nil, fmt.Errorf("devfile is not flattened")
+	}
+
+	appliedComponents := map[string]bool{}
+	for _, command := range workspace.Commands {
+		if command.Apply != nil {
+			appliedComponents[command.Apply.Component] = true
+		}
+	}
+	preStartComponents := map[string]bool{}
+	if workspace.Events != nil {
+		preStartCommands, err := lifecycle.GetCommandsForEvent(workspace.Events.PreStart, workspace.Commands)
+		if err != nil {
+			return nil, err
+		}
+		for _, command := range preStartCommands {
+			if command.Apply != nil {
+				preStartComponents[command.Apply.Component] = true
+			}
+		}
+	}
+
+	var components []dw.Component
+	for _, component := range workspace.Components {
+		if component.Kubernetes == nil && component.Openshift == nil {
+			continue
+		}
+		deploy := preStartComponents[component.Name]
+		if component.Attributes.Exists(constants.DeployByDefaultAttribute) {
+			var err error
+			deployByDefault := component.Attributes.GetBoolean(constants.DeployByDefaultAttribute, &err)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read attribute %s on component %s: %w", constants.DeployByDefaultAttribute, component.Name, err)
+			}
+			deploy = deploy || deployByDefault
+		} else {
+			deploy = deploy || !appliedComponents[component.Name]
+		}
+		if deploy {
+			components = append(components, component)
+		}
+	}
+	return components, nil
+}
+
+// GetComponentObjects parses the manifests of kubernetes and openshift components into the objects they define.
+// Manifests may be inlined or referenced by URI, in which case they are fetched using httpClient. Each manifest may
+// contain multiple YAML documents.
+func GetComponentObjects(components []dw.Component, httpClient network.HTTPGetter) ([]ComponentObject, error) {
+	var objects []ComponentObject
+	for _, component := range components {
+		var location dw.K8sLikeComponentLocation
+		switch {
+		case component.Kubernetes != nil:
+			location = component.Kubernetes.K8sLikeComponentLocation
+		case component.Openshift != nil:
+			location = component.Openshift.K8sLikeComponentLocation
+		default:
+			continue
+		}
+		manifest := location.Inlined
+		if location.Uri != "" {
+			fetched, err := fetchManifest(location.Uri, httpClient)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch manifest for component %s: %w", component.Name, err)
+			}
+			manifest = fetched
+		}
+		componentObjects, err := parseManifest(manifest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest for component %s: %w", component.Name, err)
+		}
+		if len(componentObjects) == 0 {
+			return nil, fmt.Errorf("manifest for component %s does not define any objects", component.Name)
+		}
+		for _, obj := range componentObjects {
+			objects = append(objects, ComponentObject{ComponentName: component.Name, Object: obj})
+		}
+	}
+	return objects, nil
+}
+
+func fetchManifest(uri string, httpClient network.HTTPGetter) (string, error) {
+	resp, err := httpClient.Get(uri)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("got status %d when fetching %s", resp.StatusCode, uri)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+func parseManifest(manifest string) ([]*unstructured.Unstructured, error) {
+	var objects []*unstructured.Unstructured
+	decoder := k8sYaml.NewYAMLOrJSONDecoder(bytes.NewBufferString(manifest), 4096)
+	for {
+		content := map[string]interface{}{}
+		if err := decoder.Decode(&content); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if len(content) == 0 {
+			// Empty YAML document
+			continue
+		}
+		obj := &unstructured.Unstructured{Object: content}
+		if obj.GetKind() == "" || obj.GetAPIVersion() == "" {
+			return nil, fmt.Errorf("object must define apiVersion and kind")
+		}
+		if strings.TrimSpace(obj.GetName()) == "" {
+			return nil, fmt.Errorf("%s object must define a name", obj.GetKind())
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
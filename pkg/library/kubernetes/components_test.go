@@ -0,0 +1,175 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package kubernetes
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/yaml"
+)
+
+type testCase struct {
+	Name   string                       `json:"name,omitempty"`
+	Input  *dw.DevWorkspaceTemplateSpec `json:"input,omitempty"`
+	Output testOutput                   `json:"output,omitempty"`
+}
+
+type testOutput struct {
+	// Components is the list of names of components that should be deployed
+	Components []string `json:"components,omitempty"`
+	ErrRegexp  *string  `json:"errRegexp,omitempty"`
+}
+
+type fakeHTTPGetter struct {
+	manifests map[string]string
+}
+
+func (f *fakeHTTPGetter) Get(location string) (*http.Response, error) {
+	manifest, ok := f.manifests[location]
+	if !ok {
+		return &http.Response{
+			StatusCode: http.StatusNotFound,
+			Body:       ioutil.NopCloser(bytes.NewBuffer(nil)),
+		}, nil
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(bytes.NewBufferString(manifest)),
+	}, nil
+}
+
+func loadAllTestCasesOrPanic(t *testing.T, fromDir string) []testCase {
+	files, err := ioutil.ReadDir(fromDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests []testCase
+	for _, file := range files {
+		bytes, err := ioutil.ReadFile(filepath.Join(fromDir, file.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var test testCase
+		if err := yaml.Unmarshal(bytes, &test); err != nil {
+			t.Fatal(err)
+		}
+		t.Log(fmt.Sprintf("Read file:\n%+v\n\n", test))
+		tests = append(tests, test)
+	}
+	return tests
+}
+
+func TestGetComponentsToDeploy(t *testing.T) {
+	tests := loadAllTestCasesOrPanic(t, "testdata")
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			components, err := GetComponentsToDeploy(tt.Input)
+			if tt.Output.ErrRegexp != nil && assert.Error(t, err) {
+				assert.Regexp(t, *tt.Output.ErrRegexp, err.Error(), "Error message should match")
+			} else {
+				if !assert.NoError(t, err, "Should not return error") {
+					return
+				}
+				var names []string
+				for _, component := range components {
+					names = append(names, component.Name)
+				}
+				assert.Equal(t, tt.Output.Components, names, "Should deploy expected components")
+			}
+		})
+	}
+}
+
+func TestGetComponentObjects(t *testing.T) {
+	httpClient := &fakeHTTPGetter{
+		manifests: map[string]string{
+			"https://example.com/cache.yaml": `
+apiVersion: v1
+kind: Service
+metadata:
+  name: cache
+spec:
+  ports:
+    - port: 6379
+`,
+		},
+	}
+	k8sComponent := func(name string, location dw.K8sLikeComponentLocation) dw.Component {
+		return dw.Component{
+			Name: name,
+			ComponentUnion: dw.ComponentUnion{
+				Kubernetes: &dw.KubernetesComponent{
+					K8sLikeComponent: dw.K8sLikeComponent{K8sLikeComponentLocation: location},
+				},
+			},
+		}
+	}
+
+	t.Run("Reads inlined and URI manifests", func(t *testing.T) {
+		components := []dw.Component{
+			k8sComponent("database", dw.K8sLikeComponentLocation{Inlined: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: postgres
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: postgres
+`}),
+			k8sComponent("cache", dw.K8sLikeComponentLocation{Uri: "https://example.com/cache.yaml"}),
+		}
+		objects, err := GetComponentObjects(components, httpClient)
+		if !assert.NoError(t, err, "Should not return error") {
+			return
+		}
+		if !assert.Len(t, objects, 3, "Should read all objects") {
+			return
+		}
+		assert.Equal(t, "database", objects[0].ComponentName)
+		assert.Equal(t, "Deployment", objects[0].Object.GetKind())
+		assert.Equal(t, "postgres", objects[0].Object.GetName())
+		assert.Equal(t, "database", objects[1].ComponentName)
+		assert.Equal(t, "Service", objects[1].Object.GetKind())
+		assert.Equal(t, "cache", objects[2].ComponentName)
+		assert.Equal(t, "cache", objects[2].Object.GetName())
+	})
+
+	t.Run("Returns error when manifest cannot be fetched", func(t *testing.T) {
+		components := []dw.Component{
+			k8sComponent("missing", dw.K8sLikeComponentLocation{Uri: "https://example.com/missing.yaml"}),
+		}
+		_, err := GetComponentObjects(components, httpClient)
+		if assert.Error(t, err, "Should return error") {
+			assert.Regexp(t, "failed to fetch manifest for component missing: got status 404", err.Error())
+		}
+	})
+
+	t.Run("Returns error when object has no kind", func(t *testing.T) {
+		components := []dw.Component{
+			k8sComponent("invalid", dw.K8sLikeComponentLocation{Inlined: "metadata:\n  name: test\n"}),
+		}
+		_, err := GetComponentObjects(components, httpClient)
+		if assert.Error(t, err, "Should return error") {
+			assert.Regexp(t, "failed to read manifest for component invalid: object must define apiVersion and kind", err.Error())
+		}
+	})
+}
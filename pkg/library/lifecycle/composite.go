@@ -0,0 +1,205 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package lifecycle
+
+import (
+	"fmt"
+	"strings"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+)
+
+// getEventCommands resolves the commands bound to an event, expanding composite commands into the commands they
+// contain. Sequential composite commands are expanded recursively, in order. Parallel composite commands are converted
+// into a single exec command that runs the commands they contain in parallel (see parallelCompositeToExecCommand).
+//
+// Returns the resolved commands and the IDs of all commands used, including commands within composite commands.
+func getEventCommands(keys []string, commands []dw.Command) (eventCommands []dw.Command, usedKeys []string, err error) {
+	boundCommands, err := getCommandsForKeys(keys, commands)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, command := range boundCommands {
+		expanded, expandedKeys, err := expandCommand(command, commands, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		eventCommands = append(eventCommands, expanded...)
+		usedKeys = append(usedKeys, expandedKeys...)
+	}
+	return eventCommands, usedKeys, nil
+}
+
+// GetCommandsForEvent returns the commands that are run for the command IDs bound to an event, with composite
+// commands expanded as described in getEventCommands.
+func GetCommandsForEvent(keys []string, commands []dw.Command) ([]dw.Command, error) {
+	eventCommands, _, err := getEventCommands(keys, commands)
+	return eventCommands, err
+}
+
+// expandCommand expands a command into the list of commands that should be run for it. Path contains the IDs of the
+// composite commands that contain command, and is used to detect cycles.
+func expandCommand(command dw.Command, commands []dw.Command, path []string) ([]dw.Command, []string, error) {
+	if command.Composite == nil {
+		return []dw.Command{command}, []string{command.Key()}, nil
+	}
+	path, err := appendToPath(command.Key(), path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if command.Composite.Parallel {
+		execCommand, keys, err := parallelCompositeToExecCommand(command, commands, path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []dw.Command{*execCommand}, keys, nil
+	}
+
+	expanded := []dw.Command{}
+	keys := []string{command.Key()}
+	for _, subCommandKey := range command.Composite.Commands {
+		subCommand, err := getCommandByKey(subCommandKey, commands)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid composite command %s: %w", command.Key(), err)
+		}
+		subExpanded, subKeys, err := expandCommand(*subCommand, commands, path)
+		if err != nil {
+			return nil, nil, err
+		}
+		expanded = append(expanded, subExpanded...)
+		keys = append(keys, subKeys...)
+	}
+	return expanded, keys, nil
+}
+
+// parallelCompositeToExecCommand converts a parallel composite command into an exec command, with the same ID, that
+// runs all commands within the composite command in parallel in a single shell script. As the script runs in a single
+// container, all commands must be exec commands that run in the same component. The script fails if any command fails.
+func parallelCompositeToExecCommand(command dw.Command, commands []dw.Command, path []string) (*dw.Command, []string, error) {
+	component, script, keys, err := compositeToScript(command, command.Key(), commands, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &dw.Command{
+		Id: command.Id,
+		CommandUnion: dw.CommandUnion{
+			Exec: &dw.ExecCommand{
+				Component:   component,
+				CommandLine: script,
+			},
+		},
+	}, keys, nil
+}
+
+// compositeToScript returns a shell script that runs all commands within a composite command, either in parallel or
+// in sequence depending on the composite command, along with the component all commands run in. ParallelKey is the ID
+// of the parallel composite command being converted, which may contain command. Path should already include the
+// composite command's ID.
+func compositeToScript(command dw.Command, parallelKey string, commands []dw.Command, path []string) (component, script string, keys []string, err error) {
+	var scripts []string
+	keys = []string{command.Key()}
+	for _, subCommandKey := range command.Composite.Commands {
+		subCommand, err := getCommandByKey(subCommandKey, commands)
+		if err != nil {
+			return "", "", nil, fmt.Errorf("invalid composite command %s: %w", command.Key(), err)
+		}
+		var subComponent, subScript string
+		switch {
+		case subCommand.Exec != nil:
+			subComponent = subCommand.Exec.Component
+			subScript = execCommandToScript(subCommand.Exec)
+			keys = append(keys, subCommand.Key())
+		case subCommand.Composite != nil:
+			subPath, err := appendToPath(subCommand.Key(), path)
+			if err != nil {
+				return "", "", nil, err
+			}
+			var subKeys []string
+			subComponent, subScript, subKeys, err = compositeToScript(*subCommand, parallelKey, commands, subPath)
+			if err != nil {
+				return "", "", nil, err
+			}
+			keys = append(keys, subKeys...)
+		default:
+			return "", "", nil, fmt.Errorf("parallel composite command %s may only contain exec commands, but %s is not an exec command",
+				parallelKey, subCommand.Key())
+		}
+		if component == "" {
+			component = subComponent
+		} else if subComponent != component {
+			return "", "", nil, fmt.Errorf("commands in parallel composite command %s must all run in the same component, but run in %s and %s",
+				parallelKey, component, subComponent)
+		}
+		scripts = append(scripts, subScript)
+	}
+	if len(scripts) == 0 {
+		return "", "", nil, fmt.Errorf("composite command %s does not contain any commands", command.Key())
+	}
+
+	if !command.Composite.Parallel {
+		return component, fmt.Sprintf("(%s)", strings.Join(scripts, " && ")), keys, nil
+	}
+	// Start all commands in the background and wait on each one individually to catch failures
+	var background []string
+	for _, subScript := range scripts {
+		background = append(background, fmt.Sprintf("%s & pids=\"$pids $!\"", subScript))
+	}
+	script = fmt.Sprintf("(pids=\"\"; %s; for pid in $pids; do wait $pid || exit 1; done)", strings.Join(background, "; "))
+	return component, script, keys, nil
+}
+
+// appendToPath adds a composite command's ID to the path of composite commands being expanded, returning an error if
+// the command is already in the path.
+func appendToPath(key string, path []string) ([]string, error) {
+	if listContains(key, path) {
+		return nil, fmt.Errorf("composite command %s contains itself: %s -> %s", key, strings.Join(path, " -> "), key)
+	}
+	newPath := make([]string, len(path), len(path)+1)
+	copy(newPath, path)
+	return append(newPath, key), nil
+}
+
+// CheckEventCompositeCommands checks that composite commands bound to devfile events can be run by the controller:
+// composite commands must not contain themselves, parallel composite commands must only run exec commands in a single
+// component, and all commands within a composite command must be supported for the event. Events that refer to
+// commands that do not exist are not reported, as they are covered by devfile validation.
+func CheckEventCompositeCommands(devfile dw.DevWorkspaceTemplateSpecContent) []error {
+	if devfile.Events == nil {
+		return nil
+	}
+	var errs []error
+	checkEvent := func(event string, keys []string, allowApply bool) {
+		for _, key := range keys {
+			command, err := getCommandByKey(key, devfile.Commands)
+			if err != nil || command.Composite == nil {
+				continue
+			}
+			expanded, _, err := expandCommand(*command, devfile.Commands, nil)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			for _, expandedCommand := range expanded {
+				if expandedCommand.Exec == nil && !(allowApply && expandedCommand.Apply != nil) {
+					errs = append(errs, fmt.Errorf("composite command %s contains command %s, which is not supported in the %s event",
+						key, expandedCommand.Key(), event))
+				}
+			}
+		}
+	}
+	checkEvent("preStart", devfile.Events.PreStart, true)
+	checkEvent("postStart", devfile.Events.PostStart, false)
+	checkEvent("preStop", devfile.Events.PreStop, false)
+	checkEvent("postStop", devfile.Events.PostStop, false)
+	return errs
+}
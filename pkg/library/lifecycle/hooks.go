@@ -0,0 +1,194 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package lifecycle
+
+import (
+	"fmt"
+	"strings"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+
+	devfileConstants "github.com/devfile/devworkspace-operator/pkg/library/constants"
+)
+
+// hookLogDir is the directory output of lifecycle hooks is written to. If the container mounts project sources, this is
+// in the projects volume, so that output is available from other containers in the DevWorkspace.
+const hookLogDir = "${PROJECTS_ROOT:-/tmp}/.devworkspace"
+
+// PostStartLogPath returns the path of the file that output of postStart commands is written to in a container.
+func PostStartLogPath(containerName string) string {
+	return fmt.Sprintf("%s/poststart-%s.log", hookLogDir, containerName)
+}
+
+// ResolvedPostStartLogPath returns the path PostStartLogPath refers to in a container, with PROJECTS_ROOT expanded
+// based on the container's environment. If PROJECTS_ROOT is not set in the container's spec, /tmp is used, matching the
+// fallback applied when the hook runs.
+func ResolvedPostStartLogPath(container corev1.Container) string {
+	logDir := "/tmp/.devworkspace"
+	for _, env := range container.Env {
+		if env.Name == devfileConstants.ProjectsRootEnvVar && env.Value != "" {
+			logDir = strings.TrimSuffix(env.Value, "/") + "/.devworkspace"
+		}
+	}
+	return fmt.Sprintf("%s/poststart-%s.log", logDir, container.Name)
+}
+
+// PreStopLogPath returns the path of the file that output of preStop commands is written to in a container.
+func PreStopLogPath(containerName string) string {
+	return fmt.Sprintf("%s/prestop-%s.log", hookLogDir, containerName)
+}
+
+// AddPostStartLifecycleHooks adds a postStart lifecycle hook to each container referenced by exec commands in the
+// devfile's postStart event. Commands are run in the order of the postStart event, and the hook fails as soon as any
+// command fails. Output of all commands is written to the file returned by PostStartLogPath.
+func AddPostStartLifecycleHooks(devfile dw.DevWorkspaceTemplateSpecContent, containers []corev1.Container) error {
+	if devfile.Events == nil {
+		return nil
+	}
+	return addLifecycleHooks("postStart", devfile.Events.PostStart, devfile.Commands, containers,
+		func(container *corev1.Container, handler *corev1.Handler) {
+			container.Lifecycle.PostStart = handler
+		}, PostStartLogPath)
+}
+
+// AddPreStopLifecycleHooks adds a preStop lifecycle hook to each container referenced by exec commands in the devfile's
+// preStop event. Commands are run in the order of the preStop event, and the hook stops as soon as any command fails.
+// Output of all commands is written to the file returned by PreStopLogPath.
+func AddPreStopLifecycleHooks(devfile dw.DevWorkspaceTemplateSpecContent, containers []corev1.Container) error {
+	if devfile.Events == nil {
+		return nil
+	}
+	return addLifecycleHooks("preStop", devfile.Events.PreStop, devfile.Commands, containers,
+		func(container *corev1.Container, handler *corev1.Handler) {
+			container.Lifecycle.PreStop = handler
+		}, PreStopLogPath)
+}
+
+// GetPostStopContainers returns containers that run the exec commands in the devfile's postStop event, for use in a
+// pod that is run once the DevWorkspace's deployment is stopped. Each container is a copy of the container in
+// deploymentContainers for the component referenced by the command, named after the command, that runs the command
+// line instead of the container's command. Containers are returned in the order of the postStop event.
+func GetPostStopContainers(devfile dw.DevWorkspaceTemplateSpecContent, deploymentContainers []corev1.Container) ([]corev1.Container, error) {
+	if devfile.Events == nil || len(devfile.Events.PostStop) == 0 {
+		return nil, nil
+	}
+	postStopCommands, err := getExecCommandsForEvent("postStop", devfile.Events.PostStop, devfile.Commands)
+	if err != nil {
+		return nil, err
+	}
+	var postStopContainers []corev1.Container
+	// Commands run more than once in the postStop event are suffixed to avoid conflicting container names
+	containerNames := map[string]bool{}
+	for _, command := range postStopCommands {
+		container := getContainerByName(command.Exec.Component, deploymentContainers)
+		if container == nil {
+			return nil, fmt.Errorf("postStop command %s refers to component %s, which is not a container in the main deployment",
+				command.Id, command.Exec.Component)
+		}
+		postStopContainer := container.DeepCopy()
+		postStopContainer.Name = uniqueContainerName(command.Id, containerNames)
+		postStopContainer.Command = []string{"/bin/sh", "-c"}
+		postStopContainer.Args = []string{execCommandToScript(command.Exec)}
+		postStopContainer.Ports = nil
+		postStopContainer.LivenessProbe = nil
+		postStopContainer.ReadinessProbe = nil
+		postStopContainer.StartupProbe = nil
+		postStopContainer.Lifecycle = nil
+		postStopContainers = append(postStopContainers, *postStopContainer)
+	}
+	return postStopContainers, nil
+}
+
+// addLifecycleHooks groups the exec commands for an event by the container they refer to and uses setHandler to add a
+// handler to each container that runs its commands in order, writing output to the file returned by logPath.
+func addLifecycleHooks(event string, commandKeys []string, commands []dw.Command, containers []corev1.Container,
+	setHandler func(*corev1.Container, *corev1.Handler), logPath func(string) string) error {
+	if len(commandKeys) == 0 {
+		return nil
+	}
+	eventCommands, err := getExecCommandsForEvent(event, commandKeys, commands)
+	if err != nil {
+		return err
+	}
+
+	componentScripts := map[string][]string{}
+	var componentOrder []string
+	for _, command := range eventCommands {
+		component := command.Exec.Component
+		if _, ok := componentScripts[component]; !ok {
+			componentOrder = append(componentOrder, component)
+		}
+		componentScripts[component] = append(componentScripts[component], execCommandToScript(command.Exec))
+	}
+
+	for _, component := range componentOrder {
+		container := getContainerByName(component, containers)
+		if container == nil {
+			return fmt.Errorf("%s commands refer to component %s, which is not a container in the main deployment", event, component)
+		}
+		if container.Lifecycle == nil {
+			container.Lifecycle = &corev1.Lifecycle{}
+		}
+		script := fmt.Sprintf("mkdir -p \"%s\" && { %s; } > \"%s\" 2>&1",
+			hookLogDir, strings.Join(componentScripts[component], " && "), logPath(container.Name))
+		setHandler(container, &corev1.Handler{
+			Exec: &corev1.ExecAction{
+				Command: []string{"/bin/sh", "-c", script},
+			},
+		})
+	}
+	return nil
+}
+
+func getExecCommandsForEvent(event string, commandKeys []string, commands []dw.Command) ([]dw.Command, error) {
+	eventCommands, _, err := getEventCommands(commandKeys, commands)
+	if err != nil {
+		return nil, err
+	}
+	for _, command := range eventCommands {
+		if command.Exec == nil {
+			return nil, fmt.Errorf("only exec-type commands are supported in the %s lifecycle binding", event)
+		}
+	}
+	return eventCommands, nil
+}
+
+// execCommandToScript converts an exec command to a shell snippet that runs the command line in a subshell, with the
+// command's environment variables set and in its working directory.
+func execCommandToScript(exec *dw.ExecCommand) string {
+	var parts []string
+	for _, env := range exec.Env {
+		parts = append(parts, fmt.Sprintf("export %s=%s", env.Name, shellQuote(env.Value)))
+	}
+	if exec.WorkingDir != "" {
+		// Working directory is set in the shell to allow referencing environment variables, e.g. ${PROJECTS_ROOT}
+		parts = append(parts, fmt.Sprintf("cd \"%s\"", exec.WorkingDir))
+	}
+	parts = append(parts, exec.CommandLine)
+	return fmt.Sprintf("(%s)", strings.Join(parts, " && "))
+}
+
+func getContainerByName(name string, containers []corev1.Container) *corev1.Container {
+	for idx, container := range containers {
+		if container.Name == name {
+			return &containers[idx]
+		}
+	}
+	return nil
+}
+
+// shellQuote wraps a value in single quotes so that it is used literally by the shell
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,176 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package lifecycle
+
+import (
+	"testing"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestGetPostStopContainers(t *testing.T) {
+	deploymentContainers := []corev1.Container{
+		{
+			Name:         "tools",
+			Image:        "tools-image",
+			Command:      []string{"sleep", "infinity"},
+			Env:          []corev1.EnvVar{{Name: "PROJECTS_ROOT", Value: "/projects"}},
+			VolumeMounts: []corev1.VolumeMount{{Name: "projects", MountPath: "/projects"}},
+			Ports:        []corev1.ContainerPort{{ContainerPort: 8080}},
+			Lifecycle: &corev1.Lifecycle{
+				PreStop: &corev1.Handler{Exec: &corev1.ExecAction{Command: []string{"true"}}},
+			},
+		},
+	}
+	execCommand := func(id, component, commandLine string) dw.Command {
+		return dw.Command{
+			Id: id,
+			CommandUnion: dw.CommandUnion{
+				Exec: &dw.ExecCommand{Component: component, CommandLine: commandLine},
+			},
+		}
+	}
+
+	t.Run("Creates containers for postStop commands in order", func(t *testing.T) {
+		devfile := dw.DevWorkspaceTemplateSpecContent{
+			Commands: []dw.Command{
+				execCommand("upload-cache", "tools", "./upload.sh"),
+				execCommand("flush", "tools", "./flush.sh"),
+			},
+			Events: &dw.Events{
+				DevWorkspaceEvents: dw.DevWorkspaceEvents{PostStop: []string{"flush", "upload-cache"}},
+			},
+		}
+		containers, err := GetPostStopContainers(devfile, deploymentContainers)
+		if !assert.NoError(t, err, "Should not return error") {
+			return
+		}
+		if !assert.Len(t, containers, 2, "Should return a container per command") {
+			return
+		}
+		assert.Equal(t, "flush", containers[0].Name, "Containers should be in order of postStop event")
+		assert.Equal(t, "upload-cache", containers[1].Name, "Containers should be in order of postStop event")
+		assert.Equal(t, "tools-image", containers[0].Image, "Should use image of referenced component")
+		assert.Equal(t, []string{"/bin/sh", "-c"}, containers[0].Command, "Should run command in shell")
+		assert.Equal(t, []string{"(./flush.sh)"}, containers[0].Args, "Should run command line")
+		assert.Equal(t, deploymentContainers[0].VolumeMounts, containers[0].VolumeMounts, "Should keep volume mounts")
+		assert.Equal(t, deploymentContainers[0].Env, containers[0].Env, "Should keep environment")
+		assert.Nil(t, containers[0].Ports, "Should remove ports")
+		assert.Nil(t, containers[0].Lifecycle, "Should remove lifecycle hooks")
+		assert.Equal(t, []string{"sleep", "infinity"}, deploymentContainers[0].Command, "Should not modify deployment containers")
+	})
+
+	t.Run("Uses distinct names for commands run more than once", func(t *testing.T) {
+		devfile := dw.DevWorkspaceTemplateSpecContent{
+			Commands: []dw.Command{
+				execCommand("flush", "tools", "./flush.sh"),
+				{
+					Id: "cleanup",
+					CommandUnion: dw.CommandUnion{
+						Composite: &dw.CompositeCommand{Commands: []string{"flush", "flush"}},
+					},
+				},
+			},
+			Events: &dw.Events{
+				DevWorkspaceEvents: dw.DevWorkspaceEvents{PostStop: []string{"flush", "cleanup"}},
+			},
+		}
+		containers, err := GetPostStopContainers(devfile, deploymentContainers)
+		if !assert.NoError(t, err, "Should not return error") {
+			return
+		}
+		var names []string
+		for _, container := range containers {
+			names = append(names, container.Name)
+		}
+		assert.Equal(t, []string{"flush", "flush-2", "flush-3"}, names, "Should suffix names of repeated commands")
+	})
+
+	t.Run("Returns error for non-exec commands", func(t *testing.T) {
+		devfile := dw.DevWorkspaceTemplateSpecContent{
+			Commands: []dw.Command{
+				{
+					Id: "apply-command",
+					CommandUnion: dw.CommandUnion{
+						Apply: &dw.ApplyCommand{Component: "tools"},
+					},
+				},
+			},
+			Events: &dw.Events{
+				DevWorkspaceEvents: dw.DevWorkspaceEvents{PostStop: []string{"apply-command"}},
+			},
+		}
+		_, err := GetPostStopContainers(devfile, deploymentContainers)
+		if assert.Error(t, err, "Should return error") {
+			assert.Regexp(t, "only exec-type commands are supported in the postStop lifecycle binding", err.Error())
+		}
+	})
+
+	t.Run("Returns error for commands referring to unknown containers", func(t *testing.T) {
+		devfile := dw.DevWorkspaceTemplateSpecContent{
+			Commands: []dw.Command{execCommand("flush", "other", "./flush.sh")},
+			Events: &dw.Events{
+				DevWorkspaceEvents: dw.DevWorkspaceEvents{PostStop: []string{"flush"}},
+			},
+		}
+		_, err := GetPostStopContainers(devfile, deploymentContainers)
+		if assert.Error(t, err, "Should return error") {
+			assert.Regexp(t, "refers to component other, which is not a container in the main deployment", err.Error())
+		}
+	})
+}
+
+func TestResolvedPostStartLogPath(t *testing.T) {
+	tests := []struct {
+		name         string
+		container    corev1.Container
+		expectedPath string
+	}{
+		{
+			name: "Uses PROJECTS_ROOT from container environment",
+			container: corev1.Container{
+				Name: "tools",
+				Env:  []corev1.EnvVar{{Name: "PROJECTS_ROOT", Value: "/projects"}},
+			},
+			expectedPath: "/projects/.devworkspace/poststart-tools.log",
+		},
+		{
+			name: "Ignores trailing slash in PROJECTS_ROOT",
+			container: corev1.Container{
+				Name: "tools",
+				Env:  []corev1.EnvVar{{Name: "PROJECTS_ROOT", Value: "/projects/"}},
+			},
+			expectedPath: "/projects/.devworkspace/poststart-tools.log",
+		},
+		{
+			name:         "Falls back to /tmp when PROJECTS_ROOT is not set",
+			container:    corev1.Container{Name: "tools"},
+			expectedPath: "/tmp/.devworkspace/poststart-tools.log",
+		},
+		{
+			name: "Falls back to /tmp when PROJECTS_ROOT is empty",
+			container: corev1.Container{
+				Name: "tools",
+				Env:  []corev1.EnvVar{{Name: "PROJECTS_ROOT", Value: ""}},
+			},
+			expectedPath: "/tmp/.devworkspace/poststart-tools.log",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedPath, ResolvedPostStartLogPath(tt.container))
+		})
+	}
+}
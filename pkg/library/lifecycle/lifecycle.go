@@ -21,6 +21,15 @@ import (
 // GetInitContainers partitions the components in a devfile's flattened spec into initContainer and non-initContainer lists
 // based off devfile lifecycle bindings and commands. Note that a component can appear in both lists, if e.g. it referred to
 // in a preStart command and in a regular command.
+//
+// Components referenced by apply commands in the preStart event are used as init containers directly. For exec commands
+// in the preStart event, an init container is created from the referenced component that runs the command line (in the
+// command's working directory) instead of the component's command; the referenced component itself remains in the
+// main deployment. Init containers are returned in the order of the preStart event.
+//
+// Composite commands in the preStart event are expanded into the commands they contain: sequential composite commands
+// result in an init container per command, while parallel composite commands result in a single init container that
+// runs all commands in parallel.
 func GetInitContainers(devfile dw.DevWorkspaceTemplateSpecContent) (initContainers, mainComponents []dw.Component, err error) {
 	components := devfile.Components
 	commands := devfile.Commands
@@ -30,7 +39,7 @@ func GetInitContainers(devfile dw.DevWorkspaceTemplateSpecContent) (initContaine
 		return nil, components, nil
 	}
 
-	initCommands, err := getCommandsForKeys(events.PreStart, commands)
+	initCommands, initCommandKeys, err := getEventCommands(events.PreStart, commands)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -38,15 +47,44 @@ func GetInitContainers(devfile dw.DevWorkspaceTemplateSpecContent) (initContaine
 	if err = checkPreStartEventCommandsValidity(initCommands); err != nil {
 		return nil, nil, err
 	}
-	initComponentKeys, err := commandListToComponentKeys(initCommands)
-	if err != nil {
-		return nil, nil, err
+	initComponentKeys := map[string]bool{}
+	// Commands may be run more than once, e.g. if bound to the preStart event both directly and through a composite
+	// command; init containers for these commands are suffixed to avoid conflicting names
+	initContainerNames := map[string]bool{}
+	for _, component := range components {
+		initContainerNames[component.Key()] = true
+	}
+	for _, command := range initCommands {
+		switch {
+		case command.Apply != nil:
+			component, err := getComponentByKey(command.Apply.Component, components)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid preStart command %s: %w", command.Key(), err)
+			}
+			if component.Container == nil {
+				// Other components (e.g. kubernetes components) are applied to the cluster rather than run as init
+				// containers
+				continue
+			}
+			if !initComponentKeys[component.Key()] {
+				initContainers = append(initContainers, *component)
+				initComponentKeys[component.Key()] = true
+			}
+		case command.Exec != nil:
+			initComponent, err := execCommandToInitComponent(command, components)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid preStart command %s: %w", command.Key(), err)
+			}
+			initComponent.Name = uniqueContainerName(initComponent.Name, initContainerNames)
+			initContainers = append(initContainers, *initComponent)
+		}
 	}
 
 	// Need to also consider components that are *both* init containers and in the main deployment
 	// Example: component is referenced in both a prestart event and a regular, non-prestart command
-	// TODO: Figure out details of handling postStop commands, since they should not be included in main deployment
-	nonInitCommands, err := removeCommandsByKeys(events.PreStart, commands)
+	// Commands bound to postStop events are run in a separate pod once the main deployment is stopped (see
+	// GetPostStopContainers), but still refer to components in the main deployment.
+	nonInitCommands, err := removeCommandsByKeys(initCommandKeys, commands)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -58,7 +96,6 @@ func GetInitContainers(devfile dw.DevWorkspaceTemplateSpecContent) (initContaine
 	for _, component := range components {
 		componentID := component.Key()
 		if initComponentKeys[componentID] {
-			initContainers = append(initContainers, component)
 			if mainComponentKeys[componentID] {
 				// Component is *also* a main component.
 				mainComponents = append(mainComponents, component)
@@ -78,12 +115,11 @@ func checkPreStartEventCommandsValidity(initCommands []dw.Command) error {
 			return err
 		}
 		switch commandType {
-		case dw.ApplyCommandType:
+		case dw.ApplyCommandType, dw.ExecCommandType:
 			continue
 		default:
-			// How a prestart exec command should be implemented is undefined currently, so we reject it.
 			// Other types of commands cannot be included in the preStart event hook.
-			return fmt.Errorf("only apply-type commands are supported in the prestart lifecycle binding")
+			return fmt.Errorf("only apply-type and exec-type commands are supported in the prestart lifecycle binding")
 		}
 	}
 	return nil
@@ -55,6 +55,14 @@ func TestGetInitContainers(t *testing.T) {
 		loadTestCaseOrPanic(t, "prestart_exec_command.yaml"),
 		loadTestCaseOrPanic(t, "prestart_apply_command.yaml"),
 		loadTestCaseOrPanic(t, "init_and_main_container.yaml"),
+		loadTestCaseOrPanic(t, "prestart_apply_and_exec_commands.yaml"),
+		loadTestCaseOrPanic(t, "prestart_exec_non_container.yaml"),
+		loadTestCaseOrPanic(t, "prestart_composite_command.yaml"),
+		loadTestCaseOrPanic(t, "prestart_parallel_composite_command.yaml"),
+		loadTestCaseOrPanic(t, "prestart_composite_cycle.yaml"),
+		loadTestCaseOrPanic(t, "prestart_parallel_composite_multiple_components.yaml"),
+		loadTestCaseOrPanic(t, "prestart_apply_kubernetes_component.yaml"),
+		loadTestCaseOrPanic(t, "prestart_duplicate_exec_commands.yaml"),
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,86 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package lifecycle
+
+import (
+	"fmt"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+)
+
+// execCommandToInitComponent creates a container component that runs an exec command from the preStart event. The
+// component is a copy of the component referenced by the command, named after the command, that runs the command line
+// in a shell in the command's working directory. Endpoints are removed, as init containers do not serve traffic.
+func execCommandToInitComponent(command dw.Command, components []dw.Component) (*dw.Component, error) {
+	exec := command.Exec
+	component, err := getComponentByKey(exec.Component, components)
+	if err != nil {
+		return nil, err
+	}
+	if component.Container == nil {
+		return nil, fmt.Errorf("component %s is not a container component", exec.Component)
+	}
+	if _, err := getComponentByKey(command.Id, components); err == nil {
+		return nil, fmt.Errorf("init container for command would conflict with component %s", command.Id)
+	}
+
+	initComponent := component.DeepCopy()
+	initComponent.Name = command.Id
+	initContainer := initComponent.Container
+	initContainer.Endpoints = nil
+	initContainer.Command = []string{"/bin/sh", "-c"}
+	script := exec.CommandLine
+	if exec.WorkingDir != "" {
+		// Working directory is set in the shell to allow referencing environment variables, e.g. ${PROJECTS_ROOT}
+		script = fmt.Sprintf("cd \"%s\" && %s", exec.WorkingDir, exec.CommandLine)
+	}
+	initContainer.Args = []string{script}
+	initContainer.Env = mergeEnv(initContainer.Env, exec.Env)
+
+	return initComponent, nil
+}
+
+// uniqueContainerName returns name, or name suffixed with an index if it is already in usedNames, and adds the returned
+// name to usedNames.
+func uniqueContainerName(name string, usedNames map[string]bool) string {
+	uniqueName := name
+	for idx := 2; usedNames[uniqueName]; idx++ {
+		uniqueName = fmt.Sprintf("%s-%d", name, idx)
+	}
+	usedNames[uniqueName] = true
+	return uniqueName
+}
+
+func getComponentByKey(key string, components []dw.Component) (*dw.Component, error) {
+	for _, component := range components {
+		if component.Key() == key {
+			return &component, nil
+		}
+	}
+	return nil, fmt.Errorf("no component with name %s is defined", key)
+}
+
+// mergeEnv returns the environment variables in base, with variables in overrides replacing variables with the same name
+func mergeEnv(base, overrides []dw.EnvVar) []dw.EnvVar {
+	var merged []dw.EnvVar
+	overridden := map[string]bool{}
+	for _, env := range overrides {
+		overridden[env.Name] = true
+	}
+	for _, env := range base {
+		if !overridden[env.Name] {
+			merged = append(merged, env)
+		}
+	}
+	return append(merged, overrides...)
+}
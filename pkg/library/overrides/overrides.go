@@ -0,0 +1,223 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+// Package overrides contains library functions for reading the overrides a DevWorkspace defines for its pods and
+// containers, through the constants.PodOverridesAttribute attribute on the DevWorkspace and the
+// constants.ContainerOverridesAttribute attribute on container components. Currently, only security contexts can be
+// overridden:
+//
+//	attributes:
+//	  controller.devfile.io/pod-overrides:
+//	    spec:
+//	      securityContext:
+//	        fsGroup: 2000
+//	components:
+//	  - name: tools
+//	    attributes:
+//	      controller.devfile.io/container-overrides:
+//	        securityContext:
+//	          readOnlyRootFilesystem: true
+//	    container:
+//	      image: quay.io/example/tools
+//
+// Fields set in an override replace the corresponding fields of the security context provisioned by the controller.
+// Only fields that are allowed by the controller's configuration may be overridden.
+package overrides
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	"github.com/devfile/api/v2/pkg/attributes"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+)
+
+// PodOverrides is the content of the constants.PodOverridesAttribute attribute
+type PodOverrides struct {
+	Spec PodSpecOverrides `json:"spec,omitempty"`
+}
+
+// PodSpecOverrides are the fields of a DevWorkspace's PodSpec that may be overridden
+type PodSpecOverrides struct {
+	SecurityContext *corev1.PodSecurityContext `json:"securityContext,omitempty"`
+}
+
+// ContainerOverrides is the content of the constants.ContainerOverridesAttribute attribute
+type ContainerOverrides struct {
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
+}
+
+// GetPodOverrides reads the constants.PodOverridesAttribute attribute of a DevWorkspace. Returns nil if the attribute
+// is not set.
+func GetPodOverrides(workspace *dw.DevWorkspaceTemplateSpec) (*PodOverrides, error) {
+	if !workspace.Attributes.Exists(constants.PodOverridesAttribute) {
+		return nil, nil
+	}
+	podOverrides := &PodOverrides{}
+	if err := readAttribute(workspace.Attributes, constants.PodOverridesAttribute, podOverrides); err != nil {
+		return nil, err
+	}
+	return podOverrides, nil
+}
+
+// GetContainerOverrides reads the constants.ContainerOverridesAttribute attribute of a component. Returns nil if the
+// attribute is not set.
+func GetContainerOverrides(component dw.Component) (*ContainerOverrides, error) {
+	if !component.Attributes.Exists(constants.ContainerOverridesAttribute) {
+		return nil, nil
+	}
+	containerOverrides := &ContainerOverrides{}
+	if err := readAttribute(component.Attributes, constants.ContainerOverridesAttribute, containerOverrides); err != nil {
+		return nil, err
+	}
+	return containerOverrides, nil
+}
+
+// CheckAllowedOverrides verifies that the security context overrides defined by a DevWorkspace and its container
+// components only set fields in allowedFields, which are the JSON names of fields in a pod or container security
+// context, e.g. 'runAsUser'.
+func CheckAllowedOverrides(workspace *dw.DevWorkspaceTemplateSpec, allowedFields []string) error {
+	allowed := map[string]bool{}
+	for _, field := range allowedFields {
+		allowed[field] = true
+	}
+	checkFields := func(element string, securityContext interface{}) error {
+		fields, err := getSetFields(securityContext)
+		if err != nil {
+			return err
+		}
+		var disallowed []string
+		for _, field := range fields {
+			if !allowed[field] {
+				disallowed = append(disallowed, field)
+			}
+		}
+		if len(disallowed) > 0 {
+			return fmt.Errorf("%s overrides security context fields that are not allowed: %s", element, strings.Join(disallowed, ", "))
+		}
+		return nil
+	}
+
+	podOverrides, err := GetPodOverrides(workspace)
+	if err != nil {
+		return err
+	}
+	if podOverrides != nil && podOverrides.Spec.SecurityContext != nil {
+		if err := checkFields("DevWorkspace", podOverrides.Spec.SecurityContext); err != nil {
+			return err
+		}
+	}
+	for _, component := range workspace.Components {
+		if component.Container == nil {
+			continue
+		}
+		containerOverrides, err := GetContainerOverrides(component)
+		if err != nil {
+			return fmt.Errorf("component %s: %w", component.Name, err)
+		}
+		if containerOverrides != nil && containerOverrides.SecurityContext != nil {
+			if err := checkFields(fmt.Sprintf("component %s", component.Name), containerOverrides.SecurityContext); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// MergePodSecurityContext returns a copy of base where fields that are set in override replace those in base.
+func MergePodSecurityContext(base, override *corev1.PodSecurityContext) (*corev1.PodSecurityContext, error) {
+	if override == nil {
+		return base, nil
+	}
+	merged := &corev1.PodSecurityContext{}
+	if err := mergeFields(base, override, merged); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// MergeContainerSecurityContext returns a copy of base where fields that are set in override replace those in base.
+func MergeContainerSecurityContext(base, override *corev1.SecurityContext) (*corev1.SecurityContext, error) {
+	if override == nil {
+		return base, nil
+	}
+	merged := &corev1.SecurityContext{}
+	if err := mergeFields(base, override, merged); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// readAttribute reads an attribute into a struct, failing if the attribute contains fields the struct does not define
+func readAttribute(attrs attributes.Attributes, key string, into interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(attrs[key].Raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(into); err != nil {
+		return fmt.Errorf("failed to read attribute %s: %w", key, err)
+	}
+	return nil
+}
+
+// getSetFields returns the sorted JSON names of the top-level fields that are set in obj
+func getSetFields(obj interface{}) ([]string, error) {
+	fields, err := toFieldMap(obj)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// mergeFields overlays the top-level fields that are set in override onto those in base, and stores the result in into
+func mergeFields(base, override, into interface{}) error {
+	merged, err := toFieldMap(base)
+	if err != nil {
+		return err
+	}
+	overrideFields, err := toFieldMap(override)
+	if err != nil {
+		return err
+	}
+	for name, value := range overrideFields {
+		merged[name] = value
+	}
+	mergedBytes, err := json.Marshal(merged)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(mergedBytes, into)
+}
+
+func toFieldMap(obj interface{}) (map[string]json.RawMessage, error) {
+	fields := map[string]json.RawMessage{}
+	objBytes, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	if string(objBytes) == "null" {
+		return fields, nil
+	}
+	if err := json.Unmarshal(objBytes, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
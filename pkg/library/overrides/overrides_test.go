@@ -0,0 +1,207 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package overrides
+
+import (
+	"testing"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	"github.com/devfile/api/v2/pkg/attributes"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+)
+
+func TestCheckAllowedOverrides(t *testing.T) {
+	tests := []struct {
+		name               string
+		podOverrides       string
+		containerOverrides string
+		allowedFields      []string
+		expectedErr        string
+	}{
+		{
+			name:          "Allows DevWorkspace without overrides",
+			allowedFields: nil,
+		},
+		{
+			name:          "Allows pod overrides of allowed fields",
+			podOverrides:  `{"spec": {"securityContext": {"fsGroup": 2000, "runAsUser": 1000}}}`,
+			allowedFields: []string{"fsGroup", "runAsUser"},
+		},
+		{
+			name:               "Allows container overrides of allowed fields",
+			containerOverrides: `{"securityContext": {"readOnlyRootFilesystem": true}}`,
+			allowedFields:      []string{"readOnlyRootFilesystem"},
+		},
+		{
+			name:          "Allows pod overrides without security context",
+			podOverrides:  `{"spec": {}}`,
+			allowedFields: nil,
+		},
+		{
+			name:          "Rejects pod overrides of fields that are not allowed",
+			podOverrides:  `{"spec": {"securityContext": {"runAsUser": 0, "fsGroup": 2000, "runAsGroup": 0}}}`,
+			allowedFields: []string{"fsGroup"},
+			expectedErr:   "DevWorkspace overrides security context fields that are not allowed: runAsGroup, runAsUser",
+		},
+		{
+			name:               "Rejects container overrides of fields that are not allowed",
+			containerOverrides: `{"securityContext": {"privileged": true}}`,
+			allowedFields:      []string{"readOnlyRootFilesystem"},
+			expectedErr:        "component tools overrides security context fields that are not allowed: privileged",
+		},
+		{
+			name:          "Rejects pod overrides of unsupported fields",
+			podOverrides:  `{"spec": {"hostNetwork": true}}`,
+			allowedFields: nil,
+			expectedErr:   `failed to read attribute controller.devfile.io/pod-overrides: json: unknown field "hostNetwork"`,
+		},
+		{
+			name:               "Rejects container overrides of unsupported fields",
+			containerOverrides: `{"image": "quay.io/example/other"}`,
+			allowedFields:      nil,
+			expectedErr:        `component tools: failed to read attribute controller.devfile.io/container-overrides: json: unknown field "image"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspace := &dw.DevWorkspaceTemplateSpec{
+				DevWorkspaceTemplateSpecContent: dw.DevWorkspaceTemplateSpecContent{
+					Components: []dw.Component{
+						{
+							Name: "tools",
+							ComponentUnion: dw.ComponentUnion{
+								Container: &dw.ContainerComponent{},
+							},
+						},
+					},
+				},
+			}
+			if tt.podOverrides != "" {
+				workspace.Attributes = attributes.Attributes{
+					constants.PodOverridesAttribute: apiext.JSON{Raw: []byte(tt.podOverrides)},
+				}
+			}
+			if tt.containerOverrides != "" {
+				workspace.Components[0].Attributes = attributes.Attributes{
+					constants.ContainerOverridesAttribute: apiext.JSON{Raw: []byte(tt.containerOverrides)},
+				}
+			}
+			err := CheckAllowedOverrides(workspace, tt.allowedFields)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestMergePodSecurityContext(t *testing.T) {
+	user := int64(1234)
+	overrideUser := int64(2000)
+	group := int64(0)
+	fsGroup := int64(3000)
+	nonRoot := true
+	tests := []struct {
+		name     string
+		base     *corev1.PodSecurityContext
+		override *corev1.PodSecurityContext
+		expected *corev1.PodSecurityContext
+	}{
+		{
+			name:     "Returns base when there is no override",
+			base:     &corev1.PodSecurityContext{RunAsUser: &user},
+			override: nil,
+			expected: &corev1.PodSecurityContext{RunAsUser: &user},
+		},
+		{
+			name:     "Uses override when there is no base",
+			base:     nil,
+			override: &corev1.PodSecurityContext{FSGroup: &fsGroup},
+			expected: &corev1.PodSecurityContext{FSGroup: &fsGroup},
+		},
+		{
+			name:     "Fields set in override replace fields in base",
+			base:     &corev1.PodSecurityContext{RunAsUser: &user, RunAsGroup: &group, RunAsNonRoot: &nonRoot},
+			override: &corev1.PodSecurityContext{RunAsUser: &overrideUser, FSGroup: &fsGroup},
+			expected: &corev1.PodSecurityContext{RunAsUser: &overrideUser, RunAsGroup: &group, RunAsNonRoot: &nonRoot, FSGroup: &fsGroup},
+		},
+		{
+			name:     "Empty override keeps base",
+			base:     &corev1.PodSecurityContext{RunAsUser: &user},
+			override: &corev1.PodSecurityContext{},
+			expected: &corev1.PodSecurityContext{RunAsUser: &user},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, err := MergePodSecurityContext(tt.base, tt.override)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.expected, merged)
+			}
+		})
+	}
+}
+
+func TestMergeContainerSecurityContext(t *testing.T) {
+	noEscalation := false
+	readOnly := true
+	user := int64(2000)
+	tests := []struct {
+		name     string
+		base     *corev1.SecurityContext
+		override *corev1.SecurityContext
+		expected *corev1.SecurityContext
+	}{
+		{
+			name:     "Returns base when there is no override",
+			base:     &corev1.SecurityContext{AllowPrivilegeEscalation: &noEscalation},
+			override: nil,
+			expected: &corev1.SecurityContext{AllowPrivilegeEscalation: &noEscalation},
+		},
+		{
+			name:     "Uses override when there is no base",
+			base:     nil,
+			override: &corev1.SecurityContext{ReadOnlyRootFilesystem: &readOnly},
+			expected: &corev1.SecurityContext{ReadOnlyRootFilesystem: &readOnly},
+		},
+		{
+			name: "Fields set in override replace fields in base",
+			base: &corev1.SecurityContext{
+				AllowPrivilegeEscalation: &noEscalation,
+				Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+			},
+			override: &corev1.SecurityContext{
+				RunAsUser:    &user,
+				Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"NET_BIND_SERVICE"}},
+			},
+			expected: &corev1.SecurityContext{
+				AllowPrivilegeEscalation: &noEscalation,
+				RunAsUser:                &user,
+				Capabilities:             &corev1.Capabilities{Add: []corev1.Capability{"NET_BIND_SERVICE"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, err := MergeContainerSecurityContext(tt.base, tt.override)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.expected, merged)
+			}
+		})
+	}
+}
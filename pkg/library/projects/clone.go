@@ -14,18 +14,26 @@
 package projects
 
 import (
+	"math"
+	"strconv"
+
 	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	"k8s.io/apimachinery/pkg/api/resource"
 
 	"github.com/devfile/devworkspace-operator/internal/images"
+	"github.com/devfile/devworkspace-operator/pkg/config"
 	"github.com/devfile/devworkspace-operator/pkg/constants"
 )
 
 const (
-	projectClonerContainerName = "project-clone"
+	ProjectClonerContainerName = "project-clone"
 	projectClonerCommandID     = "clone-projects"
 )
 
-func AddProjectClonerComponent(workspace *dw.DevWorkspaceTemplateSpec) {
+// AddProjectClonerComponent adds an init container that clones the DevWorkspace's projects. As the container is added by
+// the controller rather than the DevWorkspace, its resources are reduced where necessary to satisfy the maximums in
+// resourcePolicy instead of causing the DevWorkspace to fail.
+func AddProjectClonerComponent(workspace *dw.DevWorkspaceTemplateSpec, resourcePolicy config.ContainerResourcePolicy) {
 	if len(workspace.Projects) == 0 {
 		return
 	}
@@ -33,7 +41,7 @@ func AddProjectClonerComponent(workspace *dw.DevWorkspaceTemplateSpec) {
 	if cloneImage == "" {
 		return
 	}
-	container := getProjectClonerContainer(cloneImage)
+	container := getProjectClonerContainer(cloneImage, resourcePolicy)
 	command := getProjectClonerCommand()
 	workspace.Components = append(workspace.Components, *container)
 	workspace.Commands = append(workspace.Commands, *command)
@@ -43,18 +51,24 @@ func AddProjectClonerComponent(workspace *dw.DevWorkspaceTemplateSpec) {
 	workspace.Events.PreStart = append(workspace.Events.PreStart, projectClonerCommandID)
 }
 
-func getProjectClonerContainer(projectCloneImage string) *dw.Component {
+func getProjectClonerContainer(projectCloneImage string, resourcePolicy config.ContainerResourcePolicy) *dw.Component {
 	boolTrue := true
+	var maxRatio float64
+	if ratio, err := strconv.ParseFloat(resourcePolicy.MaxLimitRequestRatio, 64); err == nil {
+		maxRatio = ratio
+	}
+	memoryLimit, memoryRequest := clampResources(constants.ProjectCloneMemoryLimit, constants.ProjectCloneMemoryRequest, resourcePolicy.MaxMemoryLimit, maxRatio)
+	cpuLimit, cpuRequest := clampResources(constants.ProjectCloneCPULimit, constants.ProjectCloneCPURequest, resourcePolicy.MaxCpuLimit, maxRatio)
 	return &dw.Component{
-		Name: projectClonerContainerName,
+		Name: ProjectClonerContainerName,
 		ComponentUnion: dw.ComponentUnion{
 			Container: &dw.ContainerComponent{
 				Container: dw.Container{
 					Image:         projectCloneImage,
-					MemoryLimit:   constants.ProjectCloneMemoryLimit,
-					MemoryRequest: constants.ProjectCloneMemoryRequest,
-					CpuLimit:      constants.ProjectCloneCPULimit,
-					CpuRequest:    constants.ProjectCloneCPURequest,
+					MemoryLimit:   memoryLimit,
+					MemoryRequest: memoryRequest,
+					CpuLimit:      cpuLimit,
+					CpuRequest:    cpuRequest,
 					MountSources:  &boolTrue,
 				},
 			},
@@ -67,8 +81,25 @@ func getProjectClonerCommand() *dw.Command {
 		Id: projectClonerCommandID,
 		CommandUnion: dw.CommandUnion{
 			Apply: &dw.ApplyCommand{
-				Component: projectClonerContainerName,
+				Component: ProjectClonerContainerName,
 			},
 		},
 	}
 }
+
+// clampResources reduces a limit to at most maxLimit and a request to at most the limit, and raises the request if
+// necessary so that the ratio of limit to request does not exceed maxRatio. Empty or invalid maximums are ignored.
+func clampResources(limit, request, maxLimit string, maxRatio float64) (clampedLimit, clampedRequest string) {
+	limitQuantity, requestQuantity := resource.MustParse(limit), resource.MustParse(request)
+	if maxLimitQuantity, err := resource.ParseQuantity(maxLimit); err == nil && limitQuantity.Cmp(maxLimitQuantity) > 0 {
+		limitQuantity = maxLimitQuantity
+	}
+	if requestQuantity.Cmp(limitQuantity) > 0 {
+		requestQuantity = limitQuantity
+	}
+	if maxRatio > 0 && float64(limitQuantity.MilliValue()) > maxRatio*float64(requestQuantity.MilliValue()) {
+		minRequest := int64(math.Ceil(float64(limitQuantity.MilliValue()) / maxRatio))
+		requestQuantity = *resource.NewMilliQuantity(minRequest, requestQuantity.Format)
+	}
+	return limitQuantity.String(), requestQuantity.String()
+}
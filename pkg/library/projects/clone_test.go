@@ -0,0 +1,125 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package projects
+
+import (
+	"testing"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/devfile/devworkspace-operator/pkg/config"
+	"github.com/devfile/devworkspace-operator/pkg/library/container"
+)
+
+func TestProjectClonerContainerResources(t *testing.T) {
+	tests := []struct {
+		name            string
+		policy          config.ContainerResourcePolicy
+		expectedLimits  corev1.ResourceList
+		expectedRequest corev1.ResourceList
+	}{
+		{
+			name: "Uses default resources when policy has no maximums",
+			expectedLimits: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+				corev1.ResourceCPU:    resource.MustParse("1000m"),
+			},
+			expectedRequest: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("128Mi"),
+				corev1.ResourceCPU:    resource.MustParse("100m"),
+			},
+		},
+		{
+			name: "Reduces limits to maximums",
+			policy: config.ContainerResourcePolicy{
+				MaxMemoryLimit: "512Mi",
+				MaxCpuLimit:    "500m",
+			},
+			expectedLimits: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("512Mi"),
+				corev1.ResourceCPU:    resource.MustParse("500m"),
+			},
+			expectedRequest: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("128Mi"),
+				corev1.ResourceCPU:    resource.MustParse("100m"),
+			},
+		},
+		{
+			name: "Reduces requests when maximums are below default requests",
+			policy: config.ContainerResourcePolicy{
+				MaxMemoryLimit: "64Mi",
+				MaxCpuLimit:    "50m",
+			},
+			expectedLimits: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("64Mi"),
+				corev1.ResourceCPU:    resource.MustParse("50m"),
+			},
+			expectedRequest: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("64Mi"),
+				corev1.ResourceCPU:    resource.MustParse("50m"),
+			},
+		},
+		{
+			name: "Raises requests to satisfy maximum limit to request ratio",
+			policy: config.ContainerResourcePolicy{
+				MaxMemoryLimit:       "512Mi",
+				MaxCpuLimit:          "500m",
+				MaxLimitRequestRatio: "2",
+			},
+			expectedLimits: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("512Mi"),
+				corev1.ResourceCPU:    resource.MustParse("500m"),
+			},
+			expectedRequest: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("256Mi"),
+				corev1.ResourceCPU:    resource.MustParse("250m"),
+			},
+		},
+	}
+
+	config.SetupConfigForTesting(&corev1.ConfigMap{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspace := &dw.DevWorkspaceTemplateSpec{
+				DevWorkspaceTemplateSpecContent: dw.DevWorkspaceTemplateSpecContent{
+					Components: []dw.Component{*getProjectClonerContainer("test-image", tt.policy)},
+					Commands:   []dw.Command{*getProjectClonerCommand()},
+					Events: &dw.Events{
+						DevWorkspaceEvents: dw.DevWorkspaceEvents{
+							PreStart: []string{projectClonerCommandID},
+						},
+					},
+				},
+			}
+			podAdditions, err := container.GetKubeContainersFromDevfile(workspace, tt.policy)
+			if !assert.NoError(t, err, "Project clone container should satisfy resource policy") {
+				return
+			}
+			if !assert.Len(t, podAdditions.InitContainers, 1, "Should add project clone init container") {
+				return
+			}
+			resources := podAdditions.InitContainers[0].Resources
+			for name, expected := range tt.expectedLimits {
+				actual := resources.Limits[name]
+				assert.Zero(t, expected.Cmp(actual), "Unexpected %s limit: expected %s, got %s", name, expected.String(), actual.String())
+			}
+			for name, expected := range tt.expectedRequest {
+				actual := resources.Requests[name]
+				assert.Zero(t, expected.Cmp(actual), "Unexpected %s request: expected %s, got %s", name, expected.String(), actual.String())
+			}
+		})
+	}
+}
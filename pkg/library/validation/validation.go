@@ -0,0 +1,411 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+// Package validation checks a DevWorkspace for problems that would prevent it from starting. All problems found are
+// reported at once, rather than failing on the first one.
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	devfilevalidation "github.com/devfile/api/v2/pkg/validation"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/devfile/devworkspace-operator/pkg/config"
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+	devfileConstants "github.com/devfile/devworkspace-operator/pkg/library/constants"
+	"github.com/devfile/devworkspace-operator/pkg/library/container"
+	"github.com/devfile/devworkspace-operator/pkg/library/image"
+	"github.com/devfile/devworkspace-operator/pkg/library/lifecycle"
+	"github.com/devfile/devworkspace-operator/pkg/library/overrides"
+)
+
+// Problem is a single issue found when validating a DevWorkspace
+type Problem struct {
+	// Element identifies the part of the DevWorkspace the problem applies to, e.g. 'component tools'
+	Element string `json:"element"`
+	// Message describes the problem
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s", p.Element, p.Message)
+}
+
+// Problems is a list of problems found when validating a DevWorkspace
+type Problems []Problem
+
+func (p Problems) Error() string {
+	var messages []string
+	for _, problem := range p {
+		messages = append(messages, problem.String())
+	}
+	return fmt.Sprintf("DevWorkspace is invalid: %s", strings.Join(messages, "; "))
+}
+
+// ValidateDevWorkspace checks a DevWorkspace using the devfile API validators as well as checks specific to how the
+// controller runs DevWorkspaces. If workspace still has a parent or plugins (i.e. it is not flattened), checks that
+// depend on content that may be provided by the parent or plugins (such as whether commands refer to existing
+// components) are skipped. Returns nil if no problems are found.
+// Encode returns the problems as a JSON list, so that they can be stored in a DevWorkspace's status in a form that
+// clients can process.
+func (p Problems) Encode() string {
+	encoded, err := json.Marshal(p)
+	if err != nil {
+		// Problems only contain strings and always marshal successfully
+		return p.Error()
+	}
+	return string(encoded)
+}
+
+// DecodeProblems reads problems encoded by Problems.Encode
+func DecodeProblems(encoded string) (Problems, error) {
+	var problems Problems
+	if err := json.Unmarshal([]byte(encoded), &problems); err != nil {
+		return nil, err
+	}
+	return problems, nil
+}
+
+func ValidateDevWorkspace(workspace *dw.DevWorkspaceTemplateSpec) Problems {
+	var problems Problems
+	addError := func(element string, err error) {
+		if err != nil {
+			// Errors from devfile validators may span multiple lines
+			message := strings.Join(strings.Fields(err.Error()), " ")
+			problems = append(problems, Problem{Element: element, Message: message})
+		}
+	}
+
+	complete := isFlattened(workspace)
+
+	// Endpoints and volume mounts are checked separately below, as the devfile validators stop at the first issue
+	addError("components", devfilevalidation.ValidateComponents(withoutEndpointsAndVolumeMounts(workspace.Components)))
+	addError("projects", devfilevalidation.ValidateProjects(workspace.Projects))
+	addError("starterProjects", devfilevalidation.ValidateStarterProjects(workspace.StarterProjects))
+	if complete {
+		addError("commands", devfilevalidation.ValidateCommands(workspace.Commands, withApplyTargetsAsContainers(workspace.Components)))
+		if workspace.Events != nil {
+			addError("events", devfilevalidation.ValidateEvents(withoutControllerEventCommands(*workspace.Events, workspace.Commands), workspace.Commands))
+			for _, err := range lifecycle.CheckEventCompositeCommands(workspace.DevWorkspaceTemplateSpecContent) {
+				addError("events", err)
+			}
+		}
+	}
+
+	if workspace.Attributes.Exists(constants.PodSchedulingAttribute) {
+		if err := workspace.Attributes.GetInto(constants.PodSchedulingAttribute, &config.PodScheduling{}); err != nil {
+			problems = append(problems, Problem{
+				Element: "attributes",
+				Message: fmt.Sprintf("invalid %s attribute: %s", constants.PodSchedulingAttribute, err),
+			})
+		}
+	}
+	if _, err := overrides.GetPodOverrides(workspace); err != nil {
+		problems = append(problems, Problem{Element: "attributes", Message: err.Error()})
+	}
+	problems = append(problems, checkEndpoints(workspace.Components)...)
+	for _, component := range workspace.Components {
+		if image.IsImageComponent(component) {
+			if _, err := image.ReadImageComponent(component); err != nil {
+				problems = append(problems, Problem{Element: fmt.Sprintf("component %s", component.Name), Message: err.Error()})
+			}
+		}
+		if component.Container == nil {
+			continue
+		}
+		problems = append(problems, checkContainerResources(component.Name, component.Container)...)
+		problems = append(problems, checkMountSources(component.Name, component.Container)...)
+		if _, err := overrides.GetContainerOverrides(component); err != nil {
+			problems = append(problems, Problem{Element: fmt.Sprintf("component %s", component.Name), Message: err.Error()})
+		}
+	}
+	if complete {
+		problems = append(problems, checkVolumeReferences(workspace.Components)...)
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return problems
+}
+
+// CheckContainerResourcePolicy verifies that the resource limits and requests set by container components do not
+// exceed the maximums defined in policy. Defaults in policy are not checked, as values that are not set by the
+// DevWorkspace are adjusted by the controller to satisfy the policy. Returns nil if no problems are found.
+func CheckContainerResourcePolicy(workspace *dw.DevWorkspaceTemplateSpec, policy config.ContainerResourcePolicy) Problems {
+	var problems Problems
+	parse := func(value string) *resource.Quantity {
+		if value == "" {
+			return nil
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			// Invalid values are reported by ValidateDevWorkspace
+			return nil
+		}
+		return &quantity
+	}
+	var maxRatio float64
+	if ratio, err := strconv.ParseFloat(policy.MaxLimitRequestRatio, 64); err == nil {
+		maxRatio = ratio
+	}
+	for _, component := range workspace.Components {
+		if component.Container == nil {
+			continue
+		}
+		element := fmt.Sprintf("component %s", component.Name)
+		check := func(resourceName string, limit, request, maxLimit *resource.Quantity) {
+			if limit != nil && maxLimit != nil && limit.Cmp(*maxLimit) > 0 {
+				problems = append(problems, Problem{
+					Element: element,
+					Message: fmt.Sprintf("%s limit (%s) exceeds the maximum allowed (%s)", resourceName, limit.String(), maxLimit.String()),
+				})
+			}
+			if limit != nil && request != nil && maxRatio > 0 && float64(limit.MilliValue()) > maxRatio*float64(request.MilliValue()) {
+				problems = append(problems, Problem{
+					Element: element,
+					Message: fmt.Sprintf("ratio of %s limit (%s) to request (%s) exceeds the maximum allowed (%s)",
+						resourceName, limit.String(), request.String(), policy.MaxLimitRequestRatio),
+				})
+			}
+		}
+		check("memory", parse(component.Container.MemoryLimit), parse(component.Container.MemoryRequest), parse(policy.MaxMemoryLimit))
+		check("CPU", parse(component.Container.CpuLimit), parse(component.Container.CpuRequest), parse(policy.MaxCpuLimit))
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return problems
+}
+
+// checkEndpoints verifies that endpoint names are unique and that containers do not expose the same target port, as
+// all containers in a DevWorkspace share a network namespace.
+func checkEndpoints(components []dw.Component) Problems {
+	var problems Problems
+	endpointNames := map[string]string{}
+	endpointPorts := map[int]string{}
+	for _, component := range components {
+		var endpoints []dw.Endpoint
+		switch {
+		case component.Container != nil:
+			endpoints = component.Container.Endpoints
+		case component.Kubernetes != nil:
+			endpoints = component.Kubernetes.Endpoints
+		case component.Openshift != nil:
+			endpoints = component.Openshift.Endpoints
+		}
+		componentPorts := map[int]bool{}
+		for _, endpoint := range endpoints {
+			element := fmt.Sprintf("component %s", component.Name)
+			if other, ok := endpointNames[endpoint.Name]; ok {
+				problems = append(problems, Problem{
+					Element: element,
+					Message: fmt.Sprintf("endpoint name %s is already used by component %s", endpoint.Name, other),
+				})
+			} else {
+				endpointNames[endpoint.Name] = component.Name
+			}
+			if componentPorts[endpoint.TargetPort] {
+				// Endpoints within a single component may share a port
+				continue
+			}
+			componentPorts[endpoint.TargetPort] = true
+			if other, ok := endpointPorts[endpoint.TargetPort]; ok {
+				problems = append(problems, Problem{
+					Element: element,
+					Message: fmt.Sprintf("target port %d of endpoint %s is already used by component %s", endpoint.TargetPort, endpoint.Name, other),
+				})
+			} else {
+				endpointPorts[endpoint.TargetPort] = component.Name
+			}
+		}
+	}
+	return problems
+}
+
+// checkContainerResources verifies that resource limits and requests for a container are valid quantities and that
+// limits are not lower than requests.
+func checkContainerResources(name string, devfileContainer *dw.ContainerComponent) Problems {
+	var problems Problems
+	element := fmt.Sprintf("component %s", name)
+	parse := func(field, value string) *resource.Quantity {
+		if value == "" {
+			return nil
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			problems = append(problems, Problem{
+				Element: element,
+				Message: fmt.Sprintf("invalid %s %q: %s", field, value, err),
+			})
+			return nil
+		}
+		return &quantity
+	}
+	checkLimit := func(resourceName string, limit, request *resource.Quantity) {
+		if limit != nil && request != nil && limit.Cmp(*request) < 0 {
+			problems = append(problems, Problem{
+				Element: element,
+				Message: fmt.Sprintf("%s limit (%s) is less than request (%s)", resourceName, limit.String(), request.String()),
+			})
+		}
+	}
+	memoryLimit := parse("memoryLimit", devfileContainer.MemoryLimit)
+	memoryRequest := parse("memoryRequest", devfileContainer.MemoryRequest)
+	cpuLimit := parse("cpuLimit", devfileContainer.CpuLimit)
+	cpuRequest := parse("cpuRequest", devfileContainer.CpuRequest)
+	checkLimit("memory", memoryLimit, memoryRequest)
+	checkLimit("CPU", cpuLimit, cpuRequest)
+	return problems
+}
+
+// checkMountSources verifies that a container's volume mounts do not conflict with how project sources are mounted.
+func checkMountSources(name string, devfileContainer *dw.ContainerComponent) Problems {
+	var problems Problems
+	element := fmt.Sprintf("component %s", name)
+	mountSources := container.HasMountSources(devfileContainer)
+	sourceMapping := devfileContainer.SourceMapping
+	if sourceMapping == "" {
+		sourceMapping = constants.DefaultProjectsSourcesRoot
+	}
+	for _, volumeMount := range devfileContainer.VolumeMounts {
+		switch {
+		case volumeMount.Name == devfileConstants.ProjectsVolumeName && !mountSources:
+			problems = append(problems, Problem{
+				Element: element,
+				Message: fmt.Sprintf("volume %s is mounted but mountSources is false", devfileConstants.ProjectsVolumeName),
+			})
+		case volumeMount.Name != devfileConstants.ProjectsVolumeName && mountSources && volumeMount.Path == sourceMapping:
+			problems = append(problems, Problem{
+				Element: element,
+				Message: fmt.Sprintf("volume %s is mounted at %s, where project sources are mounted", volumeMount.Name, volumeMount.Path),
+			})
+		}
+	}
+	return problems
+}
+
+// checkVolumeReferences verifies that volume mounts in containers refer to volume components. The projects volume is
+// provisioned by the controller and does not need to be defined.
+func checkVolumeReferences(components []dw.Component) Problems {
+	volumes := map[string]bool{
+		devfileConstants.ProjectsVolumeName: true,
+	}
+	for _, component := range components {
+		if component.Volume != nil {
+			volumes[component.Name] = true
+		}
+	}
+	var problems Problems
+	for _, component := range components {
+		if component.Container == nil {
+			continue
+		}
+		var missing []string
+		for _, volumeMount := range component.Container.VolumeMounts {
+			if !volumes[volumeMount.Name] {
+				missing = append(missing, volumeMount.Name)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			problems = append(problems, Problem{
+				Element: fmt.Sprintf("component %s", component.Name),
+				Message: fmt.Sprintf("volume mounts refer to undefined volumes: %s", strings.Join(missing, ", ")),
+			})
+		}
+	}
+	return problems
+}
+
+func withoutEndpointsAndVolumeMounts(components []dw.Component) []dw.Component {
+	var result []dw.Component
+	for _, component := range components {
+		component = *component.DeepCopy()
+		switch {
+		case component.Container != nil:
+			component.Container.Endpoints = nil
+			component.Container.VolumeMounts = nil
+		case component.Kubernetes != nil:
+			component.Kubernetes.Endpoints = nil
+		case component.Openshift != nil:
+			component.Openshift.Endpoints = nil
+		}
+		result = append(result, component)
+	}
+	return result
+}
+
+// withoutControllerEventCommands removes commands from events where the controller supports commands that the devfile
+// API does not allow: exec commands in the preStart and postStop events (run in init containers and the postStop job
+// respectively) and composite commands containing other composite commands or exec commands in any event. Composite
+// commands are checked separately by lifecycle.CheckEventCompositeCommands.
+func withoutControllerEventCommands(events dw.Events, commands []dw.Command) dw.Events {
+	execCommands := map[string]bool{}
+	compositeCommands := map[string]bool{}
+	for _, command := range commands {
+		switch {
+		case command.Exec != nil:
+			execCommands[command.Id] = true
+		case command.Composite != nil:
+			compositeCommands[command.Id] = true
+		}
+	}
+	filter := func(commandIds []string, allowExec bool) []string {
+		var filtered []string
+		for _, commandId := range commandIds {
+			if compositeCommands[commandId] || (execCommands[commandId] && !allowExec) {
+				continue
+			}
+			filtered = append(filtered, commandId)
+		}
+		return filtered
+	}
+	events.PreStart = filter(events.PreStart, false)
+	events.PostStart = filter(events.PostStart, true)
+	events.PreStop = filter(events.PreStop, true)
+	events.PostStop = filter(events.PostStop, false)
+	return events
+}
+
+// withApplyTargetsAsContainers marks kubernetes, openshift and image components as container components, as the devfile
+// API validators only allow apply commands that refer to container components while the controller can also apply
+// these components. The result is only used for validating commands.
+func withApplyTargetsAsContainers(components []dw.Component) []dw.Component {
+	var result []dw.Component
+	for _, component := range components {
+		if component.Kubernetes != nil || component.Openshift != nil || image.IsImageComponent(component) {
+			component = *component.DeepCopy()
+			component.Container = &dw.ContainerComponent{}
+		}
+		result = append(result, component)
+	}
+	return result
+}
+
+func isFlattened(workspace *dw.DevWorkspaceTemplateSpec) bool {
+	if workspace.Parent != nil {
+		return false
+	}
+	for _, component := range workspace.Components {
+		if component.Plugin != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package validation
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/devfile/devworkspace-operator/pkg/config"
+	"sigs.k8s.io/yaml"
+)
+
+type testCase struct {
+	Name   string                       `json:"name,omitempty"`
+	Input  *dw.DevWorkspaceTemplateSpec `json:"input,omitempty"`
+	Output testOutput                   `json:"output,omitempty"`
+	// ResourcePolicy, if set, is checked with CheckContainerResourcePolicy in addition to validating the DevWorkspace
+	ResourcePolicy *config.ContainerResourcePolicy `json:"resourcePolicy,omitempty"`
+}
+
+type testOutput struct {
+	// Problems is a list of regexps matching the expected problems, in order
+	Problems []string `json:"problems,omitempty"`
+}
+
+func loadAllTestCasesOrPanic(t *testing.T, fromDir string) []testCase {
+	files, err := ioutil.ReadDir(fromDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests []testCase
+	for _, file := range files {
+		bytes, err := ioutil.ReadFile(filepath.Join(fromDir, file.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var test testCase
+		if err := yaml.Unmarshal(bytes, &test); err != nil {
+			t.Fatal(err)
+		}
+		tests = append(tests, test)
+	}
+	return tests
+}
+
+func TestValidateDevWorkspace(t *testing.T) {
+	tests := loadAllTestCasesOrPanic(t, "testdata")
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			problems := ValidateDevWorkspace(tt.Input)
+			if tt.ResourcePolicy != nil {
+				problems = append(problems, CheckContainerResourcePolicy(tt.Input, *tt.ResourcePolicy)...)
+			}
+			if len(tt.Output.Problems) == 0 {
+				assert.Nil(t, problems, "Should not find problems")
+				return
+			}
+			if !assert.Len(t, problems, len(tt.Output.Problems), "Should find all problems: %s", problems) {
+				return
+			}
+			for idx, expected := range tt.Output.Problems {
+				assert.Regexp(t, "^"+expected+"$", problems[idx].String(), "Problem should match")
+			}
+			decoded, err := DecodeProblems(problems.Encode())
+			if assert.NoError(t, err, "Should decode encoded problems") {
+				assert.Equal(t, problems, decoded, "Encoded problems should be preserved")
+			}
+		})
+	}
+}
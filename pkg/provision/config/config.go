@@ -16,20 +16,46 @@ import (
 	"fmt"
 	"strings"
 
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/devfile/devworkspace-operator/controllers/workspace/provision"
+	controllerconfig "github.com/devfile/devworkspace-operator/pkg/config"
 	"github.com/devfile/devworkspace-operator/pkg/constants"
 )
 
 const (
 	commonPVCSizeKey = "commonPVCSize"
+
+	defaultMemoryLimitKey   = "defaultMemoryLimit"
+	defaultMemoryRequestKey = "defaultMemoryRequest"
+	defaultCpuLimitKey      = "defaultCpuLimit"
+	defaultCpuRequestKey    = "defaultCpuRequest"
+	maxMemoryLimitKey       = "maxMemoryLimit"
+	maxCpuLimitKey          = "maxCpuLimit"
+	maxLimitRequestRatioKey = "maxLimitRequestRatio"
+
+	podSchedulingKey         = "podScheduling"
+	podSchedulingEnforcedKey = "podSchedulingEnforced"
 )
 
 type NamespacedConfig struct {
 	CommonPVCSize string
+	// ContainerResourcePolicy overrides the controller's container resource policy for DevWorkspaces in the namespace.
+	// Only values that are set in the configmap are overridden, and maximums can only be lowered.
+	ContainerResourcePolicy controllerconfig.ContainerResourcePolicy
+	// PodScheduling is the default scheduling configuration for DevWorkspace pods in the namespace, as YAML or JSON.
+	// See controllerconfig.PodScheduling for the format.
+	PodScheduling string
+	// PodSchedulingEnforced is scheduling configuration for DevWorkspace pods in the namespace that cannot be
+	// overridden by DevWorkspaces. It has the same format as PodScheduling.
+	//
+	// Anyone who can edit configmaps in the namespace can change this value, which usually includes the users who
+	// create DevWorkspaces there. It is therefore a way for namespace owners to constrain DevWorkspaces, not a security
+	// boundary: scheduling that users must not be able to bypass belongs in the controller's enforced configuration.
+	PodSchedulingEnforced string
 }
 
 // ReadNamespacedConfig reads the per-namespace DevWorkspace configmap and returns it as a struct. If there are
@@ -67,5 +93,79 @@ func ReadNamespacedConfig(namespace string, api provision.ClusterAPI) (*Namespac
 
 	return &NamespacedConfig{
 		CommonPVCSize: cm.Data[commonPVCSizeKey],
+		ContainerResourcePolicy: controllerconfig.ContainerResourcePolicy{
+			DefaultMemoryLimit:   cm.Data[defaultMemoryLimitKey],
+			DefaultMemoryRequest: cm.Data[defaultMemoryRequestKey],
+			DefaultCpuLimit:      cm.Data[defaultCpuLimitKey],
+			DefaultCpuRequest:    cm.Data[defaultCpuRequestKey],
+			MaxMemoryLimit:       cm.Data[maxMemoryLimitKey],
+			MaxCpuLimit:          cm.Data[maxCpuLimitKey],
+			MaxLimitRequestRatio: cm.Data[maxLimitRequestRatioKey],
+		},
+		PodScheduling:         cm.Data[podSchedulingKey],
+		PodSchedulingEnforced: cm.Data[podSchedulingEnforcedKey],
 	}, nil
 }
+
+// GetContainerResourcePolicy returns the container resource policy for DevWorkspaces in a namespace, which is the
+// controller's policy with any values set in the per-namespace configmap overriding it. Maximums set in the configmap
+// only apply if they are lower than the controller's; see ContainerResourcePolicy.WithOverrides.
+func GetContainerResourcePolicy(namespace string, api provision.ClusterAPI) (controllerconfig.ContainerResourcePolicy, error) {
+	globalPolicy := controllerconfig.ControllerCfg.GetContainerResourcePolicy()
+	namespacedConfig, err := ReadNamespacedConfig(namespace, api)
+	if err != nil {
+		return globalPolicy, fmt.Errorf("failed to read namespace-specific configuration: %w", err)
+	}
+	if namespacedConfig == nil {
+		return globalPolicy, nil
+	}
+	policy := globalPolicy.WithOverrides(&namespacedConfig.ContainerResourcePolicy)
+	if err := policy.Validate(); err != nil {
+		return policy, fmt.Errorf("invalid container resource configuration for namespace %s: %w", namespace, err)
+	}
+	return policy, nil
+}
+
+// GetPodScheduling returns the scheduling configuration for a DevWorkspace's pods. Configuration is merged from the
+// following sources, in order of increasing precedence:
+//   - the controller's default configuration
+//   - the namespace's default configuration
+//   - the DevWorkspace's constants.PodSchedulingAttribute attribute
+//   - the namespace's enforced configuration
+//   - the controller's enforced configuration
+//
+// Only the controller's enforced configuration is controlled by cluster administrators; see NamespacedConfig.PodSchedulingEnforced.
+func GetPodScheduling(workspace *dw.DevWorkspace, api provision.ClusterAPI) (controllerconfig.PodScheduling, error) {
+	var scheduling controllerconfig.PodScheduling
+	globalDefaults, globalEnforced, err := controllerconfig.ControllerCfg.GetPodScheduling()
+	if err != nil {
+		return scheduling, err
+	}
+	var namespaceDefaults, namespaceEnforced *controllerconfig.PodScheduling
+	namespacedConfig, err := ReadNamespacedConfig(workspace.Namespace, api)
+	if err != nil {
+		return scheduling, fmt.Errorf("failed to read namespace-specific configuration: %w", err)
+	}
+	if namespacedConfig != nil {
+		if namespaceDefaults, err = controllerconfig.ParsePodScheduling(namespacedConfig.PodScheduling); err != nil {
+			return scheduling, fmt.Errorf("invalid %s for namespace %s: %w", podSchedulingKey, workspace.Namespace, err)
+		}
+		if namespaceEnforced, err = controllerconfig.ParsePodScheduling(namespacedConfig.PodSchedulingEnforced); err != nil {
+			return scheduling, fmt.Errorf("invalid %s for namespace %s: %w", podSchedulingEnforcedKey, workspace.Namespace, err)
+		}
+	}
+	var workspaceScheduling *controllerconfig.PodScheduling
+	if workspace.Spec.Template.Attributes.Exists(constants.PodSchedulingAttribute) {
+		workspaceScheduling = &controllerconfig.PodScheduling{}
+		if err := workspace.Spec.Template.Attributes.GetInto(constants.PodSchedulingAttribute, workspaceScheduling); err != nil {
+			return scheduling, fmt.Errorf("failed to read attribute %s: %w", constants.PodSchedulingAttribute, err)
+		}
+	}
+	scheduling = scheduling.
+		WithOverrides(globalDefaults).
+		WithOverrides(namespaceDefaults).
+		WithOverrides(workspaceScheduling).
+		WithOverrides(namespaceEnforced).
+		WithOverrides(globalEnforced)
+	return scheduling, nil
+}
@@ -0,0 +1,153 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package config
+
+import (
+	"context"
+	"testing"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	"github.com/devfile/api/v2/pkg/attributes"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/devfile/devworkspace-operator/controllers/workspace/provision"
+	controllerconfig "github.com/devfile/devworkspace-operator/pkg/config"
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+)
+
+const testNamespace = "test-namespace"
+
+func TestGetPodSchedulingPrecedence(t *testing.T) {
+	// Each source sets priorityClassName to its own name, along with a node selector that only it sets, so that the
+	// expected priority class identifies the source with the highest precedence.
+	sources := map[string]string{
+		"globalDefault":     `{"priorityClassName": "globalDefault", "nodeSelector": {"globalDefault": "true"}}`,
+		"namespaceDefault":  `{"priorityClassName": "namespaceDefault", "nodeSelector": {"namespaceDefault": "true"}}`,
+		"attribute":         `{"priorityClassName": "attribute", "nodeSelector": {"attribute": "true"}}`,
+		"namespaceEnforced": `{"priorityClassName": "namespaceEnforced", "nodeSelector": {"namespaceEnforced": "true"}}`,
+		"globalEnforced":    `{"priorityClassName": "globalEnforced", "nodeSelector": {"globalEnforced": "true"}}`,
+	}
+	tests := []struct {
+		name             string
+		configured       []string
+		expectedPriority string
+	}{
+		{
+			name:             "Uses global defaults",
+			configured:       []string{"globalDefault"},
+			expectedPriority: "globalDefault",
+		},
+		{
+			name:             "Namespace defaults override global defaults",
+			configured:       []string{"globalDefault", "namespaceDefault"},
+			expectedPriority: "namespaceDefault",
+		},
+		{
+			name:             "Attribute overrides namespace and global defaults",
+			configured:       []string{"globalDefault", "namespaceDefault", "attribute"},
+			expectedPriority: "attribute",
+		},
+		{
+			name:             "Namespace enforced configuration overrides attribute",
+			configured:       []string{"globalDefault", "namespaceDefault", "attribute", "namespaceEnforced"},
+			expectedPriority: "namespaceEnforced",
+		},
+		{
+			name:             "Global enforced configuration overrides all other configuration",
+			configured:       []string{"globalDefault", "namespaceDefault", "attribute", "namespaceEnforced", "globalEnforced"},
+			expectedPriority: "globalEnforced",
+		},
+		{
+			name:             "Global enforced configuration overrides attribute without namespace configuration",
+			configured:       []string{"attribute", "globalEnforced"},
+			expectedPriority: "globalEnforced",
+		},
+		{
+			name:             "Attribute overrides global defaults without namespace configuration",
+			configured:       []string{"globalDefault", "attribute"},
+			expectedPriority: "attribute",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configured := map[string]bool{}
+			for _, source := range tt.configured {
+				configured[source] = true
+			}
+
+			globalConfig := &corev1.ConfigMap{Data: map[string]string{}}
+			if configured["globalDefault"] {
+				globalConfig.Data["devworkspace.pod.scheduling"] = sources["globalDefault"]
+			}
+			if configured["globalEnforced"] {
+				globalConfig.Data["devworkspace.pod.scheduling_enforced"] = sources["globalEnforced"]
+			}
+			controllerconfig.SetupConfigForTesting(globalConfig)
+
+			var objs []runtime.Object
+			if configured["namespaceDefault"] || configured["namespaceEnforced"] {
+				namespaceConfig := &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "namespaced-config",
+						Namespace: testNamespace,
+						Labels: map[string]string{
+							constants.NamespacedConfigLabelKey: "true",
+						},
+					},
+					Data: map[string]string{},
+				}
+				if configured["namespaceDefault"] {
+					namespaceConfig.Data[podSchedulingKey] = sources["namespaceDefault"]
+				}
+				if configured["namespaceEnforced"] {
+					namespaceConfig.Data[podSchedulingEnforcedKey] = sources["namespaceEnforced"]
+				}
+				objs = append(objs, namespaceConfig)
+			}
+
+			workspace := &dw.DevWorkspace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-workspace",
+					Namespace: testNamespace,
+				},
+			}
+			if configured["attribute"] {
+				var err error
+				workspace.Spec.Template.Attributes = attributes.Attributes{}.Put(constants.PodSchedulingAttribute,
+					apiext.JSON{Raw: []byte(sources["attribute"])}, &err)
+				if !assert.NoError(t, err, "Should set attribute") {
+					return
+				}
+			}
+
+			api := provision.ClusterAPI{
+				Client: fake.NewFakeClientWithScheme(scheme.Scheme, objs...),
+				Ctx:    context.Background(),
+			}
+			scheduling, err := GetPodScheduling(workspace, api)
+			if !assert.NoError(t, err, "Should not return error") {
+				return
+			}
+			assert.Equal(t, tt.expectedPriority, scheduling.PriorityClassName, "Should use configuration with highest precedence")
+			assert.Equal(t, map[string]string{tt.expectedPriority: "true"}, scheduling.NodeSelector,
+				"Fields should be replaced as a whole by the configuration with highest precedence")
+		})
+	}
+}
@@ -0,0 +1,127 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+// Package importlock stores the content of plugins and parents resolved for a DevWorkspace, so that the same
+// content is used when the DevWorkspace is restarted even if the plugin or parent changes at its source.
+package importlock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	dw "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/yaml"
+
+	"github.com/devfile/devworkspace-operator/controllers/workspace/provision"
+	maputils "github.com/devfile/devworkspace-operator/internal/map"
+	"github.com/devfile/devworkspace-operator/pkg/common"
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+	"github.com/devfile/devworkspace-operator/pkg/library/flatten"
+)
+
+// lockFilename is the key in the import lock configmap that stores the lock
+const lockFilename = "import-lock.yaml"
+
+// GetImportLock reads the import lock stored for a DevWorkspace. If no lock is stored, or the DevWorkspace requests
+// updating its imports via the DevWorkspaceUpdateImportsAnnotation, an empty lock is returned.
+func GetImportLock(workspace *dw.DevWorkspace, api provision.ClusterAPI) (flatten.ImportLock, error) {
+	if ImportsUpdateRequested(workspace) {
+		return flatten.ImportLock{}, nil
+	}
+	cm := &corev1.ConfigMap{}
+	namespacedName := types.NamespacedName{
+		Name:      common.ImportLockConfigMapName(workspace.Status.DevWorkspaceId),
+		Namespace: workspace.Namespace,
+	}
+	err := api.Client.Get(context.TODO(), namespacedName, cm)
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return flatten.ImportLock{}, nil
+		}
+		return nil, err
+	}
+	lock := flatten.ImportLock{}
+	if err := yaml.Unmarshal([]byte(cm.Data[lockFilename]), &lock); err != nil {
+		return nil, fmt.Errorf("failed to read import lock from configmap %s: %w", cm.Name, err)
+	}
+	return lock, nil
+}
+
+// ImportsUpdateRequested returns true if the DevWorkspace requests fetching its plugins and parents again via the
+// DevWorkspaceUpdateImportsAnnotation.
+func ImportsUpdateRequested(workspace *dw.DevWorkspace) bool {
+	return workspace.Annotations[constants.DevWorkspaceUpdateImportsAnnotation] == "true"
+}
+
+// SyncImportLock stores the content of resolved imports in a configmap owned by the DevWorkspace. Returns true if the
+// configmap on the cluster is up to date.
+func SyncImportLock(workspace *dw.DevWorkspace, resolved flatten.ImportLock, api provision.ClusterAPI) (inSync bool, err error) {
+	lockYaml, err := yaml.Marshal(resolved)
+	if err != nil {
+		return false, fmt.Errorf("failed to serialize import lock: %w", err)
+	}
+	specCM := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      common.ImportLockConfigMapName(workspace.Status.DevWorkspaceId),
+			Namespace: workspace.Namespace,
+			Labels:    constants.ControllerAppLabels(),
+		},
+		Data: map[string]string{
+			lockFilename: string(lockYaml),
+		},
+	}
+	if err := controllerutil.SetControllerReference(workspace, specCM, api.Scheme); err != nil {
+		return false, err
+	}
+
+	clusterCM := &corev1.ConfigMap{}
+	err = api.Client.Get(context.TODO(), types.NamespacedName{Name: specCM.Name, Namespace: specCM.Namespace}, clusterCM)
+	switch {
+	case err == nil:
+		if maputils.Equal(specCM.Data, clusterCM.Data) {
+			return true, nil
+		}
+		clusterCM.Data = specCM.Data
+		err = api.Client.Update(context.TODO(), clusterCM)
+	case k8sErrors.IsNotFound(err):
+		if len(resolved) == 0 {
+			// No need to store an empty lock
+			return true, nil
+		}
+		err = api.Client.Create(context.TODO(), specCM)
+	default:
+		return false, err
+	}
+	if k8sErrors.IsConflict(err) || k8sErrors.IsAlreadyExists(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// GetImportLockAnnotation returns the value of the DevWorkspaceImportLockAnnotation for a lock: a JSON object mapping
+// import paths to the digest of their content.
+func GetImportLockAnnotation(lock flatten.ImportLock) (string, error) {
+	if len(lock) == 0 {
+		return "", nil
+	}
+	digests, err := json.Marshal(lock.Digests())
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize import lock digests: %w", err)
+	}
+	return string(digests), nil
+}
@@ -59,7 +59,13 @@ func runCommonPVCCleanupJob(workspace *dw.DevWorkspace, clusterAPI provision.Clu
 		return nil
 	}
 
-	specJob, err := getSpecCommonPVCCleanupJob(workspace, clusterAPI)
+	// The cleanup job does not use the DevWorkspace's security context overrides, but must still be allowed to run in
+	// namespaces that enforce the restricted Pod Security Standard
+	securityContexts, err := provision.GetDefaultSecurityContexts(workspace.Namespace, clusterAPI)
+	if err != nil {
+		return err
+	}
+	specJob, err := getSpecCommonPVCCleanupJob(workspace, securityContexts, clusterAPI)
 	if err != nil {
 		return err
 	}
@@ -68,7 +74,11 @@ func runCommonPVCCleanupJob(workspace *dw.DevWorkspace, clusterAPI provision.Clu
 		return err
 	}
 	if clusterJob == nil {
-		err := clusterAPI.Client.Create(clusterAPI.Ctx, specJob)
+		clusterObj, err := securityContexts.ToClusterObject(specJob, clusterAPI.Scheme)
+		if err != nil {
+			return err
+		}
+		err = clusterAPI.Client.Create(clusterAPI.Ctx, clusterObj)
 		if err != nil && !k8sErrors.IsAlreadyExists(err) {
 			return err
 		}
@@ -106,7 +116,7 @@ func runCommonPVCCleanupJob(workspace *dw.DevWorkspace, clusterAPI provision.Clu
 	}
 }
 
-func getSpecCommonPVCCleanupJob(workspace *dw.DevWorkspace, clusterAPI provision.ClusterAPI) (*batchv1.Job, error) {
+func getSpecCommonPVCCleanupJob(workspace *dw.DevWorkspace, securityContexts *provision.SecurityContexts, clusterAPI provision.ClusterAPI) (*batchv1.Job, error) {
 	workspaceId := workspace.Status.DevWorkspaceId
 	pvcName := config.ControllerCfg.GetWorkspacePVCName()
 	jobLabels := map[string]string{
@@ -126,8 +136,7 @@ func getSpecCommonPVCCleanupJob(workspace *dw.DevWorkspace, clusterAPI provision
 			BackoffLimit: &cleanupJobBackoffLimit,
 			Template: corev1.PodTemplateSpec{
 				Spec: corev1.PodSpec{
-					RestartPolicy:   "Never",
-					SecurityContext: provision.GetDevWorkspaceSecurityContext(),
+					RestartPolicy: "Never",
 					Volumes: []corev1.Volume{
 						{
 							Name: pvcName,
@@ -170,6 +179,10 @@ func getSpecCommonPVCCleanupJob(workspace *dw.DevWorkspace, clusterAPI provision
 		},
 	}
 
+	if err := securityContexts.ApplyTo(&job.Spec.Template); err != nil {
+		return nil, err
+	}
+
 	err := controllerutil.SetControllerReference(workspace, job, clusterAPI.Scheme)
 	if err != nil {
 		return nil, err
@@ -162,6 +162,14 @@ func getSpecDeployment(webhooksSecretName, namespace string) (*appsv1.Deployment
 									Name:  constants.ControllerServiceAccountNameEnvVar,
 									Value: controllerSA,
 								},
+								{
+									Name:  config.ConfigMapNameEnvVar,
+									Value: config.ConfigMapReference.Name,
+								},
+								{
+									Name:  config.ConfigMapNamespaceEnvVar,
+									Value: config.ConfigMapReference.Namespace,
+								},
 								{
 									Name: "POD_NAME",
 									ValueFrom: &corev1.EnvVarSource{
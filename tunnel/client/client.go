@@ -0,0 +1,168 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+// Package client implements connecting to workspace endpoints exposed via the tunnel routing class. It is
+// intended to be used by local tooling and has no dependencies on Kubernetes client libraries.
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// UpgradeProtocol is the value of the Upgrade header used to open a tunnel
+	UpgradeProtocol = "devworkspace-tunnel"
+	// TunnelPathPrefix is the path prefix on the tunnel server for opening tunnels. The full path is
+	// /tunnel/<namespace>/<service>/<port>
+	TunnelPathPrefix = "/tunnel/"
+	// TunnelURLScheme is the scheme of endpoint URLs reported for the tunnel routing class
+	TunnelURLScheme = "tunnel"
+)
+
+// Target identifies a service port in the cluster that a tunnel connects to.
+type Target struct {
+	Namespace string
+	Service   string
+	Port      int
+}
+
+// Path returns the path on the tunnel server used to open a tunnel to the target.
+func (t Target) Path() string {
+	return fmt.Sprintf("%s%s/%s/%d", TunnelPathPrefix, t.Namespace, t.Service, t.Port)
+}
+
+// ParseTunnelURL parses a tunnel URL of the form tunnel://<service>.<namespace>:<port>/<path>, as reported in the
+// status of DevWorkspaces using the tunnel routing class. The path, if any, is ignored.
+func ParseTunnelURL(tunnelURL string) (Target, error) {
+	parsed, err := url.Parse(tunnelURL)
+	if err != nil {
+		return Target{}, err
+	}
+	if parsed.Scheme != TunnelURLScheme {
+		return Target{}, fmt.Errorf("unsupported URL scheme %q, expected %q", parsed.Scheme, TunnelURLScheme)
+	}
+	// Service names cannot contain dots, so the first dot separates service and namespace
+	hostParts := strings.SplitN(parsed.Hostname(), ".", 2)
+	if len(hostParts) != 2 || hostParts[0] == "" || hostParts[1] == "" {
+		return Target{}, fmt.Errorf("invalid tunnel URL host %q, expected <service>.<namespace>", parsed.Hostname())
+	}
+	port, err := strconv.Atoi(parsed.Port())
+	if err != nil {
+		return Target{}, fmt.Errorf("invalid port in tunnel URL %q: %w", tunnelURL, err)
+	}
+	return Target{
+		Namespace: hostParts[1],
+		Service:   hostParts[0],
+		Port:      port,
+	}, nil
+}
+
+// Dial opens a connection to the workspace endpoint identified by tunnelURL through the tunnel server at serverURL,
+// authenticating with the provided Kubernetes token. The server URL must use the http or https scheme; tlsConfig
+// is used for https and may be nil. The returned connection carries raw traffic to and from the endpoint.
+func Dial(ctx context.Context, serverURL, token, tunnelURL string, tlsConfig *tls.Config) (net.Conn, error) {
+	target, err := ParseTunnelURL(tunnelURL)
+	if err != nil {
+		return nil, err
+	}
+	server, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tunnel server URL: %w", err)
+	}
+
+	address := server.Host
+	if server.Port() == "" {
+		switch server.Scheme {
+		case "https":
+			address = net.JoinHostPort(server.Hostname(), "443")
+		default:
+			address = net.JoinHostPort(server.Hostname(), "80")
+		}
+	}
+	dialer := &net.Dialer{}
+	var conn net.Conn
+	switch server.Scheme {
+	case "http":
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	case "https":
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		if tlsConfig.ServerName == "" {
+			tlsConfig = tlsConfig.Clone()
+			tlsConfig.ServerName = server.Hostname()
+		}
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+	default:
+		return nil, fmt.Errorf("unsupported tunnel server URL scheme %q", server.Scheme)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to tunnel server: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, (&url.URL{Scheme: server.Scheme, Host: server.Host, Path: target.Path()}).String(), nil)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", UpgradeProtocol)
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to send tunnel request: %w", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read tunnel server response: %w", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		conn.Close()
+		return nil, &TunnelError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+	}
+	return &bufferedConn{Conn: conn, reader: reader}, nil
+}
+
+// TunnelError is returned by Dial when the tunnel server refuses to open a tunnel.
+type TunnelError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *TunnelError) Error() string {
+	return fmt.Sprintf("tunnel server returned status %d: %s", e.StatusCode, e.Message)
+}
+
+// bufferedConn reads from a buffered reader first, as the reader used for parsing the server's response
+// may already contain data sent by the endpoint.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
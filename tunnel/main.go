@@ -0,0 +1,127 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	clientconfig "sigs.k8s.io/controller-runtime/pkg/client/config"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
+
+	"github.com/devfile/devworkspace-operator/pkg/config"
+	"github.com/devfile/devworkspace-operator/tunnel/client"
+	"github.com/devfile/devworkspace-operator/tunnel/server"
+	"github.com/devfile/devworkspace-operator/version"
+)
+
+var log = logf.Log.WithName("cmd")
+
+func main() {
+	var bindAddress, tlsCertFile, tlsKeyFile string
+	var insecure bool
+	flag.StringVar(&bindAddress, "bind-address", ":8444", "The address the tunnel server binds to.")
+	flag.StringVar(&tlsCertFile, "tls-cert-file", "", "File containing the TLS certificate to serve. Required unless --insecure is set.")
+	flag.StringVar(&tlsKeyFile, "tls-key-file", "", "File containing the TLS private key matching --tls-cert-file. Required unless --insecure is set.")
+	flag.BoolVar(&insecure, "insecure", false, "Serve plain HTTP instead of TLS. Bearer tokens sent by clients are exposed to anyone able to "+
+		"observe traffic to the server; only use this for local development.")
+	flag.Parse()
+
+	logf.SetLogger(zap.New(zap.UseDevMode(config.GetDevModeEnabled())))
+
+	if err := validateTLSFlags(insecure, tlsCertFile, tlsKeyFile); err != nil {
+		log.Error(err, "Invalid flags")
+		os.Exit(1)
+	}
+	if insecure {
+		log.Info("Serving plain HTTP as --insecure is set; bearer tokens are not protected in transit")
+	}
+
+	// Print versions
+	log.Info(fmt.Sprintf("Operator Version: %s", version.Version))
+	log.Info(fmt.Sprintf("Go Version: %s", runtime.Version()))
+	log.Info(fmt.Sprintf("Go OS/Arch: %s/%s", runtime.GOOS, runtime.GOARCH))
+	log.Info(fmt.Sprintf("Commit: %s", version.Commit))
+	log.Info(fmt.Sprintf("BuildTime: %s", version.BuildTime))
+
+	// Get a config to talk to the apiserver
+	cfg, err := clientconfig.GetConfig()
+	if err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		log.Error(err, "Failed to create Kubernetes client")
+		os.Exit(1)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(client.TunnelPathPrefix, &server.Server{
+		Client: clientset,
+		Log:    logf.Log.WithName("tunnel"),
+	})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	httpServer := &http.Server{
+		Addr:    bindAddress,
+		Handler: mux,
+	}
+
+	go func() {
+		var err error
+		log.Info("Starting tunnel server", "address", bindAddress)
+		if insecure {
+			err = httpServer.ListenAndServe()
+		} else {
+			err = httpServer.ListenAndServeTLS(tlsCertFile, tlsKeyFile)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			log.Error(err, "Tunnel server failed")
+			os.Exit(1)
+		}
+	}()
+
+	<-signals.SetupSignalHandler()
+	log.Info("Shutting down tunnel server")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		log.Error(err, "Failed to shut down tunnel server")
+	}
+}
+
+// validateTLSFlags checks that the server is either configured with a TLS certificate and key or explicitly allowed to
+// serve plain HTTP, as clients authenticate to the tunnel server using bearer tokens.
+func validateTLSFlags(insecure bool, tlsCertFile, tlsKeyFile string) error {
+	if insecure {
+		if tlsCertFile != "" || tlsKeyFile != "" {
+			return errors.New("--insecure cannot be used with --tls-cert-file or --tls-key-file")
+		}
+		return nil
+	}
+	if tlsCertFile == "" || tlsKeyFile == "" {
+		return errors.New("--tls-cert-file and --tls-key-file are required unless --insecure is set")
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+// Package server implements the tunnel server used by the tunnel routing class. The server accepts HTTP upgrade
+// requests from users authenticated with a Kubernetes token and forwards raw TCP traffic to workspace services.
+// A user may open a tunnel to a namespace only if they are allowed to port-forward to pods in that namespace.
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+	"github.com/devfile/devworkspace-operator/tunnel/client"
+)
+
+const dialTimeout = 10 * time.Second
+
+// Server handles requests for opening tunnels to workspace services.
+type Server struct {
+	// Client is used to authenticate and authorize users and to look up workspace services
+	Client kubernetes.Interface
+	// Dial is used to open connections to workspace services. If nil, connections are opened
+	// to the service's cluster DNS name.
+	Dial func(ctx context.Context, network, address string) (net.Conn, error)
+	Log  logr.Logger
+}
+
+var _ http.Handler = (*Server)(nil)
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	target, err := parseTunnelPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if !strings.EqualFold(r.Header.Get("Upgrade"), client.UpgradeProtocol) {
+		w.Header().Set("Upgrade", client.UpgradeProtocol)
+		http.Error(w, fmt.Sprintf("expected Upgrade: %s", client.UpgradeProtocol), http.StatusUpgradeRequired)
+		return
+	}
+	log := s.Log.WithValues("namespace", target.Namespace, "service", target.Service, "port", target.Port)
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" || token == r.Header.Get("Authorization") {
+		http.Error(w, "bearer token is required", http.StatusUnauthorized)
+		return
+	}
+	user, err := s.authenticate(r.Context(), token)
+	if err != nil {
+		log.Error(err, "Failed to authenticate user")
+		http.Error(w, "failed to authenticate", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+	log = log.WithValues("user", user.Username)
+
+	allowed, err := s.authorize(r.Context(), user, target.Namespace)
+	if err != nil {
+		log.Error(err, "Failed to authorize user")
+		http.Error(w, "failed to authorize", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		http.Error(w, fmt.Sprintf("user %s cannot port-forward in namespace %s", user.Username, target.Namespace), http.StatusForbidden)
+		return
+	}
+
+	if err := s.checkWorkspaceService(r.Context(), target); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	backend, err := s.dial(r.Context(), target)
+	if err != nil {
+		log.Error(err, "Failed to connect to workspace service")
+		http.Error(w, "failed to connect to workspace service", http.StatusBadGateway)
+		return
+	}
+	defer backend.Close()
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "connection does not support upgrade", http.StatusInternalServerError)
+		return
+	}
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		log.Error(err, "Failed to hijack connection")
+		return
+	}
+	defer conn.Close()
+
+	_, err = fmt.Fprintf(conn, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: %s\r\n\r\n", client.UpgradeProtocol)
+	if err != nil {
+		log.Error(err, "Failed to write upgrade response")
+		return
+	}
+	log.Info("Opened tunnel")
+	proxy(conn, buf, backend)
+	log.Info("Closed tunnel")
+}
+
+// authenticate resolves the user for a token via a TokenReview. If the token is not valid, nil is returned.
+func (s *Server) authenticate(ctx context.Context, token string) (*authenticationv1.UserInfo, error) {
+	review, err := s.Client.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if !review.Status.Authenticated {
+		return nil, nil
+	}
+	return &review.Status.User, nil
+}
+
+// authorize checks whether a user may port-forward to pods in a namespace, which is equivalent in access to
+// opening a tunnel to a workspace in that namespace.
+func (s *Server) authorize(ctx context.Context, user *authenticationv1.UserInfo, namespace string) (bool, error) {
+	extra := map[string]authorizationv1.ExtraValue{}
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	review, err := s.Client.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace:   namespace,
+				Verb:        "create",
+				Resource:    "pods",
+				Subresource: "portforward",
+			},
+			User:   user.Username,
+			Groups: user.Groups,
+			UID:    user.UID,
+			Extra:  extra,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
+
+// checkWorkspaceService verifies that the target service belongs to a DevWorkspace and exposes the target port,
+// to avoid the tunnel server being used to access arbitrary services.
+func (s *Server) checkWorkspaceService(ctx context.Context, target client.Target) error {
+	service, err := s.Client.CoreV1().Services(target.Namespace).Get(ctx, target.Service, metav1.GetOptions{})
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return fmt.Errorf("service %s not found in namespace %s", target.Service, target.Namespace)
+		}
+		return fmt.Errorf("failed to get service %s: %w", target.Service, err)
+	}
+	if _, ok := service.Labels[constants.DevWorkspaceIDLabel]; !ok {
+		return fmt.Errorf("service %s does not belong to a DevWorkspace", target.Service)
+	}
+	for _, port := range service.Spec.Ports {
+		if int(port.Port) == target.Port {
+			return nil
+		}
+	}
+	return fmt.Errorf("service %s does not expose port %d", target.Service, target.Port)
+}
+
+func (s *Server) dial(ctx context.Context, target client.Target) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+	address := net.JoinHostPort(fmt.Sprintf("%s.%s.svc", target.Service, target.Namespace), strconv.Itoa(target.Port))
+	if s.Dial != nil {
+		return s.Dial(ctx, "tcp", address)
+	}
+	dialer := &net.Dialer{}
+	return dialer.DialContext(ctx, "tcp", address)
+}
+
+// parseTunnelPath parses a path of the form /tunnel/<namespace>/<service>/<port>
+func parseTunnelPath(path string) (client.Target, error) {
+	if !strings.HasPrefix(path, client.TunnelPathPrefix) {
+		return client.Target{}, fmt.Errorf("not found")
+	}
+	parts := strings.Split(strings.TrimPrefix(path, client.TunnelPathPrefix), "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		return client.Target{}, fmt.Errorf("invalid tunnel path %q, expected %s<namespace>/<service>/<port>", path, client.TunnelPathPrefix)
+	}
+	port, err := strconv.Atoi(parts[2])
+	if err != nil || port <= 0 || port > 65535 {
+		return client.Target{}, fmt.Errorf("invalid port %q", parts[2])
+	}
+	return client.Target{
+		Namespace: parts[0],
+		Service:   parts[1],
+		Port:      port,
+	}, nil
+}
+
+// proxy copies data between the user's connection and the workspace service until either side closes its
+// connection. Data already buffered from the user's connection is forwarded first.
+func proxy(conn net.Conn, buffered io.Reader, backend net.Conn) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		io.Copy(backend, buffered)
+		closeWrite(backend)
+	}()
+	go func() {
+		defer wg.Done()
+		io.Copy(conn, backend)
+		closeWrite(conn)
+	}()
+	wg.Wait()
+}
+
+func closeWrite(conn net.Conn) {
+	if tcpConn, ok := conn.(interface{ CloseWrite() error }); ok {
+		tcpConn.CloseWrite()
+	} else {
+		conn.Close()
+	}
+}
@@ -0,0 +1,214 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/devfile/devworkspace-operator/pkg/constants"
+	"github.com/devfile/devworkspace-operator/tunnel/client"
+)
+
+const (
+	testToken     = "test-token"
+	testUser      = "test-user"
+	testNamespace = "test-namespace"
+)
+
+// fakeAPIServer implements the subset of the Kubernetes API used by the tunnel server: token reviews accept
+// only testToken, subject access reviews allow testUser in testNamespace only, and services are served
+// from the provided list. Handlers run on the server's goroutines, so failures are reported with t.Errorf.
+func fakeAPIServer(t *testing.T, services ...corev1.Service) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/apis/authentication.k8s.io/v1/tokenreviews", func(w http.ResponseWriter, r *http.Request) {
+		review := &authenticationv1.TokenReview{}
+		if err := json.NewDecoder(r.Body).Decode(review); err != nil {
+			t.Errorf("Failed to decode token review: %s", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if review.Spec.Token == testToken {
+			review.Status.Authenticated = true
+			review.Status.User = authenticationv1.UserInfo{Username: testUser}
+		}
+		writeJSON(t, w, review)
+	})
+	mux.HandleFunc("/apis/authorization.k8s.io/v1/subjectaccessreviews", func(w http.ResponseWriter, r *http.Request) {
+		review := &authorizationv1.SubjectAccessReview{}
+		if err := json.NewDecoder(r.Body).Decode(review); err != nil {
+			t.Errorf("Failed to decode subject access review: %s", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		attrs := review.Spec.ResourceAttributes
+		review.Status.Allowed = review.Spec.User == testUser && attrs.Namespace == testNamespace &&
+			attrs.Verb == "create" && attrs.Resource == "pods" && attrs.Subresource == "portforward"
+		writeJSON(t, w, review)
+	})
+	for _, service := range services {
+		service := service
+		mux.HandleFunc("/api/v1/namespaces/"+service.Namespace+"/services/"+service.Name, func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(t, w, &service)
+		})
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		writeJSON(t, w, &metav1.Status{Status: metav1.StatusFailure, Reason: metav1.StatusReasonNotFound, Code: http.StatusNotFound})
+	})
+	return httptest.NewServer(mux)
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(obj); err != nil {
+		t.Errorf("Failed to encode response: %s", err)
+	}
+}
+
+// echoServer starts a loopback TCP server that echoes a single line back to the sender.
+func echoServer(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start echo server: %s", err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				line, err := bufio.NewReader(conn).ReadString('\n')
+				if err != nil {
+					return
+				}
+				conn.Write([]byte(line))
+			}()
+		}
+	}()
+	return listener
+}
+
+func workspaceService(name string, port int32, labels map[string]string) corev1.Service {
+	return corev1.Service{
+		TypeMeta: metav1.TypeMeta{Kind: "Service", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: testNamespace,
+			Labels:    labels,
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Port: port}},
+		},
+	}
+}
+
+func TestTunnel(t *testing.T) {
+	tests := []struct {
+		name           string
+		token          string
+		tunnelURL      string
+		expectedStatus int
+	}{
+		{
+			name:      "Forwards traffic to workspace service",
+			token:     testToken,
+			tunnelURL: "tunnel://workspace-service.test-namespace:8080/",
+		},
+		{
+			name:           "Rejects invalid token",
+			token:          "invalid-token",
+			tunnelURL:      "tunnel://workspace-service.test-namespace:8080/",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Rejects user without port-forward access to namespace",
+			token:          testToken,
+			tunnelURL:      "tunnel://workspace-service.other-namespace:8080/",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Rejects service not belonging to a DevWorkspace",
+			token:          testToken,
+			tunnelURL:      "tunnel://other-service.test-namespace:8080/",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Rejects port not exposed by service",
+			token:          testToken,
+			tunnelURL:      "tunnel://workspace-service.test-namespace:9090/",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	apiServer := fakeAPIServer(t,
+		workspaceService("workspace-service", 8080, map[string]string{constants.DevWorkspaceIDLabel: "test-workspace-id"}),
+		workspaceService("other-service", 8080, nil))
+	defer apiServer.Close()
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: apiServer.URL})
+	if err != nil {
+		t.Fatalf("Failed to create client: %s", err)
+	}
+
+	echo := echoServer(t)
+	defer echo.Close()
+	tunnelServer := httptest.NewServer(&Server{
+		Client: clientset,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			dialer := &net.Dialer{}
+			return dialer.DialContext(ctx, network, echo.Addr().String())
+		},
+		Log: logf.Log.WithName("tunnel-test"),
+	})
+	defer tunnelServer.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := client.Dial(context.Background(), tunnelServer.URL, tt.token, tt.tunnelURL, nil)
+			if tt.expectedStatus != 0 {
+				var tunnelErr *client.TunnelError
+				if assert.Error(t, err, "Should return error") && assert.True(t, errors.As(err, &tunnelErr), "Should return TunnelError") {
+					assert.Equal(t, tt.expectedStatus, tunnelErr.StatusCode, "Should return expected status code")
+				}
+				return
+			}
+			if !assert.NoError(t, err, "Should open tunnel") {
+				return
+			}
+			defer conn.Close()
+			_, err = conn.Write([]byte("hello workspace\n"))
+			assert.NoError(t, err, "Should write to tunnel")
+			response, err := bufio.NewReader(conn).ReadString('\n')
+			assert.NoError(t, err, "Should read from tunnel")
+			assert.Equal(t, "hello workspace\n", response, "Should receive data echoed by workspace service")
+		})
+	}
+}
@@ -31,6 +31,7 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	clientconfig "sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -92,6 +93,17 @@ func main() {
 		os.Exit(1)
 	}
 
+	// The webhook server reads the controller's configuration once on startup, as it is not able to watch the config map
+	nonCachedClient, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		log.Error(err, "Failed to create client")
+		os.Exit(1)
+	}
+	if err := config.LoadControllerConfig(nonCachedClient); err != nil {
+		log.Error(err, "Failed to read controller configuration")
+		os.Exit(1)
+	}
+
 	err = createWebhooks(mgr)
 	if err != nil {
 		log.Error(err, "Failed to create webhooks")
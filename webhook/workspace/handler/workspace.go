@@ -12,11 +12,18 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 
+	"github.com/devfile/devworkspace-operator/controllers/workspace/provision"
 	maputils "github.com/devfile/devworkspace-operator/internal/map"
 	"github.com/devfile/devworkspace-operator/pkg/constants"
+	"github.com/devfile/devworkspace-operator/pkg/library/validation"
+	nsconfig "github.com/devfile/devworkspace-operator/pkg/provision/config"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	dwv1 "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha1"
 	dwv2 "github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
@@ -31,18 +38,31 @@ func (h *WebhookHandler) MutateWorkspaceV1alpha1OnCreate(_ context.Context, req
 	}
 
 	wksp.Labels = maputils.Append(wksp.Labels, constants.DevWorkspaceCreatorLabel, req.UserInfo.UID)
+	if err := setCreatorAnnotations(&wksp.ObjectMeta, req.UserInfo); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
 
 	return h.returnPatched(req, wksp)
 }
 
-func (h *WebhookHandler) MutateWorkspaceV1alpha2OnCreate(_ context.Context, req admission.Request) admission.Response {
+func (h *WebhookHandler) MutateWorkspaceV1alpha2OnCreate(ctx context.Context, req admission.Request) admission.Response {
 	wksp := &dwv2.DevWorkspace{}
 	err := h.Decoder.Decode(req, wksp)
 	if err != nil {
 		return admission.Errored(http.StatusBadRequest, err)
 	}
 
+	if problems := validation.ValidateDevWorkspace(&wksp.Spec.Template); problems != nil {
+		return admission.Denied(problems.Error())
+	}
+	if problems := h.checkContainerResourcePolicy(ctx, req.Namespace, &wksp.Spec.Template); problems != nil {
+		return admission.Denied(problems.Error())
+	}
+
 	wksp.Labels = maputils.Append(wksp.Labels, constants.DevWorkspaceCreatorLabel, req.UserInfo.UID)
+	if err := setCreatorAnnotations(&wksp.ObjectMeta, req.UserInfo); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
 
 	return h.returnPatched(req, wksp)
 }
@@ -58,6 +78,10 @@ func (h *WebhookHandler) MutateWorkspaceV1alpha1OnUpdate(_ context.Context, req
 	if !allowed {
 		return admission.Denied(msg)
 	}
+	modifiedAnnotations, err := mutateCreatorAnnotationsOnUpdate(&oldWksp.ObjectMeta, &newWksp.ObjectMeta)
+	if err != nil {
+		return admission.Denied(err.Error())
+	}
 
 	oldCreator, found := oldWksp.Labels[constants.DevWorkspaceCreatorLabel]
 	if !found {
@@ -74,10 +98,14 @@ func (h *WebhookHandler) MutateWorkspaceV1alpha1OnUpdate(_ context.Context, req
 		return admission.Denied(fmt.Sprintf("label '%s' is assigned once devworkspace is created and is immutable", constants.DevWorkspaceCreatorLabel))
 	}
 
+	if modifiedAnnotations {
+		return h.returnPatched(req, newWksp)
+	}
+
 	return admission.Allowed("new devworkspace has the same devworkspace creator as old one")
 }
 
-func (h *WebhookHandler) MutateWorkspaceV1alpha2OnUpdate(_ context.Context, req admission.Request) admission.Response {
+func (h *WebhookHandler) MutateWorkspaceV1alpha2OnUpdate(ctx context.Context, req admission.Request) admission.Response {
 	newWksp := &dwv2.DevWorkspace{}
 	oldWksp := &dwv2.DevWorkspace{}
 	err := h.parse(req, oldWksp, newWksp)
@@ -88,6 +116,21 @@ func (h *WebhookHandler) MutateWorkspaceV1alpha2OnUpdate(_ context.Context, req
 	if !allowed {
 		return admission.Denied(msg)
 	}
+	modifiedAnnotations, err := mutateCreatorAnnotationsOnUpdate(&oldWksp.ObjectMeta, &newWksp.ObjectMeta)
+	if err != nil {
+		return admission.Denied(err.Error())
+	}
+
+	// Only validate changes to the template, to avoid blocking updates (e.g. stopping or removing finalizers) for
+	// DevWorkspaces that were created before they were validated.
+	if !equality.Semantic.DeepEqual(oldWksp.Spec.Template, newWksp.Spec.Template) {
+		if problems := validation.ValidateDevWorkspace(&newWksp.Spec.Template); problems != nil {
+			return admission.Denied(problems.Error())
+		}
+		if problems := h.checkContainerResourcePolicy(ctx, req.Namespace, &newWksp.Spec.Template); problems != nil {
+			return admission.Denied(problems.Error())
+		}
+	}
 
 	oldCreator, found := oldWksp.Labels[constants.DevWorkspaceCreatorLabel]
 	if !found {
@@ -104,5 +147,51 @@ func (h *WebhookHandler) MutateWorkspaceV1alpha2OnUpdate(_ context.Context, req
 		return admission.Denied(fmt.Sprintf("label '%s' is assigned once devworkspace is created and is immutable", constants.DevWorkspaceCreatorLabel))
 	}
 
+	if modifiedAnnotations {
+		return h.returnPatched(req, newWksp)
+	}
+
 	return admission.Allowed("new workspace has the same devworkspace as old one")
 }
+
+// setCreatorAnnotations records the username and groups of the user creating a DevWorkspace, so that the controller can
+// check that they may create the objects defined by kubernetes and openshift components.
+func setCreatorAnnotations(meta *metav1.ObjectMeta, userInfo authenticationv1.UserInfo) error {
+	groups, err := json.Marshal(userInfo.Groups)
+	if err != nil {
+		return err
+	}
+	meta.Annotations = maputils.Append(meta.Annotations, constants.DevWorkspaceCreatorUsernameAnnotation, userInfo.Username)
+	meta.Annotations = maputils.Append(meta.Annotations, constants.DevWorkspaceCreatorGroupsAnnotation, string(groups))
+	return nil
+}
+
+// mutateCreatorAnnotationsOnUpdate ensures that the creator annotations set when a DevWorkspace is created are not
+// modified. Annotations that are removed are restored, in which case modified is true. DevWorkspaces created before the
+// annotations were introduced may not add them.
+func mutateCreatorAnnotationsOnUpdate(oldMeta, newMeta *metav1.ObjectMeta) (modified bool, err error) {
+	for _, annotation := range []string{constants.DevWorkspaceCreatorUsernameAnnotation, constants.DevWorkspaceCreatorGroupsAnnotation} {
+		oldValue, oldFound := oldMeta.Annotations[annotation]
+		newValue, newFound := newMeta.Annotations[annotation]
+		switch {
+		case oldFound && !newFound:
+			newMeta.Annotations = maputils.Append(newMeta.Annotations, annotation, oldValue)
+			modified = true
+		case newValue != oldValue:
+			return false, fmt.Errorf("annotation '%s' is assigned once devworkspace is created and is immutable", annotation)
+		}
+	}
+	return modified, nil
+}
+
+// checkContainerResourcePolicy checks the container resources of a DevWorkspace against the resource policy for its
+// namespace, i.e. the controller's policy merged with the namespace's configuration. If the policy cannot be read, the
+// DevWorkspace is not checked, and the problem is reported by the controller instead.
+func (h *WebhookHandler) checkContainerResourcePolicy(ctx context.Context, namespace string, workspace *dwv2.DevWorkspaceTemplateSpec) validation.Problems {
+	policy, err := nsconfig.GetContainerResourcePolicy(namespace, provision.ClusterAPI{Client: h.Client, Ctx: ctx})
+	if err != nil {
+		log.Error(err, "Failed to read container resource configuration; skipping container resource checks", "namespace", namespace)
+		return nil
+	}
+	return validation.CheckContainerResourcePolicy(workspace, policy)
+}